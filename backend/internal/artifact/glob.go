@@ -0,0 +1,98 @@
+/**
+ * 产物路径匹配
+ * 在目录下按glob模式查找文件，模式中的 ** 匹配任意层级目录
+ */
+
+package artifact
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ValidatePattern 检查模式是否合法：必须是相对路径且不能跳出根目录
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty artifact pattern")
+	}
+	if path.IsAbs(pattern) || filepath.IsAbs(pattern) {
+		return fmt.Errorf("artifact pattern must be relative: %s", pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return fmt.Errorf("artifact pattern must not leave the working directory: %s", pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid artifact pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Glob 返回root下匹配任一模式的普通文件，路径相对root并使用'/'分隔
+func Glob(root string, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range patterns {
+			if Match(pattern, rel) {
+				matches = append(matches, rel)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Match 判断以'/'分隔的路径是否匹配模式
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// ** 匹配零个或多个目录
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,161 @@
+/**
+ * 本地磁盘产物存储
+ * 内容保存在 <root>/<摘要前两位>/<摘要>，写入先落到临时文件再原子重命名
+ */
+
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// recentGrace 最近写入的内容不会被删除，避免误删尚未结束的执行刚保存的同内容产物
+const recentGrace = 24 * time.Hour
+
+// LocalStore 本地磁盘产物存储
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore 创建以root为根目录的本地存储
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+// Put 保存内容
+func (s *LocalStore) Put(ctx context.Context, r io.Reader) (Blob, error) {
+	tmpDir := filepath.Join(s.root, "tmp")
+	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return Blob{}, err
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Blob{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	blob := Blob{Checksum: hex.EncodeToString(hash.Sum(nil)), Size: size}
+	path := s.path(blob.Checksum)
+	if _, err := os.Stat(path); err == nil {
+		// 内容已存在，刷新修改时间以延后删除
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return blob, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Blob{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+// Open 读取内容
+func (s *LocalStore) Open(ctx context.Context, checksum string) (io.ReadCloser, error) {
+	if !validChecksum(checksum) {
+		return nil, ErrNotFound
+	}
+	file, err := os.Open(s.path(checksum))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete 删除内容，最近写入过的内容会保留
+func (s *LocalStore) Delete(ctx context.Context, checksum string) error {
+	if !validChecksum(checksum) {
+		return nil
+	}
+	path := s.path(checksum)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(info.ModTime()) < recentGrace {
+		return nil
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List 列出所有内容的地址
+func (s *LocalStore) List(ctx context.Context) ([]string, error) {
+	dirs, err := os.ReadDir(s.root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.root, dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if validChecksum(entry.Name()) {
+				checksums = append(checksums, entry.Name())
+			}
+		}
+	}
+	return checksums, nil
+}
+
+func (s *LocalStore) path(checksum string) string {
+	return filepath.Join(s.root, checksum[:2], checksum)
+}
+
+// validChecksum 校验地址格式，避免拼出根目录之外的路径
+func validChecksum(checksum string) bool {
+	if len(checksum) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(checksum)
+	return err == nil
+}
+
+// contextReader 在上下文取消后中止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,185 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checksumOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func put(t *testing.T, store *LocalStore, content string) Blob {
+	t.Helper()
+	blob, err := store.Put(context.Background(), strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	return blob
+}
+
+// age 把内容的修改时间调到d之前
+func age(t *testing.T, store *LocalStore, checksum string, d time.Duration) {
+	t.Helper()
+	old := time.Now().Add(-d)
+	if err := os.Chtimes(store.path(checksum), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStorePutDedup(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root)
+
+	first := put(t, store, "report contents")
+	second := put(t, store, "report contents")
+	other := put(t, store, "other contents")
+
+	want := Blob{Checksum: checksumOf("report contents"), Size: int64(len("report contents"))}
+	if first != want || second != want {
+		t.Errorf("got %+v and %+v, want %+v", first, second, want)
+	}
+	if other.Checksum == first.Checksum {
+		t.Error("different content got the same checksum")
+	}
+
+	if _, err := os.Stat(filepath.Join(root, want.Checksum[:2], want.Checksum)); err != nil {
+		t.Errorf("content not stored at its address: %v", err)
+	}
+	tmp, err := os.ReadDir(filepath.Join(root, "tmp"))
+	if err != nil || len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v, %v", tmp, err)
+	}
+
+	checksums, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(checksums)
+	expected := []string{first.Checksum, other.Checksum}
+	sort.Strings(expected)
+	if strings.Join(checksums, ",") != strings.Join(expected, ",") {
+		t.Errorf("List: got %v, want %v", checksums, expected)
+	}
+}
+
+func TestLocalStoreOpen(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	blob := put(t, store, "hello")
+
+	reader, err := store.Open(context.Background(), blob.Checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("got %q, %v", data, err)
+	}
+
+	for _, checksum := range []string{checksumOf("missing"), "../../etc/passwd", "", strings.Repeat("z", 64)} {
+		if _, err := store.Open(context.Background(), checksum); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q): got %v, want ErrNotFound", checksum, err)
+		}
+	}
+}
+
+func TestLocalStoreDeleteGrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		reput   bool // 删除前再次保存相同内容
+		deleted bool
+	}{
+		{name: "just written", age: 0, deleted: false},
+		{name: "inside grace period", age: recentGrace - time.Hour, deleted: false},
+		{name: "past grace period", age: recentGrace + time.Hour, deleted: true},
+		{name: "saved again refreshes grace period", age: recentGrace + time.Hour, reput: true, deleted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewLocalStore(t.TempDir())
+			blob := put(t, store, "artifact")
+			age(t, store, blob.Checksum, tt.age)
+			if tt.reput {
+				put(t, store, "artifact")
+			}
+
+			if err := store.Delete(context.Background(), blob.Checksum); err != nil {
+				t.Fatal(err)
+			}
+			_, err := store.Open(context.Background(), blob.Checksum)
+			if deleted := errors.Is(err, ErrNotFound); deleted != tt.deleted {
+				t.Errorf("deleted = %v, want %v (open error: %v)", deleted, tt.deleted, err)
+			}
+		})
+	}
+}
+
+func TestLocalStoreDeleteMissing(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	for _, checksum := range []string{checksumOf("missing"), "../outside", ""} {
+		if err := store.Delete(context.Background(), checksum); err != nil {
+			t.Errorf("Delete(%q): %v", checksum, err)
+		}
+	}
+}
+
+func TestLocalStoreList(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(filepath.Join(root, "artifacts"))
+
+	checksums, err := store.List(context.Background())
+	if err != nil || checksums != nil {
+		t.Errorf("missing root: got %v, %v", checksums, err)
+	}
+
+	blob := put(t, store, "content")
+	// 临时目录、非地址文件和其他目录不计入
+	if err := os.WriteFile(filepath.Join(root, "artifacts", "tmp", "upload-1"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "artifacts", blob.Checksum[:2], "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "artifacts", "backup"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, err = store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums) != 1 || checksums[0] != blob.Checksum {
+		t.Errorf("got %v, want [%s]", checksums, blob.Checksum)
+	}
+}
+
+func TestLocalStorePutCanceled(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.Put(ctx, strings.NewReader("content")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	checksums, err := store.List(context.Background())
+	if err != nil || len(checksums) != 0 {
+		t.Errorf("canceled upload was stored: %v, %v", checksums, err)
+	}
+	tmp, _ := os.ReadDir(filepath.Join(root, "tmp"))
+	if len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+}
@@ -0,0 +1,33 @@
+/**
+ * 执行产物存储
+ * 按内容寻址(SHA-256)保存执行产物，相同内容只保存一份
+ */
+
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound 产物内容不存在
+var ErrNotFound = errors.New("artifact not found")
+
+// Blob 已保存的产物内容
+type Blob struct {
+	Checksum string // SHA-256十六进制摘要，同时作为内容地址
+	Size     int64
+}
+
+// Store 产物存储接口
+type Store interface {
+	// Put 保存内容并返回其地址，内容已存在时不会重复保存
+	Put(ctx context.Context, r io.Reader) (Blob, error)
+	// Open 按地址读取内容，不存在时返回ErrNotFound
+	Open(ctx context.Context, checksum string) (io.ReadCloser, error)
+	// Delete 删除内容，不存在时不报错
+	Delete(ctx context.Context, checksum string) error
+	// List 列出所有内容的地址
+	List(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,325 @@
+/**
+ * 备份归档
+ * 归档为tar.gz：manifest.json在最前，随后是目录文件(files/)和集合导出(mongo/<集合>.jsonl)，
+ * 最后是所有条目的SHA256SUMS
+ */
+
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"aischedule/internal/models"
+)
+
+const (
+	manifestName  = "manifest.json"
+	checksumsName = "SHA256SUMS"
+	filesPrefix   = "files/"
+	mongoPrefix   = "mongo/"
+
+	// manifestVersion 归档格式版本
+	manifestVersion = 1
+)
+
+// Manifest 归档清单
+type Manifest struct {
+	Version     int         `json:"version"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Paths       []PathEntry `json:"paths,omitempty"`
+	Database    string      `json:"database,omitempty"`
+	Collections []string    `json:"collections,omitempty"`
+}
+
+// PathEntry 备份目录在归档中的位置
+type PathEntry struct {
+	Source string `json:"source"` // 原始路径
+	Dir    string `json:"dir"`    // 归档中 files/ 下的目录名
+}
+
+// Stats 写入归档的统计
+type Stats struct {
+	Files       int
+	Collections []models.BackupCollection
+}
+
+// Write 把数据来源写为tar.gz归档
+func Write(ctx context.Context, w io.Writer, db *mongo.Database, sources models.BackupSources) (*Stats, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	aw := &archiveWriter{ctx: ctx, tw: tw, sums: make(map[string]string)}
+
+	manifest := Manifest{
+		Version:     manifestVersion,
+		CreatedAt:   time.Now(),
+		Database:    sources.Database,
+		Collections: sources.Collections,
+	}
+	used := make(map[string]bool)
+	for i, source := range sources.Paths {
+		dir := filepath.Base(source)
+		if used[dir] {
+			dir = fmt.Sprintf("%s_%d", dir, i)
+		}
+		used[dir] = true
+		manifest.Paths = append(manifest.Paths, PathEntry{Source: source, Dir: dir})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.writeBytes(manifestName, data, false); err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	for _, entry := range manifest.Paths {
+		count, err := aw.writeDir(entry.Source, filesPrefix+entry.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %w", entry.Source, err)
+		}
+		stats.Files += count
+	}
+	for _, name := range sources.Collections {
+		count, err := aw.writeCollection(db.Collection(name), mongoPrefix+name+".jsonl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to export collection %s: %w", name, err)
+		}
+		stats.Collections = append(stats.Collections, models.BackupCollection{Name: name, Documents: count})
+	}
+
+	if err := aw.writeBytes(checksumsName, aw.checksums(), false); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// archiveWriter 写入归档条目并记录摘要
+type archiveWriter struct {
+	ctx  context.Context
+	tw   *tar.Writer
+	sums map[string]string
+}
+
+// writeBytes 写入内存中的条目
+func (aw *archiveWriter) writeBytes(name string, data []byte, record bool) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := aw.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := aw.tw.Write(data); err != nil {
+		return err
+	}
+	if record {
+		sum := sha256.Sum256(data)
+		aw.sums[name] = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+// writeReader 写入指定大小的条目
+func (aw *archiveWriter) writeReader(header *tar.Header, r io.Reader) error {
+	if err := aw.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(aw.tw, hash), &contextReader{ctx: aw.ctx, r: r}); err != nil {
+		return err
+	}
+	aw.sums[header.Name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// writeDir 写入目录下的所有文件，返回文件数。符号链接按链接本身保存，其他特殊文件被忽略
+func (aw *archiveWriter) writeDir(root, prefix string) (int, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("%s is not a directory", root)
+	}
+
+	count := 0
+	err = filepath.WalkDir(root, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := aw.ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, current)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = name + "/"
+			return aw.tw.WriteHeader(header)
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(current)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, target)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			return aw.tw.WriteHeader(header)
+		case info.Mode().IsRegular():
+			file, err := os.Open(current)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if err := aw.writeReader(header, io.LimitReader(file, info.Size())); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// writeCollection 把集合导出为每行一个文档的Extended JSON，返回文档数
+func (aw *archiveWriter) writeCollection(collection *mongo.Collection, name string) (int64, error) {
+	// tar条目需要预先知道大小，先导出到临时文件
+	tmp, err := os.CreateTemp("", "backup-*.jsonl")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cursor, err := collection.Find(aw.ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(aw.ctx)
+
+	buffered := bufio.NewWriter(tmp)
+	var count int64
+	for cursor.Next(aw.ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return 0, err
+		}
+		buffered.Write(line)
+		if err := buffered.WriteByte('\n'); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return 0, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	return count, aw.writeReader(header, tmp)
+}
+
+// checksums 生成SHA256SUMS内容
+func (aw *archiveWriter) checksums() []byte {
+	names := make([]string, 0, len(aw.sums))
+	for name := range aw.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", aw.sums[name], name)
+	}
+	return []byte(b.String())
+}
+
+// parseChecksums 解析SHA256SUMS内容
+func parseChecksums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s line: %q", checksumsName, line)
+		}
+		sums[name] = sum
+	}
+	return sums, nil
+}
+
+// contextReader 在上下文取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,332 @@
+/**
+ * 备份校验与恢复
+ * 校验把归档试恢复到临时目录并核对摘要；恢复把文件解压到目标目录、把集合导入目标数据库
+ */
+
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"aischedule/internal/models"
+)
+
+const (
+	// restoreBatchSize 导入集合时每批插入的文档数
+	restoreBatchSize = 500
+	// maxDocumentLine 导出文件中单个文档的最大长度
+	maxDocumentLine = 32 << 20
+)
+
+// ErrNotEmpty 恢复目标已有数据
+var ErrNotEmpty = errors.New("restore target is not empty")
+
+// Verify 把归档试恢复到临时目录：文件写入磁盘，文档逐条解析，并核对所有条目的摘要
+func Verify(ctx context.Context, r io.Reader) error {
+	tmp, err := os.MkdirTemp("", "backup-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	_, err = restore(ctx, r, restoreTarget{dir: tmp})
+	return err
+}
+
+// Restore 恢复归档：targetDir为空时不恢复文件，db为nil时不恢复集合
+func Restore(ctx context.Context, r io.Reader, targetDir string, db *mongo.Database, overwrite bool) (*models.RestoreBackupResult, error) {
+	if targetDir == "" && db == nil {
+		return nil, fmt.Errorf("restore target not specified")
+	}
+	return restore(ctx, r, restoreTarget{dir: targetDir, db: db, overwrite: overwrite, check: true})
+}
+
+// restoreTarget 恢复目标
+type restoreTarget struct {
+	dir       string
+	db        *mongo.Database
+	overwrite bool
+	check     bool // 写入前检查目标是否已有数据
+}
+
+// restore 读取归档并写入目标，最后核对摘要
+func restore(ctx context.Context, r io.Reader, target restoreTarget) (*models.RestoreBackupResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	result := &models.RestoreBackupResult{Collections: []models.BackupCollection{}}
+	actual := make(map[string]string)
+	var expected map[string]string
+	var manifest *Manifest
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := header.Name
+		if manifest == nil && name != manifestName {
+			return nil, fmt.Errorf("invalid backup archive: %s must be the first entry", manifestName)
+		}
+
+		switch {
+		case name == manifestName:
+			if manifest, err = readManifest(tr); err != nil {
+				return nil, err
+			}
+			if err := target.prepare(ctx, manifest); err != nil {
+				return nil, err
+			}
+		case name == checksumsName:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if expected, err = parseChecksums(data); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(name, filesPrefix):
+			if target.dir == "" {
+				continue
+			}
+			sum, err := extractEntry(target.dir, strings.TrimPrefix(name, filesPrefix), header, tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore %s: %w", name, err)
+			}
+			if header.Typeflag == tar.TypeReg {
+				actual[name] = sum
+				result.Files++
+			}
+		case strings.HasPrefix(name, mongoPrefix):
+			if target.db == nil && target.check {
+				continue
+			}
+			collection := strings.TrimSuffix(strings.TrimPrefix(name, mongoPrefix), ".jsonl")
+			count, sum, err := importCollection(ctx, target.db, collection, tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore collection %s: %w", collection, err)
+			}
+			actual[name] = sum
+			result.Collections = append(result.Collections, models.BackupCollection{Name: collection, Documents: count})
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("invalid backup archive: missing %s", manifestName)
+	}
+	if expected == nil {
+		return nil, fmt.Errorf("invalid backup archive: missing %s", checksumsName)
+	}
+	for name, sum := range actual {
+		if expected[name] != sum {
+			return nil, fmt.Errorf("checksum mismatch for %s", name)
+		}
+	}
+	for name := range expected {
+		if _, ok := actual[name]; ok {
+			continue
+		}
+		restored := (strings.HasPrefix(name, filesPrefix) && target.dir != "") ||
+			(strings.HasPrefix(name, mongoPrefix) && (target.db != nil || !target.check))
+		if restored {
+			return nil, fmt.Errorf("entry %s missing from archive", name)
+		}
+	}
+	return result, nil
+}
+
+// prepare 检查并清理恢复目标
+func (t restoreTarget) prepare(ctx context.Context, manifest *Manifest) error {
+	if !t.check {
+		return nil
+	}
+	if t.dir != "" && !t.overwrite {
+		entries, err := os.ReadDir(t.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: directory %s", ErrNotEmpty, t.dir)
+		}
+	}
+	if t.db == nil {
+		return nil
+	}
+	for _, name := range manifest.Collections {
+		collection := t.db.Collection(name)
+		if t.overwrite {
+			if err := collection.Drop(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		count, err := collection.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: collection %s.%s", ErrNotEmpty, t.db.Name(), name)
+		}
+	}
+	return nil
+}
+
+// readManifest 解析归档清单
+func readManifest(r io.Reader) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// extractEntry 把条目解压到根目录下，返回普通文件的摘要
+func extractEntry(root, name string, header *tar.Header, r io.Reader) (string, error) {
+	dest, err := safeJoin(root, name)
+	if err != nil {
+		return "", err
+	}
+	mode := os.FileMode(header.Mode).Perm()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return "", os.MkdirAll(dest, mode|0700)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		os.Remove(dest)
+		return "", os.Symlink(header.Linkname, dest)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		file, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return "", err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(file, hash), r)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		os.Chtimes(dest, header.ModTime, header.ModTime)
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	default:
+		return "", nil
+	}
+}
+
+// safeJoin 拼接路径，拒绝逃出根目录的条目（包括经由已解压的符号链接）
+func safeJoin(root, name string) (string, error) {
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid entry name %q", name)
+	}
+	dest := filepath.Join(root, filepath.FromSlash(cleaned))
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if realRoot, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = realRoot
+	}
+	// 检查已存在的最深一级父目录
+	parent := filepath.Dir(dest)
+	for {
+		if real, err := filepath.EvalSymlinks(parent); err == nil {
+			if real != absRoot && !strings.HasPrefix(real, absRoot+string(filepath.Separator)) {
+				return "", fmt.Errorf("entry %q escapes the restore directory", name)
+			}
+			break
+		}
+		next := filepath.Dir(parent)
+		if next == parent {
+			break
+		}
+		parent = next
+	}
+	return dest, nil
+}
+
+// importCollection 解析导出的文档并插入集合，db为nil时只解析；返回文档数和摘要
+func importCollection(ctx context.Context, db *mongo.Database, name string, r io.Reader) (int64, string, error) {
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(r, hash))
+	scanner.Buffer(make([]byte, 64*1024), maxDocumentLine)
+
+	var count int64
+	batch := make([]interface{}, 0, restoreBatchSize)
+	flush := func() error {
+		if db == nil || len(batch) == 0 {
+			batch = batch[:0]
+			return nil
+		}
+		_, err := db.Collection(name).InsertMany(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(line, true, &doc); err != nil {
+			return 0, "", fmt.Errorf("document %d: %w", count+1, err)
+		}
+		batch = append(batch, doc)
+		count++
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return 0, "", err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", err
+	}
+	if err := flush(); err != nil {
+		return 0, "", err
+	}
+	// 读完剩余内容，保证摘要覆盖整个条目
+	if _, err := io.Copy(hash, r); err != nil {
+		return 0, "", err
+	}
+	return count, hex.EncodeToString(hash.Sum(nil)), nil
+}
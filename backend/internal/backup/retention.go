@@ -0,0 +1,61 @@
+/**
+ * 备份保留策略
+ * 按保留最近N个、每天、每周三类规则选出需要保留的备份，其余视为过期
+ */
+
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+// Expired 返回按策略应删除的备份；策略全部为0时不删除任何备份
+func Expired(backups []models.Backup, policy models.BackupRetention) []models.Backup {
+	if policy.KeepLast <= 0 && policy.Daily <= 0 && policy.Weekly <= 0 {
+		return nil
+	}
+
+	sorted := make([]models.Backup, len(backups))
+	copy(sorted, backups)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	keep := make(map[int]bool)
+	for i := 0; i < policy.KeepLast && i < len(sorted); i++ {
+		keep[i] = true
+	}
+	keepPerPeriod(sorted, policy.Daily, keep, func(t time.Time) string {
+		return t.Local().Format("2006-01-02")
+	})
+	keepPerPeriod(sorted, policy.Weekly, keep, func(t time.Time) string {
+		year, week := t.Local().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+
+	var expired []models.Backup
+	for i, item := range sorted {
+		if !keep[i] {
+			expired = append(expired, item)
+		}
+	}
+	return expired
+}
+
+// keepPerPeriod 在最近的count个有备份的周期中各保留最新的一个备份（备份已按时间倒序排列）
+func keepPerPeriod(sorted []models.Backup, count int, keep map[int]bool, period func(time.Time) string) {
+	seen := make(map[string]bool)
+	for i, item := range sorted {
+		if len(seen) >= count {
+			return
+		}
+		key := period(item.CreatedAt)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keep[i] = true
+	}
+}
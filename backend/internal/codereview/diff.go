@@ -0,0 +1,169 @@
+/**
+ * 差异切分
+ * 把统一格式的diff按文件拆开，再按大小合并为发送给模型的分块
+ */
+
+package codereview
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileDiff 单个文件的差异
+type FileDiff struct {
+	Path   string // 新版本的路径，删除的文件为旧路径
+	Patch  string // 包含diff --git头的完整片段
+	Binary bool
+}
+
+// Chunk 发送给模型的一个分块
+type Chunk struct {
+	Files []string
+	Patch string
+}
+
+// SplitDiff 把git diff的输出按文件拆开
+func SplitDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var current *FileDiff
+	var patch strings.Builder
+
+	flush := func() {
+		if current != nil {
+			current.Patch = patch.String()
+			files = append(files, *current)
+		}
+		patch.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			current = &FileDiff{Path: headerPath(line)}
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ b/"):
+			current.Path = strings.TrimSpace(strings.TrimPrefix(line, "+++ b/"))
+		case strings.HasPrefix(line, "Binary files "), strings.HasPrefix(line, "GIT binary patch"):
+			current.Binary = true
+		}
+		patch.WriteString(line)
+	}
+	flush()
+	return files
+}
+
+// NewFileDiff 把整个文件内容构造为新增文件的差异，用于不在git仓库中的文件
+func NewFileDiff(path, content string) FileDiff {
+	var patch strings.Builder
+	lines := strings.SplitAfter(content, "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	fmt.Fprintf(&patch, "diff --git a/%s b/%s\n--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, path, path, len(lines))
+	for _, line := range lines {
+		patch.WriteString("+")
+		patch.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			patch.WriteString("\n")
+		}
+	}
+	return FileDiff{Path: path, Patch: patch.String()}
+}
+
+// Chunks 把文件差异合并为不超过maxSize字节的分块，二进制文件被跳过。
+// 超过maxSize的文件按hunk拆开，单个hunk仍然超过时截断
+func Chunks(files []FileDiff, maxSize int) []Chunk {
+	var chunks []Chunk
+	var current Chunk
+
+	flush := func() {
+		if current.Patch != "" {
+			chunks = append(chunks, current)
+		}
+		current = Chunk{}
+	}
+	add := func(path, patch string) {
+		if len(current.Patch)+len(patch) > maxSize {
+			flush()
+		}
+		if len(current.Files) == 0 || current.Files[len(current.Files)-1] != path {
+			current.Files = append(current.Files, path)
+		}
+		current.Patch += patch
+	}
+
+	for _, file := range files {
+		if file.Binary {
+			continue
+		}
+		if len(file.Patch) <= maxSize {
+			add(file.Path, file.Patch)
+			continue
+		}
+
+		// 每个hunk前重复文件头，模型才能知道hunk属于哪个文件
+		header, hunks := splitHunks(file.Patch)
+		for _, hunk := range hunks {
+			part := header + hunk
+			if len(part) > maxSize {
+				part = truncate(part, maxSize)
+			}
+			add(file.Path, part)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitHunks 把单个文件的差异拆为文件头和各个hunk
+func splitHunks(patch string) (string, []string) {
+	var header strings.Builder
+	var hunks []string
+	var hunk strings.Builder
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if hunk.Len() > 0 {
+				hunks = append(hunks, hunk.String())
+				hunk.Reset()
+			}
+			hunk.WriteString(line)
+			continue
+		}
+		if hunk.Len() > 0 {
+			hunk.WriteString(line)
+		} else {
+			header.WriteString(line)
+		}
+	}
+	if hunk.Len() > 0 {
+		hunks = append(hunks, hunk.String())
+	}
+	return header.String(), hunks
+}
+
+// truncate 在行边界截断到不超过maxSize字节
+func truncate(patch string, maxSize int) string {
+	const marker = "\n... (truncated)\n"
+	cut := maxSize - len(marker)
+	if cut < 0 {
+		cut = 0
+	}
+	if i := strings.LastIndexByte(patch[:cut], '\n'); i >= 0 {
+		cut = i
+	}
+	return patch[:cut] + marker
+}
+
+// headerPath 从diff --git a/x b/x头中取出新路径
+func headerPath(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if i := strings.Index(line, " b/"); i >= 0 {
+		return line[i+len(" b/"):]
+	}
+	return line
+}
@@ -0,0 +1,198 @@
+/**
+ * 审查结果解析
+ * 定义发送给模型的提示词，并把模型的回复解析为结构化的问题
+ */
+
+package codereview
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+// SystemPrompt 要求模型以JSON返回问题的系统提示词
+const SystemPrompt = `You are a meticulous code reviewer. Review the unified diff you are given and report real problems in the changed code: bugs, security issues, race conditions, error handling mistakes, performance problems and unclear code.
+Only comment on added or modified lines. Use line numbers from the new version of the file.
+Respond with JSON only, no prose, in exactly this shape:
+{"findings": [{"file": "path/in/repo", "line": 42, "severity": "error|warning|info", "rule": "bug|security|performance|style|other", "message": "what is wrong and how to fix it"}]}
+Return {"findings": []} when there is nothing to report.`
+
+// Prompt 构建单个分块的用户提示词
+func Prompt(instructions string, chunk Chunk) string {
+	var prompt strings.Builder
+	if instructions != "" {
+		prompt.WriteString(instructions)
+		prompt.WriteString("\n\n")
+	}
+	fmt.Fprintf(&prompt, "Files: %s\n\n```diff\n%s```\n", strings.Join(chunk.Files, ", "), chunk.Patch)
+	return prompt.String()
+}
+
+// ParseFindings 从模型回复中解析问题，兼容代码块包裹和直接返回数组
+func ParseFindings(text string) ([]models.ReviewFinding, error) {
+	body := extractJSON(text)
+	if body == "" {
+		return nil, fmt.Errorf("no JSON found in model response")
+	}
+
+	var raw []struct {
+		File     string          `json:"file"`
+		Line     json.RawMessage `json:"line"`
+		Severity string          `json:"severity"`
+		Rule     string          `json:"rule"`
+		Message  string          `json:"message"`
+	}
+	if strings.HasPrefix(body, "[") {
+		if err := json.Unmarshal([]byte(body), &raw); err != nil {
+			return nil, fmt.Errorf("invalid findings: %w", err)
+		}
+	} else {
+		var wrapper struct {
+			Findings json.RawMessage `json:"findings"`
+		}
+		if err := json.Unmarshal([]byte(body), &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid findings: %w", err)
+		}
+		if len(wrapper.Findings) > 0 && string(wrapper.Findings) != "null" {
+			if err := json.Unmarshal(wrapper.Findings, &raw); err != nil {
+				return nil, fmt.Errorf("invalid findings: %w", err)
+			}
+		}
+	}
+
+	findings := []models.ReviewFinding{}
+	for _, item := range raw {
+		message := strings.TrimSpace(item.Message)
+		if message == "" {
+			continue
+		}
+		findings = append(findings, models.ReviewFinding{
+			File:     normalizePath(item.File),
+			Line:     parseLine(item.Line),
+			Severity: NormalizeSeverity(item.Severity),
+			Rule:     strings.ToLower(strings.TrimSpace(item.Rule)),
+			Message:  message,
+		})
+	}
+	return findings, nil
+}
+
+// NormalizeSeverity 把模型返回的各种严重程度归一为error、warning、info
+func NormalizeSeverity(severity string) models.ReviewSeverity {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "error", "critical", "high", "blocker", "major":
+		return models.ReviewSeverityError
+	case "info", "note", "low", "minor", "suggestion", "nit":
+		return models.ReviewSeverityInfo
+	default:
+		return models.ReviewSeverityWarning
+	}
+}
+
+// ValidateSeverity 校验严重程度参数
+func ValidateSeverity(severity string) error {
+	switch models.ReviewSeverity(severity) {
+	case models.ReviewSeverityError, models.ReviewSeverityWarning, models.ReviewSeverityInfo:
+		return nil
+	}
+	return fmt.Errorf("unsupported severity: %s", severity)
+}
+
+// AtLeast 判断severity是否不低于threshold
+func AtLeast(severity, threshold models.ReviewSeverity) bool {
+	return rank(severity) >= rank(threshold)
+}
+
+// Summarize 排序问题并统计各严重程度的数量
+func Summarize(review *models.CodeReview) {
+	sort.SliceStable(review.Findings, func(i, j int) bool {
+		a, b := review.Findings[i], review.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	review.Errors, review.Warnings, review.Infos = 0, 0, 0
+	for _, finding := range review.Findings {
+		switch finding.Severity {
+		case models.ReviewSeverityError:
+			review.Errors++
+		case models.ReviewSeverityWarning:
+			review.Warnings++
+		default:
+			review.Infos++
+		}
+	}
+}
+
+func rank(severity models.ReviewSeverity) int {
+	switch severity {
+	case models.ReviewSeverityError:
+		return 3
+	case models.ReviewSeverityWarning:
+		return 2
+	case models.ReviewSeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// extractJSON 取出回复中的JSON：优先使用```代码块，否则取第一个{或[到与之对应的最后一个括号
+func extractJSON(text string) string {
+	if start := strings.Index(text, "```"); start >= 0 {
+		rest := text[start+3:]
+		if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+			rest = rest[nl+1:]
+		}
+		if end := strings.Index(rest, "```"); end >= 0 {
+			text = rest[:end]
+		}
+	}
+	text = strings.TrimSpace(text)
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
+// normalizePath 去掉diff中的a/、b/前缀
+func normalizePath(path string) string {
+	path = strings.TrimSpace(path)
+	for _, prefix := range []string{"a/", "b/", "./"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// parseLine 解析行号，兼容数字和字符串（如"42"、"42-45"）
+func parseLine(raw json.RawMessage) int {
+	var line int
+	if json.Unmarshal(raw, &line) == nil {
+		if line < 0 {
+			return 0
+		}
+		return line
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		fmt.Sscanf(strings.TrimSpace(text), "%d", &line)
+		if line < 0 {
+			return 0
+		}
+	}
+	return line
+}
@@ -0,0 +1,135 @@
+/**
+ * SARIF导出
+ * 把审查结果转换为SARIF 2.1.0格式，供IDE和代码扫描平台展示
+ */
+
+package codereview
+
+import (
+	"sort"
+
+	"aischedule/internal/models"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	// defaultRule 模型没有给出类别时使用的规则ID
+	defaultRule = "review"
+)
+
+// SARIF日志结构，只包含用到的字段
+type (
+	SARIFLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []SARIFRun `json:"runs"`
+	}
+
+	SARIFRun struct {
+		Tool    SARIFTool     `json:"tool"`
+		Results []SARIFResult `json:"results"`
+		// 审查的提交范围，便于把结果关联到版本
+		Properties map[string]interface{} `json:"properties,omitempty"`
+	}
+
+	SARIFTool struct {
+		Driver SARIFDriver `json:"driver"`
+	}
+
+	SARIFDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri,omitempty"`
+		Rules          []SARIFRule `json:"rules"`
+	}
+
+	SARIFRule struct {
+		ID               string       `json:"id"`
+		ShortDescription SARIFMessage `json:"shortDescription"`
+	}
+
+	SARIFResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   SARIFMessage    `json:"message"`
+		Locations []SARIFLocation `json:"locations"`
+	}
+
+	SARIFMessage struct {
+		Text string `json:"text"`
+	}
+
+	SARIFLocation struct {
+		PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+	}
+
+	SARIFPhysicalLocation struct {
+		ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+		Region           *SARIFRegion          `json:"region,omitempty"`
+	}
+
+	SARIFArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	SARIFRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// SARIF 把审查结果转换为SARIF日志
+func SARIF(review *models.CodeReview, toolName string) *SARIFLog {
+	run := SARIFRun{
+		Tool:    SARIFTool{Driver: SARIFDriver{Name: toolName, Rules: []SARIFRule{}}},
+		Results: []SARIFResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, finding := range review.Findings {
+		rule := finding.Rule
+		if rule == "" {
+			rule = defaultRule
+		}
+		rules[rule] = true
+
+		location := SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: finding.File}}
+		if finding.Line > 0 {
+			location.Region = &SARIFRegion{StartLine: finding.Line}
+		}
+		run.Results = append(run.Results, SARIFResult{
+			RuleID:    rule,
+			Level:     sarifLevel(finding.Severity),
+			Message:   SARIFMessage{Text: finding.Message},
+			Locations: []SARIFLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{
+			ID:               id,
+			ShortDescription: SARIFMessage{Text: id},
+		})
+	}
+
+	if review.Base != "" || review.Head != "" {
+		run.Properties = map[string]interface{}{"base": review.Base, "head": review.Head}
+	}
+	return &SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: []SARIFRun{run}}
+}
+
+// sarifLevel 严重程度对应的SARIF级别
+func sarifLevel(severity models.ReviewSeverity) string {
+	switch severity {
+	case models.ReviewSeverityError:
+		return "error"
+	case models.ReviewSeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
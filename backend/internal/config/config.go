@@ -8,6 +8,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -27,14 +29,54 @@ type Config struct {
 	JWTSecret    string
 	JWTExpiresIn time.Duration
 
+	// 密钥加密主密钥（base64编码的32字节，或任意口令）
+	SecretsMasterKey string
+
+	// 管理员令牌，请求携带该令牌时可查看脱敏命中的规则等管理信息
+	AdminToken string
+
+	// API令牌，配置后/api/v1下的接口（包括MCP端点）必须携带该令牌或管理员令牌
+	APIToken string
+
+	// 日志脱敏规则文件（JSON），在内置规则之外追加
+	RedactionRulesFile string
+
 	// MCP配置
 	MCPServerURL string
 	MCPTimeout   time.Duration
 
+	// 已登记MCP服务端的工具发现间隔，0表示只在登记、更新和手动刷新时发现
+	MCPDiscoveryInterval time.Duration
+
+	// MCP HTTP会话的空闲超时，没有打开SSE流的会话超时后被清理
+	MCPSessionTimeout time.Duration
+
+	// aischedule mcp 转发stdio消息的服务进程MCP端点
+	MCPEndpoint string
+
+	// 大模型配置
+	LLMProvider   string // mock, openai
+	LLMBaseURL    string
+	LLMAPIKey     string
+	LLMModel      string
+	LLMTimeout    time.Duration
+	LLMReplayFile string // 模拟提供方的回放文件
+
 	// 日志配置
 	LogLevel string
 	LogFile  string
 
+	// 执行器配置
+	ArtifactDir     string
+	MaxOutputSize   int64
+	MaxArtifactSize int64
+	CgroupRoot      string
+	MetricsInterval time.Duration
+	WorkspaceDir    string
+
+	// 执行日志及产物的保留天数，0表示不自动清理
+	LogRetentionDays int
+
 	// CORS配置
 	AllowedOrigins []string
 }
@@ -53,6 +95,13 @@ func Load() *Config {
 		jwtExpiresIn = 24 * time.Hour
 	}
 
+	// 解析性能指标采样间隔
+	metricsInterval, err := time.ParseDuration(getEnv("METRICS_INTERVAL", "5s"))
+	if err != nil {
+		log.Printf("Invalid METRICS_INTERVAL format, using default: %v", err)
+		metricsInterval = 5 * time.Second
+	}
+
 	// 解析MCP超时时间
 	mcpTimeout, err := time.ParseDuration(getEnv("MCP_TIMEOUT", "30s"))
 	if err != nil {
@@ -60,8 +109,31 @@ func Load() *Config {
 		mcpTimeout = 30 * time.Second
 	}
 
+	// 解析MCP工具发现间隔
+	mcpDiscoveryInterval, err := time.ParseDuration(getEnv("MCP_DISCOVERY_INTERVAL", "10m"))
+	if err != nil {
+		log.Printf("Invalid MCP_DISCOVERY_INTERVAL format, using default: %v", err)
+		mcpDiscoveryInterval = 10 * time.Minute
+	}
+
+	// 解析MCP会话空闲超时时间
+	mcpSessionTimeout, err := time.ParseDuration(getEnv("MCP_SESSION_TIMEOUT", "30m"))
+	if err != nil {
+		log.Printf("Invalid MCP_SESSION_TIMEOUT format, using default: %v", err)
+		mcpSessionTimeout = 30 * time.Minute
+	}
+
+	// 解析大模型请求超时时间
+	llmTimeout, err := time.ParseDuration(getEnv("LLM_TIMEOUT", "60s"))
+	if err != nil {
+		log.Printf("Invalid LLM_TIMEOUT format, using default: %v", err)
+		llmTimeout = 60 * time.Second
+	}
+
+	port := getEnv("PORT", "8080")
+
 	return &Config{
-		Port:    getEnv("PORT", "8080"),
+		Port:    port,
 		GinMode: getEnv("GIN_MODE", "debug"),
 
 		MongoURI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
@@ -70,12 +142,39 @@ func Load() *Config {
 		JWTSecret:    getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
 		JWTExpiresIn: jwtExpiresIn,
 
+		SecretsMasterKey: getEnv("SECRETS_MASTER_KEY", ""),
+
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		APIToken:           getEnv("API_TOKEN", ""),
+		RedactionRulesFile: getEnv("REDACTION_RULES_FILE", ""),
+
 		MCPServerURL: getEnv("MCP_SERVER_URL", "ws://localhost:3001"),
 		MCPTimeout:   mcpTimeout,
 
+		MCPDiscoveryInterval: mcpDiscoveryInterval,
+
+		MCPSessionTimeout: mcpSessionTimeout,
+		MCPEndpoint:       getEnv("MCP_ENDPOINT", "http://localhost:"+port+"/api/v1/mcp"),
+
+		LLMProvider:   getEnv("LLM_PROVIDER", "mock"),
+		LLMBaseURL:    getEnv("LLM_BASE_URL", "https://api.openai.com/v1"),
+		LLMAPIKey:     getEnv("LLM_API_KEY", ""),
+		LLMModel:      getEnv("LLM_MODEL", "gpt-4o-mini"),
+		LLMTimeout:    llmTimeout,
+		LLMReplayFile: getEnv("LLM_REPLAY_FILE", ""),
+
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 		LogFile:  getEnv("LOG_FILE", "logs/app.log"),
 
+		ArtifactDir:     getEnv("ARTIFACT_DIR", "data/artifacts"),
+		MaxOutputSize:   getEnvInt64("MAX_OUTPUT_SIZE", 1<<20),
+		MaxArtifactSize: getEnvInt64("MAX_ARTIFACT_SIZE", 100<<20),
+		CgroupRoot:      getEnv("CGROUP_ROOT", "/sys/fs/cgroup/aischedule"),
+		MetricsInterval: metricsInterval,
+		WorkspaceDir:    getEnv("WORKSPACE_DIR", "data/workspaces"),
+
+		LogRetentionDays: int(getEnvInt64("LOG_RETENTION_DAYS", 30)),
+
 		AllowedOrigins: []string{
 			"http://localhost:5173",
 			"http://localhost:3000",
@@ -83,10 +182,53 @@ func Load() *Config {
 	}
 }
 
+// serverEnvKeys 服务自身的配置项，其中有密钥、令牌和数据库地址，不传递给任务启动的子进程；
+// 新增配置项时需要同步添加
+var serverEnvKeys = map[string]bool{
+	"PORT": true, "GIN_MODE": true,
+	"MONGODB_URI": true, "MONGODB_DATABASE": true,
+	"JWT_SECRET": true, "JWT_EXPIRES_IN": true,
+	"SECRETS_MASTER_KEY": true, "ADMIN_TOKEN": true, "API_TOKEN": true, "REDACTION_RULES_FILE": true,
+	"MCP_SERVER_URL": true, "MCP_TIMEOUT": true, "MCP_DISCOVERY_INTERVAL": true, "MCP_SESSION_TIMEOUT": true, "MCP_ENDPOINT": true,
+	"LLM_PROVIDER": true, "LLM_BASE_URL": true, "LLM_API_KEY": true, "LLM_MODEL": true, "LLM_TIMEOUT": true, "LLM_REPLAY_FILE": true,
+	"LOG_LEVEL": true, "LOG_FILE": true,
+	"ARTIFACT_DIR": true, "MAX_OUTPUT_SIZE": true, "MAX_ARTIFACT_SIZE": true, "CGROUP_ROOT": true,
+	"METRICS_INTERVAL": true, "WORKSPACE_DIR": true, "LOG_RETENTION_DAYS": true,
+}
+
+// ChildEnv 服务启动的子进程（任务脚本、MCP服务端）继承的环境变量：去掉服务自身的配置项，
+// 这些值不在脱敏范围内，不能让printenv之类的命令写进日志
+func ChildEnv() []string {
+	environ := os.Environ()
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		if serverEnvKeys[key] {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvInt64 获取整数环境变量，如果不存在或格式错误则返回默认值
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid %s format, using default: %v", key, err)
+		return defaultValue
+	}
+	return parsed
+}
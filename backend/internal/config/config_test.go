@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestChildEnv(t *testing.T) {
+	for key := range serverEnvKeys {
+		t.Setenv(key, "server-"+strings.ToLower(key))
+	}
+	t.Setenv("TASK_VISIBLE", "yes")
+	t.Setenv("PATH", "/usr/bin:/bin")
+
+	env := make(map[string]string)
+	for _, kv := range ChildEnv() {
+		key, value, _ := strings.Cut(kv, "=")
+		env[key] = value
+	}
+
+	for _, key := range []string{"SECRETS_MASTER_KEY", "ADMIN_TOKEN", "API_TOKEN", "JWT_SECRET", "MONGODB_URI", "LLM_API_KEY", "MCP_ENDPOINT"} {
+		if value, ok := env[key]; ok {
+			t.Errorf("ChildEnv() contains %s=%s", key, value)
+		}
+	}
+	for key := range serverEnvKeys {
+		if _, ok := env[key]; ok {
+			t.Errorf("ChildEnv() contains server key %s", key)
+		}
+	}
+	if env["TASK_VISIBLE"] != "yes" || env["PATH"] != "/usr/bin:/bin" {
+		t.Errorf("ChildEnv() dropped unrelated variables: TASK_VISIBLE=%q PATH=%q", env["TASK_VISIBLE"], env["PATH"])
+	}
+}
+
+// TestServerEnvKeysComplete Load读取的每个环境变量都要登记在serverEnvKeys中
+func TestServerEnvKeysComplete(t *testing.T) {
+	source, err := os.ReadFile("config.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := regexp.MustCompile(`getEnv(?:Int64)?\("([A-Z0-9_]+)"`).FindAllSubmatch(source, -1)
+	if len(matches) == 0 {
+		t.Fatal("no getEnv calls found in config.go")
+	}
+	for _, match := range matches {
+		if key := string(match[1]); !serverEnvKeys[key] {
+			t.Errorf("%s is read by Load but missing from serverEnvKeys", key)
+		}
+	}
+}
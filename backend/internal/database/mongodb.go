@@ -10,6 +10,7 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -43,6 +44,14 @@ func (m *MongoDB) GetCollection(name string) *mongo.Collection {
 	return m.database.Collection(name)
 }
 
+// GetDatabase 获取数据库，名称为空时返回当前数据库
+func (m *MongoDB) GetDatabase(name string) *mongo.Database {
+	if name == "" || name == m.database.Name() {
+		return m.database
+	}
+	return m.client.Database(name)
+}
+
 // Close 关闭数据库连接
 func (m *MongoDB) Close() error {
 	return Disconnect()
@@ -192,6 +201,71 @@ func createIndexes() error {
 		return err
 	}
 
+	// 工作流执行集合索引
+	workflowExecutionsCollection := GetCollection("workflow_executions")
+	workflowExecutionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "workflow_id", Value: 1}, {Key: "started_at", Value: -1}},
+		},
+		{
+			Keys: map[string]interface{}{"execution_log_id": 1},
+		},
+		{
+			// 调度器按到期时间领取挂起的执行
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "resume_at", Value: 1}},
+		},
+	}
+
+	_, err = workflowExecutionsCollection.Indexes().CreateMany(ctx, workflowExecutionIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 密钥集合索引
+	secretsCollection := GetCollection("secrets")
+	secretIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err = secretsCollection.Indexes().CreateMany(ctx, secretIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 备份集合索引
+	backupsCollection := GetCollection("backups")
+	backupIndexes := []mongo.IndexModel{
+		{
+			// 复合索引需要保持字段顺序
+			Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: map[string]interface{}{"checksum": 1},
+		},
+	}
+
+	_, err = backupsCollection.Indexes().CreateMany(ctx, backupIndexes)
+	if err != nil {
+		return err
+	}
+
+	// MCP服务端集合索引
+	mcpServersCollection := GetCollection("mcp_servers")
+	mcpServerIndexes := []mongo.IndexModel{
+		{
+			Keys:    map[string]interface{}{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err = mcpServersCollection.Indexes().CreateMany(ctx, mcpServerIndexes)
+	if err != nil {
+		return err
+	}
+
 	log.Println("Database indexes created successfully")
 	return nil
 }
\ No newline at end of file
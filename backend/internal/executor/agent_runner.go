@@ -0,0 +1,314 @@
+/**
+ * Agent运行器
+ * 把提示词发送给大模型，执行模型请求的工具调用并回传结果，直到模型给出最终回复
+ */
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"aischedule/internal/llm"
+	"aischedule/internal/models"
+)
+
+const (
+	defaultAgentMaxTurns = 10
+	// maxToolOutput 回传给模型的工具输出上限(字节)
+	maxToolOutput = 16 * 1024
+)
+
+// AgentRunner Agent运行器
+type AgentRunner struct {
+	providers *llmProviders
+	client    *http.Client
+}
+
+// llmProviders 服务配置的默认提供方，任务可以在参数中覆盖
+type llmProviders struct {
+	defaults llm.Config
+	provider llm.Provider
+	err      error // 默认提供方的创建错误，在运行时返回
+}
+
+// llmOverride 任务参数中覆盖服务配置的提供方，api_key可以使用secret://引用
+type llmOverride struct {
+	Provider string `json:"provider"`
+	BaseURL  string `json:"base_url"`
+	APIKey   string `json:"api_key"`
+}
+
+// agentRunnerConfig Agent运行器参数
+type agentRunnerConfig struct {
+	Prompt      string      `json:"prompt"`      // 用户提示词
+	System      string      `json:"system"`      // 系统提示词
+	Model       string      `json:"model"`       // 模型，默认使用LLM_MODEL
+	Tools       []agentTool `json:"tools"`       // 模型可以调用的工具
+	MaxTurns    int         `json:"max_turns"`   // 最多对话轮数，默认10
+	Temperature *float64    `json:"temperature"` // 采样温度
+	MaxTokens   int         `json:"max_tokens"`  // 单轮回复的令牌上限
+	Stream      bool        `json:"stream"`      // 流式输出，回复实时写入日志
+	llmOverride
+}
+
+// agentTool 工具定义：配置url时以HTTP请求执行，否则执行命令
+type agentTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // 参数的JSON Schema
+	Command     string                 `json:"command"`    // 参数以JSON写入环境变量TOOL_ARGUMENTS
+	Args        []string               `json:"args"`
+	URL         string                 `json:"url"` // 参数以JSON作为请求体
+	Method      string                 `json:"method"`
+	Headers     map[string]string      `json:"headers"`
+}
+
+// newLLMProviders 按服务配置创建默认提供方
+func newLLMProviders(defaults llm.Config) *llmProviders {
+	provider, err := llm.New(defaults)
+	return &llmProviders{defaults: defaults, provider: provider, err: err}
+}
+
+// get 返回任务使用的提供方，任务覆盖了提供方配置时单独创建
+func (p *llmProviders) get(override llmOverride) (llm.Provider, error) {
+	if override == (llmOverride{}) {
+		if p.err != nil {
+			return nil, p.err
+		}
+		return p.provider, nil
+	}
+
+	cfg := p.defaults
+	if override.Provider != "" {
+		cfg.Provider = override.Provider
+	}
+	if override.BaseURL != "" {
+		cfg.BaseURL = override.BaseURL
+	}
+	if override.APIKey != "" {
+		cfg.APIKey = override.APIKey
+	}
+	return llm.New(cfg)
+}
+
+// NewAgentRunner 创建新的Agent运行器
+func NewAgentRunner(defaults llm.Config) *AgentRunner {
+	return &AgentRunner{
+		providers: newLLMProviders(defaults),
+		client:    &http.Client{},
+	}
+}
+
+// Run 执行Agent对话
+func (r *AgentRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg agentRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Prompt == "" {
+		return nil, fmt.Errorf("agent prompt not specified")
+	}
+	if cfg.Model == "" {
+		cfg.Model = r.providers.defaults.Model
+	}
+	if cfg.MaxTurns <= 0 {
+		cfg.MaxTurns = defaultAgentMaxTurns
+	}
+
+	tools := make(map[string]agentTool, len(cfg.Tools))
+	for _, tool := range cfg.Tools {
+		if tool.Name == "" {
+			return nil, fmt.Errorf("agent tool name not specified")
+		}
+		if tool.Command == "" && tool.URL == "" {
+			return nil, fmt.Errorf("agent tool %s: command or url required", tool.Name)
+		}
+		tools[tool.Name] = tool
+	}
+
+	provider, err := r.providers.get(cfg.llmOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &llm.Request{
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+	}
+	if cfg.System != "" {
+		req.Messages = append(req.Messages, llm.Message{Role: llm.RoleSystem, Content: cfg.System})
+	}
+	req.Messages = append(req.Messages, llm.Message{Role: llm.RoleUser, Content: cfg.Prompt})
+	for _, tool := range cfg.Tools {
+		req.Tools = append(req.Tools, llm.Tool{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+	}
+
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("调用模型 %s", cfg.Model), map[string]interface{}{
+		"tools": len(req.Tools),
+	})
+
+	var usage llm.Usage
+	calls := []map[string]interface{}{}
+	for turn := 1; turn <= cfg.MaxTurns; turn++ {
+		resp, err := r.complete(ctx, rc, provider, req, cfg.Stream)
+		if err != nil {
+			return nil, err
+		}
+		usage.Add(resp.Usage)
+		rc.Log(models.LogLevelInfo, fmt.Sprintf("模型回复（第 %d 轮）", turn), map[string]interface{}{
+			"finish_reason": resp.FinishReason,
+			"tool_calls":    len(resp.Message.ToolCalls),
+			"total_tokens":  resp.Usage.TotalTokens,
+		})
+
+		resp.Message.Role = llm.RoleAssistant
+		req.Messages = append(req.Messages, resp.Message)
+		if len(resp.Message.ToolCalls) == 0 {
+			return &models.ExecutionResult{
+				Output: resp.Message.Content,
+				Data: map[string]interface{}{
+					"model":         resp.Model,
+					"turns":         turn,
+					"finish_reason": resp.FinishReason,
+					"usage": map[string]interface{}{
+						"prompt_tokens":     usage.PromptTokens,
+						"completion_tokens": usage.CompletionTokens,
+						"total_tokens":      usage.TotalTokens,
+					},
+					"tool_calls": calls,
+				},
+			}, nil
+		}
+
+		for _, call := range resp.Message.ToolCalls {
+			output, err := r.callTool(ctx, rc, tools, call)
+			record := map[string]interface{}{"name": call.Name, "arguments": call.Arguments}
+			if err != nil {
+				// 工具失败时把错误回传给模型，由模型决定如何继续
+				record["error"] = err.Error()
+				output = fmt.Sprintf("error: %v", err)
+				rc.Logf(models.LogLevelWarn, "工具 %s 调用失败: %v", call.Name, err)
+			}
+			calls = append(calls, record)
+			req.Messages = append(req.Messages, llm.Message{
+				Role:       llm.RoleTool,
+				Content:    truncateToolOutput(output),
+				ToolCallID: call.ID,
+				Name:       call.Name,
+			})
+		}
+	}
+	return nil, fmt.Errorf("agent did not finish within %d turns", cfg.MaxTurns)
+}
+
+// complete 执行一轮对话，回复按行写入日志（流式输出时实时写入）
+func (r *AgentRunner) complete(ctx context.Context, rc *RunContext, provider llm.Provider, req *llm.Request, stream bool) (*llm.Response, error) {
+	collector := newOutputCollector(rc)
+	writer := collector.Writer("llm")
+
+	var resp *llm.Response
+	var err error
+	if stream {
+		resp, err = provider.Stream(ctx, req, func(chunk llm.Chunk) error {
+			_, err := writer.Write([]byte(chunk.Content))
+			return err
+		})
+	} else if resp, err = provider.Chat(ctx, req); err == nil {
+		writer.Write([]byte(resp.Message.Content))
+	}
+	writer.Flush()
+	collector.Close()
+	return resp, err
+}
+
+// callTool 执行模型请求的工具调用，返回回传给模型的输出
+func (r *AgentRunner) callTool(ctx context.Context, rc *RunContext, tools map[string]agentTool, call llm.ToolCall) (string, error) {
+	tool, ok := tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+	arguments := call.Arguments
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("invalid tool arguments: %s", arguments)
+	}
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("调用工具 %s", call.Name), map[string]interface{}{
+		"arguments": arguments,
+	})
+
+	if tool.URL != "" {
+		return r.callHTTPTool(ctx, tool, arguments)
+	}
+
+	env := make(map[string]string, len(rc.Environment.EnvironmentVars)+2)
+	for key, value := range rc.Environment.EnvironmentVars {
+		env[key] = value
+	}
+	env["TOOL_NAME"] = call.Name
+	env["TOOL_ARGUMENTS"] = arguments
+
+	toolRC := *rc
+	toolRC.Environment.EnvironmentVars = env
+	result, err := runProcess(ctx, &toolRC, tool.Command, tool.Args)
+	if result == nil {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, result.Output)
+	}
+	return result.Output, nil
+}
+
+// callHTTPTool 以HTTP请求执行工具，参数作为JSON请求体
+func (r *AgentRunner) callHTTPTool(ctx context.Context, tool agentTool, arguments string) (string, error) {
+	method := strings.ToUpper(tool.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	var body io.Reader
+	if method != http.MethodGet {
+		body = bytes.NewReader([]byte(arguments))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, tool.URL, body)
+	if err != nil {
+		return "", err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range tool.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxToolOutput+1))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncateToolOutput(string(data)))
+	}
+	return string(data), nil
+}
+
+// truncateToolOutput 截断过长的工具输出
+func truncateToolOutput(output string) string {
+	if len(output) <= maxToolOutput {
+		return output
+	}
+	return output[:maxToolOutput] + "\n...(truncated)"
+}
@@ -0,0 +1,128 @@
+/**
+ * 执行产物采集
+ * 运行参数通过 artifacts 声明产物的glob模式，运行结束后把匹配的文件保存到产物存储
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/models"
+)
+
+const (
+	// maxArtifactFiles 单次执行按模式采集的文件数上限
+	maxArtifactFiles = 100
+	// artifactCollectTimeout 运行结束后采集产物的最长时间
+	artifactCollectTimeout = 5 * time.Minute
+)
+
+// artifactConfig 运行参数中的产物声明
+type artifactConfig struct {
+	Artifacts []string `json:"artifacts"`
+}
+
+// SaveArtifact 将数据保存为本次执行的产物，contentType为空时按名称推断
+func (rc *RunContext) SaveArtifact(ctx context.Context, name, contentType string, r io.Reader) (models.Artifact, error) {
+	if rc.Artifacts == nil {
+		return models.Artifact{}, fmt.Errorf("artifact store not configured")
+	}
+
+	blob, err := rc.Artifacts.Put(ctx, r)
+	if err != nil {
+		return models.Artifact{}, err
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	return models.Artifact{
+		Name:        name,
+		Size:        blob.Size,
+		Checksum:    blob.Checksum,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// runWithArtifacts 运行并在结束后（无论成功与否）采集声明的产物
+func runWithArtifacts(ctx context.Context, runner Runner, rc *RunContext) (*models.ExecutionResult, error) {
+	result, err := runner.Run(ctx, rc)
+
+	// 执行上下文可能已超时或被取消，采集使用独立的上下文
+	collectCtx, cancel := context.WithTimeout(context.Background(), artifactCollectTimeout)
+	defer cancel()
+
+	if collected := collectArtifacts(collectCtx, rc); len(collected) > 0 {
+		if result == nil {
+			result = &models.ExecutionResult{}
+		}
+		result.Artifacts = append(result.Artifacts, collected...)
+	}
+	return result, err
+}
+
+// collectArtifacts 在工作目录中查找匹配声明模式的文件并保存
+func collectArtifacts(ctx context.Context, rc *RunContext) []models.Artifact {
+	var cfg artifactConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		rc.Logf(models.LogLevelWarn, "产物声明无效: %v", err)
+		return nil
+	}
+	if len(cfg.Artifacts) == 0 {
+		return nil
+	}
+
+	root := rc.Environment.WorkingDirectory
+	if root == "" {
+		root = "."
+	}
+	matches, err := artifact.Glob(root, cfg.Artifacts)
+	if err != nil {
+		rc.Logf(models.LogLevelWarn, "查找产物文件失败: %v", err)
+		return nil
+	}
+	if len(matches) == 0 {
+		rc.Log(models.LogLevelWarn, "没有文件匹配声明的产物模式", map[string]interface{}{
+			"patterns": cfg.Artifacts,
+		})
+		return nil
+	}
+	if len(matches) > maxArtifactFiles {
+		rc.Logf(models.LogLevelWarn, "匹配的产物文件有 %d 个，只保存前 %d 个", len(matches), maxArtifactFiles)
+		matches = matches[:maxArtifactFiles]
+	}
+
+	artifacts := make([]models.Artifact, 0, len(matches))
+	for _, name := range matches {
+		saved, err := saveArtifactFile(ctx, rc, root, name)
+		if err != nil {
+			rc.Logf(models.LogLevelWarn, "保存产物 %s 失败: %v", name, err)
+			continue
+		}
+		artifacts = append(artifacts, saved)
+		rc.Log(models.LogLevelInfo, fmt.Sprintf("已保存产物 %s", name), map[string]interface{}{
+			"size":     saved.Size,
+			"checksum": saved.Checksum,
+		})
+	}
+	return artifacts
+}
+
+func saveArtifactFile(ctx context.Context, rc *RunContext, root, name string) (models.Artifact, error) {
+	file, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return models.Artifact{}, err
+	}
+	defer file.Close()
+
+	return rc.SaveArtifact(ctx, name, "", file)
+}
@@ -0,0 +1,161 @@
+/**
+ * 备份管理
+ * 维护backups集合中的备份记录，负责校验、保留策略清理和恢复
+ */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/backup"
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+)
+
+// ErrBackupNotFound 备份不存在
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupManager 备份管理器
+type BackupManager struct {
+	db        *database.MongoDB
+	artifacts artifact.Store
+	release   func(ctx context.Context, checksums map[string]struct{})
+}
+
+// NewBackupManager 创建备份管理器，release用于释放不再被引用的归档
+func NewBackupManager(db *database.MongoDB, artifacts artifact.Store, release func(ctx context.Context, checksums map[string]struct{})) *BackupManager {
+	return &BackupManager{
+		db:        db,
+		artifacts: artifacts,
+		release:   release,
+	}
+}
+
+// Record 保存备份记录
+func (m *BackupManager) Record(ctx context.Context, record *models.Backup) error {
+	_, err := m.collection().InsertOne(ctx, record)
+	return err
+}
+
+// Get 获取备份记录
+func (m *BackupManager) Get(ctx context.Context, id primitive.ObjectID) (*models.Backup, error) {
+	var record models.Backup
+	err := m.collection().FindOne(ctx, bson.M{"_id": id}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// List 按创建时间倒序列出备份记录
+func (m *BackupManager) List(ctx context.Context, filter bson.M) ([]models.Backup, error) {
+	cursor, err := m.collection().Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	records := []models.Backup{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Verify 把备份试恢复到临时目录校验，通过后标记为已校验
+func (m *BackupManager) Verify(ctx context.Context, record *models.Backup) error {
+	reader, err := m.artifacts.Open(ctx, record.Checksum)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := backup.Verify(ctx, reader); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record.Verified = true
+	record.VerifiedAt = &now
+	if !record.ID.IsZero() {
+		_, err = m.collection().UpdateOne(ctx, bson.M{"_id": record.ID},
+			bson.M{"$set": bson.M{"verified": true, "verified_at": now}})
+	}
+	return err
+}
+
+// Restore 恢复备份
+func (m *BackupManager) Restore(ctx context.Context, id primitive.ObjectID, req *models.RestoreBackupRequest) (*models.RestoreBackupResult, error) {
+	record, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := m.artifacts.Open(ctx, record.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var db *mongo.Database
+	if req.Database != "" {
+		db = m.db.GetDatabase(req.Database)
+	}
+	return backup.Restore(ctx, reader, req.TargetDir, db, req.Overwrite)
+}
+
+// Prune 按保留策略删除任务的旧备份，返回删除的归档名称
+func (m *BackupManager) Prune(ctx context.Context, taskID primitive.ObjectID, policy models.BackupRetention) ([]string, error) {
+	records, err := m.List(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, record := range backup.Expired(records, policy) {
+		if err := m.delete(ctx, &record); err != nil {
+			return names, err
+		}
+		names = append(names, record.Name)
+	}
+	return names, nil
+}
+
+// Delete 删除备份记录及其归档
+func (m *BackupManager) Delete(ctx context.Context, id primitive.ObjectID) error {
+	record, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return m.delete(ctx, record)
+}
+
+// delete 删除备份记录，并从生成它的执行结果中移除归档，归档不再被引用时删除内容
+func (m *BackupManager) delete(ctx context.Context, record *models.Backup) error {
+	if _, err := m.collection().DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+		return err
+	}
+	_, err := m.db.GetCollection("execution_logs").UpdateOne(ctx,
+		bson.M{"_id": record.ExecutionLogID},
+		bson.M{"$pull": bson.M{"result.artifacts": bson.M{"checksum": record.Checksum}}},
+	)
+	if err != nil {
+		return err
+	}
+	m.release(ctx, map[string]struct{}{record.Checksum: {}})
+	return nil
+}
+
+func (m *BackupManager) collection() *mongo.Collection {
+	return m.db.GetCollection("backups")
+}
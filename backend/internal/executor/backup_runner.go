@@ -0,0 +1,155 @@
+/**
+ * 数据备份运行器
+ * 把本地目录和MongoDB集合打包为tar.gz保存到产物存储，可选试恢复校验，并按保留策略清理旧备份
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/backup"
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+)
+
+// BackupRunner 数据备份运行器
+type BackupRunner struct {
+	db     *database.MongoDB
+	backup *BackupManager
+}
+
+// backupRunnerConfig 数据备份运行器参数
+type backupRunnerConfig struct {
+	Name        string                 `json:"name"`        // 归档文件名前缀，默认backup
+	Paths       []string               `json:"paths"`       // 备份的目录，相对路径基于工作目录
+	Database    string                 `json:"database"`    // 集合所在的数据库，默认当前数据库
+	Collections []string               `json:"collections"` // 备份的集合
+	Verify      bool                   `json:"verify"`      // 备份后试恢复校验
+	Retention   models.BackupRetention `json:"retention"`   // 保留策略
+}
+
+// NewBackupRunner 创建新的数据备份运行器
+func NewBackupRunner(db *database.MongoDB, manager *BackupManager) *BackupRunner {
+	return &BackupRunner{db: db, backup: manager}
+}
+
+// Run 执行备份
+func (r *BackupRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg backupRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Paths) == 0 && len(cfg.Collections) == 0 {
+		return nil, fmt.Errorf("backup sources not specified")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "backup"
+	}
+
+	db := r.db.GetDatabase(cfg.Database)
+	sources := models.BackupSources{
+		Database:    db.Name(),
+		Collections: cfg.Collections,
+	}
+	for _, path := range cfg.Paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rc.Environment.WorkingDirectory, path)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		sources.Paths = append(sources.Paths, abs)
+	}
+	if len(sources.Collections) == 0 {
+		sources.Database = ""
+	}
+
+	// 归档边生成边写入产物存储
+	started := time.Now()
+	name := fmt.Sprintf("%s-%s.tar.gz", cfg.Name, started.Format("20060102-150405"))
+	rc.Log(models.LogLevelInfo, "开始备份", map[string]interface{}{
+		"paths":       sources.Paths,
+		"collections": sources.Collections,
+	})
+
+	reader, writer := io.Pipe()
+	var stats *backup.Stats
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		stats, err = backup.Write(ctx, writer, db, sources)
+		writer.CloseWithError(err)
+		done <- err
+	}()
+	archive, err := rc.SaveArtifact(ctx, name, "application/gzip", reader)
+	reader.CloseWithError(err)
+	if writeErr := <-done; writeErr != nil {
+		return nil, writeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store backup archive: %w", err)
+	}
+
+	result := &models.ExecutionResult{Artifacts: []models.Artifact{archive}}
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("备份归档已保存: %s", name), map[string]interface{}{
+		"checksum":    archive.Checksum,
+		"size":        archive.Size,
+		"files":       stats.Files,
+		"collections": stats.Collections,
+		"duration":    time.Since(started).Milliseconds(),
+	})
+
+	record := &models.Backup{
+		ExecutionLogID: rc.ExecutionID,
+		Name:           name,
+		Checksum:       archive.Checksum,
+		Size:           archive.Size,
+		Sources:        sources,
+		Files:          stats.Files,
+		Collections:    stats.Collections,
+		CreatedAt:      started,
+	}
+	if rc.Task != nil {
+		record.TaskID = rc.Task.ID
+	}
+
+	if cfg.Verify {
+		if err := r.backup.Verify(ctx, record); err != nil {
+			rc.Logf(models.LogLevelError, "备份校验失败: %v", err)
+			return result, fmt.Errorf("backup verification failed: %w", err)
+		}
+		rc.Logf(models.LogLevelInfo, "备份校验通过")
+	}
+
+	// 备份记录只在备份（及校验）成功后保存，失败的备份不会触发清理
+	record.ID = primitive.NewObjectID()
+	if err := r.backup.Record(ctx, record); err != nil {
+		return result, err
+	}
+	result.Data = map[string]interface{}{
+		"backup_id": record.ID.Hex(),
+		"checksum":  record.Checksum,
+		"size":      record.Size,
+		"verified":  record.Verified,
+	}
+
+	if rc.Task != nil {
+		pruned, err := r.backup.Prune(ctx, rc.Task.ID, cfg.Retention)
+		if err != nil {
+			rc.Logf(models.LogLevelWarn, "清理旧备份失败: %v", err)
+		} else if len(pruned) > 0 {
+			rc.Log(models.LogLevelInfo, fmt.Sprintf("按保留策略删除了 %d 个旧备份", len(pruned)), map[string]interface{}{
+				"backups": pruned,
+			})
+		}
+	}
+	return result, nil
+}
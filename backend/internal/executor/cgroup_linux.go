@@ -0,0 +1,100 @@
+//go:build linux
+
+/**
+ * cgroups v2 管理
+ * 为每次执行创建独立的cgroup并写入CPU、内存限制
+ */
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	cgroupMountPoint = "/sys/fs/cgroup"
+	cgroupCPUPeriod  = 100000 // cpu.max 周期(微秒)
+)
+
+// cgroup 单次执行的cgroup
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// createCgroup 在root下创建名为name的cgroup并设置资源限制
+func createCgroup(root, name string, limits processLimits) (*cgroup, error) {
+	if root == "" {
+		return nil, fmt.Errorf("cgroup root not configured")
+	}
+	if _, err := os.Stat(filepath.Join(cgroupMountPoint, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not mounted at %s", cgroupMountPoint)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	// 在父cgroup中为子cgroup启用cpu和memory控制器，已启用时写入会被忽略
+	_ = os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte("+cpu +memory"), 0o644)
+
+	path := filepath.Join(root, name)
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, err
+	}
+
+	cg := &cgroup{path: path}
+	if err := cg.applyLimits(limits); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	cg.dir = dir
+	return cg, nil
+}
+
+// applyLimits 写入资源限制
+func (c *cgroup) applyLimits(limits processLimits) error {
+	if limits.MemoryBytes > 0 {
+		if err := c.write("memory.max", fmt.Sprintf("%d", limits.MemoryBytes)); err != nil {
+			return err
+		}
+		// 禁止使用swap绕过内存限制，内核未开启swap统计时忽略
+		_ = c.write("memory.swap.max", "0")
+	}
+	if limits.CPUMillis > 0 {
+		quota := limits.CPUMillis * cgroupCPUPeriod / 1000
+		if err := c.write("cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cgroup) write(file, value string) error {
+	if err := os.WriteFile(filepath.Join(c.path, file), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// destroy 结束cgroup中残留的进程并删除cgroup
+func (c *cgroup) destroy() {
+	_ = c.write("cgroup.kill", "1")
+	c.dir.Close()
+
+	// 进程退出需要一点时间，删除失败时稍后重试
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
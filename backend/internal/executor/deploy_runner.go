@@ -0,0 +1,379 @@
+/**
+ * 部署运行器
+ * 按预检、部署、健康检查的顺序执行，健康检查失败时执行回滚；每个阶段都记录为执行的步骤
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+)
+
+// 部署阶段
+const (
+	PhasePreCheck    = "pre_check"
+	PhaseDeploy      = "deploy"
+	PhaseHealthCheck = "health_check"
+	PhaseRollback    = "rollback"
+)
+
+const (
+	defaultProbeRetries  = 3
+	defaultProbeInterval = 5 * time.Second
+	defaultProbeTimeout  = 10 * time.Second
+	// maxProbeBodySize 健康检查读取的响应体上限
+	maxProbeBodySize = 64 * 1024
+)
+
+// DeployRunner 部署运行器
+type DeployRunner struct {
+	db     *database.MongoDB
+	client *http.Client
+}
+
+// deployRunnerConfig 部署运行器参数
+type deployRunnerConfig struct {
+	Version      string              `json:"version"`       // 部署的版本，默认为工作区的提交
+	PreChecks    []deployCommand     `json:"pre_checks"`    // 预检命令，任一失败则不部署
+	Deploy       deployCommand       `json:"deploy"`        // 部署命令
+	HealthChecks []healthCheckConfig `json:"health_checks"` // 部署后的健康检查
+	Rollback     *deployCommand      `json:"rollback"`      // 健康检查失败时执行的回滚命令
+}
+
+// deployCommand 部署阶段执行的命令
+type deployCommand struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// healthCheckConfig 健康检查
+type healthCheckConfig struct {
+	Name           string   `json:"name"`
+	Type           string   `json:"type"` // http, command；为空时按是否配置url推断
+	URL            string   `json:"url"`
+	Method         string   `json:"method"`
+	ExpectedStatus []int    `json:"expected_status"` // 为空时接受2xx
+	Contains       string   `json:"contains"`        // 响应体需要包含的文本
+	Command        string   `json:"command"`
+	Args           []string `json:"args"`
+	Retries        int      `json:"retries"`  // 失败后的重试次数，默认3
+	Interval       int      `json:"interval"` // 重试间隔(秒)，默认5
+	Timeout        int      `json:"timeout"`  // 单次检查超时(秒)，默认10
+}
+
+// NewDeployRunner 创建新的部署运行器
+func NewDeployRunner(db *database.MongoDB) *DeployRunner {
+	return &DeployRunner{
+		db:     db,
+		client: &http.Client{},
+	}
+}
+
+// Run 执行部署
+func (r *DeployRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg deployRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Deploy.Command == "" {
+		return nil, fmt.Errorf("deploy command not specified")
+	}
+	for i := range cfg.HealthChecks {
+		if err := normalizeHealthCheck(&cfg.HealthChecks[i], i); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &models.DeploymentInfo{Version: cfg.Version, Phases: []models.DeploymentPhase{}}
+	if rc.Template != nil && rc.Template.Trigger.Type == models.TriggerTypeRollback {
+		if version, ok := rc.Template.Trigger.Payload["version"].(string); ok && version != "" {
+			info.Version = version
+		}
+		info.RollbackOf, _ = rc.Template.Trigger.Payload["execution_log_id"].(string)
+	}
+	if info.Version == "" {
+		info.Version = rc.Commit
+	}
+	if info.Version == "" {
+		return nil, fmt.Errorf("deployment version not specified")
+	}
+	if rc.Task != nil {
+		previous, err := r.previousVersion(ctx, rc)
+		if err != nil {
+			return nil, err
+		}
+		info.PreviousVersion = previous
+	}
+
+	result := &models.ExecutionResult{Deployment: info}
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("部署版本 %s", info.Version), map[string]interface{}{
+		"version":          info.Version,
+		"previous_version": info.PreviousVersion,
+	})
+
+	// 预检
+	if len(cfg.PreChecks) > 0 {
+		err := r.phase(rc, info, PhasePreCheck, func() (int, error) {
+			for _, check := range cfg.PreChecks {
+				if _, err := r.runCommand(ctx, rc, info, PhasePreCheck, check); err != nil {
+					return 0, err
+				}
+			}
+			return 0, nil
+		})
+		if err != nil {
+			return result, fmt.Errorf("pre-check failed: %w", err)
+		}
+	}
+
+	// 部署
+	err := r.phase(rc, info, PhaseDeploy, func() (int, error) {
+		processResult, err := r.runCommand(ctx, rc, info, PhaseDeploy, cfg.Deploy)
+		if processResult != nil {
+			result.Output = processResult.Output
+			result.ExitCode = processResult.ExitCode
+			result.Metrics = processResult.Metrics
+		}
+		return 0, err
+	})
+	if err != nil {
+		return result, fmt.Errorf("deploy failed: %w", err)
+	}
+
+	// 健康检查
+	if len(cfg.HealthChecks) == 0 {
+		return result, nil
+	}
+	probeErr := r.phase(rc, info, PhaseHealthCheck, func() (int, error) {
+		attempts := 0
+		for _, check := range cfg.HealthChecks {
+			n, err := r.probe(ctx, rc, info, check)
+			attempts += n
+			if err != nil {
+				return attempts, err
+			}
+		}
+		return attempts, nil
+	})
+	if probeErr == nil {
+		return result, nil
+	}
+
+	// 回滚
+	if cfg.Rollback == nil || cfg.Rollback.Command == "" || ctx.Err() != nil {
+		return result, fmt.Errorf("health check failed: %w", probeErr)
+	}
+	if info.PreviousVersion == "" {
+		rc.Logf(models.LogLevelWarn, "没有找到之前成功部署的版本，回滚命令的 DEPLOY_VERSION 为空")
+	}
+	rollbackErr := r.phase(rc, info, PhaseRollback, func() (int, error) {
+		_, err := r.runCommand(ctx, rc, info, PhaseRollback, *cfg.Rollback)
+		return 0, err
+	})
+	if rollbackErr != nil {
+		return result, fmt.Errorf("health check failed: %v; rollback failed: %w", probeErr, rollbackErr)
+	}
+	info.RolledBack = true
+	rc.Logf(models.LogLevelWarn, "健康检查失败，已回滚到版本 %s", info.PreviousVersion)
+	return result, fmt.Errorf("health check failed, rolled back to %q: %w", info.PreviousVersion, probeErr)
+}
+
+// phase 执行一个阶段并记录结果，fn返回健康检查的尝试次数
+func (r *DeployRunner) phase(rc *RunContext, info *models.DeploymentInfo, name string, fn func() (int, error)) error {
+	rc.BeginStep(name)
+	rc.Logf(models.LogLevelInfo, "阶段开始: %s", name)
+
+	started := time.Now()
+	attempts, err := fn()
+	phase := models.DeploymentPhase{
+		Name:     name,
+		Status:   "completed",
+		Duration: time.Since(started).Milliseconds(),
+		Attempts: attempts,
+	}
+	if err != nil {
+		phase.Status = "failed"
+		phase.Error = err.Error()
+		info.Phases = append(info.Phases, phase)
+		rc.Logf(models.LogLevelError, "阶段失败: %s: %v", name, err)
+		return err
+	}
+
+	info.Phases = append(info.Phases, phase)
+	rc.CompleteStep(name)
+	rc.Logf(models.LogLevelInfo, "阶段完成: %s", name)
+	return nil
+}
+
+// runCommand 执行阶段命令，环境变量中带有部署版本信息
+func (r *DeployRunner) runCommand(ctx context.Context, rc *RunContext, info *models.DeploymentInfo, phase string, command deployCommand) (*models.ExecutionResult, error) {
+	if command.Command == "" {
+		return nil, fmt.Errorf("%s command not specified", phase)
+	}
+
+	env := make(map[string]string, len(rc.Environment.EnvironmentVars)+3)
+	for key, value := range rc.Environment.EnvironmentVars {
+		env[key] = value
+	}
+	env["DEPLOY_PHASE"] = phase
+	env["DEPLOY_VERSION"] = info.Version
+	env["DEPLOY_PREVIOUS_VERSION"] = info.PreviousVersion
+	if phase == PhaseRollback {
+		// 回滚命令部署的是上一个版本
+		env["DEPLOY_VERSION"] = info.PreviousVersion
+		env["DEPLOY_FAILED_VERSION"] = info.Version
+	}
+
+	phaseRC := *rc
+	phaseRC.Environment.EnvironmentVars = env
+	return runProcess(ctx, &phaseRC, command.Command, command.Args)
+}
+
+// probe 执行健康检查，失败时按配置重试，返回尝试次数
+func (r *DeployRunner) probe(ctx context.Context, rc *RunContext, info *models.DeploymentInfo, check healthCheckConfig) (int, error) {
+	interval := time.Duration(check.Interval) * time.Second
+	timeout := time.Duration(check.Timeout) * time.Second
+
+	var err error
+	attempt := 0
+	for attempt < check.Retries+1 {
+		attempt++
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		if check.Type == "http" {
+			err = r.probeHTTP(probeCtx, check)
+		} else {
+			_, err = r.runCommand(probeCtx, rc, info, PhaseHealthCheck, deployCommand{Command: check.Command, Args: check.Args})
+		}
+		cancel()
+
+		if err == nil {
+			rc.Logf(models.LogLevelInfo, "健康检查 %s 通过（第 %d 次）", check.Name, attempt)
+			return attempt, nil
+		}
+		rc.Logf(models.LogLevelWarn, "健康检查 %s 失败（第 %d/%d 次）: %v", check.Name, attempt, check.Retries+1, err)
+		if attempt > check.Retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+	return attempt, fmt.Errorf("%s: %w", check.Name, err)
+}
+
+// probeHTTP 发送HTTP健康检查请求
+func (r *DeployRunner) probeHTTP(ctx context.Context, check healthCheckConfig) error {
+	req, err := http.NewRequestWithContext(ctx, check.Method, check.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		return err
+	}
+
+	if !statusAccepted(resp.StatusCode, check.ExpectedStatus) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if check.Contains != "" && !strings.Contains(string(body), check.Contains) {
+		return fmt.Errorf("response does not contain %q", check.Contains)
+	}
+	return nil
+}
+
+// previousVersion 查询任务上一次成功部署的版本
+func (r *DeployRunner) previousVersion(ctx context.Context, rc *RunContext) (string, error) {
+	var previous models.ExecutionLog
+	err := r.db.GetCollection("execution_logs").FindOne(ctx,
+		bson.M{
+			"task_id":                   rc.Task.ID,
+			"_id":                       bson.M{"$ne": rc.ExecutionID},
+			"status":                    models.ExecutionStatusCompleted,
+			"result.deployment.version": bson.M{"$exists": true},
+		},
+		options.FindOne().
+			SetSort(bson.M{"started_at": -1}).
+			SetProjection(bson.M{"result.deployment.version": 1}),
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query previous deployment: %w", err)
+	}
+	return previous.Result.Deployment.Version, nil
+}
+
+// normalizeHealthCheck 校验健康检查配置并填充默认值
+func normalizeHealthCheck(check *healthCheckConfig, index int) error {
+	if check.Name == "" {
+		check.Name = fmt.Sprintf("check_%d", index+1)
+	}
+	if check.Type == "" {
+		check.Type = "command"
+		if check.URL != "" {
+			check.Type = "http"
+		}
+	}
+	switch check.Type {
+	case "http":
+		if check.URL == "" {
+			return fmt.Errorf("health check %s: url not specified", check.Name)
+		}
+		if check.Method == "" {
+			check.Method = http.MethodGet
+		}
+	case "command":
+		if check.Command == "" {
+			return fmt.Errorf("health check %s: command not specified", check.Name)
+		}
+	default:
+		return fmt.Errorf("health check %s: unsupported type %s", check.Name, check.Type)
+	}
+
+	if check.Retries < 0 {
+		check.Retries = 0
+	} else if check.Retries == 0 {
+		check.Retries = defaultProbeRetries
+	}
+	if check.Interval <= 0 {
+		check.Interval = int(defaultProbeInterval / time.Second)
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = int(defaultProbeTimeout / time.Second)
+	}
+	return nil
+}
+
+// statusAccepted 判断状态码是否符合预期，未配置时接受2xx
+func statusAccepted(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, code := range expected {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+/**
+ * HTTP运行器
+ * 负责API调用类任务：发送请求、校验结果并记录响应
+ */
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+const (
+	defaultHTTPTimeout     = 30 * time.Second
+	defaultMaxResponseSize = 1 << 20 // 1MB
+)
+
+// HTTPRunner HTTP请求运行器
+type HTTPRunner struct {
+	client *http.Client
+}
+
+// httpRunnerConfig HTTP运行器参数
+type httpRunnerConfig struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"`
+	Body        interface{}       `json:"body"` // 字符串原样发送，对象按JSON编码
+	Auth        *httpAuthConfig   `json:"auth"`
+	Success     httpSuccessConfig `json:"success"`
+	MaxBodySize int64             `json:"max_body_size"` // 响应体保留上限(字节)
+}
+
+// httpAuthConfig 认证配置
+type httpAuthConfig struct {
+	Type     string `json:"type"` // bearer, basic
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// httpSuccessConfig 成功判定条件
+type httpSuccessConfig struct {
+	StatusCodes []int       `json:"status_codes"` // 为空时接受2xx
+	JSONPath    string      `json:"json_path"`    // 对响应JSON的断言路径
+	Expected    interface{} `json:"expected"`     // 断言期望值，为空时只要求路径存在
+}
+
+// NewHTTPRunner 创建新的HTTP运行器
+func NewHTTPRunner() *HTTPRunner {
+	return &HTTPRunner{
+		client: &http.Client{},
+	}
+}
+
+// Run 执行HTTP请求
+func (r *HTTPRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg httpRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("API URL not specified")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxResponseSize
+	}
+
+	req, err := r.buildRequest(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := rc.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	client := *r.client
+	client.Timeout = timeout
+
+	rc.Logf(models.LogLevelInfo, "调用API: %s %s", cfg.Method, req.URL.Redacted())
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	result, body, err := r.captureResponse(ctx, rc, resp, cfg.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	result.Data["duration_ms"] = time.Since(start).Milliseconds()
+
+	if err := checkHTTPSuccess(&cfg.Success, resp.StatusCode, body, result); err != nil {
+		result.Success = false
+		return result, err
+	}
+
+	result.Success = true
+	rc.Logf(models.LogLevelInfo, "API调用成功: HTTP %d", resp.StatusCode)
+	return result, nil
+}
+
+// buildRequest 根据配置构建HTTP请求
+func (r *HTTPRunner) buildRequest(ctx context.Context, cfg *httpRunnerConfig) (*http.Request, error) {
+	var body io.Reader
+	contentType := ""
+	switch b := cfg.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if cfg.Auth != nil {
+		switch strings.ToLower(cfg.Auth.Type) {
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+cfg.Auth.Token)
+		case "basic":
+			req.SetBasicAuth(cfg.Auth.Username, cfg.Auth.Password)
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported auth type: %s", cfg.Auth.Type)
+		}
+	}
+
+	return req, nil
+}
+
+// captureResponse 读取响应，超过上限的部分截断并把完整响应体保存为产物
+func (r *HTTPRunner) captureResponse(ctx context.Context, rc *RunContext, resp *http.Response, limit int64) (*models.ExecutionResult, []byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	headers := make(map[string]interface{}, len(resp.Header))
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+
+	result := &models.ExecutionResult{
+		Data: map[string]interface{}{
+			"status_code": resp.StatusCode,
+			"headers":     headers,
+		},
+	}
+
+	truncated := int64(len(buf)) > limit
+	if truncated {
+		var body io.Reader = io.MultiReader(bytes.NewReader(buf), resp.Body)
+		if rc.MaxArtifact > 0 {
+			body = io.LimitReader(body, rc.MaxArtifact)
+		}
+		saved, err := rc.SaveArtifact(ctx, "response_body", resp.Header.Get("Content-Type"), body)
+		if err != nil {
+			rc.Logf(models.LogLevelWarn, "保存完整响应体失败: %v", err)
+		} else {
+			result.Artifacts = append(result.Artifacts, saved)
+			if rc.MaxArtifact > 0 && saved.Size >= rc.MaxArtifact {
+				rc.Logf(models.LogLevelWarn, "响应体超过产物上限 %d 字节，产物只保存了前 %d 字节", rc.MaxArtifact, rc.MaxArtifact)
+			}
+		}
+		buf = buf[:limit]
+		rc.Logf(models.LogLevelWarn, "响应体超过 %d 字节，已截断", limit)
+	}
+	result.Data["truncated"] = truncated
+	result.Output = string(buf)
+
+	if !truncated && len(buf) > 0 {
+		var parsed interface{}
+		if err := json.Unmarshal(buf, &parsed); err == nil {
+			result.Data["body"] = parsed
+		}
+	}
+	return result, buf, nil
+}
+
+// checkHTTPSuccess 按状态码和JSONPath断言判定请求是否成功
+func checkHTTPSuccess(cfg *httpSuccessConfig, statusCode int, body []byte, result *models.ExecutionResult) error {
+	if len(cfg.StatusCodes) > 0 {
+		matched := false
+		for _, code := range cfg.StatusCodes {
+			if code == statusCode {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unexpected status code %d, expected one of %v", statusCode, cfg.StatusCodes)
+		}
+	} else if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", statusCode)
+	}
+
+	if cfg.JSONPath == "" {
+		return nil
+	}
+	if truncated, _ := result.Data["truncated"].(bool); truncated {
+		return fmt.Errorf("cannot evaluate %s: response body truncated", cfg.JSONPath)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("cannot evaluate %s: response is not JSON", cfg.JSONPath)
+	}
+	value, err := evalJSONPath(doc, cfg.JSONPath)
+	if err != nil {
+		return fmt.Errorf("assertion %s failed: %w", cfg.JSONPath, err)
+	}
+	if cfg.Expected != nil && !valuesEqual(value, cfg.Expected) {
+		return fmt.Errorf("assertion %s failed: got %v, expected %v", cfg.JSONPath, value, cfg.Expected)
+	}
+	return nil
+}
+
+// valuesEqual 比较断言值，标量允许按字符串形式相等
+func valuesEqual(actual, expected interface{}) bool {
+	if reflect.DeepEqual(actual, expected) {
+		return true
+	}
+	switch actual.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
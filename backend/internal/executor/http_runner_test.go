@@ -0,0 +1,296 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/models"
+)
+
+// newHTTPTestServer /json返回固定文档，/status/404返回404，/echo回显请求，/large返回100字节文本
+func newHTTPTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"status":"ok","count":2,"items":[{"id":1},{"id":2}]}`)
+	})
+	mux.HandleFunc("/status/404", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":        r.Method,
+			"authorization": r.Header.Get("Authorization"),
+			"content_type":  r.Header.Get("Content-Type"),
+			"x_custom":      r.Header.Get("X-Custom"),
+			"body":          string(body),
+		})
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, strings.Repeat("0123456789", 10))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// echoed 解析/echo返回的请求信息
+func echoed(t *testing.T, result *models.ExecutionResult) map[string]string {
+	t.Helper()
+	var request map[string]string
+	if err := json.Unmarshal([]byte(result.Output), &request); err != nil {
+		t.Fatalf("invalid echo response %q: %v", result.Output, err)
+	}
+	return request
+}
+
+func TestHTTPRunner(t *testing.T) {
+	server := newHTTPTestServer(t)
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+		check   func(t *testing.T, result *models.ExecutionResult)
+	}{
+		{
+			name:    "missing url",
+			params:  map[string]interface{}{},
+			wantErr: "API URL not specified",
+		},
+		{
+			name:   "2xx parses JSON body",
+			params: map[string]interface{}{"url": server.URL + "/json"},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				if result.Data["status_code"] != http.StatusOK || result.Data["truncated"] != false {
+					t.Errorf("data = %v", result.Data)
+				}
+				body, _ := result.Data["body"].(map[string]interface{})
+				if body["status"] != "ok" {
+					t.Errorf("body = %v, want parsed JSON", result.Data["body"])
+				}
+				headers, _ := result.Data["headers"].(map[string]interface{})
+				if headers["Content-Type"] != "application/json" {
+					t.Errorf("headers = %v", headers)
+				}
+			},
+		},
+		{
+			name:    "non-2xx fails by default",
+			params:  map[string]interface{}{"url": server.URL + "/status/404"},
+			wantErr: "unexpected status code 404",
+		},
+		{
+			name:    "status not in status_codes",
+			params:  map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"status_codes": []int{201, 204}}},
+			wantErr: "unexpected status code 200, expected one of [201 204]",
+		},
+		{
+			name:   "status_codes accepts 404",
+			params: map[string]interface{}{"url": server.URL + "/status/404", "success": map[string]interface{}{"status_codes": []int{404}}},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				if result.Data["status_code"] != http.StatusNotFound {
+					t.Errorf("status_code = %v", result.Data["status_code"])
+				}
+			},
+		},
+		{
+			name:   "json_path exists",
+			params: map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"json_path": "$.items[0]"}},
+		},
+		{
+			name:   "json_path matches expected number",
+			params: map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"json_path": "$.items[1].id", "expected": 2}},
+		},
+		{
+			name:   "json_path matches expected as string",
+			params: map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"json_path": "$.count", "expected": "2"}},
+		},
+		{
+			name:    "json_path value mismatch",
+			params:  map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"json_path": "$.status", "expected": "failed"}},
+			wantErr: "assertion $.status failed: got ok, expected failed",
+		},
+		{
+			name:    "json_path missing field",
+			params:  map[string]interface{}{"url": server.URL + "/json", "success": map[string]interface{}{"json_path": "$.missing"}},
+			wantErr: `assertion $.missing failed: field "missing" not found`,
+		},
+		{
+			name:    "json_path on non-JSON response",
+			params:  map[string]interface{}{"url": server.URL + "/large", "success": map[string]interface{}{"json_path": "$.status"}},
+			wantErr: "cannot evaluate $.status: response is not JSON",
+		},
+		{
+			name:   "bearer auth and custom headers",
+			params: map[string]interface{}{"url": server.URL + "/echo", "headers": map[string]string{"X-Custom": "yes"}, "auth": map[string]interface{}{"type": "Bearer", "token": "tok"}},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				request := echoed(t, result)
+				if request["authorization"] != "Bearer tok" || request["x_custom"] != "yes" {
+					t.Errorf("request = %v", request)
+				}
+			},
+		},
+		{
+			name:   "basic auth",
+			params: map[string]interface{}{"url": server.URL + "/echo", "auth": map[string]interface{}{"type": "basic", "username": "user", "password": "pass"}},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				if request := echoed(t, result); request["authorization"] != "Basic dXNlcjpwYXNz" {
+					t.Errorf("authorization = %q", request["authorization"])
+				}
+			},
+		},
+		{
+			name:    "unsupported auth type",
+			params:  map[string]interface{}{"url": server.URL + "/echo", "auth": map[string]interface{}{"type": "digest"}},
+			wantErr: "unsupported auth type: digest",
+		},
+		{
+			name:   "object body is sent as JSON",
+			params: map[string]interface{}{"url": server.URL + "/echo", "method": "post", "body": map[string]interface{}{"name": "x"}},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				request := echoed(t, result)
+				if request["method"] != http.MethodPost || request["content_type"] != "application/json" || request["body"] != `{"name":"x"}` {
+					t.Errorf("request = %v", request)
+				}
+			},
+		},
+		{
+			name:   "string body is sent as is",
+			params: map[string]interface{}{"url": server.URL + "/echo", "method": "PUT", "body": "raw text"},
+			check: func(t *testing.T, result *models.ExecutionResult) {
+				request := echoed(t, result)
+				if request["method"] != http.MethodPut || request["content_type"] != "" || request["body"] != "raw text" {
+					t.Errorf("request = %v", request)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &RunContext{Parameters: tt.params}
+			result, err := NewHTTPRunner().Run(context.Background(), rc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want containing %q", err, tt.wantErr)
+				}
+				if result != nil && result.Success {
+					t.Errorf("Run() result.Success = true on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !result.Success {
+				t.Errorf("Run() result.Success = false")
+			}
+			if tt.check != nil {
+				tt.check(t, result)
+			}
+		})
+	}
+}
+
+func TestHTTPRunnerTruncation(t *testing.T) {
+	server := newHTTPTestServer(t)
+
+	run := func(t *testing.T, params map[string]interface{}, maxArtifact int64) (*models.ExecutionResult, []models.LogEntry, artifact.Store, error) {
+		t.Helper()
+		store := artifact.NewLocalStore(t.TempDir())
+		var mutex sync.Mutex
+		var entries []models.LogEntry
+		rc := &RunContext{
+			Parameters:  params,
+			Artifacts:   store,
+			MaxArtifact: maxArtifact,
+			Sink: func(logged ...models.LogEntry) {
+				mutex.Lock()
+				entries = append(entries, logged...)
+				mutex.Unlock()
+			},
+		}
+		result, err := NewHTTPRunner().Run(context.Background(), rc)
+		return result, entries, store, err
+	}
+
+	t.Run("body above max_body_size is truncated and saved", func(t *testing.T) {
+		result, entries, store, err := run(t, map[string]interface{}{"url": server.URL + "/large", "max_body_size": 10}, 0)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if result.Output != "0123456789" || result.Data["truncated"] != true {
+			t.Errorf("output = %q, truncated = %v", result.Output, result.Data["truncated"])
+		}
+		if len(result.Artifacts) != 1 || result.Artifacts[0].Name != "response_body" || result.Artifacts[0].Size != 100 {
+			t.Fatalf("artifacts = %+v, want full response_body", result.Artifacts)
+		}
+		reader, err := store.Open(context.Background(), result.Artifacts[0].Checksum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		if content, _ := io.ReadAll(reader); string(content) != strings.Repeat("0123456789", 10) {
+			t.Errorf("stored body = %q", content)
+		}
+		if !containsMessage(entries, "响应体超过 10 字节，已截断") {
+			t.Errorf("missing truncation warning in %v", entries)
+		}
+	})
+
+	t.Run("artifact is capped by MaxArtifact", func(t *testing.T) {
+		result, entries, _, err := run(t, map[string]interface{}{"url": server.URL + "/large", "max_body_size": 10}, 50)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if len(result.Artifacts) != 1 || result.Artifacts[0].Size != 50 {
+			t.Fatalf("artifacts = %+v, want 50 byte response_body", result.Artifacts)
+		}
+		if !containsMessage(entries, "响应体超过产物上限 50 字节") {
+			t.Errorf("missing artifact limit warning in %v", entries)
+		}
+	})
+
+	t.Run("json_path on truncated body fails", func(t *testing.T) {
+		params := map[string]interface{}{"url": server.URL + "/json", "max_body_size": 5, "success": map[string]interface{}{"json_path": "$.status"}}
+		result, _, _, err := run(t, params, 0)
+		if err == nil || !strings.Contains(err.Error(), "response body truncated") {
+			t.Fatalf("Run() error = %v, want truncated body error", err)
+		}
+		if result.Success || result.Data["body"] != nil {
+			t.Errorf("result = %+v, want failure without parsed body", result)
+		}
+	})
+
+	t.Run("body at the limit is kept", func(t *testing.T) {
+		result, _, _, err := run(t, map[string]interface{}{"url": server.URL + "/large", "max_body_size": 100}, 0)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if len(result.Output) != 100 || result.Data["truncated"] != false || len(result.Artifacts) != 0 {
+			t.Errorf("output length %d, truncated %v, artifacts %d", len(result.Output), result.Data["truncated"], len(result.Artifacts))
+		}
+	})
+}
+
+// containsMessage 日志中是否有包含text的条目
+func containsMessage(entries []models.LogEntry, text string) bool {
+	for _, entry := range entries {
+		if strings.Contains(entry.Message, text) {
+			return true
+		}
+	}
+	return false
+}
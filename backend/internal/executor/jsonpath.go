@@ -0,0 +1,88 @@
+/**
+ * JSONPath求值
+ * 支持 $.a.b、$.list[0]、$['key'] 这类常用子集
+ */
+
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// evalJSONPath 在已解析的JSON文档上求值JSONPath表达式
+func evalJSONPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("jsonpath must start with '$': %s", path)
+	}
+
+	current := doc
+	rest := path[1:]
+	for rest != "" {
+		var err error
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("empty field name in jsonpath: %s", path)
+			}
+			current, err = jsonPathField(current, key)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in jsonpath: %s", path)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				current, err = jsonPathField(current, selector[1:len(selector)-1])
+			} else {
+				index, convErr := strconv.Atoi(selector)
+				if convErr != nil {
+					return nil, fmt.Errorf("invalid index %q in jsonpath: %s", selector, path)
+				}
+				current, err = jsonPathIndex(current, index)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected character %q in jsonpath: %s", rest[0], path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+func jsonPathField(current interface{}, key string) (interface{}, error) {
+	object, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot read field %q of non-object", key)
+	}
+	value, exists := object[key]
+	if !exists {
+		return nil, fmt.Errorf("field %q not found", key)
+	}
+	return value, nil
+}
+
+func jsonPathIndex(current interface{}, index int) (interface{}, error) {
+	list, ok := current.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot index non-array with [%d]", index)
+	}
+	position := index
+	if position < 0 {
+		position += len(list)
+	}
+	if position < 0 || position >= len(list) {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	return list[position], nil
+}
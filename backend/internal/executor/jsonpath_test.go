@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"status": "ok",
+		"count": 2,
+		"items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}],
+		"dotted.key": true,
+		"nested": {"inner": {"value": null}}
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr string
+	}{
+		{path: "$", want: doc},
+		{path: "$.status", want: "ok"},
+		{path: " $.count ", want: float64(2)},
+		{path: "$.items[0].id", want: float64(1)},
+		{path: "$.items[-1].id", want: float64(2)},
+		{path: "$.items[0].tags[1]", want: "b"},
+		{path: "$['dotted.key']", want: true},
+		{path: `$["nested"].inner.value`, want: nil},
+		{path: "status", wantErr: "must start with '$'"},
+		{path: "$.missing", wantErr: `field "missing" not found`},
+		{path: "$.status.length", wantErr: `cannot read field "length" of non-object`},
+		{path: "$.items[2]", wantErr: "index 2 out of range"},
+		{path: "$.items[-3]", wantErr: "index -3 out of range"},
+		{path: "$.count[0]", wantErr: "cannot index non-array"},
+		{path: "$.items[x]", wantErr: `invalid index "x"`},
+		{path: "$.items[0", wantErr: "unterminated '['"},
+		{path: "$..status", wantErr: "empty field name"},
+		{path: "$status", wantErr: "unexpected character 's'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := evalJSONPath(doc, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("evalJSONPath() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evalJSONPath() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evalJSONPath() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
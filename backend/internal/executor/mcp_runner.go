@@ -0,0 +1,218 @@
+/**
+ * MCP工具运行器
+ * 连接外部MCP服务端，确认工具存在后以参数调用，工具结果写入执行日志和执行结果
+ */
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"aischedule/internal/mcp"
+	"aischedule/internal/models"
+)
+
+// mcpIdleTimeout 连接池中空闲连接的保留时间
+const mcpIdleTimeout = 10 * time.Minute
+
+// MCPRunner MCP工具运行器
+type MCPRunner struct {
+	pool       *mcp.Pool
+	servers    *MCPServerRegistry
+	defaultURL string
+	timeout    time.Duration
+}
+
+// mcpRunnerConfig MCP工具运行器参数
+type mcpRunnerConfig struct {
+	Server    json.RawMessage        `json:"server"`    // 已登记的服务端名称或连接配置，默认连接MCP_SERVER_URL
+	Tool      string                 `json:"tool"`      // 工具名称
+	Arguments map[string]interface{} `json:"arguments"` // 工具参数，支持模板
+	Timeout   int                    `json:"timeout"`   // 超时(秒)，默认MCP_TIMEOUT
+}
+
+// NewMCPRunner 创建新的MCP工具运行器，defaultURL为任务未指定服务端时连接的地址
+func NewMCPRunner(pool *mcp.Pool, servers *MCPServerRegistry, defaultURL string, timeout time.Duration) *MCPRunner {
+	return &MCPRunner{
+		pool:       pool,
+		servers:    servers,
+		defaultURL: defaultURL,
+		timeout:    timeout,
+	}
+}
+
+// Run 调用MCP工具
+func (r *MCPRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg mcpRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Tool == "" {
+		return nil, fmt.Errorf("mcp tool not specified")
+	}
+
+	timeout := r.timeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	server, err := r.serverConfig(ctx, rc, cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := server.ResolveTransport(); err != nil {
+		return nil, err
+	}
+
+	rc.Logf(models.LogLevelInfo, "连接MCP服务端: %s", server.String())
+	var result *mcp.CallToolResult
+	err = r.pool.Do(ctx, server, func(client *mcp.Client) error {
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list mcp tools: %w", err)
+		}
+		if !hasTool(tools, cfg.Tool) {
+			return fmt.Errorf("mcp tool %q not found, available: %s", cfg.Tool, toolNames(tools))
+		}
+
+		rc.Log(models.LogLevelInfo, fmt.Sprintf("调用MCP工具 %s", cfg.Tool), map[string]interface{}{
+			"server":    client.ServerInfo().Name,
+			"arguments": cfg.Arguments,
+		})
+		result, err = client.CallTool(ctx, cfg.Tool, cfg.Arguments)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := writeToolContent(rc, result.Content)
+	data := map[string]interface{}{
+		"server":   server.String(),
+		"tool":     cfg.Tool,
+		"content":  result.Content,
+		"is_error": result.IsError,
+	}
+	var parsed interface{}
+	if json.Unmarshal([]byte(output), &parsed) == nil {
+		data["result"] = parsed
+	}
+
+	execResult := &models.ExecutionResult{
+		Success: !result.IsError,
+		Output:  output,
+		Data:    data,
+	}
+	if result.IsError {
+		execResult.ExitCode = 1
+		return execResult, fmt.Errorf("mcp tool %s failed: %s", cfg.Tool, truncateToolOutput(output))
+	}
+	return execResult, nil
+}
+
+// serverConfig 任务的服务端配置：已登记的服务端按名称查找，stdio服务端继承任务的环境变量和工作目录
+func (r *MCPRunner) serverConfig(ctx context.Context, rc *RunContext, ref json.RawMessage) (mcp.ClientConfig, error) {
+	name, inline, err := parseMCPServerRef(ref)
+	if err != nil {
+		return mcp.ClientConfig{}, err
+	}
+	var cfg mcp.ClientConfig
+	switch {
+	case name != "":
+		if cfg, err = r.servers.Resolve(ctx, name); err != nil {
+			return cfg, err
+		}
+	case inline != nil:
+		cfg = *inline
+	default:
+		return mcp.ClientConfig{URL: r.defaultURL}, nil
+	}
+	if transport, _ := cfg.ResolveTransport(); transport != mcp.TransportStdio {
+		return cfg, nil
+	}
+
+	env := make(map[string]string, len(rc.Environment.EnvironmentVars)+len(cfg.Env))
+	for key, value := range rc.Environment.EnvironmentVars {
+		env[key] = value
+	}
+	for key, value := range cfg.Env {
+		env[key] = value
+	}
+	cfg.Env = env
+	if cfg.Dir == "" {
+		cfg.Dir = rc.Environment.WorkingDirectory
+	}
+	return cfg, nil
+}
+
+// parseMCPServerRef 解析server参数：字符串为已登记的服务端名称，对象为连接配置，为空时两者都不返回
+func parseMCPServerRef(ref json.RawMessage) (string, *mcp.ClientConfig, error) {
+	ref = json.RawMessage(strings.TrimSpace(string(ref)))
+	if len(ref) == 0 || string(ref) == "null" {
+		return "", nil, nil
+	}
+	if ref[0] == '"' {
+		var name string
+		if err := json.Unmarshal(ref, &name); err != nil {
+			return "", nil, fmt.Errorf("invalid mcp server: %w", err)
+		}
+		return name, nil, nil
+	}
+	var cfg mcp.ClientConfig
+	if err := json.Unmarshal(ref, &cfg); err != nil {
+		return "", nil, fmt.Errorf("invalid mcp server: %w", err)
+	}
+	return "", &cfg, nil
+}
+
+// writeToolContent 把工具结果中的文本写入执行日志，返回拼接后的文本
+func writeToolContent(rc *RunContext, content []mcp.Content) string {
+	collector := newOutputCollector(rc)
+	writer := collector.Writer("mcp")
+
+	texts := make([]string, 0, len(content))
+	for _, block := range content {
+		text := block.Text
+		if block.Type != "text" {
+			text = fmt.Sprintf("[%s %s]", block.Type, block.MimeType)
+		}
+		texts = append(texts, text)
+		writer.Write([]byte(text + "\n"))
+	}
+	writer.Flush()
+	collector.Close()
+	return strings.Join(texts, "\n")
+}
+
+// hasTool 工具列表中是否有指定工具
+func hasTool(tools []mcp.Tool, name string) bool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toolNames 工具名称列表，用于错误信息
+func toolNames(tools []mcp.Tool) string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
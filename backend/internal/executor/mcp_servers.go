@@ -0,0 +1,518 @@
+/**
+ * MCP服务端登记
+ * 维护mcp_servers集合，定期执行tools/list缓存工具定义，
+ * 保存任务和工作流时据此检查引用的服务端、工具和参数
+ */
+
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/mcp"
+	"aischedule/internal/models"
+	"aischedule/internal/secrets"
+)
+
+var (
+	// ErrMCPServerNotFound MCP服务端不存在
+	ErrMCPServerNotFound = errors.New("mcp server not found")
+	// ErrMCPServerExists 同名MCP服务端已存在
+	ErrMCPServerExists = errors.New("mcp server already exists")
+)
+
+// MCPServerError MCP服务端配置错误
+type MCPServerError struct {
+	Err error
+}
+
+func (e *MCPServerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MCPServerError) Unwrap() error {
+	return e.Err
+}
+
+// mcpServerNamePattern MCP服务端名称格式
+var mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// maskedValue 响应中代替明文令牌和密码的值
+const maskedValue = "******"
+
+// MCPServerRegistry MCP服务端登记表
+type MCPServerRegistry struct {
+	db      *database.MongoDB
+	secrets *secrets.Store
+	pool    *mcp.Pool
+	timeout time.Duration
+}
+
+// NewMCPServerRegistry 创建MCP服务端登记表，timeout为单次工具发现的超时
+func NewMCPServerRegistry(db *database.MongoDB, store *secrets.Store, pool *mcp.Pool, timeout time.Duration) *MCPServerRegistry {
+	return &MCPServerRegistry{
+		db:      db,
+		secrets: store,
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+// List 按名称列出MCP服务端
+func (r *MCPServerRegistry) List(ctx context.Context) ([]models.MCPServer, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	servers := []models.MCPServer{}
+	if err := cursor.All(ctx, &servers); err != nil {
+		return nil, err
+	}
+	for i := range servers {
+		maskServer(&servers[i])
+	}
+	return servers, nil
+}
+
+// Get 获取MCP服务端，明文令牌和密码已遮盖
+func (r *MCPServerRegistry) Get(ctx context.Context, name string) (*models.MCPServer, error) {
+	server, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	maskServer(server)
+	return server, nil
+}
+
+// Create 登记MCP服务端，随后在后台发现工具
+func (r *MCPServerRegistry) Create(ctx context.Context, req *models.CreateMCPServerRequest) (*models.MCPServer, error) {
+	now := time.Now()
+	server := &models.MCPServer{
+		Name:        req.Name,
+		Description: req.Description,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Transport:   req.Transport,
+		Command:     req.Command,
+		Args:        req.Args,
+		Env:         req.Env,
+		Dir:         req.Dir,
+		URL:         req.URL,
+		Headers:     req.Headers,
+		Auth:        req.Auth,
+		Tools:       []models.MCPToolInfo{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := validateMCPServer(server); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.collection().InsertOne(ctx, server); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrMCPServerExists
+		}
+		return nil, err
+	}
+	r.refreshLater(server)
+	maskServer(server)
+	return server, nil
+}
+
+// Update 更新MCP服务端，连接配置变化后在后台重新发现工具
+func (r *MCPServerRegistry) Update(ctx context.Context, name string, req *models.UpdateMCPServerRequest) (*models.MCPServer, error) {
+	server, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		server.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		server.Enabled = *req.Enabled
+	}
+	if req.Transport != nil {
+		server.Transport = *req.Transport
+	}
+	if req.Command != nil {
+		server.Command = *req.Command
+	}
+	if req.Args != nil {
+		server.Args = *req.Args
+	}
+	if req.Env != nil {
+		server.Env = *req.Env
+	}
+	if req.Dir != nil {
+		server.Dir = *req.Dir
+	}
+	if req.URL != nil {
+		server.URL = *req.URL
+	}
+	if req.Headers != nil {
+		server.Headers = *req.Headers
+	}
+	if req.Auth != nil {
+		// type为空表示移除认证；遮盖后的令牌和密码表示沿用原值
+		auth := *req.Auth
+		if server.Auth != nil {
+			if auth.Token == maskedValue {
+				auth.Token = server.Auth.Token
+			}
+			if auth.Password == maskedValue {
+				auth.Password = server.Auth.Password
+			}
+		}
+		server.Auth = &auth
+		if auth.Type == "" {
+			server.Auth = nil
+		}
+	}
+	if err := validateMCPServer(server); err != nil {
+		return nil, err
+	}
+
+	server.UpdatedAt = time.Now()
+	update := bson.M{
+		"description": server.Description,
+		"enabled":     server.Enabled,
+		"transport":   server.Transport,
+		"command":     server.Command,
+		"args":        server.Args,
+		"env":         server.Env,
+		"dir":         server.Dir,
+		"url":         server.URL,
+		"headers":     server.Headers,
+		"auth":        server.Auth,
+		"updated_at":  server.UpdatedAt,
+	}
+	if _, err := r.collection().UpdateOne(ctx, bson.M{"name": name}, bson.M{"$set": update}); err != nil {
+		return nil, err
+	}
+	r.refreshLater(server)
+	maskServer(server)
+	return server, nil
+}
+
+// Delete 删除MCP服务端
+func (r *MCPServerRegistry) Delete(ctx context.Context, name string) error {
+	result, err := r.collection().DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrMCPServerNotFound
+	}
+	return nil
+}
+
+// Refresh 立即发现MCP服务端的工具并返回更新后的记录，发现失败记录在last_error中
+func (r *MCPServerRegistry) Refresh(ctx context.Context, name string) (*models.MCPServer, error) {
+	server, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.discover(ctx, server)
+	return r.Get(ctx, name)
+}
+
+// RunDiscovery 定期发现所有已启用MCP服务端的工具，interval为0时不执行
+func (r *MCPServerRegistry) RunDiscovery(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cursor, err := r.collection().Find(ctx, bson.M{"enabled": true})
+		if err == nil {
+			var servers []models.MCPServer
+			if err = cursor.All(ctx, &servers); err == nil {
+				for i := range servers {
+					r.discover(ctx, &servers[i])
+				}
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to load mcp servers: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Resolve 按名称获取已启用的MCP服务端的连接配置，令牌、请求头和环境变量中的密钥引用已替换
+func (r *MCPServerRegistry) Resolve(ctx context.Context, name string) (mcp.ClientConfig, error) {
+	server, err := r.find(ctx, name)
+	if err != nil {
+		return mcp.ClientConfig{}, err
+	}
+	if !server.Enabled {
+		return mcp.ClientConfig{}, fmt.Errorf("mcp server %s is disabled", name)
+	}
+	return r.clientConfig(ctx, server)
+}
+
+// ValidateTask 检查mcp_tool参数引用的服务端和工具存在，且参数符合工具的inputSchema；
+// 服务端尚未成功发现过工具时只检查服务端
+func (r *MCPServerRegistry) ValidateTask(ctx context.Context, taskType models.TaskType, params map[string]interface{}) error {
+	if taskType != models.TaskTypeMCPTool {
+		return nil
+	}
+	var cfg mcpRunnerConfig
+	if err := decodeParams(params, &cfg); err != nil {
+		return err
+	}
+	if cfg.Tool == "" {
+		return fmt.Errorf("mcp tool not specified")
+	}
+	name, inline, err := parseMCPServerRef(cfg.Server)
+	if err != nil {
+		return err
+	}
+	if inline != nil {
+		_, err := inline.ResolveTransport()
+		return err
+	}
+	if name == "" || strings.Contains(name, "{{") || strings.Contains(cfg.Tool, "{{") {
+		return nil
+	}
+
+	server, err := r.find(ctx, name)
+	if errors.Is(err, ErrMCPServerNotFound) {
+		return fmt.Errorf("mcp server %q is not registered", name)
+	}
+	if err != nil {
+		return err
+	}
+	if !server.Enabled {
+		return fmt.Errorf("mcp server %q is disabled", name)
+	}
+	if server.ToolsUpdatedAt == nil {
+		return nil
+	}
+
+	for _, tool := range server.Tools {
+		if tool.Name != cfg.Tool {
+			continue
+		}
+		if len(tool.InputSchema) == 0 {
+			return nil
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+			return nil
+		}
+		if err := mcp.ValidateArguments(schema, cfg.Arguments); err != nil {
+			return fmt.Errorf("mcp tool %s: %w", cfg.Tool, err)
+		}
+		return nil
+	}
+
+	names := make([]mcp.Tool, 0, len(server.Tools))
+	for _, tool := range server.Tools {
+		names = append(names, mcp.Tool{Name: tool.Name})
+	}
+	return fmt.Errorf("mcp server %q has no tool %q, available: %s", name, cfg.Tool, toolNames(names))
+}
+
+// discover 执行tools/list并保存结果，失败时保留上一次的工具列表
+func (r *MCPServerRegistry) discover(ctx context.Context, server *models.MCPServer) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var tools []mcp.Tool
+	cfg, err := r.clientConfig(ctx, server)
+	if err == nil {
+		err = r.pool.Do(ctx, cfg, func(client *mcp.Client) error {
+			var listErr error
+			tools, listErr = client.ListTools(ctx)
+			return listErr
+		})
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"last_checked_at": now}}
+	if err != nil {
+		update["$set"].(bson.M)["last_error"] = err.Error()
+	} else {
+		infos := make([]models.MCPToolInfo, 0, len(tools))
+		for _, tool := range tools {
+			schema, _ := json.Marshal(tool.InputSchema)
+			infos = append(infos, models.MCPToolInfo{Name: tool.Name, Description: tool.Description, InputSchema: schema})
+		}
+		update["$set"].(bson.M)["tools"] = infos
+		update["$set"].(bson.M)["tools_updated_at"] = now
+		update["$unset"] = bson.M{"last_error": ""}
+	}
+
+	// 使用独立的ctx，发现超时后仍然记录错误
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	if _, saveErr := r.collection().UpdateOne(saveCtx, bson.M{"_id": server.ID}, update); saveErr != nil {
+		log.Printf("Failed to save tools of mcp server %s: %v", server.Name, saveErr)
+	}
+	if err != nil {
+		log.Printf("Failed to discover tools of mcp server %s: %v", server.Name, err)
+	}
+}
+
+// refreshLater 在后台发现已启用服务端的工具
+func (r *MCPServerRegistry) refreshLater(server *models.MCPServer) {
+	if !server.Enabled {
+		return
+	}
+	copied := *server
+	go r.discover(context.Background(), &copied)
+}
+
+// clientConfig 构造连接配置：认证转换为请求头，并替换其中的密钥引用
+func (r *MCPServerRegistry) clientConfig(ctx context.Context, server *models.MCPServer) (mcp.ClientConfig, error) {
+	cfg := mcp.ClientConfig{
+		Transport: server.Transport,
+		Command:   server.Command,
+		Args:      server.Args,
+		Dir:       server.Dir,
+		URL:       server.URL,
+	}
+
+	refs := append(secrets.CollectRefs(server.Headers), secrets.CollectRefs(server.Env)...)
+	if server.Auth != nil {
+		refs = append(refs, secrets.CollectRefs([]string{server.Auth.Token, server.Auth.Username, server.Auth.Password})...)
+	}
+	resolver := r.secrets.NewResolver(refs)
+
+	headers, err := resolver.ResolveEnv(ctx, server.Headers)
+	if err != nil {
+		return cfg, err
+	}
+	if auth := server.Auth; auth != nil {
+		if headers == nil {
+			headers = make(map[string]string, 1)
+		}
+		values, err := resolver.ResolveEnv(ctx, map[string]string{
+			"token":    auth.Token,
+			"username": auth.Username,
+			"password": auth.Password,
+		})
+		if err != nil {
+			return cfg, err
+		}
+		switch auth.Type {
+		case "bearer":
+			headers["Authorization"] = "Bearer " + values["token"]
+		case "basic":
+			credentials := base64.StdEncoding.EncodeToString([]byte(values["username"] + ":" + values["password"]))
+			headers["Authorization"] = "Basic " + credentials
+		case "header":
+			headers[auth.Header] = values["token"]
+		}
+	}
+	cfg.Headers = headers
+
+	if cfg.Env, err = resolver.ResolveEnv(ctx, server.Env); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (r *MCPServerRegistry) find(ctx context.Context, name string) (*models.MCPServer, error) {
+	var server models.MCPServer
+	err := r.collection().FindOne(ctx, bson.M{"name": name}).Decode(&server)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMCPServerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+func (r *MCPServerRegistry) collection() *mongo.Collection {
+	return r.db.GetCollection("mcp_servers")
+}
+
+// validateMCPServer 校验名称、传输方式和认证配置
+func validateMCPServer(server *models.MCPServer) error {
+	if !mcpServerNamePattern.MatchString(server.Name) {
+		return &MCPServerError{fmt.Errorf("invalid mcp server name %q: only letters, digits, '_', '.' and '-' are allowed", server.Name)}
+	}
+
+	cfg := mcp.ClientConfig{Transport: server.Transport, Command: server.Command, URL: server.URL}
+	transport, err := cfg.ResolveTransport()
+	if err != nil {
+		return &MCPServerError{err}
+	}
+	server.Transport = transport
+
+	switch transport {
+	case mcp.TransportStdio:
+		if server.Command == "" {
+			return &MCPServerError{fmt.Errorf("stdio transport requires command")}
+		}
+		if server.Auth != nil {
+			return &MCPServerError{fmt.Errorf("auth is not supported by stdio transport, pass credentials in env")}
+		}
+	case mcp.TransportWebSocket:
+		if !strings.HasPrefix(server.URL, "ws://") && !strings.HasPrefix(server.URL, "wss://") {
+			return &MCPServerError{fmt.Errorf("websocket transport requires a ws:// or wss:// url")}
+		}
+	case mcp.TransportHTTP:
+		if !strings.HasPrefix(server.URL, "http://") && !strings.HasPrefix(server.URL, "https://") {
+			return &MCPServerError{fmt.Errorf("http transport requires an http:// or https:// url")}
+		}
+	}
+
+	if auth := server.Auth; auth != nil {
+		switch auth.Type {
+		case "bearer":
+			if auth.Token == "" {
+				return &MCPServerError{fmt.Errorf("bearer auth requires token")}
+			}
+		case "basic":
+			if auth.Username == "" {
+				return &MCPServerError{fmt.Errorf("basic auth requires username")}
+			}
+		case "header":
+			if auth.Header == "" || auth.Token == "" {
+				return &MCPServerError{fmt.Errorf("header auth requires header and token")}
+			}
+		default:
+			return &MCPServerError{fmt.Errorf("unsupported auth type: %s", auth.Type)}
+		}
+	}
+	return nil
+}
+
+// maskServer 遮盖响应中的明文令牌和密码，密钥引用原样返回
+func maskServer(server *models.MCPServer) {
+	if server.Auth == nil {
+		return
+	}
+	auth := *server.Auth
+	if auth.Token != "" && len(secrets.Refs(auth.Token)) == 0 {
+		auth.Token = maskedValue
+	}
+	if auth.Password != "" && len(secrets.Refs(auth.Password)) == 0 {
+		auth.Password = maskedValue
+	}
+	server.Auth = &auth
+}
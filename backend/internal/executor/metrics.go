@@ -0,0 +1,125 @@
+/**
+ * 执行性能指标采样
+ * 进程运行期间定时采样进程树的资源使用，并汇总峰值和平均值
+ */
+
+package executor
+
+import (
+	"sync"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+// MetricsSink 性能指标的写入端
+type MetricsSink func(metric models.PerformanceMetrics)
+
+// metricsSampler 进程树的性能指标采样器
+type metricsSampler struct {
+	rc       *RunContext
+	probe    *processProbe
+	interval time.Duration
+
+	mutex   sync.Mutex
+	summary models.MetricsSummary
+	sumCPU  float64
+	sumRSS  int64
+	sumDisk float64
+	sumNet  float64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startMetricsSampler 开始采样以pid为进程组首进程的进程树，间隔为0时不采样
+func startMetricsSampler(rc *RunContext, pid int) *metricsSampler {
+	if rc.MetricsInterval <= 0 {
+		return nil
+	}
+	probe := newProcessProbe(pid)
+	if probe == nil {
+		return nil
+	}
+
+	s := &metricsSampler{
+		rc:       rc,
+		probe:    probe,
+		interval: rc.MetricsInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *metricsSampler) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			metric, ok := s.probe.sample()
+			if !ok {
+				continue
+			}
+			s.record(metric)
+			if s.rc.Metrics != nil {
+				s.rc.Metrics(metric)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// record 累计到汇总
+func (s *metricsSampler) record(metric models.PerformanceMetrics) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.summary.Samples++
+	s.sumCPU += metric.CPUUsage
+	s.sumRSS += metric.MemoryRSS
+	s.sumDisk += metric.DiskIO
+	s.sumNet += metric.NetworkIO
+
+	if metric.CPUUsage > s.summary.PeakCPU {
+		s.summary.PeakCPU = metric.CPUUsage
+	}
+	if metric.MemoryRSS > s.summary.PeakMemoryRSS {
+		s.summary.PeakMemoryRSS = metric.MemoryRSS
+	}
+	if metric.DiskIO > s.summary.PeakDiskIO {
+		s.summary.PeakDiskIO = metric.DiskIO
+	}
+	if metric.NetworkIO > s.summary.PeakNetworkIO {
+		s.summary.PeakNetworkIO = metric.NetworkIO
+	}
+}
+
+// Stop 停止采样并返回汇总，未采到样本时返回nil
+func (s *metricsSampler) Stop() *models.MetricsSummary {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.summary.Samples == 0 {
+		return nil
+	}
+	summary := s.summary
+	n := float64(summary.Samples)
+	summary.AvgCPU = s.sumCPU / n
+	summary.AvgMemoryRSS = s.sumRSS / int64(summary.Samples)
+	summary.AvgDiskIO = s.sumDisk / n
+	summary.AvgNetworkIO = s.sumNet / n
+	return &summary
+}
@@ -0,0 +1,251 @@
+//go:build linux
+
+/**
+ * 基于/proc的进程树资源采样
+ */
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+const (
+	procRoot   = "/proc"
+	clockTicks = 100 // USER_HZ，Linux上固定为100
+	megabyte   = 1024 * 1024
+)
+
+// procCounters 单个进程的累计计数
+type procCounters struct {
+	cpuTicks  uint64
+	diskBytes uint64
+}
+
+// processProbe 采样进程树：根进程的所有后代以及同进程组的进程
+type processProbe struct {
+	pgid     int
+	memTotal int64
+	pageSize int64
+
+	lastTime time.Time
+	lastProc map[int]procCounters
+	lastNet  uint64
+}
+
+// newProcessProbe 创建以pgid为根的进程树采样器
+func newProcessProbe(pgid int) *processProbe {
+	p := &processProbe{
+		pgid:     pgid,
+		memTotal: readMemTotal(),
+		pageSize: int64(os.Getpagesize()),
+		lastTime: time.Now(),
+		lastProc: make(map[int]procCounters),
+	}
+	p.lastNet, _ = readNetBytes(pgid)
+	return p
+}
+
+// sample 采样一次，进程组已无进程时返回false
+func (p *processProbe) sample() (models.PerformanceMetrics, bool) {
+	now := time.Now()
+	elapsed := now.Sub(p.lastTime).Seconds()
+	if elapsed <= 0 {
+		return models.PerformanceMetrics{}, false
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return models.PerformanceMetrics{}, false
+	}
+
+	stats := make(map[int]procStat)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if stat, ok := readProcStat(pid); ok {
+			stats[pid] = stat
+		}
+	}
+
+	current := make(map[int]procCounters)
+	var rss int64
+	var cpuDelta, diskDelta uint64
+	for pid, stat := range stats {
+		if stat.pgid != p.pgid && !isDescendant(pid, p.pgid, stats) {
+			continue
+		}
+
+		counters := procCounters{
+			cpuTicks:  stat.cpuTicks,
+			diskBytes: readProcDiskBytes(pid),
+		}
+		current[pid] = counters
+		rss += stat.rssPages * p.pageSize
+
+		// 新出现的进程按全部累计量计入本次采样
+		previous := p.lastProc[pid]
+		if counters.cpuTicks >= previous.cpuTicks {
+			cpuDelta += counters.cpuTicks - previous.cpuTicks
+		}
+		if counters.diskBytes >= previous.diskBytes {
+			diskDelta += counters.diskBytes - previous.diskBytes
+		}
+	}
+	if len(current) == 0 {
+		return models.PerformanceMetrics{}, false
+	}
+
+	// 网络计数为进程所在网络命名空间的接口总量
+	var netDelta uint64
+	if netBytes, ok := readNetBytes(p.pgid); ok {
+		if netBytes >= p.lastNet {
+			netDelta = netBytes - p.lastNet
+		}
+		p.lastNet = netBytes
+	}
+
+	p.lastTime = now
+	p.lastProc = current
+
+	metric := models.PerformanceMetrics{
+		CPUUsage:  float64(cpuDelta) / clockTicks / elapsed * 100,
+		MemoryRSS: rss,
+		DiskIO:    float64(diskDelta) / megabyte / elapsed,
+		NetworkIO: float64(netDelta) / megabyte / elapsed,
+		Timestamp: now,
+	}
+	if p.memTotal > 0 {
+		metric.MemoryUsage = float64(rss) / float64(p.memTotal) * 100
+	}
+	return metric, true
+}
+
+// isDescendant 判断pid是否为root的后代进程
+func isDescendant(pid, root int, stats map[int]procStat) bool {
+	for depth := 0; depth < 64; depth++ {
+		stat, ok := stats[pid]
+		if !ok || stat.ppid <= 1 {
+			return false
+		}
+		if stat.ppid == root {
+			return true
+		}
+		pid = stat.ppid
+	}
+	return false
+}
+
+// procStat /proc/[pid]/stat 中用到的字段
+type procStat struct {
+	ppid     int
+	pgid     int
+	cpuTicks uint64
+	rssPages int64
+}
+
+func readProcStat(pid int) (procStat, bool) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, false
+	}
+
+	// 进程名可能包含空格，从最后一个')'之后开始解析
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	// fields[0]为第3个字段(state)
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+
+	ppid, _ := strconv.Atoi(fields[1])
+	pgid, _ := strconv.Atoi(fields[2])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+	return procStat{
+		ppid:     ppid,
+		pgid:     pgid,
+		cpuTicks: utime + stime,
+		rssPages: rss,
+	}, true
+}
+
+// readProcDiskBytes 读取进程累计的磁盘读写字节数
+func readProcDiskBytes(pid int) uint64 {
+	file, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "io"))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	var total uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (key != "read_bytes" && key != "write_bytes") {
+			continue
+		}
+		n, _ := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		total += n
+	}
+	return total
+}
+
+// readNetBytes 读取进程所在网络命名空间除lo外的收发字节总数
+func readNetBytes(pid int) (uint64, bool) {
+	file, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "net", "dev"))
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	var total uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		total += rx + tx
+	}
+	return total, true
+}
+
+// readMemTotal 读取系统总内存(字节)
+func readMemTotal() int64 {
+	file, err := os.Open(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
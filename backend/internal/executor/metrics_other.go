@@ -0,0 +1,19 @@
+//go:build !linux
+
+/**
+ * 非Linux平台不支持进程树采样
+ */
+
+package executor
+
+import "aischedule/internal/models"
+
+type processProbe struct{}
+
+func newProcessProbe(pgid int) *processProbe {
+	return nil
+}
+
+func (p *processProbe) sample() (models.PerformanceMetrics, bool) {
+	return models.PerformanceMetrics{}, false
+}
@@ -0,0 +1,188 @@
+/**
+ * 进程输出收集
+ * 将stdout/stderr按行写入执行日志并实时推送，同时限制保留的输出总量
+ */
+
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+const (
+	maxLineLength      = 64 * 1024 // 单行最大长度，超出部分按新行处理
+	outputFlushSize    = 100       // 缓冲达到该行数时提前写入
+	outputFlushPeriod  = 200 * time.Millisecond
+	defaultOutputLimit = 1 << 20
+)
+
+// outputCollector 进程输出收集器
+type outputCollector struct {
+	rc    *RunContext
+	limit int64
+
+	mutex    sync.Mutex
+	pending  []models.LogEntry
+	retained strings.Builder
+	size     int64
+	dropped  int64 // 超出上限被丢弃的行数
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newOutputCollector 创建输出收集器并启动定时刷新
+func newOutputCollector(rc *RunContext) *outputCollector {
+	limit := rc.MaxOutput
+	if limit <= 0 {
+		limit = defaultOutputLimit
+	}
+
+	c := &outputCollector{
+		rc:    rc,
+		limit: limit,
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go c.flushLoop()
+	return c
+}
+
+// Writer 返回指定输出流的写入端
+func (c *outputCollector) Writer(stream string) *streamWriter {
+	return &streamWriter{collector: c, stream: stream}
+}
+
+// Close 停止刷新并写入剩余日志，多次调用是安全的
+func (c *outputCollector) Close() {
+	select {
+	case <-c.stop:
+		return
+	default:
+		close(c.stop)
+	}
+	<-c.done
+
+	c.mutex.Lock()
+	dropped := c.dropped
+	c.mutex.Unlock()
+	if dropped > 0 {
+		c.rc.Log(models.LogLevelWarn, "进程输出超过保留上限，部分输出已丢弃", map[string]interface{}{
+			"limit_bytes":   c.limit,
+			"dropped_lines": dropped,
+		})
+	}
+}
+
+// Output 返回保留的输出内容
+func (c *outputCollector) Output() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.retained.String()
+}
+
+// addLine 记录一行输出
+func (c *outputCollector) addLine(stream string, line []byte) {
+	text := strings.TrimSuffix(string(line), "\r")
+
+	c.mutex.Lock()
+	if c.size+int64(len(text))+1 > c.limit {
+		c.dropped++
+		c.mutex.Unlock()
+		return
+	}
+	c.size += int64(len(text)) + 1
+	c.retained.WriteString(text)
+	c.retained.WriteByte('\n')
+
+	c.pending = append(c.pending, models.LogEntry{
+		Timestamp: time.Now(),
+		Level:     models.LogLevelInfo,
+		Message:   text,
+		Stream:    stream,
+	})
+	full := len(c.pending) >= outputFlushSize
+	c.mutex.Unlock()
+
+	if full {
+		select {
+		case c.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flushLoop 定时把缓冲的日志写入执行日志，所有写入都在此协程中进行以保证顺序
+func (c *outputCollector) flushLoop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(outputFlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.kick:
+			c.flush()
+		case <-c.stop:
+			c.flush()
+			return
+		}
+	}
+}
+
+func (c *outputCollector) flush() {
+	c.mutex.Lock()
+	batch := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+
+	c.rc.Emit(batch...)
+}
+
+// streamWriter 按行切分写入的数据
+type streamWriter struct {
+	collector *outputCollector
+	stream    string
+	buf       []byte
+}
+
+// Write 实现io.Writer
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	consumed := 0
+	for {
+		i := bytes.IndexByte(w.buf[consumed:], '\n')
+		if i < 0 {
+			break
+		}
+		w.collector.addLine(w.stream, w.buf[consumed:consumed+i])
+		consumed += i + 1
+	}
+	for len(w.buf)-consumed >= maxLineLength {
+		w.collector.addLine(w.stream, w.buf[consumed:consumed+maxLineLength])
+		consumed += maxLineLength
+	}
+
+	if consumed > 0 {
+		w.buf = append(w.buf[:0], w.buf[consumed:]...)
+	}
+	return len(p), nil
+}
+
+// Flush 写入最后一段不以换行结尾的输出
+func (w *streamWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.collector.addLine(w.stream, w.buf)
+		w.buf = nil
+	}
+}
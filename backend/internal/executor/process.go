@@ -0,0 +1,201 @@
+/**
+ * 本地进程运行
+ * 负责按执行环境配置启动子进程：工作目录、环境变量、资源限制和输出采集
+ */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"aischedule/internal/config"
+	"aischedule/internal/models"
+)
+
+// processWaitDelay 进程退出后等待输出管道关闭的最长时间
+const processWaitDelay = 5 * time.Second
+
+// processLimits 解析后的资源限制，零值表示不限制
+type processLimits struct {
+	CPUMillis   int64         // CPU限制（千分之一核）
+	MemoryBytes int64         // 内存限制（字节）
+	TimeLimit   time.Duration // 运行时间限制
+}
+
+// runProcess 按执行环境启动进程，实时采集输出并等待其退出
+func runProcess(ctx context.Context, rc *RunContext, name string, args []string) (*models.ExecutionResult, error) {
+	limits, err := parseResourceLimits(rc.Environment.ResourceLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := rc.Environment.WorkingDirectory; dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid working directory: %s is not a directory", dir)
+		}
+	}
+
+	if limits.TimeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.TimeLimit)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = rc.Environment.WorkingDirectory
+	cmd.Env = mergeEnv(config.ChildEnv(), rc.Environment.EnvironmentVars)
+	cmd.WaitDelay = processWaitDelay
+
+	sandbox := newProcessSandbox(rc, limits)
+	defer sandbox.cleanup()
+	sandbox.prepare(cmd)
+
+	collector := newOutputCollector(rc)
+	stdout := collector.Writer("stdout")
+	stderr := collector.Writer("stderr")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	result := &models.ExecutionResult{ExitCode: -1}
+
+	err = cmd.Start()
+	if err == nil {
+		sampler := startMetricsSampler(rc, cmd.Process.Pid)
+		err = cmd.Wait()
+		result.Metrics = sampler.Stop()
+	}
+	stdout.Flush()
+	stderr.Flush()
+	collector.Close()
+
+	result.Output = collector.Output()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if err != nil {
+		if limits.TimeLimit > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("time limit of %s exceeded: %w", limits.TimeLimit, context.DeadlineExceeded)
+			rc.Logf(models.LogLevelError, "进程运行超过时间限制 %s，已终止整个进程组", limits.TimeLimit)
+			return result, err
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			rc.Logf(models.LogLevelError, "脚本执行失败，退出码 %d", result.ExitCode)
+		} else {
+			rc.Logf(models.LogLevelError, "脚本执行失败: %v", err)
+		}
+		return result, err
+	}
+
+	rc.Logf(models.LogLevelInfo, "脚本执行成功，退出码 %d", result.ExitCode)
+	return result, nil
+}
+
+// mergeEnv 在继承的环境变量上叠加任务配置的环境变量
+func mergeEnv(base []string, overrides map[string]string) []string {
+	env := make([]string, 0, len(base)+len(overrides))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, overridden := overrides[key]; overridden {
+			continue
+		}
+		env = append(env, kv)
+	}
+	for key, value := range overrides {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// parseResourceLimits 解析资源限制配置
+func parseResourceLimits(limits models.ResourceLimits) (processLimits, error) {
+	var parsed processLimits
+	var err error
+
+	if parsed.CPUMillis, err = parseCPULimit(limits.CPULimit); err != nil {
+		return parsed, err
+	}
+	if parsed.MemoryBytes, err = parseMemoryLimit(limits.MemoryLimit); err != nil {
+		return parsed, err
+	}
+	if limits.TimeLimit > 0 {
+		parsed.TimeLimit = time.Duration(limits.TimeLimit) * time.Second
+	}
+	return parsed, nil
+}
+
+// parseCPULimit 解析CPU限制，支持 "500m"、"0.5"、"2" 等格式
+func parseCPULimit(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(value, "m") {
+		millis, err := strconv.ParseInt(strings.TrimSuffix(value, "m"), 10, 64)
+		if err != nil || millis <= 0 {
+			return 0, fmt.Errorf("invalid cpu limit: %s", value)
+		}
+		return millis, nil
+	}
+
+	cores, err := strconv.ParseFloat(value, 64)
+	if err != nil || cores <= 0 {
+		return 0, fmt.Errorf("invalid cpu limit: %s", value)
+	}
+	return int64(math.Ceil(cores * 1000)), nil
+}
+
+// memoryUnits 内存单位，二进制单位(Ki/Mi/Gi)和十进制单位(K/M/G)
+var memoryUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"K", 1000},
+	{"k", 1000},
+	{"M", 1000 * 1000},
+	{"G", 1000 * 1000 * 1000},
+	{"T", 1000 * 1000 * 1000 * 1000},
+}
+
+// parseMemoryLimit 解析内存限制，支持 "256Mi"、"1G"、"1048576" 等格式
+func parseMemoryLimit(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	number := value
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			number = strings.TrimSuffix(value, unit.suffix)
+			break
+		}
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("invalid memory limit: %s", value)
+	}
+	return int64(amount * float64(multiplier)), nil
+}
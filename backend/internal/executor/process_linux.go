@@ -0,0 +1,162 @@
+//go:build linux
+
+/**
+ * Linux进程沙箱
+ * 通过进程组、cgroups v2（不可用时退化为rlimit）限制子进程。
+ * rlimit由本程序以包装器身份启动时在exec目标程序之前设置，子进程及其派生的进程从第一条指令起受限
+ */
+
+package executor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"aischedule/internal/models"
+)
+
+// limitWrapperArg 以rlimit包装器身份启动本程序的参数：
+// <本程序> limitWrapperArg <内存字节> <CPU秒> <目标程序路径> <目标程序argv...>
+const limitWrapperArg = "__aischedule_rlimit_exec"
+
+// processSandbox 子进程的资源隔离
+type processSandbox struct {
+	rc     *RunContext
+	limits processLimits
+	cgroup *cgroup
+}
+
+// newProcessSandbox 创建进程沙箱，优先使用cgroups v2
+func newProcessSandbox(rc *RunContext, limits processLimits) *processSandbox {
+	s := &processSandbox{rc: rc, limits: limits}
+
+	if limits.CPUMillis > 0 || limits.MemoryBytes > 0 {
+		cg, err := createCgroup(rc.CgroupRoot, rc.ExecutionID.Hex(), limits)
+		if err != nil {
+			rc.Logf(models.LogLevelWarn, "cgroups v2 不可用，改用rlimit限制资源: %v", err)
+		} else {
+			s.cgroup = cg
+		}
+	}
+	return s
+}
+
+// prepare 在进程启动前配置进程组和cgroup，未使用cgroup时改为通过rlimit包装器启动
+func (s *processSandbox) prepare(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if s.cgroup != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(s.cgroup.dir.Fd())
+	} else {
+		s.wrap(cmd)
+	}
+
+	// 超时或取消时强制结束整个进程组，避免遗留孙进程
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+}
+
+// wrap 改为由本程序的rlimit包装器启动目标程序。进程启动后再设置rlimit存在窗口期，
+// 窗口期内派生的孙进程不受限制，因此限制必须在exec之前生效
+func (s *processSandbox) wrap(cmd *exec.Cmd) {
+	var memory, cpu uint64
+	if s.limits.MemoryBytes > 0 {
+		memory = uint64(s.limits.MemoryBytes)
+	}
+	if s.limits.CPUMillis > 0 {
+		// rlimit只能限制CPU总时间，按 核数 × 时间限制 折算
+		if s.limits.TimeLimit > 0 {
+			cpu = uint64(math.Ceil(float64(s.limits.CPUMillis) / 1000 * s.limits.TimeLimit.Seconds()))
+		} else {
+			s.rc.Logf(models.LogLevelWarn, "未配置时间限制，无法通过rlimit限制CPU")
+		}
+	}
+	// 目标程序不存在时由Start返回原来的错误
+	if (memory == 0 && cpu == 0) || cmd.Err != nil {
+		return
+	}
+
+	args := []string{cmd.Args[0], limitWrapperArg, strconv.FormatUint(memory, 10), strconv.FormatUint(cpu, 10), cmd.Path}
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+}
+
+// RunLimitWrapper 本程序以rlimit包装器身份启动时，设置资源限制后exec目标程序，不再返回；
+// 其他情况直接返回。需要在main的最开始调用
+func RunLimitWrapper() {
+	if len(os.Args) < 6 || os.Args[1] != limitWrapperArg {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "rlimit wrapper: "+format+"\n", args...)
+		os.Exit(126)
+	}
+
+	memory, err := strconv.ParseUint(os.Args[2], 10, 64)
+	if err != nil {
+		fail("invalid memory limit %q", os.Args[2])
+	}
+	cpu, err := strconv.ParseUint(os.Args[3], 10, 64)
+	if err != nil {
+		fail("invalid cpu limit %q", os.Args[3])
+	}
+	path := os.Args[4]
+
+	// 先准备好exec的参数：内存限制可能低于本进程已占用的地址空间，设置之后不能再分配内存
+	pathp, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		fail("%v", err)
+	}
+	argv, err := syscall.SlicePtrFromStrings(os.Args[5:])
+	if err != nil {
+		fail("%v", err)
+	}
+	envv, err := syscall.SlicePtrFromStrings(os.Environ())
+	if err != nil {
+		fail("%v", err)
+	}
+
+	if memory > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: memory, Max: memory}); err != nil {
+			fail("set memory limit: %v", err)
+		}
+	}
+	if cpu > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpu, Max: cpu}); err != nil {
+			fail("set cpu limit: %v", err)
+		}
+	}
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_EXECVE,
+		uintptr(unsafe.Pointer(pathp)),
+		uintptr(unsafe.Pointer(&argv[0])),
+		uintptr(unsafe.Pointer(&envv[0])))
+	fail("exec %s: %v", path, errno)
+}
+
+// cleanup 进程结束后释放cgroup
+func (s *processSandbox) cleanup() {
+	if s.cgroup != nil {
+		s.cgroup.destroy()
+	}
+}
+
+// killProcessGroup 向整个进程组发送SIGKILL
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+
+/**
+ * 非Linux平台的进程沙箱
+ * 只支持时间限制，CPU和内存限制不生效
+ */
+
+package executor
+
+import (
+	"os/exec"
+
+	"aischedule/internal/models"
+)
+
+// processSandbox 子进程的资源隔离
+type processSandbox struct{}
+
+// newProcessSandbox 创建进程沙箱
+func newProcessSandbox(rc *RunContext, limits processLimits) *processSandbox {
+	if limits.CPUMillis > 0 || limits.MemoryBytes > 0 {
+		rc.Logf(models.LogLevelWarn, "当前平台不支持CPU和内存限制，仅时间限制生效")
+	}
+	return &processSandbox{}
+}
+
+func (s *processSandbox) prepare(cmd *exec.Cmd) {}
+
+func (s *processSandbox) cleanup() {}
+
+// RunLimitWrapper 只在Linux上使用rlimit包装器
+func RunLimitWrapper() {}
+
+// killProcessGroup 结束进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
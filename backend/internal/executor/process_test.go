@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+)
+
+func TestMergeEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      []string
+		overrides map[string]string
+		want      []string
+	}{
+		{
+			name: "no overrides",
+			base: []string{"A=1", "B=2"},
+			want: []string{"A=1", "B=2"},
+		},
+		{
+			name:      "override replaces inherited value",
+			base:      []string{"A=1", "B=2"},
+			overrides: map[string]string{"B": "3"},
+			want:      []string{"A=1", "B=3"},
+		},
+		{
+			name:      "override adds new variable",
+			base:      []string{"A=1"},
+			overrides: map[string]string{"C": "x=y"},
+			want:      []string{"A=1", "C=x=y"},
+		},
+		{
+			name:      "empty override value is kept",
+			base:      []string{"A=1"},
+			overrides: map[string]string{"A": ""},
+			want:      []string{"A="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeEnv(tt.base, tt.overrides)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunProcessEnvironment 服务的密钥和令牌不会出现在任务进程的环境变量中，
+// 任务配置的环境变量仍然生效
+func TestRunProcessEnvironment(t *testing.T) {
+	if _, err := exec.LookPath("env"); err != nil {
+		t.Skip("env not available")
+	}
+	secrets := map[string]string{
+		"SECRETS_MASTER_KEY": "master-key-value",
+		"ADMIN_TOKEN":        "admin-token-value",
+		"API_TOKEN":          "api-token-value",
+		"JWT_SECRET":         "jwt-secret-value",
+		"MONGODB_URI":        "mongodb://user:password@db:27017",
+		"LLM_API_KEY":        "llm-key-value",
+	}
+	for key, value := range secrets {
+		t.Setenv(key, value)
+	}
+	t.Setenv("INHERITED_VAR", "inherited")
+
+	rc := &RunContext{
+		ExecutionID: primitive.NewObjectID(),
+		Environment: models.ExecutionEnvironment{
+			EnvironmentVars: map[string]string{"TASK_VAR": "task", "INHERITED_VAR": "overridden"},
+		},
+	}
+	result, err := runProcess(context.Background(), rc, "env", nil)
+	if err != nil {
+		t.Fatalf("runProcess() error = %v", err)
+	}
+
+	for key, value := range secrets {
+		if strings.Contains(result.Output, key+"=") || strings.Contains(result.Output, value) {
+			t.Errorf("child environment exposes %s", key)
+		}
+	}
+	for _, line := range []string{"TASK_VAR=task", "INHERITED_VAR=overridden"} {
+		if !strings.Contains(result.Output, line+"\n") {
+			t.Errorf("child environment missing %s:\n%s", line, result.Output)
+		}
+	}
+}
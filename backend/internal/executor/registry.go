@@ -0,0 +1,95 @@
+/**
+ * 运行中执行的登记表
+ * 按ExecutionID记录正在运行的执行及其取消函数
+ */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrExecutionNotRunning 执行不在本进程中运行
+var ErrExecutionNotRunning = errors.New("execution is not running")
+
+// CancelError 执行被取消的原因
+type CancelError struct {
+	By     string // 认证身份
+	Label  string // 调用方自报的说明
+	Reason string
+	At     time.Time
+}
+
+func (e *CancelError) Error() string {
+	return fmt.Sprintf("execution cancelled by %s", e.By)
+}
+
+// ExecutionRegistry 运行中执行的登记表
+type ExecutionRegistry struct {
+	executions map[string]context.CancelCauseFunc
+	mutex      sync.RWMutex
+}
+
+// NewExecutionRegistry 创建新的登记表
+func NewExecutionRegistry() *ExecutionRegistry {
+	return &ExecutionRegistry{
+		executions: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// register 登记执行，返回可被取消的上下文和注销函数
+func (r *ExecutionRegistry) register(ctx context.Context, executionID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	r.mutex.Lock()
+	r.executions[executionID] = cancel
+	r.mutex.Unlock()
+
+	return ctx, func() {
+		r.mutex.Lock()
+		delete(r.executions, executionID)
+		r.mutex.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel 取消运行中的执行，by为取消者的认证身份，label为调用方自报的说明
+func (r *ExecutionRegistry) Cancel(executionID, by, label, reason string) error {
+	r.mutex.RLock()
+	cancel, exists := r.executions[executionID]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return ErrExecutionNotRunning
+	}
+	cancel(&CancelError{By: by, Label: label, Reason: reason, At: time.Now()})
+	return nil
+}
+
+// IsRunning 检查执行是否正在运行
+func (r *ExecutionRegistry) IsRunning(executionID string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, exists := r.executions[executionID]
+	return exists
+}
+
+// Count 获取运行中的执行数量
+func (r *ExecutionRegistry) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.executions)
+}
+
+// cancelCause 返回上下文被取消的原因（如果是通过Cancel取消的）
+func cancelCause(ctx context.Context) *CancelError {
+	var cancelErr *CancelError
+	if errors.As(context.Cause(ctx), &cancelErr) {
+		return cancelErr
+	}
+	return nil
+}
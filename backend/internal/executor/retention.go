@@ -0,0 +1,132 @@
+/**
+ * 执行日志保留
+ * 删除执行日志时一并释放其产物，产物内容在不再被任何日志或备份记录引用后才删除
+ */
+
+package executor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/models"
+)
+
+// retentionCheckInterval 过期日志的清理间隔
+const retentionCheckInterval = time.Hour
+
+// DeleteExecutionLogs 删除匹配的执行日志及不再被引用的产物，返回删除的日志数量
+func (e *DefaultTaskExecutor) DeleteExecutionLogs(ctx context.Context, filter bson.M) (int64, error) {
+	collection := e.db.GetCollection("execution_logs")
+
+	// 先记录将被删除的日志引用的产物
+	cursor, err := collection.Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"result.artifacts.checksum": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var logs []models.ExecutionLog
+	if err := cursor.All(ctx, &logs); err != nil {
+		return 0, err
+	}
+
+	checksums := make(map[string]struct{})
+	for _, executionLog := range logs {
+		for _, item := range executionLog.Result.Artifacts {
+			checksums[item.Checksum] = struct{}{}
+		}
+	}
+
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	e.releaseArtifacts(ctx, checksums)
+	return result.DeletedCount, nil
+}
+
+// releaseArtifacts 删除不再被任何执行日志或备份记录引用的产物内容
+func (e *DefaultTaskExecutor) releaseArtifacts(ctx context.Context, checksums map[string]struct{}) {
+	collection := e.db.GetCollection("execution_logs")
+	backups := e.db.GetCollection("backups")
+	for checksum := range checksums {
+		count, err := collection.CountDocuments(ctx, bson.M{"result.artifacts.checksum": checksum})
+		if err != nil {
+			log.Printf("Failed to count artifact references: %v", err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		// 备份归档的保留由备份策略决定，不随执行日志删除
+		count, err = backups.CountDocuments(ctx, bson.M{"checksum": checksum})
+		if err != nil {
+			log.Printf("Failed to count backup references: %v", err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		if err := e.artifacts.Delete(ctx, checksum); err != nil {
+			log.Printf("Failed to delete artifact %s: %v", checksum, err)
+		}
+	}
+}
+
+// sweepArtifacts 删除存储中所有未被引用的产物内容
+func (e *DefaultTaskExecutor) sweepArtifacts(ctx context.Context) error {
+	all, err := e.artifacts.List(ctx)
+	if err != nil {
+		return err
+	}
+	checksums := make(map[string]struct{}, len(all))
+	for _, checksum := range all {
+		checksums[checksum] = struct{}{}
+	}
+	e.releaseArtifacts(ctx, checksums)
+	return nil
+}
+
+// PurgeExecutionLogs 删除指定时间之前结束的执行日志，运行中的执行不受影响
+func (e *DefaultTaskExecutor) PurgeExecutionLogs(ctx context.Context, before time.Time) (int64, error) {
+	return e.DeleteExecutionLogs(ctx, bson.M{
+		"status":       bson.M{"$ne": models.ExecutionStatusRunning},
+		"completed_at": bson.M{"$lt": before},
+	})
+}
+
+// RunRetention 定期清理超过保留天数的执行日志（days为0时保留全部日志），
+// 并删除已没有日志引用的产物和遗留的工作区检出目录
+func (e *DefaultTaskExecutor) RunRetention(ctx context.Context, days int) {
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if days > 0 {
+			before := time.Now().AddDate(0, 0, -days)
+			deleted, err := e.PurgeExecutionLogs(ctx, before)
+			if err != nil {
+				log.Printf("Failed to purge execution logs: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d execution logs older than %d days", deleted, days)
+			}
+		}
+		if err := e.sweepArtifacts(ctx); err != nil {
+			log.Printf("Failed to sweep artifacts: %v", err)
+		}
+		if err := e.workspaces.Sweep(); err != nil {
+			log.Printf("Failed to sweep workspaces: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
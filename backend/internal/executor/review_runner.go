@@ -0,0 +1,415 @@
+/**
+ * 代码审查运行器
+ * 收集git范围或上次成功审查以来的变更，分块发送给大模型，把回复解析为结构化的问题
+ */
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/codereview"
+	"aischedule/internal/database"
+	"aischedule/internal/llm"
+	"aischedule/internal/models"
+)
+
+const (
+	defaultReviewChunkSize = 16 * 1024
+	defaultReviewMaxChunks = 20
+	// maxReviewFileSize 按修改时间收集文件时跳过更大的文件
+	maxReviewFileSize = 256 * 1024
+	// defaultReviewRange 未配置范围时审查最近一次提交
+	defaultReviewRange = "HEAD~1..HEAD"
+	// emptyTree git的空树对象
+	emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+)
+
+// CodeReviewRunner 代码审查运行器
+type CodeReviewRunner struct {
+	db        *database.MongoDB
+	providers *llmProviders
+}
+
+// reviewRunnerConfig 代码审查运行器参数
+type reviewRunnerConfig struct {
+	Range            string   `json:"range"`              // git范围，如main...HEAD、abc123..HEAD，默认HEAD~1..HEAD
+	SinceLastSuccess bool     `json:"since_last_success"` // 审查上次成功审查以来的变更
+	Paths            []string `json:"paths"`              // 只审查这些路径
+	Instructions     string   `json:"instructions"`       // 附加的审查要求
+	Model            string   `json:"model"`
+	Temperature      *float64 `json:"temperature"`
+	MaxChunkSize     int      `json:"max_chunk_size"` // 单个分块的上限(字节)，默认16KB
+	MaxChunks        int      `json:"max_chunks"`     // 最多发送的分块数，默认20
+	FailOn           string   `json:"fail_on"`        // 存在不低于该严重程度的问题时执行失败：error, warning, info
+	llmOverride
+}
+
+// NewCodeReviewRunner 创建新的代码审查运行器
+func NewCodeReviewRunner(db *database.MongoDB, defaults llm.Config) *CodeReviewRunner {
+	return &CodeReviewRunner{
+		db:        db,
+		providers: newLLMProviders(defaults),
+	}
+}
+
+// Run 执行代码审查
+func (r *CodeReviewRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg reviewRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Range != "" && cfg.SinceLastSuccess {
+		return nil, fmt.Errorf("range and since_last_success are mutually exclusive")
+	}
+	if cfg.FailOn != "" {
+		if err := codereview.ValidateSeverity(cfg.FailOn); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Model == "" {
+		cfg.Model = r.providers.defaults.Model
+	}
+	if cfg.MaxChunkSize <= 0 {
+		cfg.MaxChunkSize = defaultReviewChunkSize
+	}
+	if cfg.MaxChunks <= 0 {
+		cfg.MaxChunks = defaultReviewMaxChunks
+	}
+	for _, path := range cfg.Paths {
+		if strings.HasPrefix(path, "-") || filepath.IsAbs(path) || strings.Contains(filepath.ToSlash(path), "..") {
+			return nil, fmt.Errorf("invalid review path %q", path)
+		}
+	}
+
+	provider, err := r.providers.get(cfg.llmOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &models.CodeReview{Model: cfg.Model, Files: []string{}, Findings: []models.ReviewFinding{}}
+	files, err := r.collect(ctx, rc, &cfg, review)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := codereview.Chunks(files, cfg.MaxChunkSize)
+	for _, file := range files {
+		if !file.Binary {
+			review.Files = append(review.Files, file.Path)
+		}
+	}
+	if len(chunks) > cfg.MaxChunks {
+		rc.Logf(models.LogLevelWarn, "变更过多，只审查前 %d 个分块（共 %d 个）", cfg.MaxChunks, len(chunks))
+		chunks = chunks[:cfg.MaxChunks]
+		review.Truncated = true
+	}
+	review.Chunks = len(chunks)
+	result := &models.ExecutionResult{Review: review}
+	if len(chunks) == 0 {
+		rc.Logf(models.LogLevelInfo, "没有需要审查的变更")
+		result.Output = "no changes to review"
+		return result, nil
+	}
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("审查 %d 个文件，分为 %d 个分块", len(review.Files), len(chunks)), map[string]interface{}{
+		"base": review.Base,
+		"head": review.Head,
+	})
+
+	var usage llm.Usage
+	failed := 0
+	for i, chunk := range chunks {
+		resp, err := provider.Chat(ctx, &llm.Request{
+			Model:       cfg.Model,
+			Temperature: cfg.Temperature,
+			Messages: []llm.Message{
+				{Role: llm.RoleSystem, Content: codereview.SystemPrompt},
+				{Role: llm.RoleUser, Content: codereview.Prompt(cfg.Instructions, chunk)},
+			},
+		})
+		if err != nil {
+			return result, fmt.Errorf("review chunk %d: %w", i+1, err)
+		}
+		usage.Add(resp.Usage)
+
+		findings, err := codereview.ParseFindings(resp.Message.Content)
+		if err != nil {
+			failed++
+			rc.Logf(models.LogLevelWarn, "无法解析第 %d 个分块的审查结果: %v", i+1, err)
+			continue
+		}
+		review.Findings = append(review.Findings, findings...)
+		rc.Logf(models.LogLevelInfo, "第 %d/%d 个分块审查完成，发现 %d 个问题", i+1, len(chunks), len(findings))
+	}
+	if failed == len(chunks) {
+		return result, fmt.Errorf("failed to parse review results from model")
+	}
+
+	codereview.Summarize(review)
+	var output strings.Builder
+	for _, finding := range review.Findings {
+		location := finding.File
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+		}
+		fmt.Fprintf(&output, "%s [%s] %s\n", location, finding.Severity, finding.Message)
+	}
+	result.Output = output.String()
+	result.Data = map[string]interface{}{
+		"usage": map[string]interface{}{
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"total_tokens":      usage.TotalTokens,
+		},
+		"unparsed_chunks": failed,
+	}
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("代码审查完成，发现 %d 个问题", len(review.Findings)), map[string]interface{}{
+		"errors":   review.Errors,
+		"warnings": review.Warnings,
+		"infos":    review.Infos,
+	})
+
+	if cfg.FailOn != "" {
+		threshold := models.ReviewSeverity(cfg.FailOn)
+		for _, finding := range review.Findings {
+			if codereview.AtLeast(finding.Severity, threshold) {
+				return result, fmt.Errorf("code review found issues with severity %s or higher", cfg.FailOn)
+			}
+		}
+	}
+	return result, nil
+}
+
+// collect 收集需要审查的变更：git范围的差异，或上次成功审查以来的变更
+func (r *CodeReviewRunner) collect(ctx context.Context, rc *RunContext, cfg *reviewRunnerConfig, review *models.CodeReview) ([]codereview.FileDiff, error) {
+	dir := rc.Environment.WorkingDirectory
+	head := rc.Commit
+	if head == "" {
+		// 没有配置工作区时，工作目录可以是本地仓库
+		if out, err := reviewGit(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err == nil {
+			head = strings.TrimSpace(out)
+		}
+	}
+
+	if !cfg.SinceLastSuccess {
+		spec := cfg.Range
+		if spec == "" {
+			spec = defaultReviewRange
+		}
+		base, tip, symmetric := parseRange(spec)
+		var err error
+		if review.Base, err = r.resolve(ctx, rc, base, head); err != nil {
+			return nil, err
+		}
+		if review.Head, err = r.resolve(ctx, rc, tip, head); err != nil {
+			return nil, err
+		}
+		return r.diff(ctx, dir, review.Base, review.Head, symmetric, cfg.Paths)
+	}
+
+	previous, err := r.lastSuccess(ctx, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不在git仓库中时按修改时间收集文件
+	if head == "" {
+		var since time.Time
+		if previous != nil {
+			since = previous.StartedAt
+			rc.Logf(models.LogLevelInfo, "审查 %s 以来修改的文件", since.Format(time.RFC3339))
+		} else {
+			rc.Logf(models.LogLevelInfo, "没有成功的审查记录，审查所有文件")
+		}
+		return changedFiles(dir, cfg.Paths, since)
+	}
+
+	review.Head = head
+	if previous != nil && previous.Result.Review.Head != "" {
+		review.Base, err = r.resolve(ctx, rc, previous.Result.Review.Head, head)
+		if err != nil {
+			return nil, err
+		}
+		rc.Logf(models.LogLevelInfo, "审查上次成功审查的提交 %s 以来的变更", review.Base)
+	} else {
+		rc.Logf(models.LogLevelInfo, "没有成功的审查记录，审查最近一次提交")
+		if review.Base, err = r.resolve(ctx, rc, "HEAD~1", head); err != nil {
+			// 第一个提交没有父提交，与空树比较
+			review.Base = emptyTree
+		}
+	}
+	if review.Base == review.Head {
+		return nil, nil
+	}
+	return r.diff(ctx, dir, review.Base, review.Head, false, cfg.Paths)
+}
+
+// resolve 把范围端点解析为提交SHA。HEAD相对的表达式基于检出的提交；
+// 工作区中缺少的分支、标签或提交从仓库镜像中拉取
+func (r *CodeReviewRunner) resolve(ctx context.Context, rc *RunContext, ref, head string) (string, error) {
+	if ref == "" || ref == "HEAD" {
+		if head == "" {
+			return "", fmt.Errorf("working directory is not a git repository")
+		}
+		return head, nil
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+
+	dir := rc.Environment.WorkingDirectory
+	out, err := reviewGit(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err == nil {
+		return strings.TrimSpace(out), nil
+	}
+	if rc.Workspace == nil {
+		return "", fmt.Errorf("ref %q not found in repository", ref)
+	}
+
+	if strings.HasPrefix(ref, "HEAD") {
+		// 浅检出缺少历史，补全后重试
+		if _, err := rc.Workspace.Fetch(ctx, head); err != nil {
+			return "", err
+		}
+		out, err := reviewGit(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		if err != nil {
+			return "", fmt.Errorf("ref %q not found in repository", ref)
+		}
+		return strings.TrimSpace(out), nil
+	}
+	return rc.Workspace.Fetch(ctx, ref)
+}
+
+// diff 生成两个提交之间的差异，symmetric为true时与合并基准比较（A...B）
+func (r *CodeReviewRunner) diff(ctx context.Context, dir, base, head string, symmetric bool, paths []string) ([]codereview.FileDiff, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames"}
+	if symmetric {
+		args = append(args, base+"..."+head)
+	} else {
+		args = append(args, base, head)
+	}
+	args = append(args, "--")
+	out, err := reviewGit(ctx, dir, append(args, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	return codereview.SplitDiff(out), nil
+}
+
+// lastSuccess 查找同一任务上一次成功的代码审查
+func (r *CodeReviewRunner) lastSuccess(ctx context.Context, rc *RunContext) (*models.ExecutionLog, error) {
+	if rc.Task == nil {
+		return nil, nil
+	}
+	var previous models.ExecutionLog
+	err := r.db.GetCollection("execution_logs").FindOne(ctx,
+		bson.M{
+			"task_id":       rc.Task.ID,
+			"_id":           bson.M{"$ne": rc.ExecutionID},
+			"status":        models.ExecutionStatusCompleted,
+			"result.review": bson.M{"$exists": true},
+		},
+		options.FindOne().
+			SetSort(bson.M{"started_at": -1}).
+			SetProjection(bson.M{"started_at": 1, "result.review.head": 1}),
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query previous review: %w", err)
+	}
+	return &previous, nil
+}
+
+// parseRange 解析A..B、A...B或单个A（等同A..HEAD）
+func parseRange(spec string) (string, string, bool) {
+	if i := strings.Index(spec, "..."); i >= 0 {
+		return spec[:i], spec[i+3:], true
+	}
+	if i := strings.Index(spec, ".."); i >= 0 {
+		return spec[:i], spec[i+2:], false
+	}
+	return spec, "HEAD", false
+}
+
+// changedFiles 收集since之后修改的文本文件，since为零值时收集所有文件
+func changedFiles(dir string, paths []string, since time.Time) ([]codereview.FileDiff, error) {
+	roots := paths
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	var files []codereview.FileDiff
+	for _, root := range roots {
+		err := filepath.WalkDir(filepath.Join(dir, root), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if name := entry.Name(); path != filepath.Join(dir, root) && (strings.HasPrefix(name, ".") || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if !info.ModTime().After(since) || info.Size() > maxReviewFileSize {
+				return nil
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if bytes.IndexByte(data, 0) >= 0 {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, codereview.NewFileDiff(filepath.ToSlash(rel), string(data)))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// reviewGit 在目录中执行git命令，失败时错误中包含stderr
+func reviewGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,111 @@
+/**
+ * 任务运行器
+ * 定义各类任务/步骤的运行器接口和运行上下文
+ */
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/models"
+	"aischedule/internal/templating"
+	"aischedule/internal/workspace"
+)
+
+// Runner 运行器接口，负责某一类任务的实际执行
+type Runner interface {
+	Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error)
+}
+
+// LogSink 运行日志的写入端，负责持久化并推送日志条目
+type LogSink func(entries ...models.LogEntry)
+
+// StepSink 运行阶段的写入端，completed为false表示阶段开始，为true表示阶段完成
+type StepSink func(step string, completed bool)
+
+// RunContext 单次运行的上下文
+type RunContext struct {
+	ExecutionID primitive.ObjectID
+	Task        *models.Task
+
+	// 运行参数（任务的AgentConfig.Parameters或工作流步骤的Parameters），已完成模板渲染
+	Parameters map[string]interface{}
+	// 参数模板数据
+	Template    *templating.Data
+	Timeout     time.Duration
+	Environment models.ExecutionEnvironment
+	// 工作区检出的提交，未配置工作区时为空
+	Commit string
+	// 检出的工作区，未配置工作区时为nil
+	Workspace *workspace.Workspace
+
+	// 产物存储
+	Artifacts artifact.Store
+	// 进程输出保留上限(字节)
+	MaxOutput int64
+	// 运行器直接保存的单个产物（如完整响应体）的上限(字节)，0表示不限制
+	MaxArtifact int64
+	// 本地进程cgroup的父目录
+	CgroupRoot string
+	// 性能指标采样间隔，0表示不采样
+	MetricsInterval time.Duration
+
+	Sink    LogSink
+	Metrics MetricsSink
+	Steps   StepSink
+}
+
+// Emit 写入日志条目
+func (rc *RunContext) Emit(entries ...models.LogEntry) {
+	if rc.Sink != nil && len(entries) > 0 {
+		rc.Sink(entries...)
+	}
+}
+
+// BeginStep 记录阶段开始
+func (rc *RunContext) BeginStep(step string) {
+	if rc.Steps != nil {
+		rc.Steps(step, false)
+	}
+}
+
+// CompleteStep 记录阶段完成
+func (rc *RunContext) CompleteStep(step string) {
+	if rc.Steps != nil {
+		rc.Steps(step, true)
+	}
+}
+
+// Log 写一条日志
+func (rc *RunContext) Log(level models.LogLevel, message string, data map[string]interface{}) {
+	rc.Emit(models.LogEntry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// Logf 写一条格式化日志
+func (rc *RunContext) Logf(level models.LogLevel, format string, args ...interface{}) {
+	rc.Log(level, fmt.Sprintf(format, args...), nil)
+}
+
+// decodeParams 将参数映射解码到运行器的配置结构体
+func decodeParams(params map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("invalid parameters: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid parameters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/**
+ * 脚本运行器
+ * 负责执行本地命令，实时采集stdout/stderr并记录退出码
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"aischedule/internal/models"
+)
+
+// ScriptRunner 脚本运行器
+type ScriptRunner struct{}
+
+// scriptRunnerConfig 脚本运行器参数
+type scriptRunnerConfig struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// NewScriptRunner 创建新的脚本运行器
+func NewScriptRunner() *ScriptRunner {
+	return &ScriptRunner{}
+}
+
+// Run 执行脚本命令
+func (r *ScriptRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg scriptRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("script command not specified")
+	}
+
+	return runProcess(ctx, rc, cfg.Command, cfg.Args)
+}
@@ -7,261 +7,595 @@ package executor
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os/exec"
+	"path/filepath"
+	"sync"
 	"time"
 
+	"aischedule/internal/artifact"
+	"aischedule/internal/config"
 	"aischedule/internal/database"
+	"aischedule/internal/llm"
+	"aischedule/internal/mcp"
 	"aischedule/internal/models"
+	"aischedule/internal/redact"
+	"aischedule/internal/secrets"
+	"aischedule/internal/templating"
 	"aischedule/internal/websocket"
+	"aischedule/internal/workspace"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // TaskExecutor 任务执行器接口
 type TaskExecutor interface {
-	Execute(ctx context.Context, task *models.Task) error
+	Execute(ctx context.Context, task *models.Task, trigger models.TriggerInfo) error
 }
 
 // DefaultTaskExecutor 默认任务执行器
 type DefaultTaskExecutor struct {
-	db        *database.MongoDB
-	wsManager *websocket.Manager
+	db             *database.MongoDB
+	wsManager      *websocket.Manager
+	artifacts      artifact.Store
+	secrets        *secrets.Store
+	redactor       *redact.Redactor
+	maskers        sync.Map // 执行日志ID -> *secrets.Masker
+	workspaces     *workspace.Manager
+	checkouts      sync.Map // 执行日志ID -> *workspace.Workspace
+	maxOutput      int64
+	maxArtifact    int64
+	cgroupRoot     string
+	metricsEvery   time.Duration
+	registry       *ExecutionRegistry
+	httpRunner     *HTTPRunner
+	scriptRunner   *ScriptRunner
+	testRunner     *TestRunner
+	backupRunner   *BackupRunner
+	deployRunner   *DeployRunner
+	agentRunner    *AgentRunner
+	reviewRunner   *CodeReviewRunner
+	mcpRunner      *MCPRunner
+	workflowRunner *WorkflowRunner
+	backups        *BackupManager
+	mcpServers     *MCPServerRegistry
 }
 
 // NewDefaultTaskExecutor 创建新的默认任务执行器
-func NewDefaultTaskExecutor(db *database.MongoDB, wsManager *websocket.Manager) *DefaultTaskExecutor {
-	return &DefaultTaskExecutor{
-		db:        db,
-		wsManager: wsManager,
+func NewDefaultTaskExecutor(db *database.MongoDB, wsManager *websocket.Manager, cfg *config.Config) *DefaultTaskExecutor {
+	llmConfig := llm.Config{
+		Provider:   cfg.LLMProvider,
+		BaseURL:    cfg.LLMBaseURL,
+		APIKey:     cfg.LLMAPIKey,
+		Model:      cfg.LLMModel,
+		Timeout:    cfg.LLMTimeout,
+		ReplayFile: cfg.LLMReplayFile,
 	}
+	e := &DefaultTaskExecutor{
+		db:           db,
+		wsManager:    wsManager,
+		artifacts:    artifact.NewLocalStore(cfg.ArtifactDir),
+		secrets:      secrets.NewStore(db, cfg.SecretsMasterKey),
+		redactor:     newRedactor(cfg.RedactionRulesFile),
+		workspaces:   workspace.NewManager(cfg.WorkspaceDir),
+		maxOutput:    cfg.MaxOutputSize,
+		maxArtifact:  cfg.MaxArtifactSize,
+		cgroupRoot:   cfg.CgroupRoot,
+		metricsEvery: cfg.MetricsInterval,
+		registry:     NewExecutionRegistry(),
+		httpRunner:   NewHTTPRunner(),
+		scriptRunner: NewScriptRunner(),
+		testRunner:   NewTestRunner(),
+		deployRunner: NewDeployRunner(db),
+		agentRunner:  NewAgentRunner(llmConfig),
+		reviewRunner: NewCodeReviewRunner(db, llmConfig),
+	}
+	e.backups = NewBackupManager(db, e.artifacts, e.releaseArtifacts)
+	e.backupRunner = NewBackupRunner(db, e.backups)
+	mcpPool := mcp.NewPool(mcpIdleTimeout)
+	e.mcpServers = NewMCPServerRegistry(db, e.secrets, mcpPool, cfg.MCPTimeout)
+	e.mcpRunner = NewMCPRunner(mcpPool, e.mcpServers, cfg.MCPServerURL, cfg.MCPTimeout)
+	e.workflowRunner = NewWorkflowRunner(db, e.secrets, e.masker, e.redactor, map[models.TaskType]Runner{
+		models.TaskTypeScript:     e.scriptRunner,
+		models.TaskTypeAPI:        e.httpRunner,
+		models.TaskTypeAutoTest:   e.testRunner,
+		models.TaskTypeDataBackup: e.backupRunner,
+		models.TaskTypeDeployment: e.deployRunner,
+		models.TaskTypeCodeReview: e.reviewRunner,
+		models.TaskTypeAgent:      e.agentRunner,
+		models.TaskTypeMCPTool:    e.mcpRunner,
+	})
+	return e
+}
+
+// newRedactor 创建日志脱敏器，规则文件无效时只使用内置规则
+func newRedactor(rulesFile string) *redact.Redactor {
+	rules := redact.DefaultRules()
+	if rulesFile != "" {
+		custom, err := redact.LoadRules(rulesFile)
+		if err != nil {
+			log.Printf("Failed to load redaction rules, using built-in rules only: %v", err)
+		} else {
+			rules = append(rules, custom...)
+		}
+	}
+	return redact.New(rules)
+}
+
+// Registry 获取运行中执行的登记表
+func (e *DefaultTaskExecutor) Registry() *ExecutionRegistry {
+	return e.registry
+}
+
+// Artifacts 获取产物存储
+func (e *DefaultTaskExecutor) Artifacts() artifact.Store {
+	return e.artifacts
+}
+
+// Backups 获取备份管理器
+func (e *DefaultTaskExecutor) Backups() *BackupManager {
+	return e.backups
+}
+
+// MCPServers 获取MCP服务端登记表
+func (e *DefaultTaskExecutor) MCPServers() *MCPServerRegistry {
+	return e.mcpServers
+}
+
+// Secrets 获取密钥存储
+func (e *DefaultTaskExecutor) Secrets() *secrets.Store {
+	return e.secrets
 }
 
 // Execute 执行任务
-func (e *DefaultTaskExecutor) Execute(ctx context.Context, task *models.Task) error {
+func (e *DefaultTaskExecutor) Execute(ctx context.Context, task *models.Task, trigger models.TriggerInfo) error {
+	if trigger.Type == "" {
+		trigger.Type = "scheduled"
+	}
+
 	// 创建执行日志
+	logID := trigger.LogID
+	if logID.IsZero() {
+		logID = primitive.NewObjectID()
+	}
 	executionLog := &models.ExecutionLog{
-		ID:        primitive.NewObjectID(),
-		TaskID:    task.ID.Hex(),
-		Status:    models.ExecutionStatusRunning,
-		StartTime: time.Now(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Logs:      []models.LogEntry{},
-		PerformanceMetrics: []models.PerformanceMetric{},
+		ID:             logID,
+		TaskID:         task.ID,
+		ExecutionID:    logID.Hex(),
+		Status:         models.ExecutionStatusRunning,
+		StartedAt:      time.Now(),
+		AgentID:        task.AgentConfig.AgentID,
+		AgentType:      task.AgentConfig.AgentType,
+		WorkflowID:     task.WorkflowID,
+		CompletedSteps: []string{},
+		Logs:           []models.LogEntry{},
+		Metrics:        []models.PerformanceMetrics{},
+		TriggerType:    trigger.Type,
+		TriggerBy:      trigger.By,
+		ScheduledTime:  trigger.ScheduledTime,
+		TriggerPayload: trigger.Payload,
+		MaxRetries:     task.AgentConfig.Retries,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	// 保存执行日志
 	collection := e.db.GetCollection("execution_logs")
-	result, err := collection.InsertOne(ctx, executionLog)
-	if err != nil {
+	if _, err := collection.InsertOne(ctx, executionLog); err != nil {
 		return fmt.Errorf("failed to create execution log: %w", err)
 	}
-	executionLog.ID = result.InsertedID.(primitive.ObjectID)
+
+	e.addLogEntry(ctx, executionLog.ID, models.LogLevelInfo, "任务开始执行", "executor", nil)
+	return e.run(ctx, task, executionLog, trigger)
+}
+
+// run 执行任务并写入最终状态，新的执行和恢复的工作流执行共用；
+// 工作流挂起时执行日志状态为waiting，不视为失败
+func (e *DefaultTaskExecutor) run(ctx context.Context, task *models.Task, executionLog *models.ExecutionLog, trigger models.TriggerInfo) error {
+	logID := executionLog.ID
+
+	// 本次执行解析出的密钥值和匹配脱敏规则的文本在写入日志和结果前替换
+	masker := secrets.NewMasker()
+	e.maskers.Store(logID, masker)
+	defer e.maskers.Delete(logID)
+	defer e.releaseWorkspace(logID)
+
+	// 登记执行，使其可以被取消
+	ctx, unregister := e.registry.register(ctx, executionLog.ExecutionID)
+	defer unregister()
 
 	// 发送开始执行的WebSocket消息
 	e.wsManager.SendToTopic("task_execution", websocket.MessageTypeStatus, map[string]interface{}{
-		"task_id":        task.ID.Hex(),
-		"execution_id":   executionLog.ID.Hex(),
-		"status":         "started",
-		"message":        fmt.Sprintf("任务 %s 开始执行", task.Name),
+		"task_id":      task.ID.Hex(),
+		"execution_id": executionLog.ID.Hex(),
+		"status":       "started",
+		"message":      fmt.Sprintf("任务 %s 开始执行", task.Name),
 	})
 
-	// 添加开始日志
-	e.addLogEntry(ctx, executionLog.ID, models.LogLevelInfo, "任务开始执行", "executor", nil)
-
 	// 根据任务类型执行不同的逻辑
+	var result *models.ExecutionResult
 	var executeErr error
 	switch task.Type {
 	case models.TaskTypeScript:
-		executeErr = e.executeScript(ctx, task, executionLog)
+		result, executeErr = e.executeScript(ctx, task, executionLog, trigger)
 	case models.TaskTypeAPI:
-		executeErr = e.executeAPI(ctx, task, executionLog)
+		result, executeErr = e.executeAPI(ctx, task, executionLog, trigger)
+	case models.TaskTypeAutoTest:
+		result, executeErr = e.executeAutoTest(ctx, task, executionLog, trigger)
+	case models.TaskTypeDataBackup:
+		result, executeErr = e.executeDataBackup(ctx, task, executionLog, trigger)
+	case models.TaskTypeDeployment:
+		result, executeErr = e.executeDeployment(ctx, task, executionLog, trigger)
+	case models.TaskTypeCodeReview:
+		result, executeErr = e.executeCodeReview(ctx, task, executionLog, trigger)
 	case models.TaskTypeWorkflow:
-		executeErr = e.executeWorkflow(ctx, task, executionLog)
+		result, executeErr = e.executeWorkflow(ctx, task, executionLog, trigger)
 	case models.TaskTypeAgent:
-		executeErr = e.executeAgent(ctx, task, executionLog)
+		result, executeErr = e.executeAgent(ctx, task, executionLog, trigger)
+	case models.TaskTypeMCPTool:
+		result, executeErr = e.executeMCPTool(ctx, task, executionLog, trigger)
 	default:
 		executeErr = fmt.Errorf("unsupported task type: %s", task.Type)
 	}
 
-	// 更新执行状态
+	if result == nil {
+		result = &models.ExecutionResult{}
+	}
+	result.Success = executeErr == nil
+	if executeErr != nil {
+		result.Error = executeErr.Error()
+	}
+
+	// 更新执行状态（执行上下文可能已超时，后续写入使用独立的上下文）
 	endTime := time.Now()
 	status := models.ExecutionStatusCompleted
-	if executeErr != nil {
+	if cancelled := cancelCause(ctx); cancelled != nil {
+		status = models.ExecutionStatusCancelled
+		executionLog.CancelledBy = cancelled.By
+		executionLog.CancelLabel = cancelled.Label
+		result.Error = cancelled.Error()
+		executeErr = cancelled
+		e.addLogEntry(context.Background(), executionLog.ID, models.LogLevelWarn,
+			fmt.Sprintf("任务已被 %s 取消", cancelled.By), "executor",
+			map[string]interface{}{"reason": cancelled.Reason, "label": cancelled.Label})
+	} else if errors.Is(executeErr, errWorkflowWaiting) {
+		status = models.ExecutionStatusWaiting
+		result.Error = ""
+		executeErr = nil
+		e.addLogEntry(context.Background(), executionLog.ID, models.LogLevelInfo, "工作流等待中，由调度器恢复执行", "executor", nil)
+	} else if executeErr != nil {
 		status = models.ExecutionStatusFailed
-		e.addLogEntry(ctx, executionLog.ID, models.LogLevelError, 
+		if errors.Is(executeErr, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			status = models.ExecutionStatusTimeout
+		}
+		e.addLogEntry(context.Background(), executionLog.ID, models.LogLevelError,
 			fmt.Sprintf("任务执行失败: %v", executeErr), "executor", nil)
 	} else {
-		e.addLogEntry(ctx, executionLog.ID, models.LogLevelInfo, "任务执行完成", "executor", nil)
+		e.addLogEntry(context.Background(), executionLog.ID, models.LogLevelInfo, "任务执行完成", "executor", nil)
 	}
 
 	// 更新执行日志
-	e.updateExecutionLog(ctx, executionLog.ID, status, endTime, executeErr)
+	e.RedactResult(logID, result)
+	e.updateExecutionLog(executionLog, status, endTime, result)
 
 	// 发送完成的WebSocket消息
 	e.wsManager.SendToTopic("task_execution", websocket.MessageTypeStatus, map[string]interface{}{
-		"task_id":        task.ID.Hex(),
-		"execution_id":   executionLog.ID.Hex(),
-		"status":         string(status),
-		"message":        fmt.Sprintf("任务 %s 执行完成", task.Name),
-		"error":          executeErr,
+		"task_id":      task.ID.Hex(),
+		"execution_id": executionLog.ID.Hex(),
+		"status":       string(status),
+		"message":      executionMessage(task, status),
+		"error":        result.Error,
+		"cancelled_by": executionLog.CancelledBy,
 	})
 
 	return executeErr
 }
 
-// executeScript 执行脚本任务
-func (e *DefaultTaskExecutor) executeScript(ctx context.Context, task *models.Task, log *models.ExecutionLog) error {
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行脚本任务", "script_executor", nil)
+// executionMessage 执行结束时WebSocket消息的说明
+func executionMessage(task *models.Task, status models.ExecutionStatus) string {
+	if status == models.ExecutionStatusWaiting {
+		return fmt.Sprintf("任务 %s 等待中", task.Name)
+	}
+	return fmt.Sprintf("任务 %s 执行完成", task.Name)
+}
 
-	// 从Agent配置中获取脚本信息
-	scriptConfig, ok := task.AgentConfig["script"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid script configuration")
+// newRunContext 为任务构建运行上下文，并在运行前渲染参数模板、解析密钥引用
+func (e *DefaultTaskExecutor) newRunContext(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo, source string) (*RunContext, error) {
+	data := templating.NewData(task, log.ExecutionID, log.StartedAt, trigger)
+	data.Run.Attempt = log.RetryCount + 1
+	params, err := templating.RenderParams(task.AgentConfig.Parameters, data, task.AgentConfig.StrictTemplates)
+	if err != nil {
+		return nil, err
 	}
 
-	command, ok := scriptConfig["command"].(string)
-	if !ok {
-		return fmt.Errorf("script command not specified")
+	// 工作区的仓库地址和ref同样支持模板（如按触发数据检出分支）
+	environment := task.Environment
+	var spec *models.WorkspaceSpec
+	if task.Environment.Workspace != nil {
+		rendered := *task.Environment.Workspace
+		if rendered.Repo, err = templating.Render("workspace.repo", rendered.Repo, data, task.AgentConfig.StrictTemplates); err != nil {
+			return nil, err
+		}
+		if rendered.Ref, err = templating.Render("workspace.ref", rendered.Ref, data, task.AgentConfig.StrictTemplates); err != nil {
+			return nil, err
+		}
+		// 回滚时检出目标执行所用的提交
+		if commit, ok := trigger.Payload["commit"].(string); ok && commit != "" && trigger.Type == models.TriggerTypeRollback {
+			rendered.Ref = commit
+		}
+		spec = &rendered
 	}
 
-	args := []string{}
-	if argsInterface, exists := scriptConfig["args"]; exists {
-		if argsList, ok := argsInterface.([]interface{}); ok {
-			for _, arg := range argsList {
-				if argStr, ok := arg.(string); ok {
-					args = append(args, argStr)
-				}
+	// 只解析任务配置中直接写出的引用
+	refs := append(secrets.CollectRefs(task.AgentConfig.Parameters), secrets.CollectRefs(task.Environment.EnvironmentVars)...)
+	if spec != nil {
+		refs = append(refs, secrets.Refs(task.Environment.Workspace.Repo)...)
+	}
+	if len(refs) > 0 {
+		resolver := e.secrets.NewResolver(refs)
+		if params, err = resolver.ResolveParams(ctx, params); err != nil {
+			return nil, err
+		}
+		if environment.EnvironmentVars, err = resolver.ResolveEnv(ctx, task.Environment.EnvironmentVars); err != nil {
+			return nil, err
+		}
+		if spec != nil {
+			if spec.Repo, err = resolver.ResolveString(ctx, spec.Repo); err != nil {
+				return nil, err
 			}
 		}
+		e.masker(log.ID).Add(resolver.Values()...)
 	}
 
-	// 执行命令
-	cmd := exec.CommandContext(ctx, command, args...)
-	
-	// 设置环境变量
-	if task.Environment != nil {
-		for key, value := range task.Environment {
-			if valueStr, ok := value.(string); ok {
-				cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, valueStr))
-			}
+	// 检出工作区，工作目录相对检出目录
+	var ws *workspace.Workspace
+	if spec != nil {
+		if ws, err = e.prepareWorkspace(ctx, log, spec, source); err != nil {
+			return nil, err
 		}
+		environment.WorkingDirectory = filepath.Join(ws.Dir, environment.WorkingDirectory)
 	}
 
-	// 执行并获取输出
-	output, err := cmd.CombinedOutput()
-	
-	if err != nil {
-		e.addLogEntry(ctx, log.ID, models.LogLevelError, 
-			fmt.Sprintf("脚本执行失败: %v", err), "script_executor", 
-			map[string]interface{}{"output": string(output)})
-		return err
+	return &RunContext{
+		ExecutionID:     log.ID,
+		Task:            task,
+		Parameters:      params,
+		Template:        data,
+		Timeout:         time.Duration(task.AgentConfig.Timeout) * time.Second,
+		Environment:     environment,
+		Commit:          log.CommitSHA,
+		Workspace:       ws,
+		Artifacts:       e.artifacts,
+		MaxOutput:       e.maxOutput,
+		MaxArtifact:     e.maxArtifact,
+		CgroupRoot:      e.cgroupRoot,
+		MetricsInterval: e.metricsEvery,
+		Sink: func(entries ...models.LogEntry) {
+			for i := range entries {
+				if entries[i].Source == "" {
+					entries[i].Source = source
+				}
+			}
+			// 执行上下文可能已超时，日志写入使用独立的上下文
+			e.addLogEntries(context.Background(), log.ID, entries)
+		},
+		Metrics: func(metric models.PerformanceMetrics) {
+			e.addMetric(context.Background(), log.ID, metric)
+		},
+		Steps: func(step string, completed bool) {
+			e.recordStep(context.Background(), log, step, completed)
+		},
+	}, nil
+}
+
+// recordStep 记录当前阶段，阶段完成时追加到已完成阶段列表，并推送WebSocket消息
+func (e *DefaultTaskExecutor) recordStep(ctx context.Context, executionLog *models.ExecutionLog, step string, completed bool) {
+	update := bson.M{
+		"$set": bson.M{"current_step_id": step, "updated_at": time.Now()},
+	}
+	status := "step_started"
+	if completed {
+		update["$push"] = bson.M{"completed_steps": step}
+		status = "step_completed"
 	}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, 
-		"脚本执行成功", "script_executor", 
-		map[string]interface{}{"output": string(output)})
+	_, err := e.db.GetCollection("execution_logs").UpdateOne(ctx, bson.M{"_id": executionLog.ID}, update)
+	if err != nil {
+		log.Printf("Failed to record execution step: %v", err)
+	}
 
-	return nil
+	e.wsManager.SendToTopic("task_execution", websocket.MessageTypeStatus, map[string]interface{}{
+		"task_id":      executionLog.TaskID.Hex(),
+		"execution_id": executionLog.ID.Hex(),
+		"status":       status,
+		"step":         step,
+	})
 }
 
-// executeAPI 执行API任务
-func (e *DefaultTaskExecutor) executeAPI(ctx context.Context, task *models.Task, log *models.ExecutionLog) error {
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行API任务", "api_executor", nil)
+// prepareWorkspace 检出执行的工作区，并把提交SHA记录到执行日志；检出目录在执行结束时删除
+func (e *DefaultTaskExecutor) prepareWorkspace(ctx context.Context, log *models.ExecutionLog, spec *models.WorkspaceSpec, source string) (*workspace.Workspace, error) {
+	logf := func(format string, args ...interface{}) {
+		e.addLogEntry(context.Background(), log.ID, models.LogLevelInfo, fmt.Sprintf(format, args...), source, nil)
+	}
 
-	// 从Agent配置中获取API信息
-	apiConfig, ok := task.AgentConfig["api"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid API configuration")
+	ws, err := e.workspaces.Prepare(ctx, log.ID.Hex(), spec, logf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare workspace: %w", err)
 	}
+	e.checkouts.Store(log.ID, ws)
 
-	url, ok := apiConfig["url"].(string)
-	if !ok {
-		return fmt.Errorf("API URL not specified")
+	log.CommitSHA = ws.Commit
+	_, err = e.db.GetCollection("execution_logs").UpdateOne(ctx,
+		bson.M{"_id": log.ID},
+		bson.M{"$set": bson.M{"commit_sha": ws.Commit, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		ws.Release()
+		return nil, fmt.Errorf("failed to record workspace commit: %w", err)
 	}
 
-	method := "GET"
-	if methodInterface, exists := apiConfig["method"]; exists {
-		if methodStr, ok := methodInterface.(string); ok {
-			method = methodStr
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, fmt.Sprintf("工作区已检出 %s", ws.Commit), source,
+		map[string]interface{}{"ref": ws.Ref, "commit": ws.Commit})
+	return ws, nil
+}
+
+// releaseWorkspace 删除执行的检出目录
+func (e *DefaultTaskExecutor) releaseWorkspace(logID primitive.ObjectID) {
+	if ws, ok := e.checkouts.LoadAndDelete(logID); ok {
+		if err := ws.(*workspace.Workspace).Release(); err != nil {
+			log.Printf("Failed to remove workspace: %v", err)
 		}
 	}
+}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, 
-		fmt.Sprintf("调用API: %s %s", method, url), "api_executor", nil)
+// executeScript 执行脚本任务
+func (e *DefaultTaskExecutor) executeScript(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行脚本任务", "script_executor", nil)
 
-	// TODO: 实现实际的HTTP请求逻辑
-	// 这里应该使用http.Client发送请求
+	rc, err := e.newRunContext(ctx, task, log, trigger, "script_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.scriptRunner, rc)
+}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "API调用成功", "api_executor", nil)
+// executeAPI 执行API任务
+func (e *DefaultTaskExecutor) executeAPI(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行API任务", "api_executor", nil)
 
-	return nil
+	rc, err := e.newRunContext(ctx, task, log, trigger, "api_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.httpRunner, rc)
 }
 
-// executeWorkflow 执行工作流任务
-func (e *DefaultTaskExecutor) executeWorkflow(ctx context.Context, task *models.Task, log *models.ExecutionLog) error {
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行工作流任务", "workflow_executor", nil)
+// executeAutoTest 执行自动测试任务
+func (e *DefaultTaskExecutor) executeAutoTest(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行自动测试任务", "test_executor", nil)
 
-	// 从Agent配置中获取工作流ID
-	workflowConfig, ok := task.AgentConfig["workflow"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid workflow configuration")
+	rc, err := e.newRunContext(ctx, task, log, trigger, "test_executor")
+	if err != nil {
+		return nil, err
 	}
+	return runWithArtifacts(ctx, e.testRunner, rc)
+}
+
+// executeDataBackup 执行数据备份任务
+func (e *DefaultTaskExecutor) executeDataBackup(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行数据备份任务", "backup_executor", nil)
 
-	workflowID, ok := workflowConfig["workflow_id"].(string)
-	if !ok {
-		return fmt.Errorf("workflow ID not specified")
+	rc, err := e.newRunContext(ctx, task, log, trigger, "backup_executor")
+	if err != nil {
+		return nil, err
 	}
+	return runWithArtifacts(ctx, e.backupRunner, rc)
+}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, 
-		fmt.Sprintf("执行工作流: %s", workflowID), "workflow_executor", nil)
+// executeDeployment 执行部署任务
+func (e *DefaultTaskExecutor) executeDeployment(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行部署任务", "deploy_executor", nil)
 
-	// TODO: 实现实际的工作流执行逻辑
-	// 这里应该调用工作流引擎
+	rc, err := e.newRunContext(ctx, task, log, trigger, "deploy_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.deployRunner, rc)
+}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "工作流执行成功", "workflow_executor", nil)
+// executeCodeReview 执行代码审查任务
+func (e *DefaultTaskExecutor) executeCodeReview(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行代码审查任务", "review_executor", nil)
 
-	return nil
+	rc, err := e.newRunContext(ctx, task, log, trigger, "review_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.reviewRunner, rc)
+}
+
+// executeWorkflow 执行工作流任务
+func (e *DefaultTaskExecutor) executeWorkflow(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行工作流任务", "workflow_executor", nil)
+
+	rc, err := e.newRunContext(ctx, task, log, trigger, "workflow_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.workflowRunner, rc)
 }
 
 // executeAgent 执行Agent任务
-func (e *DefaultTaskExecutor) executeAgent(ctx context.Context, task *models.Task, log *models.ExecutionLog) error {
+func (e *DefaultTaskExecutor) executeAgent(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
 	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行Agent任务", "agent_executor", nil)
 
-	// 从Agent配置中获取Agent信息
-	agentConfig, ok := task.AgentConfig["agent"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid agent configuration")
+	if agentType := task.AgentConfig.AgentType; agentType != "" {
+		e.addLogEntry(ctx, log.ID, models.LogLevelInfo,
+			fmt.Sprintf("执行Agent: %s", agentType), "agent_executor", nil)
 	}
 
-	agentType, ok := agentConfig["type"].(string)
-	if !ok {
-		return fmt.Errorf("agent type not specified")
+	rc, err := e.newRunContext(ctx, task, log, trigger, "agent_executor")
+	if err != nil {
+		return nil, err
 	}
+	return runWithArtifacts(ctx, e.agentRunner, rc)
+}
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, 
-		fmt.Sprintf("执行Agent: %s", agentType), "agent_executor", nil)
-
-	// TODO: 实现实际的Agent执行逻辑
-	// 这里应该调用相应的Agent
+// executeMCPTool 执行MCP工具任务
+func (e *DefaultTaskExecutor) executeMCPTool(ctx context.Context, task *models.Task, log *models.ExecutionLog, trigger models.TriggerInfo) (*models.ExecutionResult, error) {
+	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "开始执行MCP工具任务", "mcp_executor", nil)
 
-	e.addLogEntry(ctx, log.ID, models.LogLevelInfo, "Agent执行成功", "agent_executor", nil)
+	rc, err := e.newRunContext(ctx, task, log, trigger, "mcp_executor")
+	if err != nil {
+		return nil, err
+	}
+	return runWithArtifacts(ctx, e.mcpRunner, rc)
+}
 
+// masker 获取执行的脱敏器，执行已结束时返回nil（nil脱敏器不做替换）
+func (e *DefaultTaskExecutor) masker(logID primitive.ObjectID) *secrets.Masker {
+	if masker, ok := e.maskers.Load(logID); ok {
+		return masker.(*secrets.Masker)
+	}
 	return nil
 }
 
+// RedactEntry 对执行的日志条目脱敏
+func (e *DefaultTaskExecutor) RedactEntry(logID primitive.ObjectID, entry *models.LogEntry) {
+	e.redactor.Entry(entry, e.masker(logID))
+}
+
+// RedactResult 对执行结果脱敏
+func (e *DefaultTaskExecutor) RedactResult(logID primitive.ObjectID, result *models.ExecutionResult) {
+	if rules := e.redactor.Result(result, e.masker(logID)); len(rules) > 0 {
+		result.Redacted = true
+		result.RedactionRules = rules
+	}
+}
+
 // addLogEntry 添加日志条目
-func (e *DefaultTaskExecutor) addLogEntry(ctx context.Context, logID primitive.ObjectID, 
+func (e *DefaultTaskExecutor) addLogEntry(ctx context.Context, logID primitive.ObjectID,
 	level models.LogLevel, message, source string, data map[string]interface{}) {
-	
-	logEntry := models.LogEntry{
+
+	e.addLogEntries(ctx, logID, []models.LogEntry{{
 		Level:     level,
 		Message:   message,
 		Timestamp: time.Now(),
 		Source:    source,
 		Data:      data,
+	}})
+}
+
+// addLogEntries 批量添加日志条目，并逐条推送WebSocket消息
+func (e *DefaultTaskExecutor) addLogEntries(ctx context.Context, logID primitive.ObjectID, entries []models.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	// 写入和推送前脱敏
+	for i := range entries {
+		e.RedactEntry(logID, &entries[i])
 	}
 
 	collection := e.db.GetCollection("execution_logs")
@@ -269,7 +603,7 @@ func (e *DefaultTaskExecutor) addLogEntry(ctx context.Context, logID primitive.O
 		ctx,
 		map[string]interface{}{"_id": logID},
 		map[string]interface{}{
-			"$push": map[string]interface{}{"logs": logEntry},
+			"$push": map[string]interface{}{"logs": map[string]interface{}{"$each": entries}},
 			"$set":  map[string]interface{}{"updated_at": time.Now()},
 		},
 	)
@@ -278,44 +612,82 @@ func (e *DefaultTaskExecutor) addLogEntry(ctx context.Context, logID primitive.O
 	}
 
 	// 发送日志WebSocket消息
-	e.wsManager.SendToTopic("execution_logs", websocket.MessageTypeLog, map[string]interface{}{
+	for _, entry := range entries {
+		message := map[string]interface{}{
+			"execution_id": logID.Hex(),
+			"level":        string(entry.Level),
+			"message":      entry.Message,
+			"source":       entry.Source,
+			"timestamp":    entry.Timestamp.Unix(),
+			"data":         entry.Data,
+		}
+		if entry.Stream != "" {
+			message["stream"] = entry.Stream
+		}
+		if entry.Redacted {
+			message["redacted"] = true
+		}
+		e.wsManager.SendToTopic("execution_logs", websocket.MessageTypeLog, message)
+	}
+}
+
+// addMetric 添加性能指标，并推送WebSocket消息
+func (e *DefaultTaskExecutor) addMetric(ctx context.Context, logID primitive.ObjectID, metric models.PerformanceMetrics) {
+	collection := e.db.GetCollection("execution_logs")
+	_, err := collection.UpdateOne(
+		ctx,
+		map[string]interface{}{"_id": logID},
+		map[string]interface{}{
+			"$push": map[string]interface{}{"metrics": metric},
+			"$set":  map[string]interface{}{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to add performance metric: %v", err)
+	}
+
+	e.wsManager.SendToTopic("execution_logs", websocket.MessageTypeMetric, map[string]interface{}{
 		"execution_id": logID.Hex(),
-		"level":        string(level),
-		"message":      message,
-		"source":       source,
-		"timestamp":    logEntry.Timestamp.Unix(),
-		"data":         data,
+		"cpu_usage":    metric.CPUUsage,
+		"memory_usage": metric.MemoryUsage,
+		"memory_rss":   metric.MemoryRSS,
+		"disk_io":      metric.DiskIO,
+		"network_io":   metric.NetworkIO,
+		"timestamp":    metric.Timestamp.Unix(),
 	})
 }
 
 // updateExecutionLog 更新执行日志
-func (e *DefaultTaskExecutor) updateExecutionLog(ctx context.Context, logID primitive.ObjectID, 
-	status models.ExecutionStatus, endTime time.Time, executeErr error) {
-	
+func (e *DefaultTaskExecutor) updateExecutionLog(executionLog *models.ExecutionLog,
+	status models.ExecutionStatus, endTime time.Time, result *models.ExecutionResult) {
+
 	update := map[string]interface{}{
 		"status":     status,
-		"end_time":   endTime,
+		"result":     result,
 		"updated_at": time.Now(),
 	}
-
-	if executeErr != nil {
-		update["result"] = map[string]interface{}{
-			"success": false,
-			"error":   executeErr.Error(),
-		}
-	} else {
-		update["result"] = map[string]interface{}{
-			"success": true,
-		}
+	// 挂起的工作流尚未结束
+	if status != models.ExecutionStatusWaiting {
+		update["completed_at"] = endTime
+		update["duration"] = endTime.Sub(executionLog.StartedAt).Milliseconds()
+	}
+	if executionLog.CancelledBy != "" {
+		update["cancelled_by"] = executionLog.CancelledBy
 	}
+	if executionLog.CancelLabel != "" {
+		update["cancel_label"] = executionLog.CancelLabel
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
 	collection := e.db.GetCollection("execution_logs")
 	_, err := collection.UpdateOne(
 		ctx,
-		map[string]interface{}{"_id": logID},
+		map[string]interface{}{"_id": executionLog.ID},
 		map[string]interface{}{"$set": update},
 	)
 	if err != nil {
 		log.Printf("Failed to update execution log: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,173 @@
+/**
+ * 自动测试运行器
+ * 执行测试命令，解析测试报告并记录每个测试的结果，有测试失败时执行失败
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/models"
+	"aischedule/internal/testreport"
+)
+
+const (
+	// maxReportSize 单个报告文件的大小上限
+	maxReportSize = 64 << 20
+	// maxLoggedFailures 逐条写入日志的失败测试数量上限
+	maxLoggedFailures = 20
+)
+
+// TestRunner 自动测试运行器
+type TestRunner struct{}
+
+// testRunnerConfig 自动测试运行器参数
+type testRunnerConfig struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Format  string   `json:"format"`  // auto, go_json, junit, tap
+	Reports []string `json:"reports"` // 报告文件的glob模式（相对工作目录），为空时解析命令输出
+}
+
+// NewTestRunner 创建新的自动测试运行器
+func NewTestRunner() *TestRunner {
+	return &TestRunner{}
+}
+
+// Run 执行测试命令并解析报告
+func (r *TestRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg testRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("test command not specified")
+	}
+	if err := testreport.ValidateFormat(cfg.Format); err != nil {
+		return nil, err
+	}
+	for _, pattern := range cfg.Reports {
+		if err := artifact.ValidatePattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	started := time.Now()
+	result, runErr := runProcess(ctx, rc, cfg.Command, cfg.Args)
+	if ctx.Err() != nil {
+		return result, runErr
+	}
+
+	var report *models.TestReport
+	var err error
+	if len(cfg.Reports) > 0 {
+		report, err = r.readReports(rc, cfg, started)
+	} else {
+		report, err = testreport.Parse(cfg.Format, []byte(result.Output))
+	}
+	if err != nil {
+		rc.Logf(models.LogLevelError, "解析测试报告失败: %v", err)
+		if runErr != nil {
+			return result, runErr
+		}
+		return result, err
+	}
+	result.Tests = report
+
+	rc.Log(models.LogLevelInfo, fmt.Sprintf("测试完成：共 %d 个，通过 %d 个，失败 %d 个，跳过 %d 个",
+		report.Total, report.Passed, report.Failed, report.Skipped), map[string]interface{}{
+		"format":  report.Format,
+		"total":   report.Total,
+		"passed":  report.Passed,
+		"failed":  report.Failed,
+		"skipped": report.Skipped,
+	})
+
+	if report.Failed > 0 {
+		logged := 0
+		for _, test := range report.Results {
+			if test.Status != models.TestStatusFailed {
+				continue
+			}
+			if logged == maxLoggedFailures {
+				rc.Logf(models.LogLevelError, "另有 %d 个测试失败未列出", report.Failed-logged)
+				break
+			}
+			rc.Log(models.LogLevelError, fmt.Sprintf("测试失败: %s", testreport.Key(test)), map[string]interface{}{
+				"suite":   test.Suite,
+				"name":    test.Name,
+				"message": test.Message,
+			})
+			logged++
+		}
+		return result, fmt.Errorf("%d of %d tests failed", report.Failed, report.Total)
+	}
+
+	// 没有测试失败但命令失败（如覆盖率不达标）时同样视为失败
+	return result, runErr
+}
+
+// readReports 读取并合并本次运行生成的报告文件
+func (r *TestRunner) readReports(rc *RunContext, cfg testRunnerConfig, started time.Time) (*models.TestReport, error) {
+	root := rc.Environment.WorkingDirectory
+	if root == "" {
+		root = "."
+	}
+	matches, err := artifact.Glob(root, cfg.Reports)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*models.TestReport
+	for _, name := range matches {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		// 跳过之前的运行遗留的报告
+		if info.ModTime().Before(started.Add(-time.Second)) {
+			rc.Logf(models.LogLevelWarn, "报告文件 %s 不是本次运行生成的，已忽略", name)
+			continue
+		}
+
+		data, err := readReportFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read test report %s: %w", name, err)
+		}
+		report, err := testreport.Parse(cfg.Format, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no test report files matched %v", cfg.Reports)
+	}
+	return testreport.Merge(reports), nil
+}
+
+// readReportFile 读取报告文件，超过大小上限时报错
+func readReportFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxReportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxReportSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxReportSize)
+	}
+	return data, nil
+}
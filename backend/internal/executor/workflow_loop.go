@@ -0,0 +1,174 @@
+/**
+ * 工作流循环步骤
+ * for-each按items表达式的结果逐个（或按并行度同时）执行步骤的动作，
+ * while在每次迭代前计算条件；模板中以.Loop、表达式中以iteration访问当前迭代
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"aischedule/internal/models"
+	"aischedule/internal/templating"
+)
+
+// workflowMaxIterations 循环步骤默认的迭代次数上限
+const workflowMaxIterations = 100
+
+// loopIteration 一次迭代的结果
+type loopIteration struct {
+	attempts int
+	result   *models.ExecutionResult
+	err      error
+}
+
+// runLoop 执行循环步骤，任一迭代失败（重试之后）时不再开始新的迭代，步骤失败
+func (w *workflowRun) runLoop(ctx context.Context, step *models.WorkflowStep) (int, *models.ExecutionResult, error) {
+	cfg := step.Loop
+	if cfg == nil {
+		return 0, nil, fmt.Errorf("loop step requires loop configuration")
+	}
+	maxIterations := cfg.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = workflowMaxIterations
+	}
+
+	if cfg.Items == "" {
+		return w.runWhile(ctx, step, cfg.While, maxIterations)
+	}
+	items, err := w.exprs.List(cfg.Items, w.templateData(step, 1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("loop.items: %w", err)
+	}
+	if len(items) > maxIterations {
+		return 0, nil, fmt.Errorf("loop has %d items, more than max_iterations %d", len(items), maxIterations)
+	}
+	return w.runForEach(ctx, step, items, cfg.Parallelism)
+}
+
+// runForEach 对每个元素执行一次动作，最多parallelism个迭代同时执行
+func (w *workflowRun) runForEach(ctx context.Context, step *models.WorkflowStep, items []interface{}, parallelism int) (int, *models.ExecutionResult, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	w.rc.Logf(models.LogLevelInfo, "步骤 %s 共%d次迭代，并行度%d", step.ID, len(items), parallelism)
+
+	iterations := make([]loopIteration, len(items))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	started := 0
+	for i, item := range items {
+		slots <- struct{}{}
+		if failed.Load() || ctx.Err() != nil {
+			<-slots
+			break
+		}
+		started++
+		wg.Add(1)
+		go func(i int, item interface{}) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			w.rc.Logf(models.LogLevelInfo, "步骤 %s 第%d次迭代", step.ID, i+1)
+			attempts, result, err := w.runAction(ctx, step, &templating.Loop{Index: i, Item: item})
+			iterations[i] = loopIteration{attempts: attempts, result: result, err: err}
+			if err != nil {
+				failed.Store(true)
+			}
+		}(i, item)
+	}
+	wg.Wait()
+	return loopResult(iterations[:started], ctx.Err())
+}
+
+// runWhile 条件成立时重复执行动作，iteration.last为上一次迭代结果的data
+func (w *workflowRun) runWhile(ctx context.Context, step *models.WorkflowStep, condition string, maxIterations int) (int, *models.ExecutionResult, error) {
+	var iterations []loopIteration
+	var last map[string]interface{}
+	for i := 0; ctx.Err() == nil; i++ {
+		loop := &templating.Loop{Index: i, Last: last}
+		data := w.templateData(step, 1)
+		data.Loop = loop
+		matched, err := w.exprs.Condition(condition, data)
+		if err != nil {
+			return loopResult(iterations, fmt.Errorf("loop.while: %w", err))
+		}
+		if !matched {
+			break
+		}
+		if i >= maxIterations {
+			return loopResult(iterations, fmt.Errorf("loop did not finish within %d iterations", maxIterations))
+		}
+
+		w.rc.Logf(models.LogLevelInfo, "步骤 %s 第%d次迭代", step.ID, i+1)
+		attempts, result, err := w.runAction(ctx, step, loop)
+		iterations = append(iterations, loopIteration{attempts: attempts, result: result, err: err})
+		if err != nil {
+			break
+		}
+		last = nil
+		if result != nil {
+			last = result.Data
+		}
+	}
+	return loopResult(iterations, ctx.Err())
+}
+
+// loopResult 汇总各次迭代：data.results为每次迭代的状态和data，输出按迭代拼接，
+// 产物和测试统计累加；第一个失败的迭代（或loopErr）作为步骤的错误
+func loopResult(iterations []loopIteration, loopErr error) (int, *models.ExecutionResult, error) {
+	result := &models.ExecutionResult{}
+	results := make([]interface{}, len(iterations))
+	var output strings.Builder
+	attempts := 0
+	failure := loopErr
+	for i, iteration := range iterations {
+		attempts += iteration.attempts
+		entry := map[string]interface{}{"index": i, "success": iteration.err == nil}
+		if r := iteration.result; r != nil {
+			entry["exit_code"] = r.ExitCode
+			if len(r.Data) > 0 {
+				entry["data"] = r.Data
+			}
+			if r.Output != "" {
+				fmt.Fprintf(&output, "[%d]\n%s\n", i, strings.TrimRight(r.Output, "\n"))
+			}
+			if r.ExitCode != 0 && result.ExitCode == 0 {
+				result.ExitCode = r.ExitCode
+			}
+			result.Artifacts = append(result.Artifacts, r.Artifacts...)
+			if r.Tests != nil {
+				if result.Tests == nil {
+					result.Tests = &models.TestReport{Format: r.Tests.Format, Results: []models.TestResult{}}
+				}
+				result.Tests.Total += r.Tests.Total
+				result.Tests.Passed += r.Tests.Passed
+				result.Tests.Failed += r.Tests.Failed
+				result.Tests.Skipped += r.Tests.Skipped
+				result.Tests.Duration += r.Tests.Duration
+			}
+		}
+		if iteration.err != nil {
+			entry["error"] = iteration.err.Error()
+			if failure == nil || failure == loopErr {
+				failure = fmt.Errorf("iteration %d failed: %w", i, iteration.err)
+			}
+		}
+		results[i] = entry
+	}
+
+	result.Success = failure == nil
+	result.Output = output.String()
+	result.Data = map[string]interface{}{
+		"iterations": len(iterations),
+		"results":    results,
+	}
+	return attempts, result, failure
+}
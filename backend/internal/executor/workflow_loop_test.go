@@ -0,0 +1,385 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+// loopStep 以echo动作执行的循环步骤，参数name渲染为当前元素
+func loopStep(cfg models.LoopConfig) models.WorkflowStep {
+	return models.WorkflowStep{
+		ID: "loop", Type: models.StepTypeLoop, Action: "echo", Loop: &cfg,
+		Parameters: map[string]interface{}{"name": "item-{{.Loop.Item}}"},
+	}
+}
+
+// echoRunner 返回当前迭代的序号、元素和渲染后的参数，fail(index)为true的迭代失败
+func echoRunner(fail func(index int) bool) Runner {
+	return runnerFunc(func(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+		loop := rc.Template.Loop
+		result := &models.ExecutionResult{
+			Success: true,
+			Output:  fmt.Sprint(rc.Parameters["name"]),
+			Data:    map[string]interface{}{"index": loop.Index, "n": loop.Index + 1},
+		}
+		if fail != nil && fail(loop.Index) {
+			result.Success, result.ExitCode = false, 3
+			return result, errors.New("boom")
+		}
+		return result, nil
+	})
+}
+
+// runLoopStep 执行工作流中唯一的循环步骤
+func runLoopStep(t *testing.T, ctx context.Context, step models.WorkflowStep, runner Runner) (int, *models.ExecutionResult, error) {
+	t.Helper()
+	run := newTestRun(t, models.Workflow{Steps: []models.WorkflowStep{step}}, map[models.TaskType]Runner{"echo": runner}, nil)
+	run.vars = map[string]interface{}{"targets": []interface{}{"x", "y"}}
+	return run.runLoop(ctx, &run.workflow.Steps[0])
+}
+
+// iterationResults data.results中每次迭代的index、success和error
+func iterationResults(result *models.ExecutionResult) []string {
+	entries, _ := result.Data["results"].([]interface{})
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		e := entry.(map[string]interface{})
+		line := fmt.Sprintf("%v:%v", e["index"], e["success"])
+		if message, ok := e["error"]; ok {
+			line += ":" + message.(string)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestLoopForEach(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      models.LoopConfig
+		fail     func(index int) bool
+		attempts int
+		results  []string
+		output   string
+		wantErr  string
+	}{
+		{
+			name:     "runs every item in order",
+			cfg:      models.LoopConfig{Items: "['a', 'b', 'c']"},
+			attempts: 3,
+			results:  []string{"0:true", "1:true", "2:true"},
+			output:   "[0]\nitem-a\n[1]\nitem-b\n[2]\nitem-c\n",
+		},
+		{
+			name:     "items from vars",
+			cfg:      models.LoopConfig{Items: "vars.targets"},
+			attempts: 2,
+			results:  []string{"0:true", "1:true"},
+			output:   "[0]\nitem-x\n[1]\nitem-y\n",
+		},
+		{
+			name:    "empty list runs nothing",
+			cfg:     models.LoopConfig{Items: "[]"},
+			results: []string{},
+		},
+		{
+			name:     "items at max_iterations",
+			cfg:      models.LoopConfig{Items: "[1, 2]", MaxIterations: 2},
+			attempts: 2,
+			results:  []string{"0:true", "1:true"},
+			output:   "[0]\nitem-1\n[1]\nitem-2\n",
+		},
+		{
+			name:    "items above max_iterations",
+			cfg:     models.LoopConfig{Items: "[1, 2, 3]", MaxIterations: 2},
+			wantErr: "loop has 3 items, more than max_iterations 2",
+		},
+		{
+			name:    "items expression error",
+			cfg:     models.LoopConfig{Items: "vars.missing"},
+			wantErr: "loop.items: no such key: missing",
+		},
+		{
+			name:     "failure stops new iterations",
+			cfg:      models.LoopConfig{Items: "[1, 2, 3, 4]"},
+			fail:     func(index int) bool { return index == 1 },
+			attempts: 2,
+			results:  []string{"0:true", "1:false:boom"},
+			output:   "[0]\nitem-1\n[1]\nitem-2\n",
+			wantErr:  "iteration 1 failed: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, result, err := runLoopStep(t, context.Background(), loopStep(tt.cfg), echoRunner(tt.fail))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if tt.results == nil {
+				if result != nil {
+					t.Errorf("got result %+v before any iteration", result)
+				}
+				return
+			}
+			if attempts != tt.attempts {
+				t.Errorf("attempts: got %d, want %d", attempts, tt.attempts)
+			}
+			if got := iterationResults(result); !reflect.DeepEqual(got, tt.results) {
+				t.Errorf("results: got %v, want %v", got, tt.results)
+			}
+			if result.Output != tt.output {
+				t.Errorf("output: got %q, want %q", result.Output, tt.output)
+			}
+			if result.Data["iterations"] != len(tt.results) || result.Success != (tt.wantErr == "") {
+				t.Errorf("iterations=%v success=%v", result.Data["iterations"], result.Success)
+			}
+		})
+	}
+}
+
+func TestLoopForEachIterationData(t *testing.T) {
+	_, result, err := runLoopStep(t, context.Background(), loopStep(models.LoopConfig{Items: "['a', 'b']"}), echoRunner(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"index": 0, "success": true, "exit_code": 0, "data": map[string]interface{}{"index": 0, "n": 1}},
+		map[string]interface{}{"index": 1, "success": true, "exit_code": 0, "data": map[string]interface{}{"index": 1, "n": 2}},
+	}
+	if !reflect.DeepEqual(result.Data["results"], want) {
+		t.Errorf("results: got %v, want %v", result.Data["results"], want)
+	}
+}
+
+func TestLoopForEachParallelism(t *testing.T) {
+	// 前两次迭代互相等待，只有同时执行时才能完成；同时执行的迭代数不超过并行度
+	var active, peak atomic.Int32
+	var started sync.WaitGroup
+	started.Add(2)
+	runner := runnerFunc(func(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			current := peak.Load()
+			if n <= current || peak.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		if rc.Template.Loop.Index < 2 {
+			started.Done()
+			done := make(chan struct{})
+			go func() { started.Wait(); close(done) }()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("iterations did not run in parallel")
+			}
+		}
+		return &models.ExecutionResult{Success: true, Data: map[string]interface{}{"item": rc.Template.Loop.Item}}, nil
+	})
+
+	attempts, result, err := runLoopStep(t, context.Background(), loopStep(models.LoopConfig{Items: "[1, 2, 3, 4, 5]", Parallelism: 2}), runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 5 || result.Data["iterations"] != 5 {
+		t.Errorf("attempts=%d iterations=%v", attempts, result.Data["iterations"])
+	}
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency: got %d, want 2", got)
+	}
+	// 结果按元素顺序排列，与完成顺序无关
+	for i, entry := range result.Data["results"].([]interface{}) {
+		data := entry.(map[string]interface{})["data"].(map[string]interface{})
+		if data["item"] != int64(i+1) {
+			t.Errorf("results[%d] item = %v", i, data["item"])
+		}
+	}
+}
+
+func TestLoopWhile(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      models.LoopConfig
+		fail     func(index int) bool
+		attempts int
+		results  []string
+		wantErr  string
+	}{
+		{
+			name:     "repeats until the last result matches",
+			cfg:      models.LoopConfig{While: "iteration.index == 0 || iteration.last.n < 3"},
+			attempts: 3,
+			results:  []string{"0:true", "1:true", "2:true"},
+		},
+		{
+			name:    "false condition runs nothing",
+			cfg:     models.LoopConfig{While: "false"},
+			results: []string{},
+		},
+		{
+			name:     "max_iterations exceeded",
+			cfg:      models.LoopConfig{While: "true", MaxIterations: 3},
+			attempts: 3,
+			results:  []string{"0:true", "1:true", "2:true"},
+			wantErr:  "loop did not finish within 3 iterations",
+		},
+		{
+			name:     "condition error",
+			cfg:      models.LoopConfig{While: "iteration.index < 1 || vars.missing"},
+			attempts: 1,
+			results:  []string{"0:true"},
+			wantErr:  "loop.while: no such key: missing",
+		},
+		{
+			name:     "failed iteration stops the loop",
+			cfg:      models.LoopConfig{While: "true"},
+			fail:     func(index int) bool { return index == 2 },
+			attempts: 3,
+			results:  []string{"0:true", "1:true", "2:false:boom"},
+			wantErr:  "iteration 2 failed: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, result, err := runLoopStep(t, context.Background(), loopStep(tt.cfg), echoRunner(tt.fail))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("attempts: got %d, want %d", attempts, tt.attempts)
+			}
+			if got := iterationResults(result); !reflect.DeepEqual(got, tt.results) {
+				t.Errorf("results: got %v, want %v", got, tt.results)
+			}
+		})
+	}
+}
+
+func TestLoopCancelled(t *testing.T) {
+	t.Run("cancelled inside an iteration", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		runner := runnerFunc(func(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+			if rc.Template.Loop.Index == 1 {
+				cancel()
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &models.ExecutionResult{Success: true}, nil
+		})
+		_, result, err := runLoopStep(t, ctx, loopStep(models.LoopConfig{Items: "[1, 2, 3, 4]"}), runner)
+		if err == nil || err.Error() != "iteration 1 failed: context canceled" {
+			t.Fatalf("got error %v", err)
+		}
+		if got := iterationResults(result); !reflect.DeepEqual(got, []string{"0:true", "1:false:context canceled"}) {
+			t.Errorf("results: %v", got)
+		}
+	})
+
+	t.Run("cancelled between iterations", func(t *testing.T) {
+		for _, cfg := range []models.LoopConfig{{Items: "[1, 2, 3]"}, {While: "true"}} {
+			ctx, cancel := context.WithCancel(context.Background())
+			runner := runnerFunc(func(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+				cancel()
+				return &models.ExecutionResult{Success: true}, nil
+			})
+			_, result, err := runLoopStep(t, ctx, loopStep(cfg), runner)
+			cancel()
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%+v: got error %v, want context.Canceled", cfg, err)
+			}
+			if result == nil || result.Data["iterations"] != 1 || result.Success {
+				t.Errorf("%+v: got result %+v, want one iteration", cfg, result)
+			}
+		}
+	})
+}
+
+func TestLoopInWorkflow(t *testing.T) {
+	workflow := models.Workflow{Steps: []models.WorkflowStep{
+		loopStep(models.LoopConfig{Items: "[1, 2, 3]"}),
+		{ID: "after", Type: models.StepTypeAction, Action: "ok", Condition: "steps.loop.result.iterations == 3 && steps.loop.result.results[2].data.n == 3"},
+	}}
+	runners := testRunners()
+	runners["echo"] = echoRunner(nil)
+	run := newTestRun(t, workflow, runners, nil)
+	if err := run.execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := run.statusLine(); got != "loop=completed after=completed" {
+		t.Errorf("statuses: %s", got)
+	}
+
+	failing := newTestRun(t, workflow, map[models.TaskType]Runner{"echo": echoRunner(func(index int) bool { return index == 0 }), "ok": runners["ok"]}, nil)
+	err := failing.execute(context.Background())
+	if err == nil || err.Error() != "step loop failed: iteration 0 failed: boom" {
+		t.Errorf("got error %v", err)
+	}
+	if got := failing.statusLine(); got != "loop=failed after=skipped" {
+		t.Errorf("statuses: %s", got)
+	}
+}
+
+func TestLoopResult(t *testing.T) {
+	artifact := func(name string) models.Artifact { return models.Artifact{Name: name} }
+	iterations := []loopIteration{
+		{attempts: 1, result: &models.ExecutionResult{
+			Success: true, Output: "first\n", Artifacts: []models.Artifact{artifact("a.txt")},
+			Tests: &models.TestReport{Format: "junit", Total: 3, Passed: 3, Duration: 1500},
+		}},
+		{attempts: 2, result: &models.ExecutionResult{
+			ExitCode: 4, Output: "second", Artifacts: []models.Artifact{artifact("b.txt")},
+			Tests: &models.TestReport{Format: "junit", Total: 2, Passed: 1, Failed: 1, Duration: 500},
+		}, err: errors.New("tests failed")},
+		{attempts: 1, result: &models.ExecutionResult{ExitCode: 5}, err: errors.New("later")},
+		{attempts: 1, err: errors.New("no result")},
+	}
+
+	attempts, result, err := loopResult(iterations, context.Canceled)
+	if attempts != 5 {
+		t.Errorf("attempts: got %d, want 5", attempts)
+	}
+	// 第一个失败的迭代优先于loopErr
+	if err == nil || err.Error() != "iteration 1 failed: tests failed" {
+		t.Errorf("got error %v", err)
+	}
+	if result.Success || result.ExitCode != 4 {
+		t.Errorf("success=%v exit_code=%d", result.Success, result.ExitCode)
+	}
+	if result.Output != "[0]\nfirst\n[1]\nsecond\n" {
+		t.Errorf("output: %q", result.Output)
+	}
+	if len(result.Artifacts) != 2 || result.Artifacts[0].Name != "a.txt" || result.Artifacts[1].Name != "b.txt" {
+		t.Errorf("artifacts: %+v", result.Artifacts)
+	}
+	tests := result.Tests
+	if tests == nil || tests.Format != "junit" || tests.Total != 5 || tests.Passed != 4 || tests.Failed != 1 || tests.Duration != 2000 {
+		t.Errorf("tests: %+v", tests)
+	}
+	if got := strings.Join(iterationResults(result), " "); got != "0:true 1:false:tests failed 2:false:later 3:false:no result" {
+		t.Errorf("results: %s", got)
+	}
+
+	_, _, err = loopResult(nil, context.Canceled)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("empty loop: got %v, want context.Canceled", err)
+	}
+}
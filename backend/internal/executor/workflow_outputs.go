@@ -0,0 +1,213 @@
+/**
+ * 工作流步骤输出
+ * 步骤通过outputs声明输出，成功后从JSON标准输出、工作目录中的文件或模型回复中提取，
+ * 后续步骤在参数模板中以.Steps.<id>.Outputs.<name>、在条件中以steps.<id>.outputs.<name>引用；
+ * 超过大小上限的输出和运行器数据保存为产物，步骤结果中只保留对产物的引用
+ */
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+const (
+	// workflowOutputLimit 单个声明输出序列化为JSON后的上限(字节)
+	workflowOutputLimit = 16 * 1024
+	// workflowDataLimit 步骤结果中运行器data序列化为JSON后的上限(字节)
+	workflowDataLimit = 64 * 1024
+	// workflowOutputFileLimit 输出文件的读取上限(字节)
+	workflowOutputFileLimit = 1 << 20
+)
+
+// extractOutputs 从成功步骤的结果中提取声明的输出，任一输出提取失败时步骤失败
+func (w *workflowRun) extractOutputs(step *models.WorkflowStep, result *models.ExecutionResult) (map[string]interface{}, error) {
+	outputs := make(map[string]interface{}, len(step.Outputs))
+	for _, output := range step.Outputs {
+		value, err := w.extractOutput(output, result)
+		if err != nil {
+			return outputs, fmt.Errorf("output %s: %w", output.Name, err)
+		}
+		outputs[output.Name] = value
+	}
+	return outputs, nil
+}
+
+func (w *workflowRun) extractOutput(output models.StepOutput, result *models.ExecutionResult) (interface{}, error) {
+	var doc interface{}
+	switch output.From {
+	case models.OutputSourceStdout:
+		value, ok := lastJSON(result.Output)
+		if !ok {
+			return nil, fmt.Errorf("stdout contains no JSON value")
+		}
+		doc = value
+	case models.OutputSourceFile:
+		content, err := w.readOutputFile(output.File)
+		if err != nil {
+			return nil, err
+		}
+		doc = jsonOrText(content)
+	case models.OutputSourceResponse:
+		doc = jsonOrText(stripCodeFence(result.Output))
+	default:
+		return nil, fmt.Errorf("unsupported output source %q", output.From)
+	}
+
+	if output.Path == "" {
+		return doc, nil
+	}
+	if text, ok := doc.(string); ok {
+		return nil, fmt.Errorf("path %s requires JSON content, got %q", output.Path, truncateToolOutput(text))
+	}
+	return evalJSONPath(doc, output.Path)
+}
+
+// readOutputFile 读取工作目录中的输出文件；步骤必须有工作目录（或工作区），
+// 解析符号链接后的路径不能离开工作目录
+func (w *workflowRun) readOutputFile(name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("file %q must be a relative path inside the working directory", name)
+	}
+	if w.rc.Environment.WorkingDirectory == "" {
+		return "", fmt.Errorf("file outputs require a working directory or workspace")
+	}
+	root, err := filepath.EvalSymlinks(w.rc.Environment.WorkingDirectory)
+	if err != nil {
+		return "", err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return "", err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("file %q resolves outside the working directory", name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, workflowOutputFileLimit+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > workflowOutputFileLimit {
+		return "", fmt.Errorf("file %q is larger than %d bytes", name, workflowOutputFileLimit)
+	}
+	return string(content), nil
+}
+
+// lastJSON 解析JSON标准输出：整个输出是一个JSON值，或者取最后一行JSON（前面可以有其他日志）
+func lastJSON(output string) (interface{}, bool) {
+	if value, err := decodeJSON(output); err == nil {
+		return value, true
+	}
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "{") && !strings.HasPrefix(line, "[") {
+			continue
+		}
+		if value, err := decodeJSON(line); err == nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// jsonOrText 内容是JSON时返回解析后的值，否则返回去掉首尾空白的文本
+func jsonOrText(content string) interface{} {
+	if value, err := decodeJSON(content); err == nil {
+		return value
+	}
+	return strings.TrimSpace(content)
+}
+
+// decodeJSON 解析一个完整的JSON值，数字保留原始写法以免大整数在模板中变成科学计数法
+func decodeJSON(content string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected content after JSON value")
+	}
+	return value, nil
+}
+
+// stripCodeFence 去掉模型回复中包裹JSON的```代码块
+func stripCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return text
+	}
+	body := strings.TrimSuffix(trimmed[3:], "```")
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:]
+	}
+	return body
+}
+
+// limitOutputs 检查步骤数据和声明输出的大小，超过上限的值保存为产物并替换为引用
+func (w *workflowRun) limitOutputs(step *models.WorkflowStep, outcome *stepOutcome) {
+	if outcome.result != nil && len(outcome.result.Data) > 0 {
+		if ref, spilled := w.spill(step, "data", outcome.result.Data, workflowDataLimit, outcome.result); spilled {
+			outcome.result.Data = ref
+		}
+	}
+	for name, value := range outcome.outputs {
+		if ref, spilled := w.spill(step, "outputs/"+name, value, workflowOutputLimit, outcome.result); spilled {
+			outcome.outputs[name] = ref
+		}
+	}
+}
+
+// spill 值序列化后超过limit时保存为产物outputs/<step>/<name>.json并追加到步骤结果的产物中，
+// 返回{spilled, artifact, checksum, size}引用；没有产物存储时只保留引用中的大小
+func (w *workflowRun) spill(step *models.WorkflowStep, name string, value interface{}, limit int, result *models.ExecutionResult) (map[string]interface{}, bool) {
+	raw, err := json.Marshal(value)
+	if err != nil || len(raw) <= limit {
+		return nil, false
+	}
+
+	artifactName := fmt.Sprintf("outputs/%s/%s.json", step.ID, name)
+	ref := map[string]interface{}{"spilled": true, "size": len(raw)}
+	ctx, cancel := context.WithTimeout(context.Background(), artifactCollectTimeout)
+	defer cancel()
+	saved, err := w.rc.SaveArtifact(ctx, artifactName, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		w.rc.Logf(models.LogLevelWarn, "步骤 %s 的 %s 超过 %d 字节，保存为产物失败，已丢弃: %v", step.ID, name, limit, err)
+		return ref, true
+	}
+	if result != nil {
+		result.Artifacts = append(result.Artifacts, saved)
+	}
+	ref["artifact"] = saved.Name
+	ref["checksum"] = saved.Checksum
+	w.rc.Log(models.LogLevelInfo, fmt.Sprintf("步骤 %s 的 %s 超过 %d 字节，已保存为产物 %s", step.ID, name, limit, artifactName), map[string]interface{}{
+		"step_id": step.ID,
+		"size":    len(raw),
+	})
+	return ref, true
+}
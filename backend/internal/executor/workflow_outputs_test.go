@@ -0,0 +1,374 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/models"
+)
+
+func TestLastJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   interface{}
+		ok     bool
+	}{
+		{name: "whole output", output: "{\n  \"version\": \"1.2.0\"\n}\n", want: map[string]interface{}{"version": "1.2.0"}, ok: true},
+		{name: "last line after logs", output: "building...\n{\"a\": 1}\ndone\n{\"a\": 2}\n", want: map[string]interface{}{"a": json.Number("2")}, ok: true},
+		{name: "array line", output: "log\n[1, 2]", want: []interface{}{json.Number("1"), json.Number("2")}, ok: true},
+		{name: "scalar output", output: "42", want: json.Number("42"), ok: true},
+		{name: "large integer keeps digits", output: `{"id": 12345678901234567890}`, want: map[string]interface{}{"id": json.Number("12345678901234567890")}, ok: true},
+		{name: "broken last line falls back", output: "{\"a\": 1}\n{\"a\": ", want: map[string]interface{}{"a": json.Number("1")}, ok: true},
+		{name: "trailing content on the line", output: "{\"a\": 1} extra", ok: false},
+		{name: "no json", output: "all good\n", ok: false},
+		{name: "empty", output: "", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lastJSON(tt.output)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, %v, want %#v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "json fence", text: "```json\n{\"a\": 1}\n```", want: "{\"a\": 1}\n"},
+		{name: "plain fence with spaces", text: "  ```\n[1]\n```  \n", want: "[1]\n"},
+		{name: "no fence", text: "{\"a\": 1}", want: "{\"a\": 1}"},
+		{name: "text around fence", text: "Here:\n```json\n{}\n```", want: "Here:\n```json\n{}\n```"},
+		{name: "only backticks", text: "`````", want: "`````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripCodeFence(tt.text); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOutputFile(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	outside := filepath.Join(root, "secret.txt")
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(work, "out", "result.json"), `{"ok": true}`)
+	write(filepath.Join(work, "big.txt"), strings.Repeat("x", workflowOutputFileLimit+1))
+	write(outside, "top secret")
+	symlink := func(target, name string) {
+		if err := os.Symlink(target, filepath.Join(work, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink(outside, "escape.txt")
+	symlink(root, "parent")
+	symlink(filepath.Join(work, "out", "result.json"), "inside.json")
+
+	tests := []struct {
+		name    string
+		workDir string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{name: "file in working directory", workDir: work, file: "out/result.json", want: `{"ok": true}`},
+		{name: "symlink inside working directory", workDir: work, file: "inside.json", want: `{"ok": true}`},
+		{name: "symlinked working directory", workDir: filepath.Join(work, "parent", "work"), file: "out/result.json", want: `{"ok": true}`},
+		{name: "symlink to a file outside", workDir: work, file: "escape.txt", wantErr: `file "escape.txt" resolves outside the working directory`},
+		{name: "symlinked directory outside", workDir: work, file: "parent/secret.txt", wantErr: `file "parent/secret.txt" resolves outside the working directory`},
+		{name: "parent path", workDir: work, file: "../secret.txt", wantErr: `file "../secret.txt" must be a relative path inside the working directory`},
+		{name: "absolute path", workDir: work, file: outside, wantErr: "must be a relative path inside the working directory"},
+		{name: "no working directory", file: "out/result.json", wantErr: "file outputs require a working directory or workspace"},
+		{name: "missing file", workDir: work, file: "missing.json", wantErr: "no such file or directory"},
+		{name: "too large", workDir: work, file: "big.txt", wantErr: `file "big.txt" is larger than 1048576 bytes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &workflowRun{rc: &RunContext{Environment: models.ExecutionEnvironment{WorkingDirectory: tt.workDir}}}
+			got, err := run.readOutputFile(tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %q, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpill(t *testing.T) {
+	step := &models.WorkflowStep{ID: "build"}
+	small := map[string]interface{}{"a": "b"}
+	large := map[string]interface{}{"blob": strings.Repeat("x", 100)}
+	raw, _ := json.Marshal(large)
+
+	t.Run("below limit", func(t *testing.T) {
+		run := &workflowRun{rc: &RunContext{Artifacts: artifact.NewLocalStore(t.TempDir())}}
+		if ref, spilled := run.spill(step, "data", small, 100, &models.ExecutionResult{}); spilled || ref != nil {
+			t.Errorf("got %v, %v", ref, spilled)
+		}
+	})
+
+	t.Run("saved as artifact", func(t *testing.T) {
+		store := artifact.NewLocalStore(t.TempDir())
+		run := &workflowRun{rc: &RunContext{Artifacts: store}}
+		result := &models.ExecutionResult{}
+		ref, spilled := run.spill(step, "outputs/report", large, 100, result)
+		if !spilled {
+			t.Fatal("not spilled")
+		}
+		if len(result.Artifacts) != 1 {
+			t.Fatalf("artifacts: %+v", result.Artifacts)
+		}
+		saved := result.Artifacts[0]
+		want := map[string]interface{}{
+			"spilled":  true,
+			"size":     len(raw),
+			"artifact": "outputs/build/outputs/report.json",
+			"checksum": saved.Checksum,
+		}
+		if !reflect.DeepEqual(ref, want) || saved.Name != "outputs/build/outputs/report.json" || saved.ContentType != "application/json" {
+			t.Errorf("got %v, artifact %+v", ref, saved)
+		}
+		reader, err := store.Open(context.Background(), saved.Checksum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || string(content) != string(raw) {
+			t.Errorf("stored content %q, %v", content, err)
+		}
+	})
+
+	t.Run("without artifact store", func(t *testing.T) {
+		run := &workflowRun{rc: &RunContext{}}
+		result := &models.ExecutionResult{}
+		ref, spilled := run.spill(step, "data", large, 100, result)
+		if !spilled || !reflect.DeepEqual(ref, map[string]interface{}{"spilled": true, "size": len(raw)}) || len(result.Artifacts) != 0 {
+			t.Errorf("got %v, %v, artifacts %v", ref, spilled, result.Artifacts)
+		}
+	})
+}
+
+func TestExtractOutputs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"report.json": `{"coverage": 87.5, "failed": ["a", "b"]}`,
+		"version.txt": "1.2.0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := &workflowRun{rc: &RunContext{Environment: models.ExecutionEnvironment{WorkingDirectory: dir}}}
+
+	tests := []struct {
+		name    string
+		output  models.StepOutput
+		result  string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name:   "stdout path",
+			output: models.StepOutput{Name: "v", From: models.OutputSourceStdout, Path: "$.version"},
+			result: "building\n{\"version\": \"1.2.0\"}\n",
+			want:   "1.2.0",
+		},
+		{
+			name:   "stdout whole value",
+			output: models.StepOutput{Name: "v", From: models.OutputSourceStdout},
+			result: `{"a": [1]}`,
+			want:   map[string]interface{}{"a": []interface{}{json.Number("1")}},
+		},
+		{
+			name:    "stdout without JSON",
+			output:  models.StepOutput{Name: "v", From: models.OutputSourceStdout},
+			result:  "all good",
+			wantErr: "output v: stdout contains no JSON value",
+		},
+		{
+			name:    "stdout path not found",
+			output:  models.StepOutput{Name: "v", From: models.OutputSourceStdout, Path: "$.missing"},
+			result:  `{"version": "1.2.0"}`,
+			wantErr: `output v: field "missing" not found`,
+		},
+		{
+			name:   "json file path",
+			output: models.StepOutput{Name: "c", From: models.OutputSourceFile, File: "report.json", Path: "$.failed[1]"},
+			want:   "b",
+		},
+		{
+			name:   "text file is trimmed",
+			output: models.StepOutput{Name: "v", From: models.OutputSourceFile, File: "version.txt"},
+			want:   "1.2.0",
+		},
+		{
+			name:    "path on text file",
+			output:  models.StepOutput{Name: "v", From: models.OutputSourceFile, File: "version.txt", Path: "$.version"},
+			wantErr: `output v: path $.version requires JSON content, got "1.2.0"`,
+		},
+		{
+			name:    "missing file",
+			output:  models.StepOutput{Name: "v", From: models.OutputSourceFile, File: "missing.json"},
+			wantErr: "no such file or directory",
+		},
+		{
+			name:   "response in code fence",
+			output: models.StepOutput{Name: "r", From: models.OutputSourceResponse, Path: "$.summary"},
+			result: "```json\n{\"summary\": \"ok\"}\n```",
+			want:   "ok",
+		},
+		{
+			name:   "text response",
+			output: models.StepOutput{Name: "r", From: models.OutputSourceResponse},
+			result: "  Looks good.\n",
+			want:   "Looks good.",
+		},
+		{
+			name:    "path on text response",
+			output:  models.StepOutput{Name: "r", From: models.OutputSourceResponse, Path: "$.x"},
+			result:  "Looks good.",
+			wantErr: `output r: path $.x requires JSON content, got "Looks good."`,
+		},
+		{
+			name:    "unsupported source",
+			output:  models.StepOutput{Name: "v", From: "env"},
+			wantErr: `output v: unsupported output source "env"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &models.WorkflowStep{ID: "s", Outputs: []models.StepOutput{tt.output}}
+			outputs, err := run.extractOutputs(step, &models.ExecutionResult{Output: tt.result})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := outputs[tt.output.Name]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitOutputs(t *testing.T) {
+	// 序列化后的JSON字符串比内容多两个引号
+	sized := func(n int) string { return strings.Repeat("x", n-2) }
+	step := &models.WorkflowStep{ID: "build"}
+
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		outputs map[string]interface{}
+		spilled []string
+	}{
+		{
+			name:    "output at the limit is kept",
+			outputs: map[string]interface{}{"log": sized(workflowOutputLimit)},
+		},
+		{
+			name:    "output above the limit",
+			outputs: map[string]interface{}{"log": sized(workflowOutputLimit + 1), "version": "1.2.0"},
+			spilled: []string{"outputs/build/outputs/log.json"},
+		},
+		{
+			name: "data between the output and data limits is kept",
+			data: map[string]interface{}{"body": sized(workflowDataLimit / 2)},
+		},
+		{
+			name:    "data above the limit",
+			data:    map[string]interface{}{"body": sized(workflowDataLimit)},
+			outputs: map[string]interface{}{"version": "1.2.0"},
+			spilled: []string{"outputs/build/data.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &workflowRun{rc: &RunContext{Artifacts: artifact.NewLocalStore(t.TempDir())}}
+			outcome := stepOutcome{result: &models.ExecutionResult{Data: tt.data}, outputs: map[string]interface{}{}}
+			for name, value := range tt.outputs {
+				outcome.outputs[name] = value
+			}
+			run.limitOutputs(step, &outcome)
+
+			var names []string
+			for _, saved := range outcome.result.Artifacts {
+				names = append(names, saved.Name)
+			}
+			if !reflect.DeepEqual(names, tt.spilled) {
+				t.Fatalf("artifacts: got %v, want %v", names, tt.spilled)
+			}
+			for name, value := range tt.outputs {
+				ref, spilled := outcome.outputs[name].(map[string]interface{})
+				if want := len(tt.spilled) > 0 && strings.HasSuffix(tt.spilled[0], "/"+name+".json"); spilled != want {
+					t.Errorf("output %s spilled=%v, want %v", name, spilled, want)
+				} else if spilled && ref["artifact"] != tt.spilled[0] {
+					t.Errorf("output %s ref: %v", name, ref)
+				} else if !spilled && outcome.outputs[name] != value {
+					t.Errorf("output %s changed to %v", name, outcome.outputs[name])
+				}
+			}
+			if tt.data != nil {
+				spilled := outcome.result.Data["spilled"] == true
+				if want := len(tt.spilled) > 0 && strings.HasSuffix(tt.spilled[0], "/data.json"); spilled != want {
+					t.Errorf("data spilled=%v, want %v: %v", spilled, want, outcome.result.Data["size"])
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowOutputErrors(t *testing.T) {
+	runners := map[models.TaskType]Runner{
+		"text": runnerFunc(func(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+			return &models.ExecutionResult{Success: true, Output: "plain text"}, nil
+		}),
+	}
+	workflow := models.Workflow{Steps: []models.WorkflowStep{
+		{ID: "a", Type: models.StepTypeAction, Action: "text", Outputs: []models.StepOutput{
+			{Name: "v", From: models.OutputSourceResponse, Path: "$.x"},
+		}},
+		action("b", "text"),
+	}}
+	run := newTestRun(t, workflow, runners, nil)
+	err := run.execute(context.Background())
+	if err == nil || err.Error() != `step a failed: output v: path $.x requires JSON content, got "plain text"` {
+		t.Errorf("got %v", err)
+	}
+	if got := run.statusLine(); got != "a=failed b=skipped" {
+		t.Errorf("statuses: %s", got)
+	}
+}
@@ -0,0 +1,987 @@
+/**
+ * 工作流运行器
+ * 加载工作流定义，从起始步骤开始沿连接和成功/失败跳转执行步骤，
+ * 动作步骤交给对应任务类型的运行器，步骤状态和结果随执行写入workflow_executions；
+ * 等待步骤未满足时工作流挂起，由调度器按记录的时间恢复（见workflow_wait.go）
+ */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+	"aischedule/internal/redact"
+	"aischedule/internal/secrets"
+	"aischedule/internal/service"
+	"aischedule/internal/templating"
+)
+
+const (
+	// workflowRetryDelay 步骤重试的基础间隔，第n次重试等待n倍
+	workflowRetryDelay = 5 * time.Second
+	// workflowStepOutput 步骤结果中保留的输出长度上限(字节)
+	workflowStepOutput = 4 * 1024
+)
+
+// WorkflowRunner 工作流运行器
+type WorkflowRunner struct {
+	db       *database.MongoDB
+	secrets  *secrets.Store
+	masker   func(primitive.ObjectID) *secrets.Masker
+	redactor *redact.Redactor
+	runners  map[models.TaskType]Runner
+}
+
+// workflowRunnerConfig 工作流任务参数
+type workflowRunnerConfig struct {
+	WorkflowID string                 `json:"workflow_id"` // 为空时使用任务的workflow_id
+	Variables  map[string]interface{} `json:"variables"`   // 运行变量，条件中以vars访问
+}
+
+// NewWorkflowRunner 创建新的工作流运行器，runners为步骤动作（任务类型）对应的运行器，
+// masker返回执行的脱敏器，步骤参数中解析出的密钥值加入其中；步骤结果保存前经redactor脱敏
+func NewWorkflowRunner(db *database.MongoDB, store *secrets.Store, masker func(primitive.ObjectID) *secrets.Masker, redactor *redact.Redactor, runners map[models.TaskType]Runner) *WorkflowRunner {
+	return &WorkflowRunner{
+		db:       db,
+		secrets:  store,
+		masker:   masker,
+		redactor: redactor,
+		runners:  runners,
+	}
+}
+
+// Run 执行工作流
+func (r *WorkflowRunner) Run(ctx context.Context, rc *RunContext) (*models.ExecutionResult, error) {
+	var cfg workflowRunnerConfig
+	if err := decodeParams(rc.Parameters, &cfg); err != nil {
+		return nil, err
+	}
+	workflowID, err := r.workflowID(rc, cfg.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 挂起后恢复的执行按开始时保存的定义继续，新的执行读取当前的工作流
+	execution, err := r.suspendedExecution(ctx, rc)
+	if err != nil {
+		return nil, err
+	}
+	var workflow models.Workflow
+	if execution != nil {
+		workflow = definitionWorkflow(execution)
+	} else {
+		err = r.db.GetCollection("workflows").FindOne(ctx, bson.M{"_id": workflowID}).Decode(&workflow)
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("workflow %s not found", workflowID.Hex())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load workflow: %w", err)
+		}
+	}
+
+	graph, err := newWorkflowGraph(&workflow)
+	if err != nil {
+		return nil, err
+	}
+	exprs, err := templating.CompileWorkflow(workflow.Steps, workflow.Connections)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &workflowRun{
+		runner:   r,
+		rc:       rc,
+		workflow: &workflow,
+		graph:    graph,
+		exprs:    exprs,
+		vars:     cfg.Variables,
+		statuses: make(map[string]models.StepStatus, len(workflow.Steps)),
+		steps:    make(map[string]templating.Step, len(workflow.Steps)),
+		waits:    map[string]models.WorkflowWait{},
+	}
+	if execution != nil {
+		run.restore(execution)
+		rc.Log(models.LogLevelInfo, fmt.Sprintf("恢复工作流: %s", workflow.Name), map[string]interface{}{
+			"workflow_id":           workflow.ID.Hex(),
+			"workflow_execution_id": run.execution.ID.Hex(),
+		})
+	} else {
+		if err := run.start(ctx); err != nil {
+			return nil, err
+		}
+		rc.Log(models.LogLevelInfo, fmt.Sprintf("执行工作流: %s", workflow.Name), map[string]interface{}{
+			"workflow_id":           workflow.ID.Hex(),
+			"workflow_execution_id": run.execution.ID.Hex(),
+			"type":                  workflow.Type,
+			"start_step":            graph.start,
+		})
+	}
+
+	runErr := run.execute(ctx)
+	if errors.Is(runErr, errWorkflowWaiting) {
+		run.suspend()
+	} else {
+		run.finish(runErr)
+	}
+	return run.result(runErr), runErr
+}
+
+// workflowID 工作流ID：任务参数优先，其次是任务的workflow_id
+func (r *WorkflowRunner) workflowID(rc *RunContext, value string) (primitive.ObjectID, error) {
+	if value != "" {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("invalid workflow ID: %q", value)
+		}
+		return id, nil
+	}
+	if rc.Task != nil && rc.Task.WorkflowID != nil {
+		return *rc.Task.WorkflowID, nil
+	}
+	return primitive.NilObjectID, fmt.Errorf("workflow ID not specified")
+}
+
+// workflowGraph 工作流的步骤图，边来自连接和步骤的成功/失败跳转
+type workflowGraph struct {
+	steps       map[string]*models.WorkflowStep
+	order       map[string]int // 步骤在定义中的位置，顺序执行时按此排序
+	start       string
+	roots       []string
+	incoming    map[string][]string
+	outgoing    map[string][]string
+	connections map[string][]models.WorkflowConnection
+}
+
+// newWorkflowGraph 构建步骤图。没有定义任何连接和跳转时：顺序和条件工作流按定义顺序串联，
+// 并行工作流的步骤全部同时开始。起始步骤和没有前置步骤的步骤作为入口
+func newWorkflowGraph(workflow *models.Workflow) (*workflowGraph, error) {
+	if len(workflow.Steps) == 0 {
+		return nil, fmt.Errorf("workflow %s has no steps", workflow.Name)
+	}
+
+	g := &workflowGraph{
+		steps:       make(map[string]*models.WorkflowStep, len(workflow.Steps)),
+		order:       make(map[string]int, len(workflow.Steps)),
+		start:       workflow.StartStep,
+		incoming:    make(map[string][]string),
+		outgoing:    make(map[string][]string),
+		connections: make(map[string][]models.WorkflowConnection),
+	}
+	for i := range workflow.Steps {
+		step := &workflow.Steps[i]
+		if err := service.ValidateStepID(step.ID); err != nil {
+			return nil, err
+		}
+		if _, exists := g.steps[step.ID]; exists {
+			return nil, fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		g.steps[step.ID] = step
+		g.order[step.ID] = i
+	}
+	if g.start == "" {
+		g.start = workflow.Steps[0].ID
+	}
+	if g.steps[g.start] == nil {
+		return nil, fmt.Errorf("start step %q not found", g.start)
+	}
+
+	connections := workflow.Connections
+	hasJumps := false
+	for _, step := range workflow.Steps {
+		hasJumps = hasJumps || len(step.OnSuccess) > 0 || len(step.OnFailure) > 0
+	}
+	if len(connections) == 0 && !hasJumps && workflow.Type != models.WorkflowTypeParallel {
+		chain := []string{g.start}
+		for _, step := range workflow.Steps {
+			if step.ID != g.start {
+				chain = append(chain, step.ID)
+			}
+		}
+		for i := 1; i < len(chain); i++ {
+			connections = append(connections, models.WorkflowConnection{From: chain[i-1], To: chain[i]})
+		}
+	}
+
+	for _, connection := range connections {
+		if g.steps[connection.From] == nil || g.steps[connection.To] == nil {
+			return nil, fmt.Errorf("connection %s -> %s references unknown step", connection.From, connection.To)
+		}
+		g.connections[connection.From] = append(g.connections[connection.From], connection)
+		g.addEdge(connection.From, connection.To)
+	}
+	for _, step := range workflow.Steps {
+		for _, next := range append(append([]string{}, step.OnSuccess...), step.OnFailure...) {
+			if g.steps[next] == nil {
+				return nil, fmt.Errorf("step %s: next step %q not found", step.ID, next)
+			}
+			g.addEdge(step.ID, next)
+		}
+	}
+
+	// 起始步骤总是入口，指向它的边不参与等待
+	delete(g.incoming, g.start)
+	g.roots = []string{g.start}
+	for _, step := range workflow.Steps {
+		if step.ID != g.start && len(g.incoming[step.ID]) == 0 {
+			g.roots = append(g.roots, step.ID)
+		}
+	}
+	if cycle := g.findCycle(); cycle != "" {
+		return nil, fmt.Errorf("workflow has a cycle through step %s", cycle)
+	}
+	return g, nil
+}
+
+func (g *workflowGraph) addEdge(from, to string) {
+	for _, existing := range g.outgoing[from] {
+		if existing == to {
+			return
+		}
+	}
+	g.outgoing[from] = append(g.outgoing[from], to)
+	g.incoming[to] = append(g.incoming[to], from)
+}
+
+// findCycle 拓扑排序后仍有入边未处理的步骤在环上，返回其中按定义顺序的第一个
+func (g *workflowGraph) findCycle() string {
+	remaining := make(map[string]int, len(g.steps))
+	for id := range g.steps {
+		remaining[id] = len(g.incoming[id])
+	}
+	queue := append([]string{}, g.roots...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range g.outgoing[id] {
+			if next == g.start {
+				continue
+			}
+			remaining[next]--
+			if remaining[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	cycle := ""
+	for id, count := range remaining {
+		if count > 0 && (cycle == "" || g.order[id] < g.order[cycle]) {
+			cycle = id
+		}
+	}
+	return cycle
+}
+
+// successPath 步骤成功后进入的步骤：on_success和条件成立的连接；
+// 条件工作流只进入第一个条件成立的连接
+func (g *workflowGraph) successPath(step *models.WorkflowStep, exclusive bool, match func(string) (bool, error)) ([]string, error) {
+	next := append([]string{}, step.OnSuccess...)
+	for _, connection := range g.connections[step.ID] {
+		ok, err := match(connection.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("connection %s -> %s: %w", connection.From, connection.To, err)
+		}
+		if ok {
+			next = append(next, connection.To)
+			if exclusive {
+				break
+			}
+		}
+	}
+	return next, nil
+}
+
+// stepOutcome 单个步骤的执行结果
+type stepOutcome struct {
+	id      string
+	status  models.StepStatus
+	matched bool // 条件步骤的条件是否成立
+	result  *models.ExecutionResult
+	outputs map[string]interface{} // 声明的输出
+	err     error
+
+	redactionRules []string // 脱敏命中的规则
+}
+
+// workflowRun 一次工作流执行的状态
+type workflowRun struct {
+	runner    *WorkflowRunner
+	rc        *RunContext
+	workflow  *models.Workflow
+	graph     *workflowGraph
+	execution *models.WorkflowExecution
+
+	exprs *templating.Expressions // 已编译的条件和表达式
+	vars  map[string]interface{}  // 运行变量
+
+	mutex     sync.Mutex
+	statuses  map[string]models.StepStatus
+	steps     map[string]templating.Step // 已结束步骤的信息，供参数模板和条件使用
+	waits     map[string]models.WorkflowWait
+	artifacts []models.Artifact
+
+	pending   map[string]int  // 尚未确定是否进入的入边数量
+	activated map[string]bool // 至少有一条入边进入
+	ready     []string
+	failure   error // 导致工作流失败的第一个错误
+	aborted   bool  // 出现没有on_failure处理的失败，不再开始新的步骤
+}
+
+// start 记录工作流执行，所有步骤初始为等待中
+func (w *workflowRun) start(ctx context.Context) error {
+	w.prepare()
+	now := w.execution.StartedAt
+	if _, err := w.runner.db.GetCollection("workflow_executions").InsertOne(ctx, w.execution); err != nil {
+		return fmt.Errorf("failed to create workflow execution: %w", err)
+	}
+	_, err := w.runner.db.GetCollection("execution_logs").UpdateOne(ctx,
+		bson.M{"_id": w.rc.ExecutionID},
+		bson.M{"$set": bson.M{"workflow_id": w.workflow.ID, "workflow_version": w.workflow.Version, "updated_at": now}},
+	)
+	if err != nil {
+		log.Printf("Failed to link execution log to workflow: %v", err)
+	}
+	_, err = w.runner.db.GetCollection("workflows").UpdateOne(ctx,
+		bson.M{"_id": w.workflow.ID},
+		bson.M{"$inc": bson.M{"usage_count": 1}},
+	)
+	if err != nil {
+		log.Printf("Failed to update workflow usage count: %v", err)
+	}
+	return nil
+}
+
+// prepare 创建执行记录和初始调度状态：入口步骤就绪，其余步骤等待入边确定
+func (w *workflowRun) prepare() {
+	now := time.Now()
+	w.execution = &models.WorkflowExecution{
+		ID:             primitive.NewObjectID(),
+		WorkflowID:     w.workflow.ID,
+		ExecutionLogID: w.rc.ExecutionID,
+		Status:         models.StepStatusRunning,
+		CurrentStep:    w.graph.start,
+		StepStatuses:   make(map[string]models.StepStatus, len(w.workflow.Steps)),
+		StepResults:    map[string]interface{}{},
+		StartedAt:      now,
+		Definition: &models.WorkflowDefinition{
+			Name:        w.workflow.Name,
+			Type:        w.workflow.Type,
+			Version:     w.workflow.Version,
+			Steps:       w.workflow.Steps,
+			Connections: w.workflow.Connections,
+			StartStep:   w.workflow.StartStep,
+		},
+	}
+	if w.rc.Task != nil {
+		w.execution.TaskID = w.rc.Task.ID
+	}
+
+	w.pending = make(map[string]int, len(w.workflow.Steps))
+	w.activated = make(map[string]bool, len(w.workflow.Steps))
+	for _, step := range w.workflow.Steps {
+		w.statuses[step.ID] = models.StepStatusPending
+		w.execution.StepStatuses[step.ID] = models.StepStatusPending
+		w.pending[step.ID] = len(w.graph.incoming[step.ID])
+	}
+	for _, id := range w.graph.roots {
+		w.activated[id] = true
+		w.ready = append(w.ready, id)
+	}
+}
+
+// execute 调度步骤直到没有可执行的步骤：并行工作流同时执行所有就绪步骤，其余类型逐个执行
+func (w *workflowRun) execute(ctx context.Context) error {
+	parallel := w.workflow.Type == models.WorkflowTypeParallel
+	outcomes := make(chan stepOutcome)
+	running := 0
+
+	for {
+		for len(w.ready) > 0 && (parallel || running == 0) && !w.aborted && ctx.Err() == nil {
+			id := w.ready[0]
+			w.ready = w.ready[1:]
+			running++
+			w.setStatus(id, models.StepStatusRunning)
+			go func(step *models.WorkflowStep) {
+				outcomes <- w.runStep(ctx, step)
+			}(w.graph.steps[id])
+		}
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		if outcome.status == models.StepStatusWaiting {
+			continue
+		}
+		w.settle(outcome)
+	}
+
+	// 其余步骤都已结束时仍有等待中的步骤，工作流挂起；失败或取消后不再等待
+	if waiting := w.waitingSteps(); len(waiting) > 0 {
+		if !w.aborted && ctx.Err() == nil {
+			return errWorkflowWaiting
+		}
+		for _, id := range waiting {
+			w.clearWait(id)
+			w.setStatus(id, models.StepStatusSkipped)
+		}
+	}
+
+	// 因失败、取消或分支未进入而没有执行的步骤
+	for _, step := range w.workflow.Steps {
+		if w.status(step.ID) == models.StepStatusPending {
+			w.setStatus(step.ID, models.StepStatusSkipped)
+		}
+	}
+
+	if w.failure != nil {
+		return w.failure
+	}
+	if cancelled := cancelCause(ctx); cancelled != nil {
+		return cancelled
+	}
+	return ctx.Err()
+}
+
+// settle 记录步骤结果，并决定它的后续步骤是否进入
+func (w *workflowRun) settle(outcome stepOutcome) {
+	step := w.graph.steps[outcome.id]
+	exclusive := w.workflow.Type == models.WorkflowTypeConditional
+	match := func(condition string) (bool, error) {
+		return w.evaluate(condition, w.templateData(step, 1))
+	}
+
+	var next []string
+	var err error
+	switch {
+	case outcome.status == models.StepStatusFailed:
+		if !step.ContinueOnError {
+			if w.failure == nil {
+				w.failure = fmt.Errorf("step %s failed: %w", step.ID, outcome.err)
+			}
+			w.aborted = len(step.OnFailure) == 0
+			next = step.OnFailure
+		} else if len(step.OnFailure) > 0 {
+			next = step.OnFailure
+		} else {
+			next, err = w.graph.successPath(step, exclusive, match)
+		}
+	case step.Type == models.StepTypeCondition && !outcome.matched:
+		next = step.OnFailure
+	default:
+		// 完成的步骤和因条件不满足而跳过的步骤都进入成功分支
+		next, err = w.graph.successPath(step, exclusive, match)
+	}
+	if err != nil {
+		w.rc.Logf(models.LogLevelError, "步骤 %s 的后续步骤无法确定: %v", step.ID, err)
+		if w.failure == nil {
+			w.failure = err
+		}
+		w.aborted = true
+		next = nil
+	}
+
+	taken := make(map[string]bool, len(next))
+	for _, id := range next {
+		taken[id] = true
+	}
+	for _, id := range w.graph.outgoing[step.ID] {
+		w.decide(id, taken[id])
+	}
+}
+
+// decide 确定一条入边是否进入，所有入边确定后步骤就绪或跳过；跳过的步骤其后续步骤同样不会进入
+func (w *workflowRun) decide(id string, taken bool) {
+	if w.status(id) != models.StepStatusPending || w.pending[id] == 0 {
+		return
+	}
+	w.pending[id]--
+	if taken {
+		w.activated[id] = true
+	}
+	if w.pending[id] > 0 {
+		return
+	}
+
+	if w.activated[id] {
+		w.ready = append(w.ready, id)
+		sort.SliceStable(w.ready, func(i, j int) bool {
+			return w.graph.order[w.ready[i]] < w.graph.order[w.ready[j]]
+		})
+		return
+	}
+	w.setStatus(id, models.StepStatusSkipped)
+	for _, next := range w.graph.outgoing[id] {
+		w.decide(next, false)
+	}
+}
+
+// runStep 执行单个步骤：条件步骤计算条件，动作步骤按重试次数调用对应的运行器，
+// 循环步骤逐次执行动作，等待步骤检查等待是否结束
+func (w *workflowRun) runStep(ctx context.Context, step *models.WorkflowStep) stepOutcome {
+	outcome := stepOutcome{id: step.ID}
+	startedAt := time.Now()
+	wait, resuming := w.waitFor(step.ID)
+	w.rc.BeginStep(step.ID)
+	if resuming {
+		startedAt = wait.StartedAt
+		w.rc.Logf(models.LogLevelInfo, "检查等待步骤 %s", step.ID)
+	} else {
+		w.rc.Log(models.LogLevelInfo, fmt.Sprintf("开始执行步骤 %s", stepLabel(step)), map[string]interface{}{
+			"step_id": step.ID,
+			"type":    step.Type,
+			"action":  step.Action,
+		})
+	}
+
+	attempts := 0
+	switch step.Type {
+	case models.StepTypeCondition:
+		outcome.matched, outcome.err = w.evaluate(step.Condition, w.templateData(step, 1))
+		outcome.result = &models.ExecutionResult{
+			Success: outcome.err == nil,
+			Data:    map[string]interface{}{"result": outcome.matched},
+		}
+	case models.StepTypeAction, models.StepTypeLoop, models.StepTypeWait, "":
+		if !resuming {
+			matched, err := w.evaluate(step.Condition, w.templateData(step, 1))
+			if err != nil {
+				outcome.err = err
+				break
+			}
+			if !matched {
+				outcome.status = models.StepStatusSkipped
+				w.rc.Logf(models.LogLevelInfo, "步骤 %s 的条件不满足，跳过", step.ID)
+				break
+			}
+		}
+		switch step.Type {
+		case models.StepTypeLoop:
+			attempts, outcome.result, outcome.err = w.runLoop(ctx, step)
+		case models.StepTypeWait:
+			outcome.status, outcome.result, outcome.err = w.runWait(step, wait, resuming)
+		default:
+			attempts, outcome.result, outcome.err = w.runAction(ctx, step, nil)
+			if outcome.err == nil && len(step.Outputs) > 0 {
+				if outcome.result == nil {
+					outcome.result = &models.ExecutionResult{Success: true}
+				}
+				outcome.outputs, outcome.err = w.extractOutputs(step, outcome.result)
+			}
+		}
+	default:
+		outcome.err = fmt.Errorf("step type %s is not supported", step.Type)
+	}
+
+	if outcome.status == models.StepStatusWaiting {
+		return outcome
+	}
+	w.redact(&outcome)
+	w.limitOutputs(step, &outcome)
+	if outcome.status == "" {
+		outcome.status = models.StepStatusCompleted
+		if outcome.err != nil {
+			outcome.status = models.StepStatusFailed
+		}
+	}
+	w.record(step, outcome, attempts, startedAt)
+	return outcome
+}
+
+// runAction 执行动作步骤，失败时按步骤的重试次数重试；loop为循环步骤的当前迭代
+func (w *workflowRun) runAction(ctx context.Context, step *models.WorkflowStep, loop *templating.Loop) (int, *models.ExecutionResult, error) {
+	runner, ok := w.runner.runners[models.TaskType(step.Action)]
+	if !ok {
+		return 0, nil, fmt.Errorf("unsupported step action: %q", step.Action)
+	}
+
+	var result *models.ExecutionResult
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		result, err = w.runAttempt(ctx, runner, step, attempt, loop)
+		if err == nil || attempt > step.Retries || ctx.Err() != nil {
+			break
+		}
+
+		delay := time.Duration(attempt) * workflowRetryDelay
+		w.rc.Log(models.LogLevelWarn, fmt.Sprintf("步骤 %s 第%d次执行失败，%s后重试: %v", step.ID, attempt, delay, err),
+			map[string]interface{}{"step_id": step.ID, "attempt": attempt})
+		select {
+		case <-ctx.Done():
+			return attempt, result, err
+		case <-time.After(delay):
+		}
+	}
+	return attempt, result, err
+}
+
+// runAttempt 以步骤的参数和超时执行一次动作
+func (w *workflowRun) runAttempt(ctx context.Context, runner Runner, step *models.WorkflowStep, attempt int, loop *templating.Loop) (*models.ExecutionResult, error) {
+	stepRC, err := w.stepContext(ctx, step, attempt, loop)
+	if err != nil {
+		return nil, err
+	}
+	if stepRC.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stepRC.Timeout)
+		defer cancel()
+	}
+
+	result, err := runWithArtifacts(ctx, runner, stepRC)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && step.Timeout > 0 {
+		err = fmt.Errorf("step timed out after %s: %w", stepRC.Timeout, err)
+	}
+	return result, err
+}
+
+// stepContext 为步骤构建运行上下文：渲染步骤参数、解析其中的密钥引用，日志标记步骤ID
+func (w *workflowRun) stepContext(ctx context.Context, step *models.WorkflowStep, attempt int, loop *templating.Loop) (*RunContext, error) {
+	data := w.templateData(step, attempt)
+	data.Loop = loop
+	strict := w.rc.Task != nil && w.rc.Task.AgentConfig.StrictTemplates
+	params, err := templating.RenderParams(step.Parameters, data, strict)
+	if err != nil {
+		return nil, err
+	}
+	if refs := secrets.CollectRefs(step.Parameters); len(refs) > 0 {
+		resolver := w.runner.secrets.NewResolver(refs)
+		if params, err = resolver.ResolveParams(ctx, params); err != nil {
+			return nil, err
+		}
+		if masker := w.runner.masker(w.rc.ExecutionID); masker != nil {
+			masker.Add(resolver.Values()...)
+		}
+	}
+
+	// 运行器读取的任务配置改为步骤的配置
+	var task models.Task
+	if w.rc.Task != nil {
+		task = *w.rc.Task
+	}
+	task.Type = models.TaskType(step.Action)
+	task.AgentConfig.Parameters = step.Parameters
+	task.AgentConfig.Timeout = step.Timeout
+	task.AgentConfig.Retries = step.Retries
+	if step.AgentID != "" {
+		task.AgentConfig.AgentID = step.AgentID
+	}
+
+	stepRC := *w.rc
+	stepRC.Task = &task
+	stepRC.Parameters = params
+	stepRC.Template = data
+	if step.Timeout > 0 {
+		stepRC.Timeout = time.Duration(step.Timeout) * time.Second
+	}
+	stepRC.Sink = func(entries ...models.LogEntry) {
+		for i := range entries {
+			if entries[i].StepID == "" {
+				entries[i].StepID = step.ID
+			}
+		}
+		w.rc.Emit(entries...)
+	}
+	stepRC.Steps = func(phase string, completed bool) {
+		if w.rc.Steps != nil {
+			w.rc.Steps(step.ID+"/"+phase, completed)
+		}
+	}
+	return &stepRC, nil
+}
+
+// templateData 步骤参数模板和条件可用的数据，Steps为此刻已结束步骤的快照
+func (w *workflowRun) templateData(step *models.WorkflowStep, attempt int) *templating.Data {
+	var data templating.Data
+	if w.rc.Template != nil {
+		data = *w.rc.Template
+	}
+	data.Params = step.Parameters
+	data.Vars = w.vars
+	data.Run.Attempt = attempt
+
+	w.mutex.Lock()
+	data.Steps = make(map[string]templating.Step, len(w.steps))
+	for id, info := range w.steps {
+		data.Steps[id] = info
+	}
+	w.mutex.Unlock()
+	return &data
+}
+
+// evaluate 计算条件，条件为空时成立。条件是表达式，例如
+// steps.test.result.failed == 0 && trigger.branch == "main"，结果为null时不成立
+func (w *workflowRun) evaluate(condition string, data *templating.Data) (bool, error) {
+	matched, err := w.exprs.Condition(condition, data)
+	if err != nil {
+		return false, fmt.Errorf("condition: %w", err)
+	}
+	return matched, nil
+}
+
+// record 保存步骤的状态和结果
+func (w *workflowRun) record(step *models.WorkflowStep, outcome stepOutcome, attempts int, startedAt time.Time) {
+	stepResult := stepResultDoc(outcome, attempts, startedAt, time.Now())
+	errText := ""
+	if outcome.err != nil {
+		errText = outcome.err.Error()
+	}
+	info := stepInfo(outcome.status, outcome.result, outcome.outputs, errText)
+
+	w.mutex.Lock()
+	w.steps[step.ID] = info
+	if outcome.result != nil {
+		w.artifacts = append(w.artifacts, outcome.result.Artifacts...)
+	}
+	w.mutex.Unlock()
+
+	w.statusChanged(step.ID, outcome.status, bson.M{"step_results." + step.ID: stepResult})
+
+	level := models.LogLevelInfo
+	message := fmt.Sprintf("步骤 %s 执行完成", step.ID)
+	switch outcome.status {
+	case models.StepStatusFailed:
+		level = models.LogLevelError
+		message = fmt.Sprintf("步骤 %s 执行失败: %v", step.ID, outcome.err)
+		if step.ContinueOnError {
+			level = models.LogLevelWarn
+		}
+	case models.StepStatusSkipped:
+		message = fmt.Sprintf("步骤 %s 已跳过", step.ID)
+	}
+	entry := models.LogEntry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Data:      map[string]interface{}{"step_id": step.ID, "status": outcome.status},
+	}
+	if len(outcome.redactionRules) > 0 {
+		entry.Redacted = true
+		entry.RedactionRules = uniqueStrings(outcome.redactionRules)
+	}
+	w.rc.Emit(entry)
+	w.rc.CompleteStep(step.ID)
+}
+
+// stepResultDoc workflow_executions.step_results中保存的步骤结果
+func stepResultDoc(outcome stepOutcome, attempts int, startedAt, completedAt time.Time) map[string]interface{} {
+	stepResult := map[string]interface{}{
+		"status":       outcome.status,
+		"started_at":   startedAt,
+		"completed_at": completedAt,
+		"duration":     completedAt.Sub(startedAt).Milliseconds(),
+	}
+	if attempts > 0 {
+		stepResult["attempts"] = attempts
+	}
+	if outcome.result != nil {
+		stepResult["success"] = outcome.result.Success
+		stepResult["exit_code"] = outcome.result.ExitCode
+		if outcome.result.Output != "" {
+			stepResult["output"] = truncateStepOutput(outcome.result.Output)
+		}
+		if len(outcome.result.Data) > 0 {
+			stepResult["data"] = outcome.result.Data
+		}
+		if outcome.result.Tests != nil {
+			stepResult["tests"] = map[string]int{
+				"total":   outcome.result.Tests.Total,
+				"passed":  outcome.result.Tests.Passed,
+				"failed":  outcome.result.Tests.Failed,
+				"skipped": outcome.result.Tests.Skipped,
+			}
+		}
+	}
+	if len(outcome.outputs) > 0 {
+		stepResult["outputs"] = outcome.outputs
+	}
+	// 命中的规则只记录在步骤的完成日志中（非管理员请求看不到），step_results只保留标记
+	if len(outcome.redactionRules) > 0 {
+		stepResult["redacted"] = true
+	}
+	if outcome.err != nil {
+		stepResult["error"] = outcome.err.Error()
+	}
+	return stepResult
+}
+
+// redact 对步骤的结果、声明输出和错误脱敏（步骤参数中解析出的密钥值和脱敏规则）。
+// 步骤结果和输出会写入workflow_executions、提供给后续步骤、超过上限时保存为产物，因此在这些之前处理
+func (w *workflowRun) redact(outcome *stepOutcome) {
+	masker := w.runner.masker(w.rc.ExecutionID)
+	if outcome.result != nil {
+		if rules := w.runner.redactor.Result(outcome.result, masker); len(rules) > 0 {
+			outcome.redactionRules = append(outcome.redactionRules, rules...)
+		}
+	}
+	if outcome.outputs != nil {
+		masked, rules := w.runner.redactor.Value(outcome.outputs, masker)
+		outcome.outputs = masked.(map[string]interface{})
+		outcome.redactionRules = append(outcome.redactionRules, rules...)
+	}
+	if outcome.err != nil {
+		if masked, rules := w.runner.redactor.String(outcome.err.Error(), masker); len(rules) > 0 {
+			outcome.err = &redactedError{message: masked, err: outcome.err}
+			outcome.redactionRules = append(outcome.redactionRules, rules...)
+		}
+	}
+}
+
+// redactedError 脱敏后的错误，保留原错误供errors.Is判断（如超时）
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string { return e.message }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+func (w *workflowRun) status(id string) models.StepStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.statuses[id]
+}
+
+// setStatus 更新步骤状态并写入工作流执行
+func (w *workflowRun) setStatus(id string, status models.StepStatus) {
+	set := bson.M{}
+	if status == models.StepStatusRunning {
+		set["current_step"] = id
+	}
+	w.statusChanged(id, status, set)
+}
+
+func (w *workflowRun) statusChanged(id string, status models.StepStatus, set bson.M) {
+	w.mutex.Lock()
+	w.statuses[id] = status
+	w.mutex.Unlock()
+
+	set["step_statuses."+id] = status
+	w.update(set)
+}
+
+// update 更新工作流执行记录（执行上下文可能已超时或取消，使用独立的上下文）
+func (w *workflowRun) update(set bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := w.runner.db.GetCollection("workflow_executions").UpdateOne(ctx,
+		bson.M{"_id": w.execution.ID},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Printf("Failed to update workflow execution: %v", err)
+	}
+}
+
+// finish 记录工作流执行的最终状态
+func (w *workflowRun) finish(runErr error) {
+	completedAt := time.Now()
+	w.execution.CompletedAt = &completedAt
+	w.execution.Duration = completedAt.Sub(w.execution.StartedAt).Milliseconds()
+	w.execution.Status = models.StepStatusCompleted
+	set := bson.M{
+		"completed_at": completedAt,
+		"duration":     w.execution.Duration,
+	}
+	if runErr != nil {
+		w.execution.Status = models.StepStatusFailed
+		w.execution.Error = runErr.Error()
+		set["error"] = w.execution.Error
+	}
+	set["status"] = w.execution.Status
+	w.update(set)
+}
+
+// result 工作流任务的执行结果：各步骤的状态，以及步骤产生的产物
+func (w *workflowRun) result(runErr error) *models.ExecutionResult {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	lines := make([]string, 0, len(w.workflow.Steps))
+	for _, step := range w.workflow.Steps {
+		lines = append(lines, fmt.Sprintf("%s: %s", step.ID, w.statuses[step.ID]))
+	}
+	statuses := make(map[string]interface{}, len(w.statuses))
+	for id, status := range w.statuses {
+		statuses[id] = status
+	}
+
+	return &models.ExecutionResult{
+		Success:   runErr == nil,
+		Output:    strings.Join(lines, "\n"),
+		Artifacts: w.artifacts,
+		Data: map[string]interface{}{
+			"workflow_id":           w.workflow.ID.Hex(),
+			"workflow_execution_id": w.execution.ID.Hex(),
+			"step_statuses":         statuses,
+		},
+	}
+}
+
+// stepInfo 参数模板和条件中的步骤信息，result为运行器结果加上success、exit_code和测试统计
+func stepInfo(status models.StepStatus, result *models.ExecutionResult, outputs map[string]interface{}, errText string) templating.Step {
+	info := templating.Step{Status: string(status), Output: map[string]interface{}{}, Result: map[string]interface{}{}, Outputs: outputs, Error: errText}
+	if result == nil {
+		return info
+	}
+	for key, value := range result.Data {
+		info.Result[key] = value
+	}
+	if len(result.Data) > 0 {
+		info.Output = result.Data
+	}
+	info.Result["success"] = result.Success
+	info.Result["exit_code"] = result.ExitCode
+	if result.Tests != nil {
+		info.Result["total"] = result.Tests.Total
+		info.Result["passed"] = result.Tests.Passed
+		info.Result["failed"] = result.Tests.Failed
+		info.Result["skipped"] = result.Tests.Skipped
+	}
+	return info
+}
+
+// stepLabel 日志中的步骤名称
+func stepLabel(step *models.WorkflowStep) string {
+	if step.Name == "" || step.Name == step.ID {
+		return step.ID
+	}
+	return fmt.Sprintf("%s (%s)", step.ID, step.Name)
+}
+
+// truncateStepOutput 截断步骤结果中保存的输出
+func truncateStepOutput(output string) string {
+	if len(output) <= workflowStepOutput {
+		return output
+	}
+	return output[:workflowStepOutput] + "\n...(truncated)"
+}
+
+// uniqueStrings 去重并保持首次出现的顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,475 @@
+/**
+ * 工作流等待步骤
+ * 等待未满足时不占用协程：步骤记录在workflow_executions.waits中，其余步骤结束后工作流挂起，
+ * 执行日志状态为waiting；调度器定期领取resume_at已到的挂起执行，从保存的调度状态继续
+ */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/models"
+	"aischedule/internal/templating"
+	"aischedule/internal/websocket"
+)
+
+// workflowWaitPoll 等待条件成立时默认的检查间隔
+const workflowWaitPoll = time.Minute
+
+// 等待的类型
+const (
+	waitDuration  = "duration"
+	waitUntil     = "until"
+	waitSignal    = "signal"
+	waitCondition = "condition"
+)
+
+// errWorkflowWaiting 工作流因等待步骤挂起，不是失败
+var errWorkflowWaiting = errors.New("workflow is waiting")
+
+// runWait 检查等待步骤：已满足时完成，超过步骤超时时失败，否则记录下次检查时间并返回waiting
+func (w *workflowRun) runWait(step *models.WorkflowStep, wait models.WorkflowWait, resuming bool) (models.StepStatus, *models.ExecutionResult, error) {
+	if step.Wait == nil {
+		return models.StepStatusFailed, nil, fmt.Errorf("wait step requires wait configuration")
+	}
+	now := time.Now()
+	data := w.templateData(step, 1)
+	if !resuming {
+		var err error
+		if wait, err = w.newWait(step, data, now); err != nil {
+			return models.StepStatusFailed, nil, err
+		}
+	}
+
+	output := map[string]interface{}{"kind": wait.Kind}
+	done := false
+	switch wait.Kind {
+	case waitDuration, waitUntil:
+		done = !now.Before(*wait.Until)
+	case waitSignal:
+		signal, ok, err := w.signal(wait.Signal)
+		if err != nil {
+			return models.StepStatusFailed, nil, err
+		}
+		if ok {
+			done = true
+			output["signal"] = wait.Signal
+			output["payload"] = signal.Payload
+			output["received_at"] = signal.ReceivedAt
+		}
+	case waitCondition:
+		matched, err := w.exprs.Condition(step.Wait.Condition, data)
+		if err != nil {
+			return models.StepStatusFailed, nil, fmt.Errorf("wait.condition: %w", err)
+		}
+		done = matched
+	default:
+		return models.StepStatusFailed, nil, fmt.Errorf("unknown wait kind %q", wait.Kind)
+	}
+
+	if done {
+		w.clearWait(step.ID)
+		output["waited"] = now.Sub(wait.StartedAt).Seconds()
+		return models.StepStatusCompleted, &models.ExecutionResult{Success: true, Data: output}, nil
+	}
+	if wait.Deadline != nil && !now.Before(*wait.Deadline) {
+		w.clearWait(step.ID)
+		return models.StepStatusFailed, nil, fmt.Errorf("wait timed out after %ds", step.Timeout)
+	}
+
+	// 下次检查：到达目标时间、条件的检查间隔或超时，只等待信号时由信号唤醒
+	var next *time.Time
+	switch wait.Kind {
+	case waitDuration, waitUntil:
+		next = wait.Until
+	case waitCondition:
+		poll := workflowWaitPoll
+		if step.Wait.PollInterval > 0 {
+			poll = time.Duration(step.Wait.PollInterval) * time.Second
+		}
+		at := now.Add(poll)
+		next = &at
+	}
+	if wait.Deadline != nil && (next == nil || wait.Deadline.Before(*next)) {
+		next = wait.Deadline
+	}
+	wait.ResumeAt = next
+	w.setWait(step.ID, wait)
+
+	message := fmt.Sprintf("步骤 %s 等待中", step.ID)
+	switch {
+	case wait.Kind == waitSignal:
+		message = fmt.Sprintf("步骤 %s 等待信号 %s", step.ID, wait.Signal)
+	case next != nil:
+		message = fmt.Sprintf("步骤 %s 等待中，下次检查时间 %s", step.ID, next.Format(time.RFC3339))
+	}
+	w.rc.Log(models.LogLevelInfo, message, map[string]interface{}{"step_id": step.ID, "kind": wait.Kind})
+	return models.StepStatusWaiting, nil, nil
+}
+
+// newWait 按步骤配置开始等待，时长和时间支持参数模板
+func (w *workflowRun) newWait(step *models.WorkflowStep, data *templating.Data, now time.Time) (models.WorkflowWait, error) {
+	cfg := step.Wait
+	strict := w.rc.Task != nil && w.rc.Task.AgentConfig.StrictTemplates
+	wait := models.WorkflowWait{StartedAt: now}
+	switch {
+	case cfg.Duration != "":
+		value, err := templating.Render("wait.duration", cfg.Duration, data, strict)
+		if err != nil {
+			return wait, err
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			return wait, fmt.Errorf("invalid wait duration %q", value)
+		}
+		until := now.Add(d)
+		wait.Kind = waitDuration
+		wait.Until = &until
+	case cfg.Until != "":
+		value, err := templating.Render("wait.until", cfg.Until, data, strict)
+		if err != nil {
+			return wait, err
+		}
+		until, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			return wait, fmt.Errorf("invalid wait until %q, expected RFC3339", value)
+		}
+		wait.Kind = waitUntil
+		wait.Until = &until
+	case cfg.Signal != "":
+		wait.Kind = waitSignal
+		wait.Signal = cfg.Signal
+	case cfg.Condition != "":
+		wait.Kind = waitCondition
+	default:
+		return wait, fmt.Errorf("wait requires one of duration, until, signal and condition")
+	}
+	if step.Timeout > 0 {
+		deadline := now.Add(time.Duration(step.Timeout) * time.Second)
+		wait.Deadline = &deadline
+	}
+	return wait, nil
+}
+
+// signal 读取工作流执行收到的信号
+func (w *workflowRun) signal(name string) (models.WorkflowSignal, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doc struct {
+		Signals map[string]models.WorkflowSignal `bson:"signals"`
+	}
+	err := w.runner.db.GetCollection("workflow_executions").FindOne(ctx,
+		bson.M{"_id": w.execution.ID},
+		options.FindOne().SetProjection(bson.M{"signals." + name: 1}),
+	).Decode(&doc)
+	if err != nil {
+		return models.WorkflowSignal{}, false, fmt.Errorf("failed to read signals: %w", err)
+	}
+	signal, ok := doc.Signals[name]
+	return signal, ok, nil
+}
+
+func (w *workflowRun) waitFor(id string) (models.WorkflowWait, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	wait, ok := w.waits[id]
+	return wait, ok
+}
+
+// setWait 记录等待中的步骤
+func (w *workflowRun) setWait(id string, wait models.WorkflowWait) {
+	w.mutex.Lock()
+	w.waits[id] = wait
+	w.mutex.Unlock()
+	w.statusChanged(id, models.StepStatusWaiting, bson.M{"waits." + id: wait})
+}
+
+// clearWait 等待结束，workflow_executions.waits中的记录保留
+func (w *workflowRun) clearWait(id string) {
+	w.mutex.Lock()
+	delete(w.waits, id)
+	w.mutex.Unlock()
+}
+
+// waitingSteps 等待中的步骤，按定义顺序
+func (w *workflowRun) waitingSteps() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	ids := make([]string, 0, len(w.waits))
+	for id := range w.waits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return w.graph.order[ids[i]] < w.graph.order[ids[j]] })
+	return ids
+}
+
+// suspend 保存调度状态并挂起工作流执行，resume_at为等待步骤中最早的检查时间
+func (w *workflowRun) suspend() {
+	w.mutex.Lock()
+	var resumeAt *time.Time
+	signals := make([]string, 0)
+	for _, wait := range w.waits {
+		if wait.ResumeAt != nil && (resumeAt == nil || wait.ResumeAt.Before(*resumeAt)) {
+			resumeAt = wait.ResumeAt
+		}
+		if wait.Kind == waitSignal {
+			signals = append(signals, wait.Signal)
+		}
+	}
+	state := &models.WorkflowState{
+		Pending:   w.pending,
+		Activated: w.activated,
+		Artifacts: w.artifacts,
+	}
+	w.mutex.Unlock()
+	if w.failure != nil {
+		state.Failure = w.failure.Error()
+	}
+
+	w.execution.Status = models.StepStatusWaiting
+	w.execution.ResumeAt = resumeAt
+	w.update(bson.M{
+		"status":       models.StepStatusWaiting,
+		"current_step": "",
+		"state":        state,
+		"resume_at":    resumeAt,
+	})
+
+	// 挂起前已经收到的信号立即唤醒；之后收到的信号由发送方唤醒
+	for _, name := range signals {
+		if _, ok, err := w.signal(name); err == nil && ok {
+			now := time.Now()
+			w.execution.ResumeAt = &now
+			w.update(bson.M{"resume_at": now})
+			break
+		}
+	}
+
+	message := "工作流等待中，收到信号后继续"
+	if w.execution.ResumeAt != nil {
+		message = fmt.Sprintf("工作流等待中，下次检查时间 %s", w.execution.ResumeAt.Format(time.RFC3339))
+	}
+	w.rc.Log(models.LogLevelInfo, message, map[string]interface{}{
+		"workflow_execution_id": w.execution.ID.Hex(),
+		"waiting_steps":         w.waitingSteps(),
+	})
+}
+
+// suspendedExecution 加载本次任务执行挂起的工作流执行，返回nil表示这是新的执行。
+// 没有定义快照的执行无法确认步骤是否与保存的状态一致，不恢复
+func (r *WorkflowRunner) suspendedExecution(ctx context.Context, rc *RunContext) (*models.WorkflowExecution, error) {
+	var execution models.WorkflowExecution
+	err := r.db.GetCollection("workflow_executions").FindOne(ctx,
+		bson.M{"execution_log_id": rc.ExecutionID}).Decode(&execution)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow execution: %w", err)
+	}
+	if execution.State == nil {
+		return nil, fmt.Errorf("workflow execution %s cannot be resumed", execution.ID.Hex())
+	}
+	if execution.Definition == nil {
+		return nil, fmt.Errorf("workflow execution %s has no definition snapshot and cannot be resumed", execution.ID.Hex())
+	}
+	return &execution, nil
+}
+
+// definitionWorkflow 按执行保存的定义快照构造工作流
+func definitionWorkflow(execution *models.WorkflowExecution) models.Workflow {
+	definition := execution.Definition
+	return models.Workflow{
+		ID:          execution.WorkflowID,
+		Name:        definition.Name,
+		Type:        definition.Type,
+		Version:     definition.Version,
+		Steps:       definition.Steps,
+		Connections: definition.Connections,
+		StartStep:   definition.StartStep,
+	}
+}
+
+// restore 从挂起的工作流执行恢复调度状态，等待中的步骤重新就绪，再次检查等待是否结束
+func (w *workflowRun) restore(execution *models.WorkflowExecution) {
+	w.execution = execution
+	w.pending = execution.State.Pending
+	w.activated = execution.State.Activated
+	if w.pending == nil {
+		w.pending = map[string]int{}
+	}
+	if w.activated == nil {
+		w.activated = map[string]bool{}
+	}
+	if execution.State.Failure != "" {
+		w.failure = errors.New(execution.State.Failure)
+	}
+	w.artifacts = execution.State.Artifacts
+
+	for _, step := range w.workflow.Steps {
+		status, ok := execution.StepStatuses[step.ID]
+		if !ok {
+			status = models.StepStatusPending
+		}
+		w.statuses[step.ID] = status
+		switch status {
+		case models.StepStatusCompleted, models.StepStatusFailed, models.StepStatusSkipped:
+			w.steps[step.ID] = restoredStep(status, execution.StepResults[step.ID])
+		case models.StepStatusWaiting:
+			if wait, ok := execution.Waits[step.ID]; ok {
+				w.waits[step.ID] = wait
+			}
+			w.ready = append(w.ready, step.ID)
+		}
+	}
+
+	w.execution.Status = models.StepStatusRunning
+	w.execution.ResumeAt = nil
+	w.update(bson.M{"status": models.StepStatusRunning})
+}
+
+// restoredStep 从保存的步骤结果恢复参数模板和条件中的步骤信息
+func restoredStep(status models.StepStatus, value interface{}) templating.Step {
+	var saved struct {
+		Success  bool                   `bson:"success"`
+		ExitCode int                    `bson:"exit_code"`
+		Data     map[string]interface{} `bson:"data"`
+		Tests    *models.TestReport     `bson:"tests"`
+		Outputs  map[string]interface{} `bson:"outputs"`
+		Error    string                 `bson:"error"`
+	}
+	if value != nil {
+		if raw, err := bson.Marshal(value); err == nil {
+			if err := bson.Unmarshal(raw, &saved); err != nil {
+				log.Printf("Failed to restore workflow step result: %v", err)
+			}
+		}
+	}
+	result := &models.ExecutionResult{
+		Success:  saved.Success,
+		ExitCode: saved.ExitCode,
+		Data:     saved.Data,
+		Tests:    saved.Tests,
+	}
+	return stepInfo(status, result, saved.Outputs, saved.Error)
+}
+
+// ResumeWorkflows 领取resume_at已到的挂起工作流执行并在后台恢复，由调度器定期调用
+func (e *DefaultTaskExecutor) ResumeWorkflows(ctx context.Context) error {
+	collection := e.db.GetCollection("workflow_executions")
+	for {
+		var execution models.WorkflowExecution
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"status": models.StepStatusWaiting, "resume_at": bson.M{"$lte": time.Now()}},
+			bson.M{"$set": bson.M{"status": models.StepStatusRunning}, "$unset": bson.M{"resume_at": ""}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&execution)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim waiting workflow: %w", err)
+		}
+		go e.resumeWorkflow(execution)
+	}
+}
+
+// resumeWorkflow 以原执行日志继续执行挂起的工作流任务
+func (e *DefaultTaskExecutor) resumeWorkflow(execution models.WorkflowExecution) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var executionLog models.ExecutionLog
+	err := e.db.GetCollection("execution_logs").FindOne(ctx, bson.M{"_id": execution.ExecutionLogID}).Decode(&executionLog)
+	if err != nil {
+		e.abandonWorkflow(execution, fmt.Sprintf("failed to load execution log: %v", err))
+		return
+	}
+	if executionLog.Status != models.ExecutionStatusWaiting {
+		e.abandonWorkflow(execution, fmt.Sprintf("execution is %s", executionLog.Status))
+		return
+	}
+	var task models.Task
+	if err := e.db.GetCollection("tasks").FindOne(ctx, bson.M{"_id": executionLog.TaskID}).Decode(&task); err != nil {
+		e.abandonWorkflow(execution, fmt.Sprintf("failed to load task: %v", err))
+		e.finishAbandoned(&executionLog, fmt.Errorf("failed to load task: %w", err))
+		return
+	}
+
+	_, err = e.db.GetCollection("execution_logs").UpdateOne(ctx,
+		bson.M{"_id": executionLog.ID, "status": models.ExecutionStatusWaiting},
+		bson.M{"$set": bson.M{"status": models.ExecutionStatusRunning, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to mark execution %s as running: %v", executionLog.ID.Hex(), err)
+	}
+	executionLog.Status = models.ExecutionStatusRunning
+	e.addLogEntry(ctx, executionLog.ID, models.LogLevelInfo, "工作流恢复执行", "executor", map[string]interface{}{
+		"workflow_execution_id": execution.ID.Hex(),
+	})
+
+	trigger := models.TriggerInfo{
+		Type:          executionLog.TriggerType,
+		By:            executionLog.TriggerBy,
+		ScheduledTime: executionLog.ScheduledTime,
+		Payload:       executionLog.TriggerPayload,
+		LogID:         executionLog.ID,
+	}
+	runCtx := context.Background()
+	if task.AgentConfig.Timeout > 0 {
+		var runCancel context.CancelFunc
+		runCtx, runCancel = context.WithTimeout(runCtx, time.Duration(task.AgentConfig.Timeout)*time.Second)
+		defer runCancel()
+	}
+	if err := e.run(runCtx, &task, &executionLog, trigger); err != nil {
+		log.Printf("Resumed workflow task %s failed: %v", task.Name, err)
+	}
+}
+
+// abandonWorkflow 无法恢复的挂起执行标记为失败
+func (e *DefaultTaskExecutor) abandonWorkflow(execution models.WorkflowExecution, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := e.db.GetCollection("workflow_executions").UpdateOne(ctx,
+		bson.M{"_id": execution.ID},
+		bson.M{"$set": bson.M{
+			"status":       models.StepStatusFailed,
+			"error":        reason,
+			"completed_at": now,
+			"duration":     now.Sub(execution.StartedAt).Milliseconds(),
+		}},
+	)
+	if err != nil {
+		log.Printf("Failed to update workflow execution: %v", err)
+	}
+	log.Printf("Workflow execution %s cannot be resumed: %s", execution.ID.Hex(), reason)
+}
+
+// finishAbandoned 无法恢复时结束等待中的执行日志
+func (e *DefaultTaskExecutor) finishAbandoned(executionLog *models.ExecutionLog, err error) {
+	e.addLogEntry(context.Background(), executionLog.ID, models.LogLevelError,
+		fmt.Sprintf("工作流无法恢复: %v", err), "executor", nil)
+	e.updateExecutionLog(executionLog, models.ExecutionStatusFailed, time.Now(),
+		&models.ExecutionResult{Success: false, Error: err.Error()})
+	e.wsManager.SendToTopic("task_execution", websocket.MessageTypeStatus, map[string]interface{}{
+		"task_id":      executionLog.TaskID.Hex(),
+		"execution_id": executionLog.ID.Hex(),
+		"status":       string(models.ExecutionStatusFailed),
+		"message":      "工作流无法恢复",
+		"error":        err.Error(),
+	})
+}
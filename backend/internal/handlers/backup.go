@@ -0,0 +1,176 @@
+/**
+ * 备份处理器
+ * 负责备份记录的查询、校验、恢复和删除
+ */
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/artifact"
+	"aischedule/internal/backup"
+	"aischedule/internal/executor"
+	"aischedule/internal/middleware"
+	"aischedule/internal/models"
+)
+
+// BackupHandler 备份处理器
+type BackupHandler struct {
+	backups *executor.BackupManager
+}
+
+// NewBackupHandler 创建新的备份处理器
+func NewBackupHandler(backups *executor.BackupManager) *BackupHandler {
+	return &BackupHandler{
+		backups: backups,
+	}
+}
+
+// GetBackups 获取备份列表，可按task_id过滤
+func (h *BackupHandler) GetBackups(c *gin.Context) {
+	filter := bson.M{}
+	if taskID := c.Query("task_id"); taskID != "" {
+		objectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+		filter["task_id"] = objectID
+	}
+
+	records, err := h.backups.List(c.Request.Context(), filter)
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    records,
+	})
+}
+
+// GetBackup 获取单个备份
+func (h *BackupHandler) GetBackup(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	record, err := h.backups.Get(c.Request.Context(), objectID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    record,
+	})
+}
+
+// VerifyBackup 把备份试恢复到临时目录校验
+func (h *BackupHandler) VerifyBackup(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	record, err := h.backups.Get(c.Request.Context(), objectID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if err := h.backups.Verify(c.Request.Context(), record); err != nil {
+		if errors.Is(err, artifact.ErrNotFound) {
+			h.handleError(c, err)
+			return
+		}
+		middleware.HandleError(c, http.StatusUnprocessableEntity, "verification_failed", err.Error(), nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    record,
+		"message": "备份校验通过",
+	})
+}
+
+// RestoreBackup 恢复备份，会写入服务器上的目录和数据库，只允许管理员调用
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		middleware.HandleForbiddenError(c)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	var req models.RestoreBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+	if req.TargetDir == "" && req.Database == "" {
+		middleware.HandleValidationError(c, fmt.Errorf("target_dir or database is required"))
+		return
+	}
+
+	result, err := h.backups.Restore(c.Request.Context(), objectID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"message": "备份恢复成功",
+	})
+}
+
+// DeleteBackup 删除备份
+func (h *BackupHandler) DeleteBackup(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	if err := h.backups.Delete(c.Request.Context(), objectID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "备份删除成功",
+	})
+}
+
+// handleError 将备份相关的错误转换为HTTP响应
+func (h *BackupHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, executor.ErrBackupNotFound):
+		middleware.HandleNotFoundError(c, "备份")
+	case errors.Is(err, artifact.ErrNotFound):
+		middleware.HandleNotFoundError(c, "备份归档")
+	case errors.Is(err, backup.ErrNotEmpty):
+		middleware.HandleError(c, http.StatusConflict, "conflict", err.Error(), nil)
+	default:
+		middleware.HandleInternalError(c, err)
+	}
+}
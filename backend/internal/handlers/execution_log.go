@@ -6,8 +6,13 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,20 +21,32 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"aischedule/internal/artifact"
+	"aischedule/internal/codereview"
 	"aischedule/internal/database"
+	"aischedule/internal/executor"
 	"aischedule/internal/middleware"
 	"aischedule/internal/models"
+	"aischedule/internal/service"
+	"aischedule/internal/testreport"
+	"aischedule/internal/websocket"
 )
 
 // ExecutionLogHandler 执行日志处理器
 type ExecutionLogHandler struct {
-	db *database.MongoDB
+	db        *database.MongoDB
+	executor  *executor.DefaultTaskExecutor
+	wsManager *websocket.Manager
+	logs      *service.ExecutionLogService
 }
 
 // NewExecutionLogHandler 创建新的执行日志处理器
-func NewExecutionLogHandler(db *database.MongoDB) *ExecutionLogHandler {
+func NewExecutionLogHandler(db *database.MongoDB, executor *executor.DefaultTaskExecutor, wsManager *websocket.Manager) *ExecutionLogHandler {
 	return &ExecutionLogHandler{
-		db: db,
+		db:        db,
+		executor:  executor,
+		wsManager: wsManager,
+		logs:      service.NewExecutionLogService(db),
 	}
 }
 
@@ -91,6 +108,11 @@ func (h *ExecutionLogHandler) GetExecutionLogs(c *gin.Context) {
 		middleware.HandleInternalError(c, err)
 		return
 	}
+	if !middleware.IsAdmin(c) {
+		for i := range logs {
+			service.HideRedactionRules(&logs[i])
+		}
+	}
 
 	response := models.ExecutionLogListResponse{
 		Logs: logs,
@@ -116,11 +138,9 @@ func (h *ExecutionLogHandler) GetExecutionLog(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.GetCollection("execution_logs")
-	var log models.ExecutionLog
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&log)
+	log, err := h.logs.Get(c.Request.Context(), objectID, middleware.IsAdmin(c))
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, service.ErrExecutionLogNotFound) {
 			middleware.HandleNotFoundError(c, "执行日志不存在")
 			return
 		}
@@ -197,6 +217,7 @@ func (h *ExecutionLogHandler) UpdateExecutionLog(c *gin.Context) {
 	}
 
 	if req.Result != nil {
+		h.executor.RedactResult(objectID, req.Result)
 		update["result"] = *req.Result
 	}
 
@@ -223,6 +244,9 @@ func (h *ExecutionLogHandler) UpdateExecutionLog(c *gin.Context) {
 		middleware.HandleInternalError(c, err)
 		return
 	}
+	if !middleware.IsAdmin(c) {
+		service.HideRedactionRules(&log)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -253,6 +277,7 @@ func (h *ExecutionLogHandler) AddLogEntry(c *gin.Context) {
 		Source:    req.Source,
 		Data:      req.Data,
 	}
+	h.executor.RedactEntry(objectID, &logEntry)
 
 	collection := h.db.GetCollection("execution_logs")
 	result, err := collection.UpdateOne(
@@ -327,6 +352,111 @@ func (h *ExecutionLogHandler) AddPerformanceMetric(c *gin.Context) {
 	})
 }
 
+// CancelExecution 取消运行中的执行
+func (h *ExecutionLogHandler) CancelExecution(c *gin.Context) {
+	logID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(logID)
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	var req models.CancelExecutionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+	}
+	// 取消者取自认证身份，不信任请求体
+	cancelledBy := middleware.Identity(c)
+
+	collection := h.db.GetCollection("execution_logs")
+	var log models.ExecutionLog
+	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&log)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			middleware.HandleNotFoundError(c, "执行日志不存在")
+			return
+		}
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	if log.Status != models.ExecutionStatusRunning && log.Status != models.ExecutionStatusWaiting {
+		middleware.HandleError(c, http.StatusConflict, "conflict", "执行已结束，无法取消", gin.H{"status": log.Status})
+		return
+	}
+
+	executionID := log.ExecutionID
+	if executionID == "" {
+		executionID = log.ID.Hex()
+	}
+
+	// 执行在本进程中运行时，取消其上下文并结束进程树，由执行器写入最终状态
+	err = h.executor.Registry().Cancel(executionID, cancelledBy, req.Label, req.Reason)
+	if err == nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "执行取消中",
+		})
+		return
+	}
+	if err != executor.ErrExecutionNotRunning {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	// 执行已不在运行（例如服务重启后遗留的记录或挂起的工作流），直接标记为已取消
+	now := time.Now()
+	_, err = collection.UpdateOne(
+		c.Request.Context(),
+		bson.M{"_id": objectID, "status": bson.M{"$in": []models.ExecutionStatus{models.ExecutionStatusRunning, models.ExecutionStatusWaiting}}},
+		bson.M{"$set": bson.M{
+			"status":         models.ExecutionStatusCancelled,
+			"cancelled_by":   cancelledBy,
+			"cancel_label":   req.Label,
+			"completed_at":   now,
+			"duration":       now.Sub(log.StartedAt).Milliseconds(),
+			"result.success": false,
+			"result.error":   "execution cancelled by " + cancelledBy,
+			"updated_at":     now,
+		}},
+	)
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	// 挂起的工作流不再恢复
+	_, err = h.db.GetCollection("workflow_executions").UpdateOne(
+		c.Request.Context(),
+		bson.M{"execution_log_id": objectID, "status": models.StepStatusWaiting},
+		bson.M{"$set": bson.M{
+			"status":       models.StepStatusFailed,
+			"error":        "execution cancelled by " + cancelledBy,
+			"completed_at": now,
+		}},
+	)
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	h.wsManager.SendToTopic("task_execution", websocket.MessageTypeStatus, gin.H{
+		"task_id":      log.TaskID.Hex(),
+		"execution_id": log.ID.Hex(),
+		"status":       string(models.ExecutionStatusCancelled),
+		"message":      "执行已取消",
+		"cancelled_by": cancelledBy,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "执行已取消",
+	})
+}
+
 // GetExecutionStats 获取执行统计
 func (h *ExecutionLogHandler) GetExecutionStats(c *gin.Context) {
 	collection := h.db.GetCollection("execution_logs")
@@ -391,14 +521,14 @@ func (h *ExecutionLogHandler) DeleteExecutionLog(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.GetCollection("execution_logs")
-	result, err := collection.DeleteOne(c.Request.Context(), bson.M{"_id": objectID})
+	// 同时释放不再被引用的产物
+	deleted, err := h.executor.DeleteExecutionLogs(c.Request.Context(), bson.M{"_id": objectID})
 	if err != nil {
 		middleware.HandleInternalError(c, err)
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	if deleted == 0 {
 		middleware.HandleNotFoundError(c, "执行日志不存在")
 		return
 	}
@@ -407,4 +537,251 @@ func (h *ExecutionLogHandler) DeleteExecutionLog(c *gin.Context) {
 		"success": true,
 		"message": "执行日志删除成功",
 	})
-}
\ No newline at end of file
+}
+
+// GetArtifact 下载执行产物，名称为空时返回产物列表
+func (h *ExecutionLogHandler) GetArtifact(c *gin.Context) {
+	logID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(logID)
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	collection := h.db.GetCollection("execution_logs")
+	var log models.ExecutionLog
+	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID},
+		options.FindOne().SetProjection(bson.M{"result.artifacts": 1})).Decode(&log)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			middleware.HandleNotFoundError(c, "执行日志不存在")
+			return
+		}
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if name == "" {
+		artifacts := log.Result.Artifacts
+		if artifacts == nil {
+			artifacts = []models.Artifact{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    artifacts,
+		})
+		return
+	}
+
+	var found *models.Artifact
+	for i := range log.Result.Artifacts {
+		if log.Result.Artifacts[i].Name == name {
+			found = &log.Result.Artifacts[i]
+			break
+		}
+	}
+	if found == nil {
+		middleware.HandleNotFoundError(c, "产物")
+		return
+	}
+
+	reader, err := h.executor.Artifacts().Open(c.Request.Context(), found.Checksum)
+	if err != nil {
+		if errors.Is(err, artifact.ErrNotFound) {
+			middleware.HandleNotFoundError(c, "产物内容")
+			return
+		}
+		middleware.HandleInternalError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	contentType := found.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("ETag", `"`+found.Checksum+`"`)
+	c.DataFromReader(http.StatusOK, found.Size, contentType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(found.Name)}),
+		"X-Checksum-Sha256":   found.Checksum,
+	})
+}
+
+// GetTestResults 获取执行的测试报告，可按status过滤测试结果
+func (h *ExecutionLogHandler) GetTestResults(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	log, err := h.findTestReport(c.Request.Context(), bson.M{"_id": objectID}, nil)
+	if err != nil {
+		h.handleTestReportError(c, err)
+		return
+	}
+
+	report := log.Result.Tests
+	if status := c.Query("status"); status != "" {
+		filtered := *report
+		filtered.Results = []models.TestResult{}
+		for _, result := range report.Results {
+			if string(result.Status) == status {
+				filtered.Results = append(filtered.Results, result)
+			}
+		}
+		report = &filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// CompareTestResults 对比两次执行的测试结果，未指定base时与同一任务上一次有测试报告的执行对比
+func (h *ExecutionLogHandler) CompareTestResults(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	head, err := h.findTestReport(ctx, bson.M{"_id": objectID}, nil)
+	if err != nil {
+		h.handleTestReportError(c, err)
+		return
+	}
+
+	var base *models.ExecutionLog
+	if baseID := c.Query("base"); baseID != "" {
+		baseObjectID, err := primitive.ObjectIDFromHex(baseID)
+		if err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+		base, err = h.findTestReport(ctx, bson.M{"_id": baseObjectID}, nil)
+		if err != nil {
+			h.handleTestReportError(c, err)
+			return
+		}
+	} else {
+		base, err = h.findTestReport(ctx, bson.M{
+			"task_id":    head.TaskID,
+			"started_at": bson.M{"$lt": head.StartedAt},
+		}, options.FindOne().SetSort(bson.M{"started_at": -1}))
+		if err != nil {
+			h.handleTestReportError(c, err)
+			return
+		}
+	}
+
+	comparison := testreport.Compare(base.Result.Tests, head.Result.Tests)
+	comparison.BaseExecutionID = base.ID.Hex()
+	comparison.HeadExecutionID = head.ID.Hex()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    comparison,
+	})
+}
+
+// findTestReport 查找带测试报告的执行日志
+func (h *ExecutionLogHandler) findTestReport(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*models.ExecutionLog, error) {
+	if opts == nil {
+		opts = options.FindOne()
+	}
+	filter["result.tests"] = bson.M{"$exists": true}
+	opts.SetProjection(bson.M{"task_id": 1, "execution_id": 1, "started_at": 1, "result.tests": 1})
+
+	var log models.ExecutionLog
+	if err := h.db.GetCollection("execution_logs").FindOne(ctx, filter, opts).Decode(&log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// handleTestReportError 处理测试报告查询错误
+func (h *ExecutionLogHandler) handleTestReportError(c *gin.Context, err error) {
+	if err == mongo.ErrNoDocuments {
+		middleware.HandleNotFoundError(c, "测试报告")
+		return
+	}
+	middleware.HandleInternalError(c, err)
+}
+
+// GetCodeReview 获取执行的代码审查结果，可按severity（不低于该严重程度）和file过滤
+func (h *ExecutionLogHandler) GetCodeReview(c *gin.Context) {
+	review, ok := h.findCodeReview(c)
+	if !ok {
+		return
+	}
+
+	severity := c.Query("severity")
+	if severity != "" {
+		if err := codereview.ValidateSeverity(severity); err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+	}
+	file := c.Query("file")
+	if severity != "" || file != "" {
+		filtered := *review
+		filtered.Findings = []models.ReviewFinding{}
+		for _, finding := range review.Findings {
+			if severity != "" && !codereview.AtLeast(finding.Severity, models.ReviewSeverity(severity)) {
+				continue
+			}
+			if file != "" && finding.File != file && !strings.HasPrefix(finding.File, strings.TrimSuffix(file, "/")+"/") {
+				continue
+			}
+			filtered.Findings = append(filtered.Findings, finding)
+		}
+		review = &filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    review,
+	})
+}
+
+// ExportCodeReviewSARIF 以SARIF格式导出代码审查结果
+func (h *ExecutionLogHandler) ExportCodeReviewSARIF(c *gin.Context) {
+	review, ok := h.findCodeReview(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "review-" + c.Param("id") + ".sarif",
+	}))
+	c.Header("Content-Type", "application/sarif+json")
+	c.JSON(http.StatusOK, codereview.SARIF(review, "aischedule-code-review"))
+}
+
+// findCodeReview 查找执行日志的代码审查结果，失败时已写入响应
+func (h *ExecutionLogHandler) findCodeReview(c *gin.Context) (*models.CodeReview, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		middleware.HandleValidationError(c, err)
+		return nil, false
+	}
+
+	var log models.ExecutionLog
+	err = h.db.GetCollection("execution_logs").FindOne(c.Request.Context(),
+		bson.M{"_id": objectID, "result.review": bson.M{"$exists": true}},
+		options.FindOne().SetProjection(bson.M{"result.review": 1}),
+	).Decode(&log)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			middleware.HandleNotFoundError(c, "代码审查结果")
+			return nil, false
+		}
+		middleware.HandleInternalError(c, err)
+		return nil, false
+	}
+	return log.Result.Review, true
+}
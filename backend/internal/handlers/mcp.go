@@ -0,0 +1,55 @@
+/**
+ * MCP处理器
+ * 在Gin路由上挂载MCP的Streamable HTTP传输和旧版SSE传输
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"aischedule/internal/database"
+	"aischedule/internal/mcp"
+	"aischedule/internal/middleware"
+	"aischedule/internal/scheduler"
+	"aischedule/internal/service"
+	"aischedule/internal/websocket"
+)
+
+// MCPHandler MCP处理器
+type MCPHandler struct {
+	transport *mcp.HTTPTransport
+}
+
+// NewMCPHandler 创建新的MCP处理器，messagesPath为旧版SSE客户端发送消息的地址，
+// 资源更新通知由WebSocket管理器推送的执行状态消息驱动
+func NewMCPHandler(db *database.MongoDB, scheduler *scheduler.Scheduler, validator service.TaskValidator, wsManager *websocket.Manager, messagesPath string, sessionTimeout time.Duration) *MCPHandler {
+	server := mcp.New(service.NewTaskService(db, scheduler, validator), service.NewExecutionLogService(db), service.NewWorkflowService(db, validator))
+	mcp.WatchExecutions(server, wsManager)
+	return &MCPHandler{
+		transport: mcp.NewHTTPTransport(server, messagesPath, sessionTimeout),
+	}
+}
+
+// Streamable 处理Streamable HTTP端点的POST、GET和DELETE请求
+func (h *MCPHandler) Streamable(c *gin.Context) {
+	h.transport.ServeStreamable(c.Writer, h.request(c))
+}
+
+// SSE 旧版传输的SSE端点
+func (h *MCPHandler) SSE(c *gin.Context) {
+	h.transport.ServeSSE(c.Writer, h.request(c))
+}
+
+// Messages 旧版传输的消息端点
+func (h *MCPHandler) Messages(c *gin.Context) {
+	h.transport.ServeMessages(c.Writer, h.request(c))
+}
+
+// request 把管理员标记带入工具调用的上下文
+func (h *MCPHandler) request(c *gin.Context) *http.Request {
+	return c.Request.WithContext(mcp.WithAdmin(c.Request.Context(), middleware.IsAdmin(c)))
+}
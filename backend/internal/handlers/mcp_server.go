@@ -0,0 +1,141 @@
+/**
+ * MCP服务端登记处理器
+ * 负责外部MCP服务端的登记、查询和工具发现，响应中的明文令牌和密码已遮盖
+ */
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aischedule/internal/executor"
+	"aischedule/internal/middleware"
+	"aischedule/internal/models"
+)
+
+// MCPServerHandler MCP服务端登记处理器
+type MCPServerHandler struct {
+	servers *executor.MCPServerRegistry
+}
+
+// NewMCPServerHandler 创建新的MCP服务端登记处理器
+func NewMCPServerHandler(servers *executor.MCPServerRegistry) *MCPServerHandler {
+	return &MCPServerHandler{
+		servers: servers,
+	}
+}
+
+// GetMCPServers 获取MCP服务端列表
+func (h *MCPServerHandler) GetMCPServers(c *gin.Context) {
+	list, err := h.servers.List(c.Request.Context())
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// GetMCPServer 获取单个MCP服务端及发现的工具
+func (h *MCPServerHandler) GetMCPServer(c *gin.Context) {
+	server, err := h.servers.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    server,
+	})
+}
+
+// CreateMCPServer 登记MCP服务端，工具在后台发现
+func (h *MCPServerHandler) CreateMCPServer(c *gin.Context) {
+	var req models.CreateMCPServerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	server, err := h.servers.Create(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    server,
+		"message": "MCP服务端登记成功",
+	})
+}
+
+// UpdateMCPServer 更新MCP服务端
+func (h *MCPServerHandler) UpdateMCPServer(c *gin.Context) {
+	var req models.UpdateMCPServerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	server, err := h.servers.Update(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    server,
+		"message": "MCP服务端更新成功",
+	})
+}
+
+// DeleteMCPServer 删除MCP服务端
+func (h *MCPServerHandler) DeleteMCPServer(c *gin.Context) {
+	if err := h.servers.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "MCP服务端删除成功",
+	})
+}
+
+// RefreshMCPServer 立即发现MCP服务端的工具，发现失败记录在last_error中
+func (h *MCPServerHandler) RefreshMCPServer(c *gin.Context) {
+	server, err := h.servers.Refresh(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    server,
+	})
+}
+
+// handleError 将登记表的错误转换为HTTP响应
+func (h *MCPServerHandler) handleError(c *gin.Context, err error) {
+	var serverErr *executor.MCPServerError
+	switch {
+	case errors.Is(err, executor.ErrMCPServerNotFound):
+		middleware.HandleNotFoundError(c, "MCP服务端不存在")
+	case errors.Is(err, executor.ErrMCPServerExists):
+		middleware.HandleError(c, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.As(err, &serverErr):
+		middleware.HandleValidationError(c, err)
+	default:
+		middleware.HandleInternalError(c, err)
+	}
+}
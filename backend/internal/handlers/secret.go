@@ -0,0 +1,128 @@
+/**
+ * 密钥处理器
+ * 负责密钥相关的HTTP请求处理，响应中只包含密钥的元数据
+ */
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"aischedule/internal/middleware"
+	"aischedule/internal/models"
+	"aischedule/internal/secrets"
+)
+
+// SecretHandler 密钥处理器
+type SecretHandler struct {
+	store *secrets.Store
+}
+
+// NewSecretHandler 创建新的密钥处理器
+func NewSecretHandler(store *secrets.Store) *SecretHandler {
+	return &SecretHandler{
+		store: store,
+	}
+}
+
+// GetSecrets 获取密钥列表
+func (h *SecretHandler) GetSecrets(c *gin.Context) {
+	list, err := h.store.List(c.Request.Context())
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// GetSecret 获取单个密钥的元数据
+func (h *SecretHandler) GetSecret(c *gin.Context) {
+	secret, err := h.store.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    secret,
+	})
+}
+
+// CreateSecret 创建密钥
+func (h *SecretHandler) CreateSecret(c *gin.Context) {
+	var req models.CreateSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	secret, err := h.store.Create(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    secret,
+		"message": "密钥创建成功",
+	})
+}
+
+// UpdateSecret 更新密钥
+func (h *SecretHandler) UpdateSecret(c *gin.Context) {
+	var req models.UpdateSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	secret, err := h.store.Update(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    secret,
+		"message": "密钥更新成功",
+	})
+}
+
+// DeleteSecret 删除密钥
+func (h *SecretHandler) DeleteSecret(c *gin.Context) {
+	if err := h.store.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "密钥删除成功",
+	})
+}
+
+// handleError 将密钥存储的错误转换为HTTP响应
+func (h *SecretHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
+		middleware.HandleNotFoundError(c, "密钥不存在")
+	case errors.Is(err, secrets.ErrExists):
+		middleware.HandleError(c, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.Is(err, secrets.ErrNoMasterKey):
+		middleware.HandleError(c, http.StatusServiceUnavailable, "secrets_disabled", err.Error(), nil)
+	case errors.Is(err, secrets.ErrInvalidName):
+		middleware.HandleValidationError(c, err)
+	default:
+		middleware.HandleInternalError(c, err)
+	}
+}
@@ -8,12 +8,14 @@ package handlers
 import (
 	"net/http"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 
 	"aischedule/internal/database"
+	"aischedule/internal/executor"
 	"aischedule/internal/middleware"
 	"aischedule/internal/scheduler"
 	"aischedule/internal/websocket"
@@ -23,14 +25,16 @@ import (
 type SystemHandler struct {
 	db        *database.MongoDB
 	scheduler *scheduler.Scheduler
+	executor  *executor.DefaultTaskExecutor
 	wsManager *websocket.Manager
 }
 
 // NewSystemHandler 创建新的系统处理器
-func NewSystemHandler(db *database.MongoDB, scheduler *scheduler.Scheduler, wsManager *websocket.Manager) *SystemHandler {
+func NewSystemHandler(db *database.MongoDB, scheduler *scheduler.Scheduler, executor *executor.DefaultTaskExecutor, wsManager *websocket.Manager) *SystemHandler {
 	return &SystemHandler{
 		db:        db,
 		scheduler: scheduler,
+		executor:  executor,
 		wsManager: wsManager,
 	}
 }
@@ -243,14 +247,24 @@ func (h *SystemHandler) GetLogs(c *gin.Context) {
 // ClearLogs 清理日志
 func (h *SystemHandler) ClearLogs(c *gin.Context) {
 	// 获取清理参数
-	days := c.DefaultQuery("days", "30")
-	
-	// 这里可以实现清理日志的逻辑
-	// 例如删除指定天数之前的执行日志
-	
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 {
+		middleware.HandleError(c, http.StatusBadRequest, "validation_error", "days必须为非负整数", nil)
+		return
+	}
+
+	// 删除指定天数之前结束的执行日志及其不再被引用的产物
+	before := time.Now().AddDate(0, 0, -days)
+	deleted, err := h.executor.PurgeExecutionLogs(c.Request.Context(), before)
+	if err != nil {
+		middleware.HandleInternalError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "日志清理成功，清理了 " + days + " 天前的日志",
+		"message": "日志清理成功，清理了 " + strconv.Itoa(days) + " 天前的日志",
+		"deleted": deleted,
 	})
 }
 
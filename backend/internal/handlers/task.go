@@ -6,6 +6,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,19 +21,24 @@ import (
 	"aischedule/internal/middleware"
 	"aischedule/internal/models"
 	"aischedule/internal/scheduler"
+	"aischedule/internal/service"
+	"aischedule/internal/templating"
+	"aischedule/internal/workspace"
 )
 
 // TaskHandler 任务处理器
 type TaskHandler struct {
 	db        *database.MongoDB
 	scheduler *scheduler.Scheduler
+	tasks     *service.TaskService
 }
 
-// NewTaskHandler 创建新的任务处理器
-func NewTaskHandler(db *database.MongoDB, scheduler *scheduler.Scheduler) *TaskHandler {
+// NewTaskHandler 创建新的任务处理器，validator按任务类型校验运行参数
+func NewTaskHandler(db *database.MongoDB, scheduler *scheduler.Scheduler, validator service.TaskValidator) *TaskHandler {
 	return &TaskHandler{
 		db:        db,
 		scheduler: scheduler,
+		tasks:     service.NewTaskService(db, scheduler, validator),
 	}
 }
 
@@ -44,37 +50,12 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	// 验证Cron表达式
-	if req.CronConfig.Expression != "" {
-		if err := h.scheduler.ValidateCronExpression(req.CronConfig.Expression); err != nil {
-			middleware.HandleValidationError(c, err)
-			return
-		}
-	}
-
-	task := &models.Task{
-		ID:          primitive.NewObjectID(),
-		Name:        req.Name,
-		Description: req.Description,
-		Type:        req.Type,
-		Status:      models.TaskStatusInactive,
-		CronConfig:  req.CronConfig,
-		AgentConfig: req.AgentConfig,
-		Environment: req.Environment,
-		WorkflowID:  req.WorkflowID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	collection := h.db.GetCollection("tasks")
-	result, err := collection.InsertOne(c.Request.Context(), task)
+	task, err := h.tasks.Create(c.Request.Context(), &req)
 	if err != nil {
-		middleware.HandleInternalError(c, err)
+		handleTaskError(c, err)
 		return
 	}
 
-	task.ID = result.InsertedID.(primitive.ObjectID)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"data":    task,
@@ -86,61 +67,17 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 func (h *TaskHandler) GetTasks(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	status := c.Query("status")
-	taskType := c.Query("type")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
-
-	// 构建查询条件
-	filter := bson.M{}
-	if status != "" {
-		filter["status"] = status
-	}
-	if taskType != "" {
-		filter["type"] = taskType
-	}
-
-	collection := h.db.GetCollection("tasks")
-
-	// 获取总数
-	total, err := collection.CountDocuments(c.Request.Context(), filter)
-	if err != nil {
-		middleware.HandleInternalError(c, err)
-		return
-	}
 
-	// 获取任务列表
-	skip := (page - 1) * limit
-	cursor, err := collection.Find(c.Request.Context(), filter, 
-		options.Find().
-			SetSkip(int64(skip)).
-			SetLimit(int64(limit)).
-			SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	response, err := h.tasks.List(c.Request.Context(), service.TaskFilter{
+		Status: c.Query("status"),
+		Type:   c.Query("type"),
+		Page:   page,
+		Limit:  limit,
+	})
 	if err != nil {
 		middleware.HandleInternalError(c, err)
 		return
 	}
-	defer cursor.Close(c.Request.Context())
-
-	var tasks []models.Task
-	if err := cursor.All(c.Request.Context(), &tasks); err != nil {
-		middleware.HandleInternalError(c, err)
-		return
-	}
-
-	response := models.TaskListResponse{
-		Tasks: tasks,
-		Pagination: models.Pagination{
-			Page:  page,
-			Limit: limit,
-			Total: int(total),
-		},
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -157,15 +94,9 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.GetCollection("tasks")
-	var task models.Task
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&task)
+	task, err := h.tasks.Get(c.Request.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			middleware.HandleNotFoundError(c, "任务不存在")
-			return
-		}
-		middleware.HandleInternalError(c, err)
+		handleTaskError(c, err)
 		return
 	}
 
@@ -192,7 +123,7 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 
 	// 验证Cron表达式
 	if req.CronConfig != nil && req.CronConfig.Expression != "" {
-		if err := h.scheduler.ValidateCronExpression(req.CronConfig.Expression); err != nil {
+		if _, err := scheduler.ParseSchedule(*req.CronConfig); err != nil {
 			middleware.HandleValidationError(c, err)
 			return
 		}
@@ -222,12 +153,37 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		update["agent_config"] = *req.AgentConfig
 	}
 	if req.Environment != nil {
+		if req.Environment.Workspace != nil {
+			if err := workspace.Validate(req.Environment.Workspace); err != nil {
+				middleware.HandleValidationError(c, err)
+				return
+			}
+		}
 		update["environment"] = *req.Environment
 	}
 	if req.WorkflowID != nil {
 		update["workflow_id"] = *req.WorkflowID
 	}
 
+	// 类型或参数变化时按更新后的任务校验参数
+	if req.Type != nil || req.AgentConfig != nil {
+		task, err := h.tasks.Get(c.Request.Context(), objectID)
+		if err != nil {
+			handleTaskError(c, err)
+			return
+		}
+		if req.Type != nil {
+			task.Type = *req.Type
+		}
+		if req.AgentConfig != nil {
+			task.AgentConfig = *req.AgentConfig
+		}
+		if err := h.tasks.ValidateParams(c.Request.Context(), task.Type, task.AgentConfig.Parameters); err != nil {
+			handleTaskError(c, err)
+			return
+		}
+	}
+
 	collection := h.db.GetCollection("tasks")
 	result, err := collection.UpdateOne(
 		c.Request.Context(),
@@ -298,47 +254,39 @@ func (h *TaskHandler) StartTask(c *gin.Context) {
 		return
 	}
 
-	// 获取任务
-	collection := h.db.GetCollection("tasks")
-	var task models.Task
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&task)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			middleware.HandleNotFoundError(c, "任务不存在")
-			return
-		}
-		middleware.HandleInternalError(c, err)
+	if err := h.tasks.Start(c.Request.Context(), objectID); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
-	// 添加到调度器
-	if err := h.scheduler.AddTask(&task); err != nil {
-		middleware.HandleInternalError(c, err)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "任务启动成功",
+	})
+}
+
+// StopTask 停止任务
+func (h *TaskHandler) StopTask(c *gin.Context) {
+	taskID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		middleware.HandleValidationError(c, err)
 		return
 	}
 
-	// 更新任务状态
-	_, err = collection.UpdateOne(
-		c.Request.Context(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"status":     models.TaskStatusActive,
-			"updated_at": time.Now(),
-		}},
-	)
-	if err != nil {
-		middleware.HandleInternalError(c, err)
+	if err := h.tasks.Stop(c.Request.Context(), objectID); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "任务启动成功",
+		"message": "任务停止成功",
 	})
 }
 
-// StopTask 停止任务
-func (h *TaskHandler) StopTask(c *gin.Context) {
+// ExecuteTask 立即执行任务
+func (h *TaskHandler) ExecuteTask(c *gin.Context) {
 	taskID := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(taskID)
 	if err != nil {
@@ -346,48 +294,94 @@ func (h *TaskHandler) StopTask(c *gin.Context) {
 		return
 	}
 
-	// 从调度器中移除任务
-	h.scheduler.RemoveTask(objectID)
+	var req models.ExecuteTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+	}
+	if req.TriggeredBy == "" {
+		req.TriggeredBy = "api"
+	}
 
-	// 更新任务状态
-	collection := h.db.GetCollection("tasks")
-	result, err := collection.UpdateOne(
-		c.Request.Context(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"status":     models.TaskStatusInactive,
-			"updated_at": time.Now(),
-		}},
-	)
+	// 立即执行任务
+	logID, err := h.tasks.Execute(c.Request.Context(), objectID, models.TriggerInfo{
+		Type:    "manual",
+		By:      req.TriggeredBy,
+		Payload: req.Payload,
+	})
 	if err != nil {
-		middleware.HandleInternalError(c, err)
+		handleTaskError(c, err)
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		middleware.HandleNotFoundError(c, "任务不存在")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"execution_log_id": logID.Hex()},
+		"message": "任务执行已启动",
+	})
+}
+
+// PreviewSchedule 预览调度配置之后的运行时间
+func (h *TaskHandler) PreviewSchedule(c *gin.Context) {
+	var req models.SchedulePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	runs, err := h.tasks.PreviewSchedule(req.CronConfig, req.Count)
+	if err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "任务停止成功",
+		"data":    gin.H{"next_runs": runs},
 	})
 }
 
-// ExecuteTask 立即执行任务
-func (h *TaskHandler) ExecuteTask(c *gin.Context) {
-	taskID := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(taskID)
+// handleTaskError 把任务服务的错误转换为HTTP响应
+func handleTaskError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		middleware.HandleNotFoundError(c, "任务不存在")
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		middleware.HandleNotFoundError(c, "工作流不存在")
+	case errors.Is(err, service.ErrWorkflowExecutionNotFound):
+		middleware.HandleNotFoundError(c, "工作流执行不存在")
+	case errors.As(err, &validationErr):
+		middleware.HandleValidationError(c, validationErr.Err)
+	default:
+		middleware.HandleInternalError(c, err)
+	}
+}
+
+// RollbackTask 回滚部署任务，按目标执行的版本和提交重新部署
+func (h *TaskHandler) RollbackTask(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		middleware.HandleValidationError(c, err)
 		return
 	}
 
-	// 获取任务
-	collection := h.db.GetCollection("tasks")
+	var req models.RollbackTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+	}
+	if req.TriggeredBy == "" {
+		req.TriggeredBy = "api"
+	}
+
+	ctx := c.Request.Context()
 	var task models.Task
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&task)
+	err = h.db.GetCollection("tasks").FindOne(ctx, bson.M{"_id": objectID}).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			middleware.HandleNotFoundError(c, "任务不存在")
@@ -396,12 +390,148 @@ func (h *TaskHandler) ExecuteTask(c *gin.Context) {
 		middleware.HandleInternalError(c, err)
 		return
 	}
+	if task.Type != models.TaskTypeDeployment {
+		middleware.HandleError(c, http.StatusBadRequest, "invalid_task_type", "只有部署任务可以回滚", nil)
+		return
+	}
 
-	// 立即执行任务
-	h.scheduler.ExecuteTaskNow(&task)
+	// 只有成功的部署可以作为回滚目标
+	filter := bson.M{
+		"task_id":                   task.ID,
+		"status":                    models.ExecutionStatusCompleted,
+		"result.deployment.version": bson.M{"$exists": true},
+	}
+	var target *models.ExecutionLog
+	if req.ExecutionLogID != "" {
+		logID, err := primitive.ObjectIDFromHex(req.ExecutionLogID)
+		if err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+		filter["_id"] = logID
+		var executionLog models.ExecutionLog
+		err = h.db.GetCollection("execution_logs").FindOne(ctx, filter).Decode(&executionLog)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				middleware.HandleNotFoundError(c, "成功的部署记录")
+				return
+			}
+			middleware.HandleInternalError(c, err)
+			return
+		}
+		target = &executionLog
+	} else {
+		// 未指定时回滚到与当前版本不同的最近一次成功部署
+		cursor, err := h.db.GetCollection("execution_logs").Find(ctx, filter,
+			options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(100))
+		if err != nil {
+			middleware.HandleInternalError(c, err)
+			return
+		}
+		var deployments []models.ExecutionLog
+		if err := cursor.All(ctx, &deployments); err != nil {
+			middleware.HandleInternalError(c, err)
+			return
+		}
+		for i := 1; i < len(deployments); i++ {
+			if deployments[i].Result.Deployment.Version != deployments[0].Result.Deployment.Version {
+				target = &deployments[i]
+				break
+			}
+		}
+		if target == nil {
+			middleware.HandleNotFoundError(c, "可回滚的部署")
+			return
+		}
+	}
+
+	version := target.Result.Deployment.Version
+	h.scheduler.ExecuteTaskNow(&task, models.TriggerInfo{
+		Type: models.TriggerTypeRollback,
+		By:   req.TriggeredBy,
+		Payload: map[string]interface{}{
+			"version":          version,
+			"commit":           target.CommitSHA,
+			"execution_log_id": target.ID.Hex(),
+		},
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "任务执行已启动",
+		"data": gin.H{
+			"execution_log_id": target.ID.Hex(),
+			"version":          version,
+			"commit":           target.CommitSHA,
+		},
+		"message": "回滚已启动",
+	})
+}
+
+// RenderPreview 预览参数模板的渲染结果
+func (h *TaskHandler) RenderPreview(c *gin.Context) {
+	var req models.RenderPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleValidationError(c, err)
+		return
+	}
+
+	task := &models.Task{Name: "preview"}
+	if req.TaskID != "" {
+		objectID, err := primitive.ObjectIDFromHex(req.TaskID)
+		if err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
+		collection := h.db.GetCollection("tasks")
+		err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(task)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				middleware.HandleNotFoundError(c, "任务不存在")
+				return
+			}
+			middleware.HandleInternalError(c, err)
+			return
+		}
+	}
+
+	strict := task.AgentConfig.StrictTemplates
+	if req.Strict != nil {
+		strict = *req.Strict
+	}
+	if req.Trigger.Type == "" {
+		req.Trigger.Type = "manual"
+	}
+
+	data := templating.NewData(task, "preview", time.Now(), req.Trigger)
+	for stepID, output := range req.Steps {
+		data.Steps[stepID] = templating.Step{Status: string(models.StepStatusCompleted), Output: output}
+	}
+
+	if req.Template != "" {
+		rendered, err := templating.Render("template", req.Template, data, strict)
+		if err != nil {
+			middleware.HandleError(c, http.StatusBadRequest, "template_error", err.Error(), nil)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    gin.H{"rendered": rendered},
+		})
+		return
+	}
+
+	params := req.Parameters
+	if params == nil {
+		params = task.AgentConfig.Parameters
+	}
+	rendered, err := templating.RenderParams(params, data, strict)
+	if err != nil {
+		middleware.HandleError(c, http.StatusBadRequest, "template_error", err.Error(), nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"parameters": rendered},
 	})
-}
\ No newline at end of file
+}
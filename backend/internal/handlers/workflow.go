@@ -6,9 +6,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,17 +19,22 @@ import (
 	"aischedule/internal/database"
 	"aischedule/internal/middleware"
 	"aischedule/internal/models"
+	"aischedule/internal/service"
 )
 
 // WorkflowHandler 工作流处理器
 type WorkflowHandler struct {
-	db *database.MongoDB
+	db        *database.MongoDB
+	validator service.TaskValidator
+	workflows *service.WorkflowService
 }
 
-// NewWorkflowHandler 创建新的工作流处理器
-func NewWorkflowHandler(db *database.MongoDB) *WorkflowHandler {
+// NewWorkflowHandler 创建新的工作流处理器，validator按步骤的动作类型校验参数
+func NewWorkflowHandler(db *database.MongoDB, validator service.TaskValidator) *WorkflowHandler {
 	return &WorkflowHandler{
-		db: db,
+		db:        db,
+		validator: validator,
+		workflows: service.NewWorkflowService(db, validator),
 	}
 }
 
@@ -41,27 +46,12 @@ func (h *WorkflowHandler) CreateWorkflow(c *gin.Context) {
 		return
 	}
 
-	workflow := &models.Workflow{
-		ID:          primitive.NewObjectID(),
-		Name:        req.Name,
-		Description: req.Description,
-		Type:        req.Type,
-		Steps:       req.Steps,
-		Connections: req.Connections,
-		Status:      models.WorkflowStatusInactive,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	collection := h.db.GetCollection("workflows")
-	result, err := collection.InsertOne(c.Request.Context(), workflow)
+	workflow, err := h.workflows.Create(c.Request.Context(), &req)
 	if err != nil {
-		middleware.HandleInternalError(c, err)
+		handleTaskError(c, err)
 		return
 	}
 
-	workflow.ID = result.InsertedID.(primitive.ObjectID)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"data":    workflow,
@@ -177,45 +167,9 @@ func (h *WorkflowHandler) UpdateWorkflow(c *gin.Context) {
 		return
 	}
 
-	// 构建更新数据
-	update := bson.M{
-		"updated_at": time.Now(),
-	}
-
-	if req.Name != nil {
-		update["name"] = *req.Name
-	}
-	if req.Description != nil {
-		update["description"] = *req.Description
-	}
-	if req.Steps != nil {
-		update["steps"] = *req.Steps
-	}
-	if req.Connections != nil {
-		update["connections"] = *req.Connections
-	}
-
-	collection := h.db.GetCollection("workflows")
-	result, err := collection.UpdateOne(
-		c.Request.Context(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": update},
-	)
+	workflow, err := h.workflows.Update(c.Request.Context(), objectID, &req)
 	if err != nil {
-		middleware.HandleInternalError(c, err)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		middleware.HandleNotFoundError(c, "工作流不存在")
-		return
-	}
-
-	// 获取更新后的工作流
-	var workflow models.Workflow
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&workflow)
-	if err != nil {
-		middleware.HandleInternalError(c, err)
+		handleTaskError(c, err)
 		return
 	}
 
@@ -253,8 +207,8 @@ func (h *WorkflowHandler) DeleteWorkflow(c *gin.Context) {
 	})
 }
 
-// ExecuteWorkflow 执行工作流
-func (h *WorkflowHandler) ExecuteWorkflow(c *gin.Context) {
+// GetWorkflowExecution 获取工作流执行状态
+func (h *WorkflowHandler) GetWorkflowExecution(c *gin.Context) {
 	workflowID := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(workflowID)
 	if err != nil {
@@ -275,113 +229,76 @@ func (h *WorkflowHandler) ExecuteWorkflow(c *gin.Context) {
 		return
 	}
 
-	// 更新工作流状态为执行中
-	_, err = collection.UpdateOne(
-		c.Request.Context(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"status":     models.WorkflowStatusRunning,
-			"updated_at": time.Now(),
-		}},
-	)
+	// 获取最近的工作流执行记录（步骤状态和结果）
+	executions, err := h.workflows.Executions(c.Request.Context(), objectID, 10)
 	if err != nil {
 		middleware.HandleInternalError(c, err)
 		return
 	}
 
-	// TODO: 实际的工作流执行逻辑
-	// 这里应该启动工作流执行引擎
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "工作流执行已启动",
-	})
-}
-
-// StopWorkflow 停止工作流
-func (h *WorkflowHandler) StopWorkflow(c *gin.Context) {
-	workflowID := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(workflowID)
+	// 获取相关的执行日志
+	logCollection := h.db.GetCollection("execution_logs")
+	cursor, err := logCollection.Find(c.Request.Context(), 
+		bson.M{"workflow_id": objectID},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetLimit(10))
 	if err != nil {
-		middleware.HandleValidationError(c, err)
+		middleware.HandleInternalError(c, err)
 		return
 	}
+	defer cursor.Close(c.Request.Context())
 
-	// 更新工作流状态
-	collection := h.db.GetCollection("workflows")
-	result, err := collection.UpdateOne(
-		c.Request.Context(),
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"status":     models.WorkflowStatusStopped,
-			"updated_at": time.Now(),
-		}},
-	)
-	if err != nil {
+	var logs []models.ExecutionLog
+	if err := cursor.All(c.Request.Context(), &logs); err != nil {
 		middleware.HandleInternalError(c, err)
 		return
 	}
-
-	if result.MatchedCount == 0 {
-		middleware.HandleNotFoundError(c, "工作流不存在")
-		return
+	if !middleware.IsAdmin(c) {
+		for i := range logs {
+			service.HideRedactionRules(&logs[i])
+		}
 	}
 
-	// TODO: 实际的工作流停止逻辑
-	// 这里应该停止工作流执行引擎
-
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "工作流停止成功",
+		"data": gin.H{
+			"workflow":   workflow,
+			"executions": executions,
+			"logs":       logs,
+		},
 	})
 }
 
-// GetWorkflowExecution 获取工作流执行状态
-func (h *WorkflowHandler) GetWorkflowExecution(c *gin.Context) {
-	workflowID := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(workflowID)
+// SignalWorkflowExecution 向工作流执行发送信号，请求体为可选的信号数据（JSON对象）
+func (h *WorkflowHandler) SignalWorkflowExecution(c *gin.Context) {
+	workflowID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		middleware.HandleValidationError(c, err)
 		return
 	}
-
-	// 获取工作流
-	collection := h.db.GetCollection("workflows")
-	var workflow models.Workflow
-	err = collection.FindOne(c.Request.Context(), bson.M{"_id": objectID}).Decode(&workflow)
+	executionID, err := primitive.ObjectIDFromHex(c.Param("execution_id"))
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			middleware.HandleNotFoundError(c, "工作流不存在")
-			return
-		}
-		middleware.HandleInternalError(c, err)
+		middleware.HandleValidationError(c, err)
 		return
 	}
 
-	// 获取相关的执行日志
-	logCollection := h.db.GetCollection("execution_logs")
-	cursor, err := logCollection.Find(c.Request.Context(), 
-		bson.M{"workflow_id": workflowID},
-		options.Find().
-			SetSort(bson.D{{Key: "created_at", Value: -1}}).
-			SetLimit(10))
-	if err != nil {
-		middleware.HandleInternalError(c, err)
-		return
+	var payload map[string]interface{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			middleware.HandleValidationError(c, err)
+			return
+		}
 	}
-	defer cursor.Close(c.Request.Context())
 
-	var logs []models.ExecutionLog
-	if err := cursor.All(c.Request.Context(), &logs); err != nil {
-		middleware.HandleInternalError(c, err)
+	name := c.Param("name")
+	if err := h.workflows.Signal(c.Request.Context(), workflowID, executionID, name, payload); err != nil {
+		handleTaskError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
 		"success": true,
-		"data": gin.H{
-			"workflow": workflow,
-			"logs":     logs,
-		},
+		"message": fmt.Sprintf("信号 %s 已发送", name),
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,141 @@
+/**
+ * 大模型调用
+ * 定义对话补全、工具调用和流式输出的提供方接口，以及按配置创建提供方
+ */
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// 结束原因
+const (
+	FinishStop      = "stop"
+	FinishToolCalls = "tool_calls"
+	FinishLength    = "length"
+)
+
+// 提供方名称
+const (
+	ProviderMock   = "mock"
+	ProviderOpenAI = "openai"
+)
+
+// ErrNoProvider 未配置提供方
+var ErrNoProvider = errors.New("llm provider not configured")
+
+// Message 对话消息
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // 助手消息请求的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // 工具消息对应的调用ID
+	Name       string     `json:"name,omitempty"`
+}
+
+// Tool 提供给模型的工具
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // 参数的JSON Schema
+}
+
+// ToolCall 模型请求的工具调用
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON编码的参数
+}
+
+// Request 对话补全请求
+type Request struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+// Usage 令牌用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add 累加用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Response 对话补全结果
+type Response struct {
+	Model        string  `json:"model"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+	Usage        Usage   `json:"usage"`
+}
+
+// Chunk 流式输出的增量
+type Chunk struct {
+	Content string // 新增的文本
+}
+
+// StreamFunc 接收流式增量，返回错误时中止流
+type StreamFunc func(chunk Chunk) error
+
+// Provider 大模型提供方
+type Provider interface {
+	// Chat 执行一次对话补全
+	Chat(ctx context.Context, req *Request) (*Response, error)
+	// Stream 以流式执行对话补全，增量通过fn回调，结束后返回完整结果
+	Stream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error)
+}
+
+// Config 提供方配置
+type Config struct {
+	Provider   string        // mock, openai
+	BaseURL    string        // OpenAI兼容接口的地址，如https://api.openai.com/v1
+	APIKey     string        // 接口密钥
+	Model      string        // 默认模型
+	Timeout    time.Duration // 单次请求超时
+	ReplayFile string        // 模拟提供方的回放文件
+}
+
+// New 按配置创建提供方
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderMock:
+		if cfg.ReplayFile == "" {
+			return NewMockProvider(nil), nil
+		}
+		exchanges, err := LoadReplay(cfg.ReplayFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewMockProvider(exchanges), nil
+	case ProviderOpenAI:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("llm base url not specified")
+		}
+		return NewOpenAIProvider(cfg.BaseURL, cfg.APIKey, cfg.Timeout), nil
+	case "":
+		return nil, ErrNoProvider
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,156 @@
+/**
+ * 模拟提供方
+ * 不访问网络，按回放记录或固定规则生成确定的回复，用于离线开发和测试
+ */
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Exchange 一条回放记录
+type Exchange struct {
+	// Match 非空时，最后一条用户或工具消息包含该文本即返回此回复；
+	// 为空的记录按对话轮次依次返回（第N轮返回第N条）
+	Match        string     `json:"match,omitempty"`
+	Content      string     `json:"content"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+}
+
+// MockProvider 模拟提供方，回复只取决于请求内容，不保存状态
+type MockProvider struct {
+	matched    []Exchange
+	sequential []Exchange
+}
+
+// NewMockProvider 创建模拟提供方，没有回放记录时回显最后一条用户消息
+func NewMockProvider(exchanges []Exchange) *MockProvider {
+	p := &MockProvider{}
+	for _, exchange := range exchanges {
+		if exchange.Match != "" {
+			p.matched = append(p.matched, exchange)
+		} else {
+			p.sequential = append(p.sequential, exchange)
+		}
+	}
+	return p
+}
+
+// LoadReplay 读取回放文件（Exchange的JSON数组）
+func LoadReplay(path string) ([]Exchange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay file: %w", err)
+	}
+	var exchanges []Exchange
+	if err := json.Unmarshal(data, &exchanges); err != nil {
+		return nil, fmt.Errorf("invalid replay file %s: %w", path, err)
+	}
+	return exchanges, nil
+}
+
+// Chat 生成回复
+func (p *MockProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	exchange := p.lookup(req)
+	message := Message{Role: RoleAssistant, Content: exchange.Content}
+	for i, call := range exchange.ToolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%d_%d", assistantTurns(req.Messages), i)
+		}
+		if call.Arguments == "" {
+			call.Arguments = "{}"
+		}
+		message.ToolCalls = append(message.ToolCalls, call)
+	}
+
+	finish := exchange.FinishReason
+	if finish == "" {
+		finish = FinishStop
+		if len(message.ToolCalls) > 0 {
+			finish = FinishToolCalls
+		}
+	}
+
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += countTokens(m.Content)
+	}
+	completion := countTokens(message.Content)
+	return &Response{
+		Model:        req.Model,
+		Message:      message,
+		FinishReason: finish,
+		Usage: Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		},
+	}, nil
+}
+
+// Stream 生成回复，并按词拆分为增量
+func (p *MockProvider) Stream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(resp.Message.Content, " ") {
+		if word == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := fn(Chunk{Content: word}); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// lookup 查找请求对应的回放记录
+func (p *MockProvider) lookup(req *Request) Exchange {
+	last := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if role := req.Messages[i].Role; role == RoleUser || role == RoleTool {
+			last = req.Messages[i].Content
+			break
+		}
+	}
+
+	for _, exchange := range p.matched {
+		if strings.Contains(last, exchange.Match) {
+			return exchange
+		}
+	}
+	if turn := assistantTurns(req.Messages); turn < len(p.sequential) {
+		return p.sequential[turn]
+	}
+	return Exchange{Content: fmt.Sprintf("[mock:%s] %s", req.Model, last)}
+}
+
+// assistantTurns 统计请求中已有的助手回复数，即当前是第几轮
+func assistantTurns(messages []Message) int {
+	turns := 0
+	for _, m := range messages {
+		if m.Role == RoleAssistant {
+			turns++
+		}
+	}
+	return turns
+}
+
+// countTokens 粗略估计令牌数：按空白分词
+func countTokens(text string) int {
+	return len(strings.Fields(text))
+}
@@ -0,0 +1,298 @@
+/**
+ * OpenAI兼容提供方
+ * 调用OpenAI兼容的/chat/completions接口，支持工具调用和SSE流式输出
+ */
+
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 60 * time.Second
+	// maxErrorBodySize 错误响应读取的上限
+	maxErrorBodySize = 4096
+)
+
+// APIError 接口返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm api error: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// OpenAIProvider OpenAI兼容提供方
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewOpenAIProvider 创建OpenAI兼容提供方，baseURL不含/chat/completions
+func NewOpenAIProvider(baseURL, apiKey string, timeout time.Duration) *OpenAIProvider {
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// 接口的请求和响应格式
+type (
+	wireRequest struct {
+		Model       string        `json:"model"`
+		Messages    []wireMessage `json:"messages"`
+		Tools       []wireTool    `json:"tools,omitempty"`
+		Temperature *float64      `json:"temperature,omitempty"`
+		MaxTokens   int           `json:"max_tokens,omitempty"`
+		Stream      bool          `json:"stream,omitempty"`
+	}
+
+	wireMessage struct {
+		Role       string         `json:"role"`
+		Content    *string        `json:"content"`
+		ToolCalls  []wireToolCall `json:"tool_calls,omitempty"`
+		ToolCallID string         `json:"tool_call_id,omitempty"`
+		Name       string         `json:"name,omitempty"`
+	}
+
+	wireTool struct {
+		Type     string       `json:"type"`
+		Function wireFunction `json:"function"`
+	}
+
+	wireFunction struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description,omitempty"`
+		Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	}
+
+	wireToolCall struct {
+		Index    int    `json:"index"`
+		ID       string `json:"id,omitempty"`
+		Type     string `json:"type,omitempty"`
+		Function struct {
+			Name      string `json:"name,omitempty"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	}
+
+	wireResponse struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      wireMessage `json:"message"`
+			Delta        wireMessage `json:"delta"`
+			FinishReason string      `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *Usage `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+)
+
+// Chat 执行一次对话补全
+func (p *OpenAIProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := p.do(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body wireResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid llm response: %w", err)
+	}
+	if len(body.Choices) == 0 {
+		return nil, fmt.Errorf("invalid llm response: no choices")
+	}
+
+	choice := body.Choices[0]
+	result := &Response{
+		Model:        body.Model,
+		Message:      fromWireMessage(choice.Message),
+		FinishReason: choice.FinishReason,
+	}
+	if body.Usage != nil {
+		result.Usage = *body.Usage
+	}
+	return result, nil
+}
+
+// Stream 以SSE流式执行对话补全
+func (p *OpenAIProvider) Stream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	resp, err := p.do(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Response{Model: req.Model, Message: Message{Role: RoleAssistant}}
+	var content strings.Builder
+	calls := map[int]*ToolCall{}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var event wireResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("invalid llm stream event: %w", err)
+		}
+		if event.Error != nil {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: event.Error.Message}
+		}
+		if event.Model != "" {
+			result.Model = event.Model
+		}
+		if event.Usage != nil {
+			result.Usage = *event.Usage
+		}
+		for _, choice := range event.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+			if delta := choice.Delta.Content; delta != nil && *delta != "" {
+				content.WriteString(*delta)
+				if err := fn(Chunk{Content: *delta}); err != nil {
+					return nil, err
+				}
+			}
+			// 工具调用按index分片到达，名称和参数需要拼接
+			for _, part := range choice.Delta.ToolCalls {
+				call, ok := calls[part.Index]
+				if !ok {
+					call = &ToolCall{}
+					calls[part.Index] = call
+				}
+				if part.ID != "" {
+					call.ID = part.ID
+				}
+				call.Name += part.Function.Name
+				call.Arguments += part.Function.Arguments
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read llm stream: %w", err)
+	}
+
+	result.Message.Content = content.String()
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		result.Message.ToolCalls = append(result.Message.ToolCalls, *calls[index])
+	}
+	return result, nil
+}
+
+// do 发送请求，非2xx响应转换为APIError
+func (p *OpenAIProvider) do(ctx context.Context, req *Request, stream bool) (*http.Response, error) {
+	payload := wireRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      stream,
+	}
+	for _, m := range req.Messages {
+		payload.Messages = append(payload.Messages, toWireMessage(m))
+	}
+	for _, tool := range req.Tools {
+		payload.Tools = append(payload.Tools, wireTool{
+			Type:     "function",
+			Function: wireFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("llm request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		message := strings.TrimSpace(string(data))
+		var wire wireResponse
+		if json.Unmarshal(data, &wire) == nil && wire.Error != nil {
+			message = wire.Error.Message
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: message}
+	}
+	return resp, nil
+}
+
+// toWireMessage 转换为接口的消息格式
+func toWireMessage(m Message) wireMessage {
+	wire := wireMessage{Role: m.Role, ToolCallID: m.ToolCallID, Name: m.Name}
+	// 只带工具调用的助手消息content为null
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		content := m.Content
+		wire.Content = &content
+	}
+	for i, call := range m.ToolCalls {
+		part := wireToolCall{Index: i, ID: call.ID, Type: "function"}
+		part.Function.Name = call.Name
+		part.Function.Arguments = call.Arguments
+		wire.ToolCalls = append(wire.ToolCalls, part)
+	}
+	return wire
+}
+
+// fromWireMessage 从接口的消息格式转换
+func fromWireMessage(wire wireMessage) Message {
+	m := Message{Role: wire.Role, ToolCallID: wire.ToolCallID, Name: wire.Name}
+	if wire.Content != nil {
+		m.Content = *wire.Content
+	}
+	for _, part := range wire.ToolCalls {
+		m.ToolCalls = append(m.ToolCalls, ToolCall{
+			ID:        part.ID,
+			Name:      part.Function.Name,
+			Arguments: part.Function.Arguments,
+		})
+	}
+	return m
+}
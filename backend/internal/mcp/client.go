@@ -0,0 +1,328 @@
+/**
+ * MCP客户端
+ * 连接外部MCP服务端（stdio子进程、WebSocket或Streamable HTTP），列出并调用其工具
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 客户端传输方式
+const (
+	TransportStdio     = "stdio"
+	TransportWebSocket = "websocket"
+	TransportHTTP      = "http"
+)
+
+// ErrClientClosed 连接已关闭，请求没有发出，可以重连后重试
+var ErrClientClosed = errors.New("mcp connection closed")
+
+// errConnectionLost 请求发出后连接断开，工具可能已经执行，不能自动重试
+var errConnectionLost = errors.New("mcp connection lost while waiting for response")
+
+// ClientConfig 外部MCP服务端的连接配置
+type ClientConfig struct {
+	// 传输方式，为空时按配置推断：有command为stdio，ws(s)://为websocket，http(s)://为http
+	Transport string            `json:"transport,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// ResolveTransport 返回实际使用的传输方式
+func (c *ClientConfig) ResolveTransport() (string, error) {
+	switch {
+	case c.Transport != "":
+		switch c.Transport {
+		case TransportStdio, TransportWebSocket, TransportHTTP:
+			return c.Transport, nil
+		}
+		return "", fmt.Errorf("unsupported mcp transport: %s", c.Transport)
+	case c.Command != "":
+		return TransportStdio, nil
+	case strings.HasPrefix(c.URL, "ws://"), strings.HasPrefix(c.URL, "wss://"):
+		return TransportWebSocket, nil
+	case strings.HasPrefix(c.URL, "http://"), strings.HasPrefix(c.URL, "https://"):
+		return TransportHTTP, nil
+	}
+	return "", fmt.Errorf("mcp server command or url required")
+}
+
+// String 用于日志的服务端描述
+func (c *ClientConfig) String() string {
+	if c.Command != "" {
+		return strings.Join(append([]string{c.Command}, c.Args...), " ")
+	}
+	return c.URL
+}
+
+// clientTransport 客户端传输层，每条消息是一个完整的JSON-RPC消息
+type clientTransport interface {
+	send(ctx context.Context, message []byte) error
+	// messages 收到的消息
+	messages() <-chan []byte
+	// done 连接断开后关闭
+	done() <-chan struct{}
+	close() error
+}
+
+// incomingMessage 收到的响应、服务端请求或通知
+type incomingMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Client MCP客户端，一个客户端对应一个已初始化的连接，可以并发调用
+type Client struct {
+	transport clientTransport
+	nextID    int64
+
+	pending map[string]chan *incomingMessage
+	mutex   sync.Mutex
+
+	server       Implementation
+	version      string
+	instructions string
+}
+
+// Dial 连接外部MCP服务端并完成初始化握手，ctx只约束握手过程
+func Dial(ctx context.Context, cfg ClientConfig) (*Client, error) {
+	transport, err := cfg.ResolveTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	var t clientTransport
+	switch transport {
+	case TransportStdio:
+		t, err = dialStdio(cfg)
+	case TransportWebSocket:
+		t, err = dialWebSocket(ctx, cfg)
+	case TransportHTTP:
+		t, err = dialHTTP(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{transport: t, pending: make(map[string]chan *incomingMessage)}
+	go c.readLoop()
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// initialize 协商协议版本并发送initialized通知
+func (c *Client) initialize(ctx context.Context) error {
+	var result InitializeResult
+	err := c.call(ctx, "initialize", InitializeParams{
+		ProtocolVersion: supportedVersions[0],
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: serverName, Version: serverVersion},
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("mcp initialize failed: %w", err)
+	}
+	if !isSupportedVersion(result.ProtocolVersion) {
+		return fmt.Errorf("unsupported mcp protocol version: %s", result.ProtocolVersion)
+	}
+	c.server = result.ServerInfo
+	c.version = result.ProtocolVersion
+	c.instructions = result.Instructions
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ServerInfo 服务端的名称和版本
+func (c *Client) ServerInfo() Implementation {
+	return c.server
+}
+
+// ProtocolVersion 协商的协议版本
+func (c *Client) ProtocolVersion() string {
+	return c.version
+}
+
+// ListTools 列出服务端的全部工具，自动翻页
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var result ListToolsResult
+		if err := c.call(ctx, "tools/list", ListToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool 调用工具，工具执行失败时返回IsError为true的结果而不是错误
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool arguments: %w", err)
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", CallToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Alive 连接是否仍然可用
+func (c *Client) Alive() bool {
+	select {
+	case <-c.transport.done():
+		return false
+	default:
+		return true
+	}
+}
+
+// Close 关闭连接，stdio服务端的子进程随之退出
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// call 发送请求并等待响应，result为nil时丢弃响应结果
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if !c.Alive() {
+		return ErrClientClosed
+	}
+
+	id := strconv.FormatInt(atomic.AddInt64(&c.nextID, 1), 10)
+	message, err := json.Marshal(&requestMessage{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *incomingMessage, 1)
+	c.mutex.Lock()
+	c.pending[id] = ch
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.transport.send(ctx, message); err != nil {
+		if !c.Alive() {
+			return ErrClientClosed
+		}
+		return err
+	}
+
+	select {
+	case response := <-ch:
+		if response.Error != nil {
+			return response.Error
+		}
+		if result == nil || len(response.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("invalid %s response: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.transport.done():
+		return errConnectionLost
+	}
+}
+
+// notify 发送通知
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	message, err := json.Marshal(&requestMessage{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	return c.transport.send(ctx, message)
+}
+
+// readLoop 分发收到的消息：响应交给等待的请求，服务端的ping直接应答
+func (c *Client) readLoop() {
+	for {
+		select {
+		case <-c.transport.done():
+			return
+		case raw := <-c.transport.messages():
+			var message incomingMessage
+			if err := json.Unmarshal(raw, &message); err != nil {
+				log.Printf("Invalid MCP message from server: %v", err)
+				continue
+			}
+			if message.Method != "" {
+				c.handleServerRequest(&message)
+				continue
+			}
+			c.mutex.Lock()
+			ch, ok := c.pending[string(message.ID)]
+			c.mutex.Unlock()
+			if ok {
+				ch <- &message
+			}
+		}
+	}
+}
+
+// handleServerRequest 应答服务端发来的请求，通知直接忽略
+func (c *Client) handleServerRequest(message *incomingMessage) {
+	if len(message.ID) == 0 {
+		return
+	}
+	response := Response{JSONRPC: "2.0", ID: message.ID}
+	if message.Method == "ping" {
+		response.Result = struct{}{}
+	} else {
+		response.Error = &Error{Code: CodeMethodNotFound, Message: "method not found: " + message.Method}
+	}
+	data, err := json.Marshal(&response)
+	if err != nil {
+		return
+	}
+	go c.transport.send(context.Background(), data)
+}
+
+// requestMessage 客户端发出的请求或通知，参数为任意可序列化的值
+type requestMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  interface{}     `json:"params,omitempty"`
+}
+
+// isSupportedVersion 协议版本是否受支持
+func isSupportedVersion(version string) bool {
+	for _, supported := range supportedVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+/**
+ * MCP客户端传输
+ * stdio：启动子进程，按行收发JSON；WebSocket：每帧一条消息；
+ * Streamable HTTP：每条消息一次POST，响应可以是JSON或SSE流
+ */
+
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"aischedule/internal/config"
+)
+
+const (
+	// clientQueueSize 客户端收到的消息队列长度
+	clientQueueSize = 64
+	// stdioStopTimeout 关闭stdin后等待子进程退出的时间，超时后强制结束
+	stdioStopTimeout = 3 * time.Second
+	// maxClientMessage 单条消息的上限(字节)
+	maxClientMessage = 16 << 20
+)
+
+// transportState 传输层共用的消息队列和关闭状态
+type transportState struct {
+	incoming chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newTransportState() transportState {
+	return transportState{
+		incoming: make(chan []byte, clientQueueSize),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (s *transportState) messages() <-chan []byte {
+	return s.incoming
+}
+
+func (s *transportState) done() <-chan struct{} {
+	return s.closed
+}
+
+// push 把收到的消息放入队列，连接关闭后丢弃
+func (s *transportState) push(message []byte) {
+	select {
+	case s.incoming <- message:
+	case <-s.closed:
+	}
+}
+
+// shutdown 标记连接关闭，返回是否为第一次关闭
+func (s *transportState) shutdown() bool {
+	first := false
+	s.once.Do(func() {
+		close(s.closed)
+		first = true
+	})
+	return first
+}
+
+// stdioTransport 以子进程的标准输入输出通信
+type stdioTransport struct {
+	transportState
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	writes sync.Mutex
+	exited chan struct{}
+}
+
+// dialStdio 启动服务端子进程，子进程不随请求的ctx结束，由Close关闭
+func dialStdio(cfg ClientConfig) (*stdioTransport, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("mcp server command not specified")
+	}
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = config.ChildEnv()
+	for key, value := range cfg.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server: %w", err)
+	}
+
+	t := &stdioTransport{
+		transportState: newTransportState(),
+		cmd:            cmd,
+		stdin:          stdin,
+		exited:         make(chan struct{}),
+	}
+	go t.logStderr(stderr)
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+// readLoop 按行读取子进程输出，进程退出后关闭连接
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReaderSize(stdout, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			t.push(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	t.cmd.Wait()
+	close(t.exited)
+	t.shutdown()
+}
+
+// logStderr 子进程的标准错误写入服务日志
+func (t *stdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("MCP server %s: %s", t.cmd.Path, scanner.Text())
+	}
+}
+
+func (t *stdioTransport) send(ctx context.Context, message []byte) error {
+	select {
+	case <-t.closed:
+		return ErrClientClosed
+	default:
+	}
+	t.writes.Lock()
+	defer t.writes.Unlock()
+	_, err := t.stdin.Write(append(message, '\n'))
+	return err
+}
+
+// close 关闭stdin让子进程自行退出，超时后强制结束
+func (t *stdioTransport) close() error {
+	t.shutdown()
+	t.stdin.Close()
+	select {
+	case <-t.exited:
+	case <-time.After(stdioStopTimeout):
+		t.cmd.Process.Kill()
+		<-t.exited
+	}
+	return nil
+}
+
+// wsTransport WebSocket连接，每个文本帧是一条消息
+type wsTransport struct {
+	transportState
+	conn   *websocket.Conn
+	writes sync.Mutex
+}
+
+// dialWebSocket 建立WebSocket连接，ctx只约束握手
+func dialWebSocket(ctx context.Context, cfg ClientConfig) (*wsTransport, error) {
+	header := http.Header{}
+	for key, value := range cfg.Headers {
+		header.Set(key, value)
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{"mcp"}
+	conn, resp, err := dialer.DialContext(ctx, cfg.URL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect mcp server: %w (HTTP %d)", err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to connect mcp server: %w", err)
+	}
+	conn.SetReadLimit(maxClientMessage)
+
+	t := &wsTransport{transportState: newTransportState(), conn: conn}
+	go t.readLoop()
+	return t, nil
+}
+
+func (t *wsTransport) readLoop() {
+	defer t.shutdown()
+	for {
+		_, message, err := t.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		t.push(message)
+	}
+}
+
+func (t *wsTransport) send(ctx context.Context, message []byte) error {
+	select {
+	case <-t.closed:
+		return ErrClientClosed
+	default:
+	}
+	t.writes.Lock()
+	defer t.writes.Unlock()
+	if deadline, ok := ctx.Deadline(); ok {
+		t.conn.SetWriteDeadline(deadline)
+		defer t.conn.SetWriteDeadline(time.Time{})
+	}
+	return t.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+func (t *wsTransport) close() error {
+	t.shutdown()
+	t.writes.Lock()
+	t.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	t.writes.Unlock()
+	return t.conn.Close()
+}
+
+// httpTransport Streamable HTTP，响应在POST请求返回时放入队列
+type httpTransport struct {
+	transportState
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	session string
+	mutex   sync.Mutex
+}
+
+func dialHTTP(cfg ClientConfig) (*httpTransport, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("mcp server url not specified")
+	}
+	return &httpTransport{
+		transportState: newTransportState(),
+		url:            cfg.URL,
+		headers:        cfg.Headers,
+		client:         &http.Client{},
+	}, nil
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	t.mutex.Lock()
+	if t.session != "" {
+		req.Header.Set(SessionHeader, t.session)
+	}
+	t.mutex.Unlock()
+	return req, nil
+}
+
+// send POST一条消息，会话失效(404)时关闭连接以便重连
+func (t *httpTransport) send(ctx context.Context, message []byte) error {
+	select {
+	case <-t.closed:
+		return ErrClientClosed
+	default:
+	}
+
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if session := resp.Header.Get(SessionHeader); session != "" {
+		t.mutex.Lock()
+		t.session = session
+		t.mutex.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get(SessionHeader) != "":
+		t.shutdown()
+		return ErrClientClosed
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mcp server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return t.readEvents(resp.Body)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxClientMessage))
+	if err != nil {
+		return err
+	}
+	t.pushBody(body)
+	return nil
+}
+
+// listen 为当前会话打开GET SSE流接收服务端主动发送的通知，直到流结束或ctx取消
+func (t *httpTransport) listen(ctx context.Context) error {
+	req, err := t.newRequest(ctx, http.MethodGet, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mcp server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return t.readEvents(resp.Body)
+}
+
+// readEvents 读取SSE响应流中的消息事件
+func (t *httpTransport) readEvents(body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxClientMessage)
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				t.pushBody([]byte(strings.Join(data, "\n")))
+				data = data[:0]
+			}
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if len(data) > 0 {
+		t.pushBody([]byte(strings.Join(data, "\n")))
+	}
+	return scanner.Err()
+}
+
+// pushBody 放入一条消息或拆开批量消息
+func (t *httpTransport) pushBody(body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	if body[0] != '[' {
+		t.push(body)
+		return
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		t.push(body)
+		return
+	}
+	for _, message := range batch {
+		t.push(message)
+	}
+}
+
+// close 结束服务端会话
+func (t *httpTransport) close() error {
+	if !t.shutdown() {
+		return nil
+	}
+	t.mutex.Lock()
+	session := t.session
+	t.mutex.Unlock()
+	if session == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
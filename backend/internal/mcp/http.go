@@ -0,0 +1,316 @@
+/**
+ * MCP HTTP传输
+ * Streamable HTTP：单个端点，POST发送消息，GET打开SSE流接收服务端通知，DELETE结束会话；
+ * 兼容旧版HTTP+SSE：GET建立SSE流并通过endpoint事件告知消息地址，POST的响应经SSE流返回
+ */
+
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionHeader Streamable HTTP传输中携带会话ID的请求头
+const SessionHeader = "Mcp-Session-Id"
+
+// keepAliveInterval SSE流的保活间隔
+const keepAliveInterval = 30 * time.Second
+
+// httpSession HTTP会话及其打开的SSE流数量
+type httpSession struct {
+	session *Session
+	streams int
+}
+
+// HTTPTransport HTTP传输，管理会话的创建、查找和过期
+type HTTPTransport struct {
+	server       *Server
+	messagesPath string
+	idleTimeout  time.Duration
+
+	sessions map[string]*httpSession
+	mutex    sync.Mutex
+}
+
+// NewHTTPTransport 创建HTTP传输，messagesPath为旧版SSE客户端发送消息的地址，
+// 没有打开SSE流且超过idleTimeout未活动的会话会被清理
+func NewHTTPTransport(server *Server, messagesPath string, idleTimeout time.Duration) *HTTPTransport {
+	return &HTTPTransport{
+		server:       server,
+		messagesPath: messagesPath,
+		idleTimeout:  idleTimeout,
+		sessions:     make(map[string]*httpSession),
+	}
+}
+
+// SessionCount 当前的会话数量
+func (t *HTTPTransport) SessionCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.sessions)
+}
+
+// ServeStreamable 处理Streamable HTTP端点的请求
+func (t *HTTPTransport) ServeStreamable(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleStream(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeRPCError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "method not allowed")
+	}
+}
+
+// handlePost 处理客户端发送的消息，initialize请求创建新会话，其余请求必须携带会话ID
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeRPCError(w, http.StatusRequestEntityTooLarge, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var session *Session
+	if isInitialize(body) {
+		session = t.create(0)
+		w.Header().Set(SessionHeader, session.ID)
+	} else {
+		id := r.Header.Get(SessionHeader)
+		if id == "" {
+			writeRPCError(w, http.StatusBadRequest, CodeInvalidRequest, "missing "+SessionHeader+" header")
+			return
+		}
+		session = t.lookup(id)
+		if session == nil {
+			writeRPCError(w, http.StatusNotFound, CodeInvalidRequest, "session not found")
+			return
+		}
+	}
+
+	response := t.server.Handle(r.Context(), session, body)
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// handleStream 为已有会话打开SSE流，接收服务端主动发送的通知
+func (t *HTTPTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeRPCError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "GET requires Accept: text/event-stream")
+		return
+	}
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		writeRPCError(w, http.StatusBadRequest, CodeInvalidRequest, "missing "+SessionHeader+" header")
+		return
+	}
+
+	t.mutex.Lock()
+	entry, exists := t.sessions[id]
+	if exists && entry.streams > 0 {
+		t.mutex.Unlock()
+		writeRPCError(w, http.StatusConflict, CodeInvalidRequest, "session already has an open stream")
+		return
+	}
+	if exists {
+		entry.streams++
+	}
+	t.mutex.Unlock()
+	if !exists {
+		writeRPCError(w, http.StatusNotFound, CodeInvalidRequest, "session not found")
+		return
+	}
+	defer t.release(entry)
+
+	t.stream(w, r, entry.session, nil)
+}
+
+// handleDelete 客户端主动结束会话
+func (t *HTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		writeRPCError(w, http.StatusBadRequest, CodeInvalidRequest, "missing "+SessionHeader+" header")
+		return
+	}
+	if !t.remove(id) {
+		writeRPCError(w, http.StatusNotFound, CodeInvalidRequest, "session not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeSSE 旧版传输：建立SSE流，连接断开时会话结束
+func (t *HTTPTransport) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	session := t.create(1)
+	defer t.remove(session.ID)
+
+	endpoint := fmt.Sprintf("%s?sessionId=%s", t.messagesPath, session.ID)
+	t.stream(w, r, session, &sseEvent{name: "endpoint", data: []byte(endpoint)})
+}
+
+// ServeMessages 旧版传输：接收客户端消息，响应通过SSE流返回
+func (t *HTTPTransport) ServeMessages(w http.ResponseWriter, r *http.Request) {
+	session := t.lookup(r.URL.Query().Get("sessionId"))
+	if session == nil {
+		writeRPCError(w, http.StatusNotFound, CodeInvalidRequest, "session not found")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeRPCError(w, http.StatusRequestEntityTooLarge, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if response := t.server.Handle(r.Context(), session, body); response != nil {
+		if !session.send(response, true) {
+			writeRPCError(w, http.StatusNotFound, CodeInvalidRequest, "session closed")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// stream 把会话的待发送消息写为SSE事件，直到客户端断开或会话关闭
+func (t *HTTPTransport) stream(w http.ResponseWriter, r *http.Request, session *Session, first *sseEvent) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeRPCError(w, http.StatusInternalServerError, CodeInternalError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set(SessionHeader, session.ID)
+	w.WriteHeader(http.StatusOK)
+	if first != nil {
+		writeEvent(w, first.name, first.data)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.Done():
+			return
+		case message := <-session.Outgoing():
+			writeEvent(w, "message", message)
+			flusher.Flush()
+			session.touch()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// create 创建并登记会话，同时清理过期的会话；旧版传输的会话创建时已打开SSE流
+func (t *HTTPTransport) create(streams int) *Session {
+	session := NewSession("")
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.expireLocked()
+	t.sessions[session.ID] = &httpSession{session: session, streams: streams}
+	return session
+}
+
+// lookup 查找未过期的会话
+func (t *HTTPTransport) lookup(id string) *Session {
+	if id == "" {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.expireLocked()
+	entry, exists := t.sessions[id]
+	if !exists {
+		return nil
+	}
+	return entry.session
+}
+
+// release SSE流结束后更新会话的活动时间
+func (t *HTTPTransport) release(entry *httpSession) {
+	t.mutex.Lock()
+	entry.streams--
+	t.mutex.Unlock()
+	entry.session.touch()
+}
+
+// remove 关闭并移除会话
+func (t *HTTPTransport) remove(id string) bool {
+	t.mutex.Lock()
+	entry, exists := t.sessions[id]
+	delete(t.sessions, id)
+	t.mutex.Unlock()
+
+	if exists {
+		entry.session.Close()
+		t.server.forget(entry.session)
+	}
+	return exists
+}
+
+// expireLocked 清理没有SSE流且长时间未活动的会话，调用方持有锁
+func (t *HTTPTransport) expireLocked() {
+	if t.idleTimeout <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-t.idleTimeout)
+	for id, entry := range t.sessions {
+		if entry.streams == 0 && entry.session.idleSince().Before(deadline) {
+			entry.session.Close()
+			t.server.forget(entry.session)
+			delete(t.sessions, id)
+		}
+	}
+}
+
+// isInitialize 消息是否为initialize请求
+func isInitialize(body []byte) bool {
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return false
+	}
+	return req.Method == "initialize"
+}
+
+// writeEvent 写一个SSE事件，多行数据按行拆分
+func writeEvent(w io.Writer, name string, data []byte) {
+	fmt.Fprintf(w, "event: %s\n", name)
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// writeRPCError 以JSON-RPC错误作为HTTP响应体
+func writeRPCError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse(nil, code, message))
+}
@@ -0,0 +1,139 @@
+/**
+ * MCP连接池
+ * 按连接配置复用客户端，断开的连接在下次使用时重新建立，空闲连接定期关闭
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// pooledClient 连接池中的客户端
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// Pool MCP客户端连接池
+type Pool struct {
+	idleTimeout time.Duration
+
+	clients map[string]*pooledClient
+	mutex   sync.Mutex
+}
+
+// NewPool 创建连接池，超过idleTimeout未使用的连接在下次获取时关闭，0表示不关闭
+func NewPool(idleTimeout time.Duration) *Pool {
+	return &Pool{
+		idleTimeout: idleTimeout,
+		clients:     make(map[string]*pooledClient),
+	}
+}
+
+// Get 获取配置对应的客户端，没有可用连接时建立新连接
+func (p *Pool) Get(ctx context.Context, cfg ClientConfig) (*Client, error) {
+	key, err := poolKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	p.evictIdle()
+	if entry, ok := p.clients[key]; ok {
+		if entry.client.Alive() {
+			entry.lastUsed = time.Now()
+			p.mutex.Unlock()
+			return entry.client, nil
+		}
+		delete(p.clients, key)
+	}
+	p.mutex.Unlock()
+
+	client, err := Dial(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// 并发建立连接时保留先建立的一个
+	if entry, ok := p.clients[key]; ok && entry.client.Alive() {
+		go client.Close()
+		entry.lastUsed = time.Now()
+		return entry.client, nil
+	}
+	p.clients[key] = &pooledClient{client: client, lastUsed: time.Now()}
+	return client, nil
+}
+
+// Do 使用配置对应的客户端执行fn，连接已断开时重新连接并重试一次
+func (p *Pool) Do(ctx context.Context, cfg ClientConfig, fn func(*Client) error) error {
+	client, err := p.Get(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	err = fn(client)
+	if !errors.Is(err, ErrClientClosed) {
+		return err
+	}
+
+	p.remove(cfg, client)
+	if client, err = p.Get(ctx, cfg); err != nil {
+		return err
+	}
+	return fn(client)
+}
+
+// Close 关闭全部连接
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	clients := p.clients
+	p.clients = make(map[string]*pooledClient)
+	p.mutex.Unlock()
+
+	for _, entry := range clients {
+		entry.client.Close()
+	}
+}
+
+// remove 移除并关闭指定的客户端
+func (p *Pool) remove(cfg ClientConfig, client *Client) {
+	key, err := poolKey(cfg)
+	if err != nil {
+		return
+	}
+	p.mutex.Lock()
+	if entry, ok := p.clients[key]; ok && entry.client == client {
+		delete(p.clients, key)
+	}
+	p.mutex.Unlock()
+	client.Close()
+}
+
+// evictIdle 关闭空闲超时的连接，调用方持有锁
+func (p *Pool) evictIdle() {
+	if p.idleTimeout <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-p.idleTimeout)
+	for key, entry := range p.clients {
+		if entry.lastUsed.Before(deadline) {
+			delete(p.clients, key)
+			go entry.client.Close()
+		}
+	}
+}
+
+// poolKey 连接配置的键，配置完全相同的任务共用连接
+func poolKey(cfg ClientConfig) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,414 @@
+/**
+ * 提示模板
+ * 为常见意图提供一键入口：创建夜间测试、汇总最近的失败、创建代码审查工作流，
+ * 生成提示时从tasks和execution_logs集合读取当前数据填入模板
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+	"aischedule/internal/service"
+)
+
+const (
+	// promptTaskLimit 提示中列出的已有任务数量上限
+	promptTaskLimit = 20
+	// promptFailureLimit summarize_failures列出的失败执行数量上限
+	promptFailureLimit = 20
+	// promptFailureDetails 读取日志详情的失败执行数量，其余只列出摘要
+	promptFailureDetails = 5
+	// promptErrorLines 每次失败执行附带的错误日志条数
+	promptErrorLines = 5
+)
+
+// RegisterPrompts 注册调度相关的提示模板
+func RegisterPrompts(s *Server, tasks *service.TaskService, logs *service.ExecutionLogService, workflows *service.WorkflowService) {
+	s.AddPrompt(Prompt{
+		Name:        "nightly_test_run",
+		Description: "为仓库创建每晚运行的自动测试任务",
+		Arguments: []PromptArgument{
+			{Name: "repository", Description: "仓库URL或本地路径", Required: true},
+			{Name: "branch", Description: "测试的分支，默认main"},
+			{Name: "command", Description: "测试命令，默认go test ./..."},
+			{Name: "hour", Description: "每天运行的小时(0-23)，默认2"},
+			{Name: "timezone", Description: "IANA时区，例如Asia/Shanghai，默认服务器时区"},
+		},
+	}, func(ctx context.Context, args map[string]string) (*GetPromptResult, error) {
+		hour, err := promptInt(args, "hour", 2, 0, 23)
+		if err != nil {
+			return nil, err
+		}
+		command := strings.Fields(promptArg(args, "command", "go test ./..."))
+		if len(command) == 0 {
+			return nil, &Error{Code: CodeInvalidParams, Message: "command must not be empty"}
+		}
+		repository := args["repository"]
+		branch := promptArg(args, "branch", "main")
+
+		existing, err := listTaskSummaries(ctx, tasks, logs, models.TaskTypeAutoTest)
+		if err != nil {
+			return nil, err
+		}
+
+		draft := map[string]interface{}{
+			"name":        fmt.Sprintf("nightly-test-%s", repositoryName(repository)),
+			"description": fmt.Sprintf("每晚%d点在%s分支运行测试", hour, branch),
+			"type":        models.TaskTypeAutoTest,
+			"cron_config": models.CronConfig{
+				Expression: fmt.Sprintf("0 0 %d * * *", hour),
+				Timezone:   args["timezone"],
+			},
+			"agent_config": map[string]interface{}{
+				"parameters": map[string]interface{}{
+					"command": command[0],
+					"args":    command[1:],
+					"format":  "auto",
+				},
+			},
+			"environment": map[string]interface{}{
+				"workspace": models.WorkspaceSpec{Repo: repository, Ref: branch},
+			},
+			"start": true,
+		}
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "请为仓库 %s 的 %s 分支设置每晚运行的自动测试任务。\n\n", repository, branch)
+		text.WriteString("已有的自动测试任务（避免重复创建；如果已有任务覆盖同一仓库和分支，请先询问是否调整它）：\n")
+		writeJSONBlock(&text, existing)
+		text.WriteString("\n建议的create_task参数：\n")
+		writeJSONBlock(&text, draft)
+		text.WriteString("\n步骤：\n")
+		text.WriteString("1. 用preview_schedule预览cron_config，确认运行时间符合预期；\n")
+		text.WriteString("2. 根据仓库实际使用的语言和测试工具调整command和args；\n")
+		text.WriteString("3. 调用create_task创建任务，并告诉我任务ID和下一次运行时间。\n")
+
+		return promptResult(text.String()), nil
+	})
+
+	s.AddPrompt(Prompt{
+		Name:        "summarize_failures",
+		Description: "汇总最近一段时间内失败的执行并分析原因",
+		Arguments: []PromptArgument{
+			{Name: "hours", Description: "回溯的小时数，默认24"},
+			{Name: "task_id", Description: "只看这个任务的执行"},
+		},
+	}, func(ctx context.Context, args map[string]string) (*GetPromptResult, error) {
+		hours, err := promptInt(args, "hours", 24, 1, 24*30)
+		if err != nil {
+			return nil, err
+		}
+		since := time.Now().Add(-time.Duration(hours) * time.Hour)
+		filter := service.ExecutionLogFilter{
+			Statuses: []models.ExecutionStatus{models.ExecutionStatusFailed, models.ExecutionStatusTimeout},
+			Since:    &since,
+			Limit:    promptFailureLimit,
+		}
+		if args["task_id"] != "" {
+			id, err := parseID("task_id", args["task_id"])
+			if err != nil {
+				return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+			}
+			filter.TaskID = &id
+		}
+
+		failed, err := logs.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make(map[primitive.ObjectID]string)
+		failures := make([]map[string]interface{}, 0, len(failed))
+		for i, log := range failed {
+			if _, ok := names[log.TaskID]; !ok {
+				names[log.TaskID] = log.TaskID.Hex()
+				if task, err := tasks.Get(ctx, log.TaskID); err == nil {
+					names[log.TaskID] = task.Name
+				}
+			}
+
+			failure := map[string]interface{}{
+				"execution_log_id": log.ID.Hex(),
+				"task_id":          log.TaskID.Hex(),
+				"task":             names[log.TaskID],
+				"status":           log.Status,
+				"started_at":       log.StartedAt,
+				"trigger_type":     log.TriggerType,
+				"error":            log.Result.Error,
+				"exit_code":        log.Result.ExitCode,
+			}
+			if log.CommitSHA != "" {
+				failure["commit_sha"] = log.CommitSHA
+			}
+			if log.Result.Tests != nil {
+				failure["tests"] = map[string]int{
+					"total":  log.Result.Tests.Total,
+					"failed": log.Result.Tests.Failed,
+				}
+			}
+			// 日志条目不在列表摘要中，只为最近的几次失败读取详情
+			if i < promptFailureDetails {
+				if detail, err := logs.Get(ctx, log.ID, isAdmin(ctx)); err == nil {
+					failure["error_logs"] = errorLines(detail.Logs, promptErrorLines)
+					if detail.Result.Tests != nil {
+						failure["failed_tests"] = failedTests(detail.Result.Tests, promptErrorLines)
+					}
+				}
+			}
+			failures = append(failures, failure)
+		}
+
+		var text strings.Builder
+		scope := "所有任务"
+		if args["task_id"] != "" {
+			scope = fmt.Sprintf("任务 %s", args["task_id"])
+		}
+		fmt.Fprintf(&text, "请汇总%s在最近%d小时内（%s之后）失败的执行。\n\n", scope, hours, since.Format(time.RFC3339))
+		if len(failures) == 0 {
+			text.WriteString("这段时间内没有失败或超时的执行，请直接告诉我一切正常。\n")
+			return promptResult(text.String()), nil
+		}
+		fmt.Fprintf(&text, "失败和超时的执行（最多%d条，按开始时间倒序）：\n", promptFailureLimit)
+		writeJSONBlock(&text, failures)
+		text.WriteString("\n请按任务分组，说明每组的失败次数、最可能的原因和建议的修复方式；")
+		text.WriteString("原因相同的失败合并说明。需要更多日志时用get_execution_log按执行日志ID查看。\n")
+
+		return promptResult(text.String()), nil
+	})
+
+	s.AddPrompt(Prompt{
+		Name:        "code_review_workflow",
+		Description: "创建先运行测试、再做代码审查的工作流，并按计划定时执行",
+		Arguments: []PromptArgument{
+			{Name: "repository", Description: "仓库URL或本地路径", Required: true},
+			{Name: "branch", Description: "审查的分支，默认main"},
+			{Name: "schedule", Description: "6段Cron表达式，默认工作日早上9点（0 0 9 * * 1-5）"},
+			{Name: "test_command", Description: "测试命令，默认go test ./..."},
+		},
+	}, func(ctx context.Context, args map[string]string) (*GetPromptResult, error) {
+		command := strings.Fields(promptArg(args, "test_command", "go test ./..."))
+		if len(command) == 0 {
+			return nil, &Error{Code: CodeInvalidParams, Message: "test_command must not be empty"}
+		}
+		repository := args["repository"]
+		branch := promptArg(args, "branch", "main")
+		schedule := promptArg(args, "schedule", "0 0 9 * * 1-5")
+
+		reviewTasks, err := listTaskSummaries(ctx, tasks, logs, models.TaskTypeCodeReview)
+		if err != nil {
+			return nil, err
+		}
+		workflowTasks, err := listTaskSummaries(ctx, tasks, logs, models.TaskTypeWorkflow)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := workflows.List(ctx, service.WorkflowFilter{Limit: promptTaskLimit})
+		if err != nil {
+			return nil, err
+		}
+		workflowSummaries := make([]map[string]interface{}, 0, len(existing))
+		for _, workflow := range existing {
+			steps := make([]string, 0, len(workflow.Steps))
+			for _, step := range workflow.Steps {
+				steps = append(steps, fmt.Sprintf("%s(%s)", step.ID, step.Action))
+			}
+			workflowSummaries = append(workflowSummaries, map[string]interface{}{
+				"id":     workflow.ID.Hex(),
+				"name":   workflow.Name,
+				"type":   workflow.Type,
+				"status": workflow.Status,
+				"steps":  steps,
+			})
+		}
+
+		name := repositoryName(repository)
+		draftWorkflow := map[string]interface{}{
+			"name":        fmt.Sprintf("review-%s", name),
+			"description": fmt.Sprintf("运行%s分支的测试后审查自上次成功审查以来的变更", branch),
+			"type":        models.WorkflowTypeSequential,
+			"start_step":  "test",
+			"steps": []map[string]interface{}{
+				{
+					"id":                "test",
+					"name":              "运行测试",
+					"type":              models.StepTypeAction,
+					"action":            models.TaskTypeAutoTest,
+					"parameters":        map[string]interface{}{"command": command[0], "args": command[1:], "format": "auto"},
+					"continue_on_error": true,
+				},
+				{
+					"id":         "review",
+					"name":       "代码审查",
+					"type":       models.StepTypeAction,
+					"action":     models.TaskTypeCodeReview,
+					"parameters": map[string]interface{}{"since_last_success": true, "fail_on": "error"},
+				},
+			},
+			"connections": []models.WorkflowConnection{{From: "test", To: "review"}},
+		}
+		draftTask := map[string]interface{}{
+			"name":        fmt.Sprintf("review-%s", name),
+			"type":        models.TaskTypeWorkflow,
+			"cron_config": models.CronConfig{Expression: schedule},
+			"agent_config": map[string]interface{}{
+				"parameters": map[string]interface{}{"workflow_id": "<create_workflow返回的id>"},
+			},
+			"environment": map[string]interface{}{
+				"workspace": models.WorkspaceSpec{Repo: repository, Ref: branch},
+			},
+			"start": true,
+		}
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "请为仓库 %s 的 %s 分支创建代码审查工作流：先运行测试，再审查变更。\n\n", repository, branch)
+		text.WriteString("已有的工作流（如果已有可复用的，请优先复用）：\n")
+		writeJSONBlock(&text, workflowSummaries)
+		text.WriteString("\n已有的代码审查任务：\n")
+		writeJSONBlock(&text, reviewTasks)
+		text.WriteString("\n已有的工作流任务：\n")
+		writeJSONBlock(&text, workflowTasks)
+		text.WriteString("\n建议的create_workflow参数：\n")
+		writeJSONBlock(&text, draftWorkflow)
+		text.WriteString("\n建议的create_task参数：\n")
+		writeJSONBlock(&text, draftTask)
+		text.WriteString("\n步骤：\n")
+		text.WriteString("1. 根据仓库调整测试命令，调用create_workflow创建工作流；\n")
+		text.WriteString("2. 用preview_schedule确认运行时间；\n")
+		text.WriteString("3. 把工作流ID填入parameters.workflow_id，调用create_task创建工作流任务，并告诉我两者的ID。\n")
+
+		return promptResult(text.String()), nil
+	})
+}
+
+// listTaskSummaries 列出指定类型的任务摘要，附带最近一次执行的状态
+func listTaskSummaries(ctx context.Context, tasks *service.TaskService, logs *service.ExecutionLogService, taskType models.TaskType) ([]map[string]interface{}, error) {
+	list, err := tasks.List(ctx, service.TaskFilter{Type: string(taskType), Limit: promptTaskLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]map[string]interface{}, 0, len(list.Tasks))
+	for _, task := range list.Tasks {
+		summary := map[string]interface{}{
+			"id":     task.ID.Hex(),
+			"name":   task.Name,
+			"status": task.Status,
+			"cron":   task.CronConfig.Expression,
+		}
+		if task.Environment.Workspace != nil {
+			summary["repository"] = task.Environment.Workspace.Repo
+			summary["ref"] = task.Environment.Workspace.Ref
+		}
+		if task.NextRun != nil {
+			summary["next_run"] = task.NextRun
+		}
+
+		taskID := task.ID
+		recent, err := logs.List(ctx, service.ExecutionLogFilter{TaskID: &taskID, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(recent) > 0 {
+			summary["last_execution"] = map[string]interface{}{
+				"execution_log_id": recent[0].ID.Hex(),
+				"status":           recent[0].Status,
+				"started_at":       recent[0].StartedAt,
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// errorLines 最后limit条错误日志
+func errorLines(entries []models.LogEntry, limit int) []string {
+	lines := []string{}
+	for i := len(entries) - 1; i >= 0 && len(lines) < limit; i-- {
+		if entries[i].Level == models.LogLevelError {
+			lines = append([]string{entries[i].Message}, lines...)
+		}
+	}
+	return lines
+}
+
+// failedTests 前limit个失败的测试
+func failedTests(report *models.TestReport, limit int) []string {
+	names := []string{}
+	for _, result := range report.Results {
+		if result.Status != models.TestStatusFailed {
+			continue
+		}
+		if len(names) == limit {
+			break
+		}
+		name := result.Name
+		if result.Suite != "" {
+			name = result.Suite + "." + name
+		}
+		if result.Message != "" {
+			name += ": " + result.Message
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// repositoryName 从仓库URL或路径取出仓库名，用于生成任务名称
+func repositoryName(repository string) string {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(repository, "/")), ".git")
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "repo"
+	}
+	return name
+}
+
+// promptArg 读取参数，为空时返回默认值
+func promptArg(args map[string]string, name, fallback string) string {
+	if value := strings.TrimSpace(args[name]); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// promptInt 读取整数参数并检查范围
+func promptInt(args map[string]string, name string, fallback, min, max int) (int, error) {
+	value := strings.TrimSpace(args[name])
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("%s must be an integer between %d and %d", name, min, max)}
+	}
+	return n, nil
+}
+
+// writeJSONBlock 以JSON代码块写入数据
+func writeJSONBlock(text *strings.Builder, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprintf("%q", err.Error()))
+	}
+	text.WriteString("```json\n")
+	text.Write(data)
+	text.WriteString("\n```\n")
+}
+
+func promptResult(text string) *GetPromptResult {
+	return &GetPromptResult{
+		Messages: []PromptMessage{{Role: "user", Content: Content{Type: "text", Text: text}}},
+	}
+}
@@ -0,0 +1,149 @@
+/**
+ * MCP标准输入输出代理
+ * 把stdio客户端的消息转发到服务进程的Streamable HTTP端点，
+ * 任务的执行、调度和取消都由服务进程完成
+ */
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+// ProxyStdio 从in逐行读取消息POST到cfg.URL，响应和服务端通知按行写到out，
+// initialize之后打开SSE流接收通知；输入结束或ctx取消后等待进行中的请求并结束会话
+func ProxyStdio(ctx context.Context, cfg ClientConfig, in io.Reader, out io.Writer) error {
+	t, err := dialHTTP(cfg)
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	// 转发到out的消息来自POST响应和SSE流，按行互斥写入
+	var mutex sync.Mutex
+	writer := bufio.NewWriter(out)
+	writeLine := func(message []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		writer.Write(append(message, '\n'))
+		writer.Flush()
+	}
+
+	stop := make(chan struct{})
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for {
+			select {
+			case message := <-t.messages():
+				writeLine(message)
+			case <-stop:
+				// 输出请求结束前已经收到的消息
+				for {
+					select {
+					case message := <-t.messages():
+						writeLine(message)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var pending sync.WaitGroup
+	forward := func(line []byte) {
+		if err := t.send(ctx, line); err != nil {
+			log.Printf("MCP proxy request failed: %v", err)
+			if response := proxyError(line, err); response != nil {
+				writeLine(response)
+			}
+		}
+	}
+
+	var result error
+	listening := false
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			result = ctx.Err()
+			break loop
+		case <-t.done():
+			result = ErrClientClosed
+			break loop
+		case result = <-errs:
+			break loop
+		case line := <-lines:
+			// initialize创建会话，之后的请求才能带上会话头，因此同步转发
+			if isInitialize(line) {
+				forward(line)
+				if !listening && t.sessionID() != "" {
+					listening = true
+					go func() {
+						if err := t.listen(ctx); err != nil && ctx.Err() == nil {
+							log.Printf("MCP notification stream closed: %v", err)
+						}
+					}()
+				}
+				continue
+			}
+			// 其余消息并发转发，长时间的工具调用不阻塞取消通知等后续消息
+			pending.Add(1)
+			go func() {
+				defer pending.Done()
+				forward(line)
+			}()
+		}
+	}
+
+	pending.Wait()
+	close(stop)
+	<-written
+	return result
+}
+
+// sessionID 当前会话ID，initialize之前为空
+func (t *httpTransport) sessionID() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.session
+}
+
+// proxyError 转发请求失败时返回给客户端的错误响应，通知不需要响应
+func proxyError(line []byte, err error) []byte {
+	var req Request
+	if json.Unmarshal(line, &req) != nil || req.IsNotification() {
+		return nil
+	}
+	message := "mcp server unavailable: " + err.Error()
+	if errors.Is(err, ErrClientClosed) {
+		message = "mcp session expired, restart the client"
+	}
+	return marshalResponse(errorResponse(req.ID, CodeInternalError, message))
+}
@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// proxyLines 按ID索引代理写到标准输出的响应
+func proxyLines(t *testing.T, out string) map[string]incomingMessage {
+	t.Helper()
+	responses := make(map[string]incomingMessage)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var message incomingMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("invalid output line %q: %v", line, err)
+		}
+		responses[string(message.ID)] = message
+	}
+	return responses
+}
+
+func TestProxyStdio(t *testing.T) {
+	server := NewServer("test", "1.0", "")
+	server.AddTool(Tool{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		return string(args), nil
+	})
+	transport := NewHTTPTransport(server, "/messages", time.Minute)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			writeRPCError(w, http.StatusUnauthorized, CodeInvalidRequest, "unauthorized")
+			return
+		}
+		transport.ServeStreamable(w, r)
+	}))
+	defer endpoint.Close()
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+	}, "\n")
+	var out bytes.Buffer
+	cfg := ClientConfig{URL: endpoint.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := ProxyStdio(context.Background(), cfg, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("ProxyStdio() error = %v", err)
+	}
+
+	responses := proxyLines(t, out.String())
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3:\n%s", len(responses), out.String())
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		response, ok := responses[id]
+		if !ok || response.Error != nil {
+			t.Errorf("response %s = %+v, want result", id, response)
+		}
+	}
+	if !strings.Contains(string(responses["2"].Result), `hello`) {
+		t.Errorf("tools/call result = %s, want echoed arguments", responses["2"].Result)
+	}
+	if count := transport.SessionCount(); count != 0 {
+		t.Errorf("SessionCount() = %d after EOF, want 0", count)
+	}
+}
+
+func TestProxyStdioErrors(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeRPCError(w, http.StatusUnauthorized, CodeInvalidRequest, "unauthorized")
+	}))
+	defer endpoint.Close()
+
+	in := `{"jsonrpc":"2.0","id":"a","method":"ping"}` + "\n" + `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	var out bytes.Buffer
+	if err := ProxyStdio(context.Background(), ClientConfig{URL: endpoint.URL}, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("ProxyStdio() error = %v", err)
+	}
+
+	// 请求得到错误响应，通知没有输出
+	responses := proxyLines(t, out.String())
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want 1:\n%s", len(responses), out.String())
+	}
+	response := responses[`"a"`]
+	if response.Error == nil || response.Error.Code != CodeInternalError || !strings.Contains(response.Error.Message, "HTTP 401") {
+		t.Errorf("response = %+v, want internal error mentioning HTTP 401", response)
+	}
+}
@@ -0,0 +1,221 @@
+/**
+ * 调度资源
+ * 把任务定义和执行日志暴露为MCP资源，执行状态变化时通知订阅者
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+	"aischedule/internal/service"
+	"aischedule/internal/websocket"
+)
+
+// 资源URI前缀
+const (
+	taskURIPrefix      = "aischedule://tasks/"
+	executionURIPrefix = "aischedule://executions/"
+)
+
+const (
+	// resourcePageSize resources/list每页的任务数
+	resourcePageSize = 50
+	// recentExecutions 第一页附带的最近执行数，以及任务资源中的最近执行数
+	recentExecutions = 10
+)
+
+// TaskURI 任务资源的URI
+func TaskURI(id string) string {
+	return taskURIPrefix + id
+}
+
+// ExecutionURI 执行资源的URI
+func ExecutionURI(id string) string {
+	return executionURIPrefix + id
+}
+
+// taskResources 任务和执行日志资源
+type taskResources struct {
+	tasks *service.TaskService
+	logs  *service.ExecutionLogService
+}
+
+// List 分页列出任务，第一页同时列出最近的执行
+func (r *taskResources) List(ctx context.Context, cursor string) ([]Resource, string, error) {
+	page := 1
+	if cursor != "" {
+		parsed, err := strconv.Atoi(cursor)
+		if err != nil || parsed < 1 {
+			return nil, "", &Error{Code: CodeInvalidParams, Message: "invalid cursor"}
+		}
+		page = parsed
+	}
+
+	response, err := r.tasks.List(ctx, service.TaskFilter{Page: page, Limit: resourcePageSize})
+	if err != nil {
+		return nil, "", err
+	}
+
+	resources := make([]Resource, 0, len(response.Tasks)+recentExecutions)
+	for _, task := range response.Tasks {
+		resources = append(resources, Resource{
+			URI:         TaskURI(task.ID.Hex()),
+			Name:        task.Name,
+			Description: fmt.Sprintf("%s任务，状态%s", task.Type, task.Status),
+			MimeType:    "application/json",
+		})
+	}
+
+	if page == 1 {
+		executions, err := r.logs.List(ctx, service.ExecutionLogFilter{Limit: recentExecutions})
+		if err != nil {
+			return nil, "", err
+		}
+		for _, execution := range executions {
+			resources = append(resources, Resource{
+				URI:         ExecutionURI(execution.ID.Hex()),
+				Name:        fmt.Sprintf("执行 %s", execution.ID.Hex()),
+				Description: fmt.Sprintf("任务%s的执行，状态%s，开始于%s", execution.TaskID.Hex(), execution.Status, execution.StartedAt.Format("2006-01-02 15:04:05")),
+				MimeType:    "application/json",
+			})
+		}
+	}
+
+	next := ""
+	if page*resourcePageSize < response.Pagination.Total {
+		next = strconv.Itoa(page + 1)
+	}
+	return resources, next, nil
+}
+
+// Templates 任务和执行的URI模板
+func (r *taskResources) Templates() []ResourceTemplate {
+	return []ResourceTemplate{
+		{
+			URITemplate: taskURIPrefix + "{id}",
+			Name:        "任务",
+			Description: "任务定义及最近的执行",
+			MimeType:    "application/json",
+		},
+		{
+			URITemplate: executionURIPrefix + "{id}",
+			Name:        "执行日志",
+			Description: fmt.Sprintf("执行状态、结果和最后%d条日志，执行状态变化时通知订阅者", defaultLogTail),
+			MimeType:    "application/json",
+		},
+	}
+}
+
+// Read 读取任务或执行日志
+func (r *taskResources) Read(ctx context.Context, uri string) (*ResourceContents, error) {
+	var value interface{}
+	switch {
+	case strings.HasPrefix(uri, taskURIPrefix):
+		id, err := resourceID(uri, taskURIPrefix)
+		if err != nil {
+			return nil, err
+		}
+		task, err := r.tasks.Get(ctx, id)
+		if errors.Is(err, service.ErrTaskNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+		}
+		if err != nil {
+			return nil, err
+		}
+		executions, err := r.logs.List(ctx, service.ExecutionLogFilter{TaskID: &id, Limit: recentExecutions})
+		if err != nil {
+			return nil, err
+		}
+		value = map[string]interface{}{
+			"task":              task,
+			"recent_executions": executionSummaries(executions),
+		}
+
+	case strings.HasPrefix(uri, executionURIPrefix):
+		id, err := resourceID(uri, executionURIPrefix)
+		if err != nil {
+			return nil, err
+		}
+		log, err := r.logs.Get(ctx, id, isAdmin(ctx))
+		if errors.Is(err, service.ErrExecutionLogNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(log.Logs) > defaultLogTail {
+			log.Logs = log.Logs[len(log.Logs)-defaultLogTail:]
+		}
+		value = log
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+
+	text, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceContents{URI: uri, MimeType: "application/json", Text: string(text)}, nil
+}
+
+// resourceID 解析URI中的ObjectID
+func resourceID(uri, prefix string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(strings.TrimPrefix(uri, prefix))
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	return id, nil
+}
+
+// executionSummaries 任务资源中的执行摘要
+func executionSummaries(executions []models.ExecutionLog) []map[string]interface{} {
+	summaries := make([]map[string]interface{}, 0, len(executions))
+	for _, execution := range executions {
+		summary := map[string]interface{}{
+			"uri":          ExecutionURI(execution.ID.Hex()),
+			"status":       execution.Status,
+			"trigger_type": execution.TriggerType,
+			"started_at":   execution.StartedAt,
+			"duration":     execution.Duration,
+		}
+		if execution.Result.Error != "" {
+			summary["error"] = execution.Result.Error
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// WatchExecutions 监听WebSocket管理器的执行状态消息，通知订阅了对应执行和任务的会话，返回停止监听的函数
+func WatchExecutions(s *Server, manager *websocket.Manager) func() {
+	return manager.AddListener(func(topic string, message websocket.Message) {
+		if topic != "task_execution" || message.Type != websocket.MessageTypeStatus {
+			return
+		}
+		// 消息数据可能是map或gin.H，按JSON取出ID
+		var event struct {
+			TaskID      string `json:"task_id"`
+			ExecutionID string `json:"execution_id"`
+		}
+		raw, err := json.Marshal(message.Data)
+		if err != nil || json.Unmarshal(raw, &event) != nil {
+			return
+		}
+		if event.ExecutionID != "" {
+			s.ResourceUpdated(ExecutionURI(event.ExecutionID))
+		}
+		if event.TaskID != "" {
+			s.ResourceUpdated(TaskURI(event.TaskID))
+		}
+	})
+}
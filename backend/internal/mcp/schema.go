@@ -0,0 +1,251 @@
+/**
+ * 工具参数校验
+ * 按工具的inputSchema校验调用参数，支持JSON Schema中常用的子集：
+ * type、required、properties、additionalProperties、items、enum、const、
+ * minimum/maximum、minLength/maxLength、minItems/maxItems、pattern
+ */
+
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaError 参数不符合inputSchema，Path为出错的参数路径
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateArguments 按inputSchema校验工具参数；含模板表达式（{{ }}）的字符串在运行时才能确定取值，跳过校验
+func ValidateArguments(schema map[string]interface{}, arguments map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	// 统一成JSON解码后的类型：数字为float64，数组为[]interface{}（从数据库读出的模式是bson类型）
+	var normalizedSchema map[string]interface{}
+	if err := normalizeJSON(schema, &normalizedSchema); err != nil {
+		return err
+	}
+	var value interface{}
+	if err := normalizeJSON(arguments, &value); err != nil {
+		return err
+	}
+	return validateValue("arguments", normalizedSchema, value)
+}
+
+func normalizeJSON(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func validateValue(path string, schema map[string]interface{}, value interface{}) error {
+	if isTemplate(value) {
+		return nil
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(value))}
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be %v", constant)}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, item := range enum {
+			if jsonEqual(item, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be one of %v", enum)}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(path, schema, v)
+	case []interface{}:
+		return validateArray(path, schema, v)
+	case string:
+		return validateString(path, schema, v)
+	case float64:
+		return validateNumber(path, schema, v)
+	}
+	return nil
+}
+
+func validateObject(path string, schema map[string]interface{}, value map[string]interface{}) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, item := range required {
+			name, _ := item.(string)
+			if _, ok := value[name]; name != "" && !ok {
+				return &SchemaError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if property, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateValue(childPath, property, value[key]); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &SchemaError{Path: childPath, Message: "unknown property"}
+			}
+		case map[string]interface{}:
+			if err := validateValue(childPath, additional, value[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateArray(path string, schema map[string]interface{}, value []interface{}) error {
+	if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(value)) < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must have at least %v items", min)}
+	}
+	if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(value)) > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must have at most %v items", max)}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			if err := validateValue(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(path string, schema map[string]interface{}, value string) error {
+	length := float64(len([]rune(value)))
+	if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be at least %v characters", min)}
+	}
+	if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be at most %v characters", max)}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		// 无法编译的模式按服务端的实现为准，不在这里拒绝
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must match pattern %s", pattern)}
+		}
+	}
+	return nil
+}
+
+func validateNumber(path string, schema map[string]interface{}, value float64) error {
+	if min, ok := schemaNumber(schema["minimum"]); ok && value < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be >= %v", min)}
+	}
+	if max, ok := schemaNumber(schema["maximum"]); ok && value > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be <= %v", max)}
+	}
+	return nil
+}
+
+// schemaTypes type可以是单个类型或类型数组
+func schemaTypes(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			if t, ok := item.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == schemaType
+	}
+}
+
+// jsonType 值的JSON类型名称
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaNumber(value interface{}) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(x) == string(y)
+}
+
+// isTemplate 值是否为运行时渲染的模板字符串
+func isTemplate(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "{{")
+}
@@ -0,0 +1,492 @@
+/**
+ * MCP服务端
+ * 处理JSON-RPC消息并分发到已注册的工具，与具体传输方式无关
+ */
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ToolHandler 工具处理函数，返回值序列化为JSON文本作为工具结果
+type ToolHandler func(ctx context.Context, args json.RawMessage) (interface{}, error)
+
+// PromptHandler 提示模板处理函数，参数已按模板声明检查过必填项
+type PromptHandler func(ctx context.Context, args map[string]string) (*GetPromptResult, error)
+
+// ResourceProvider 资源提供者
+type ResourceProvider interface {
+	// List 分页列出资源，返回下一页的游标，没有更多时为空
+	List(ctx context.Context, cursor string) ([]Resource, string, error)
+	// Templates 资源URI模板
+	Templates() []ResourceTemplate
+	// Read 读取资源，资源不存在时返回ErrResourceNotFound
+	Read(ctx context.Context, uri string) (*ResourceContents, error)
+}
+
+// ErrResourceNotFound 资源不存在
+var ErrResourceNotFound = errors.New("resource not found")
+
+// CodeResourceNotFound 资源不存在的错误码
+const CodeResourceNotFound = -32002
+
+type registeredTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+type registeredPrompt struct {
+	prompt  Prompt
+	handler PromptHandler
+}
+
+// Server MCP服务端
+type Server struct {
+	info         Implementation
+	instructions string
+
+	tools       map[string]*registeredTool
+	order       []string
+	prompts     map[string]*registeredPrompt
+	promptOrder []string
+	resources   ResourceProvider
+	mutex     sync.RWMutex
+
+	// 按资源URI记录订阅的会话
+	subscriptions map[string]map[*Session]bool
+	subMutex      sync.Mutex
+}
+
+// NewServer 创建新的MCP服务端
+func NewServer(name, version, instructions string) *Server {
+	return &Server{
+		info:          Implementation{Name: name, Version: version},
+		instructions:  instructions,
+		tools:         make(map[string]*registeredTool),
+		prompts:       make(map[string]*registeredPrompt),
+		subscriptions: make(map[string]map[*Session]bool),
+	}
+}
+
+// SetResources 设置资源提供者
+func (s *Server) SetResources(provider ResourceProvider) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resources = provider
+}
+
+// ResourceUpdated 通知订阅了该资源的会话资源已更新，顺带清理已关闭的会话
+func (s *Server) ResourceUpdated(uri string) {
+	s.subMutex.Lock()
+	sessions := make([]*Session, 0, len(s.subscriptions[uri]))
+	for session := range s.subscriptions[uri] {
+		select {
+		case <-session.Done():
+			delete(s.subscriptions[uri], session)
+		default:
+			sessions = append(sessions, session)
+		}
+	}
+	if len(s.subscriptions[uri]) == 0 {
+		delete(s.subscriptions, uri)
+	}
+	s.subMutex.Unlock()
+
+	for _, session := range sessions {
+		session.Notify("notifications/resources/updated", ResourceUpdatedParams{URI: uri})
+	}
+}
+
+// AddTool 注册工具，同名工具会被替换
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tools[tool.Name]; !exists {
+		s.order = append(s.order, tool.Name)
+	}
+	s.tools[tool.Name] = &registeredTool{tool: tool, handler: handler}
+}
+
+// AddPrompt 注册提示模板，同名模板会被替换
+func (s *Server) AddPrompt(prompt Prompt, handler PromptHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.prompts[prompt.Name]; !exists {
+		s.promptOrder = append(s.promptOrder, prompt.Name)
+	}
+	s.prompts[prompt.Name] = &registeredPrompt{prompt: prompt, handler: handler}
+}
+
+// Handle 处理会话中的一条JSON-RPC消息（单个请求或批量请求），
+// 返回需要发回的响应，全部为通知时返回nil
+func (s *Server) Handle(ctx context.Context, session *Session, message []byte) []byte {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 {
+		return nil
+	}
+
+	if message[0] != '[' {
+		response := s.handleMessage(ctx, session, message)
+		if response == nil {
+			return nil
+		}
+		return marshalResponse(response)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(message, &batch); err != nil {
+		return marshalResponse(errorResponse(nil, CodeParseError, "parse error"))
+	}
+	if len(batch) == 0 {
+		return marshalResponse(errorResponse(nil, CodeInvalidRequest, "empty batch"))
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for _, item := range batch {
+		if response := s.handleMessage(ctx, session, item); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(responses)
+	if err != nil {
+		return marshalResponse(errorResponse(nil, CodeInternalError, err.Error()))
+	}
+	return data
+}
+
+// handleMessage 处理单个请求，通知返回nil
+func (s *Server) handleMessage(ctx context.Context, session *Session, message []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return errorResponse(nil, CodeParseError, "parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		// 客户端发来的响应（没有method）不需要处理
+		if req.Method == "" && !req.IsNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, CodeInvalidRequest, "invalid request")
+	}
+
+	session.touch()
+	result, err := s.dispatch(ctx, session, &req)
+	if req.IsNotification() {
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch 按方法名处理请求
+func (s *Server) dispatch(ctx context.Context, session *Session, req *Request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		result := s.initialize(&params)
+		session.setInitialized(result.ProtocolVersion)
+		return result, nil
+
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		return s.listTools(), nil
+
+	case "tools/call":
+		var params CallToolParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, &params)
+
+	case "prompts/list":
+		if !s.hasPrompts() {
+			return nil, &Error{Code: CodeMethodNotFound, Message: "prompts not supported"}
+		}
+		return s.listPrompts(), nil
+
+	case "prompts/get":
+		var params GetPromptParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.getPrompt(ctx, &params)
+
+	case "resources/list":
+		var params ListResourcesParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		provider, err := s.resourceProvider()
+		if err != nil {
+			return nil, err
+		}
+		resources, next, err := provider.List(ctx, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if resources == nil {
+			resources = []Resource{}
+		}
+		return &ListResourcesResult{Resources: resources, NextCursor: next}, nil
+
+	case "resources/templates/list":
+		provider, err := s.resourceProvider()
+		if err != nil {
+			return nil, err
+		}
+		return &ListResourceTemplatesResult{ResourceTemplates: provider.Templates()}, nil
+
+	case "resources/read":
+		var params ResourceParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		provider, err := s.resourceProvider()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := provider.Read(ctx, params.URI)
+		if err != nil {
+			return nil, resourceError(params.URI, err)
+		}
+		return &ReadResourceResult{Contents: []ResourceContents{*contents}}, nil
+
+	case "resources/subscribe", "resources/unsubscribe":
+		var params ResourceParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if _, err := s.resourceProvider(); err != nil {
+			return nil, err
+		}
+		if params.URI == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "uri is required"}
+		}
+		s.subscribe(session, params.URI, req.Method == "resources/subscribe")
+		return struct{}{}, nil
+
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+// initialize 协商协议版本，客户端请求的版本受支持时沿用，否则返回最新版本
+func (s *Server) initialize(params *InitializeParams) *InitializeResult {
+	version := supportedVersions[0]
+	for _, supported := range supportedVersions {
+		if params.ProtocolVersion == supported {
+			version = supported
+			break
+		}
+	}
+
+	return &InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    s.capabilities(),
+		ServerInfo:      s.info,
+		Instructions:    s.instructions,
+	}
+}
+
+// capabilities 服务端能力，设置了资源提供者时支持资源订阅，注册了提示模板时支持提示
+func (s *Server) capabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{"listChanged": false},
+	}
+	if _, err := s.resourceProvider(); err == nil {
+		capabilities["resources"] = map[string]interface{}{"subscribe": true, "listChanged": false}
+	}
+	if s.hasPrompts() {
+		capabilities["prompts"] = map[string]interface{}{"listChanged": false}
+	}
+	return capabilities
+}
+
+// resourceProvider 获取资源提供者，未设置时按方法不存在处理
+func (s *Server) resourceProvider() (ResourceProvider, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.resources == nil {
+		return nil, &Error{Code: CodeMethodNotFound, Message: "resources not supported"}
+	}
+	return s.resources, nil
+}
+
+// subscribe 订阅或取消订阅资源
+func (s *Server) subscribe(session *Session, uri string, subscribe bool) {
+	s.subMutex.Lock()
+	defer s.subMutex.Unlock()
+
+	if !subscribe {
+		delete(s.subscriptions[uri], session)
+		if len(s.subscriptions[uri]) == 0 {
+			delete(s.subscriptions, uri)
+		}
+		return
+	}
+	if s.subscriptions[uri] == nil {
+		s.subscriptions[uri] = make(map[*Session]bool)
+	}
+	s.subscriptions[uri][session] = true
+}
+
+// forget 会话结束时移除它的订阅
+func (s *Server) forget(session *Session) {
+	s.subMutex.Lock()
+	defer s.subMutex.Unlock()
+	for uri, sessions := range s.subscriptions {
+		delete(sessions, session)
+		if len(sessions) == 0 {
+			delete(s.subscriptions, uri)
+		}
+	}
+}
+
+// resourceError 资源不存在时返回约定的错误码
+func resourceError(uri string, err error) error {
+	if errors.Is(err, ErrResourceNotFound) {
+		return &Error{Code: CodeResourceNotFound, Message: err.Error(), Data: map[string]string{"uri": uri}}
+	}
+	return err
+}
+
+// listTools 按注册顺序列出工具
+func (s *Server) listTools() *ListToolsResult {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tools := make([]Tool, 0, len(s.order))
+	for _, name := range s.order {
+		tools = append(tools, s.tools[name].tool)
+	}
+	return &ListToolsResult{Tools: tools}
+}
+
+// callTool 调用工具，工具本身的错误作为isError结果返回给模型
+func (s *Server) callTool(ctx context.Context, params *CallToolParams) (*CallToolResult, error) {
+	s.mutex.RLock()
+	registered, exists := s.tools[params.Name]
+	s.mutex.RUnlock()
+	if !exists {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+
+	args := params.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+
+	value, err := registered.handler(ctx, args)
+	if err != nil {
+		return &CallToolResult{
+			Content: []Content{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}, nil
+	}
+
+	text, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &CallToolResult{Content: []Content{{Type: "text", Text: string(text)}}}, nil
+}
+
+// hasPrompts 是否注册了提示模板
+func (s *Server) hasPrompts() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.prompts) > 0
+}
+
+// listPrompts 按注册顺序列出提示模板
+func (s *Server) listPrompts() *ListPromptsResult {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	prompts := make([]Prompt, 0, len(s.promptOrder))
+	for _, name := range s.promptOrder {
+		prompts = append(prompts, s.prompts[name].prompt)
+	}
+	return &ListPromptsResult{Prompts: prompts}
+}
+
+// getPrompt 检查必填参数后生成提示消息
+func (s *Server) getPrompt(ctx context.Context, params *GetPromptParams) (*GetPromptResult, error) {
+	s.mutex.RLock()
+	registered, exists := s.prompts[params.Name]
+	s.mutex.RUnlock()
+	if !exists {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown prompt: %s", params.Name)}
+	}
+
+	args := params.Arguments
+	if args == nil {
+		args = map[string]string{}
+	}
+	for _, argument := range registered.prompt.Arguments {
+		if argument.Required && args[argument.Name] == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("missing required argument: %s", argument.Name)}
+		}
+	}
+
+	result, err := registered.handler(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if result.Description == "" {
+		result.Description = registered.prompt.Description
+	}
+	return result, nil
+}
+
+// decodeParams 解析请求参数
+func decodeParams(raw json.RawMessage, target interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: message}}
+}
+
+func marshalResponse(response *Response) []byte {
+	data, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Failed to marshal MCP response: %v", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,137 @@
+/**
+ * MCP会话
+ * 记录客户端协商的协议版本，并缓冲服务端主动发送的通知
+ */
+
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// outgoingBuffer 每个会话缓冲的待发送消息数
+const outgoingBuffer = 64
+
+// Session MCP会话，stdio连接对应一个会话，HTTP传输按Mcp-Session-Id区分会话
+type Session struct {
+	ID string
+
+	outgoing chan []byte
+	done     chan struct{}
+
+	mutex           sync.Mutex
+	protocolVersion string
+	initialized     bool
+	lastActive      time.Time
+	closed          bool
+}
+
+// NewSession 创建会话，ID为空时生成随机ID
+func NewSession(id string) *Session {
+	if id == "" {
+		id = newSessionID()
+	}
+	return &Session{
+		ID:         id,
+		outgoing:   make(chan []byte, outgoingBuffer),
+		done:       make(chan struct{}),
+		lastActive: time.Now(),
+	}
+}
+
+// Outgoing 服务端主动发送的消息
+func (s *Session) Outgoing() <-chan []byte {
+	return s.outgoing
+}
+
+// Done 会话关闭时关闭
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Notify 向客户端发送通知，缓冲区满时丢弃
+func (s *Session) Notify(method string, params interface{}) {
+	message, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal MCP notification: %v", err)
+		return
+	}
+	s.send(message, false)
+}
+
+// send 把消息放入待发送队列，wait为false且队列已满时丢弃，会话已关闭时返回false
+func (s *Session) send(message []byte, wait bool) bool {
+	if wait {
+		select {
+		case s.outgoing <- message:
+			return true
+		case <-s.done:
+			return false
+		}
+	}
+	select {
+	case s.outgoing <- message:
+		return true
+	case <-s.done:
+		return false
+	default:
+		log.Printf("MCP session %s outgoing buffer full, dropping message", s.ID)
+		return false
+	}
+}
+
+// ProtocolVersion 协商后的协议版本
+func (s *Session) ProtocolVersion() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.protocolVersion
+}
+
+// Close 关闭会话
+func (s *Session) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// touch 记录会话最近一次活动的时间
+func (s *Session) touch() {
+	s.mutex.Lock()
+	s.lastActive = time.Now()
+	s.mutex.Unlock()
+}
+
+// idleSince 会话最近一次活动的时间
+func (s *Session) idleSince() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastActive
+}
+
+// setInitialized 记录协商结果
+func (s *Session) setInitialized(version string) {
+	s.mutex.Lock()
+	s.protocolVersion = version
+	s.initialized = true
+	s.mutex.Unlock()
+}
+
+func newSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(buf)
+}
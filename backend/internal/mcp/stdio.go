@@ -0,0 +1,81 @@
+/**
+ * MCP标准输入输出传输
+ * 每行一条JSON-RPC消息，响应按行写回标准输出
+ */
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+)
+
+// maxMessageSize 单条消息的最大长度
+const maxMessageSize = 10 * 1024 * 1024
+
+// ServeStdio 从in逐行读取消息并把响应写到out，直到输入结束或上下文取消
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	session := NewSession("stdio")
+	defer s.forget(session)
+	defer session.Close()
+
+	// 响应和会话通知都写到out，按行互斥写入
+	var mutex sync.Mutex
+	writer := bufio.NewWriter(out)
+	writeLine := func(message []byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if _, err := writer.Write(append(message, '\n')); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+
+	go func() {
+		for {
+			select {
+			case message := <-session.Outgoing():
+				if err := writeLine(message); err != nil {
+					return
+				}
+			case <-session.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			response := s.Handle(ctx, session, line)
+			if response == nil {
+				continue
+			}
+			if err := writeLine(response); err != nil {
+				return err
+			}
+		}
+	}
+}
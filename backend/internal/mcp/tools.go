@@ -0,0 +1,368 @@
+/**
+ * 调度工具
+ * 把任务服务暴露为MCP工具：创建、查询、执行、暂停任务，查看执行日志和预览调度时间
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+	"aischedule/internal/service"
+)
+
+// defaultLogTail get_execution_log默认返回的日志条数
+const defaultLogTail = 100
+
+type adminKey struct{}
+
+// WithAdmin 标记调用方是否为管理员，管理员可以看到命中的脱敏规则
+func WithAdmin(ctx context.Context, admin bool) context.Context {
+	return context.WithValue(ctx, adminKey{}, admin)
+}
+
+func isAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// 服务端信息
+const (
+	serverName    = "aischedule"
+	serverVersion = "1.0.0"
+	instructions  = "AI Schedule任务调度服务。先用preview_schedule确认Cron表达式，再用create_task创建任务；run_task返回的执行日志ID可用get_execution_log查看结果。常见操作可以从提示模板开始。"
+)
+
+// New 创建注册了调度工具、提示模板和任务、执行资源的MCP服务端
+func New(tasks *service.TaskService, logs *service.ExecutionLogService, workflows *service.WorkflowService) *Server {
+	s := NewServer(serverName, serverVersion, instructions)
+	RegisterTaskTools(s, tasks, logs)
+	RegisterWorkflowTools(s, workflows)
+	RegisterPrompts(s, tasks, logs, workflows)
+	s.SetResources(&taskResources{tasks: tasks, logs: logs})
+	return s
+}
+
+// RegisterTaskTools 注册任务相关的工具
+func RegisterTaskTools(s *Server, tasks *service.TaskService, logs *service.ExecutionLogService) {
+	s.AddTool(Tool{
+		Name:        "create_task",
+		Description: "创建定时任务。cron_config.expression为6段Cron表达式（秒 分 时 日 月 周），start为true时创建后立即加入调度。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"name":        stringSchema("任务名称"),
+			"description": stringSchema("任务描述"),
+			"type":        enumSchema("任务类型", taskTypes()),
+			"cron_config": cronConfigSchema(),
+			"agent_config": map[string]interface{}{
+				"type":        "object",
+				"description": "执行配置，parameters为任务类型对应的参数",
+				"properties": map[string]interface{}{
+					"parameters":       map[string]interface{}{"type": "object"},
+					"timeout":          integerSchema("超时时间(秒)"),
+					"retries":          integerSchema("重试次数"),
+					"strict_templates": map[string]interface{}{"type": "boolean"},
+				},
+			},
+			"environment": map[string]interface{}{
+				"type":        "object",
+				"description": "执行环境：working_directory、environment_vars、workspace等",
+			},
+			"start": map[string]interface{}{"type": "boolean", "description": "创建后立即启动调度"},
+		}, "name", "type"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req struct {
+			models.CreateTaskRequest
+			Start bool `json:"start"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		if req.Name == "" || req.Type == "" {
+			return nil, errors.New("name and type are required")
+		}
+
+		task, err := tasks.Create(ctx, &req.CreateTaskRequest)
+		if err != nil {
+			return nil, err
+		}
+		if req.Start {
+			if err := tasks.Start(ctx, task.ID); err != nil {
+				return nil, fmt.Errorf("task %s created but failed to start: %w", task.ID.Hex(), err)
+			}
+			task.Status = models.TaskStatusActive
+		}
+		return task, nil
+	})
+
+	s.AddTool(Tool{
+		Name:        "list_tasks",
+		Description: "分页列出任务，按创建时间倒序，可按状态和类型过滤。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"status": enumSchema("任务状态", taskStatuses()),
+			"type":   enumSchema("任务类型", taskTypes()),
+			"page":   integerSchema("页码，从1开始"),
+			"limit":  integerSchema("每页数量，最多100"),
+		}),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var filter struct {
+			Status string `json:"status"`
+			Type   string `json:"type"`
+			Page   int    `json:"page"`
+			Limit  int    `json:"limit"`
+		}
+		if err := json.Unmarshal(args, &filter); err != nil {
+			return nil, err
+		}
+		return tasks.List(ctx, service.TaskFilter{
+			Status: filter.Status,
+			Type:   filter.Type,
+			Page:   filter.Page,
+			Limit:  filter.Limit,
+		})
+	})
+
+	s.AddTool(Tool{
+		Name:        "run_task",
+		Description: "立即执行任务，返回执行日志ID，执行在后台进行，可用get_execution_log查看进度。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"task_id":      stringSchema("任务ID"),
+			"payload":      map[string]interface{}{"type": "object", "description": "触发负载，参数模板中通过.Trigger.Payload引用"},
+			"triggered_by": stringSchema("触发者，默认mcp"),
+		}, "task_id"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req struct {
+			TaskID      string                 `json:"task_id"`
+			Payload     map[string]interface{} `json:"payload"`
+			TriggeredBy string                 `json:"triggered_by"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		id, err := parseID("task_id", req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if req.TriggeredBy == "" {
+			req.TriggeredBy = "mcp"
+		}
+
+		logID, err := tasks.Execute(ctx, id, models.TriggerInfo{
+			Type:    "manual",
+			By:      req.TriggeredBy,
+			Payload: req.Payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"execution_log_id": logID.Hex(), "status": "started"}, nil
+	})
+
+	s.AddTool(Tool{
+		Name:        "pause_task",
+		Description: "暂停任务的定时调度，暂停后仍可通过run_task手动执行。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"task_id": stringSchema("任务ID"),
+		}, "task_id"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req struct {
+			TaskID string `json:"task_id"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		id, err := parseID("task_id", req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if err := tasks.Pause(ctx, id); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"task_id": req.TaskID, "status": models.TaskStatusPaused}, nil
+	})
+
+	s.AddTool(Tool{
+		Name:        "get_execution_log",
+		Description: "查看执行日志，包括状态、结果和最后tail条日志。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"execution_log_id": stringSchema("执行日志ID"),
+			"tail":             integerSchema(fmt.Sprintf("返回的日志条数，默认%d", defaultLogTail)),
+		}, "execution_log_id"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req struct {
+			ExecutionLogID string `json:"execution_log_id"`
+			Tail           int    `json:"tail"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		id, err := parseID("execution_log_id", req.ExecutionLogID)
+		if err != nil {
+			return nil, err
+		}
+
+		log, err := logs.Get(ctx, id, isAdmin(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if req.Tail <= 0 {
+			req.Tail = defaultLogTail
+		}
+		if len(log.Logs) > req.Tail {
+			log.Logs = log.Logs[len(log.Logs)-req.Tail:]
+		}
+		return log, nil
+	})
+
+	s.AddTool(Tool{
+		Name:        "preview_schedule",
+		Description: "预览Cron表达式之后的运行时间，用于在创建任务前确认调度是否符合预期。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"expression": stringSchema("6段Cron表达式（秒 分 时 日 月 周）或@daily等描述符"),
+			"timezone":   stringSchema("IANA时区，例如Asia/Shanghai，默认服务器时区"),
+			"count":      integerSchema("预览次数，默认5，最多100"),
+		}, "expression"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req struct {
+			models.CronConfig
+			Count int `json:"count"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+
+		runs, err := tasks.PreviewSchedule(req.CronConfig, req.Count)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"next_runs": runs}, nil
+	})
+}
+
+// RegisterWorkflowTools 注册工作流相关的工具
+func RegisterWorkflowTools(s *Server, workflows *service.WorkflowService) {
+	s.AddTool(Tool{
+		Name:        "create_workflow",
+		Description: "创建工作流。步骤的action为任务类型，parameters为该类型的参数；创建后用create_task以workflow类型和parameters.workflow_id定时执行。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"name":        stringSchema("工作流名称"),
+			"description": stringSchema("工作流描述"),
+			"type":        enumSchema("工作流类型", workflowTypes()),
+			"steps": map[string]interface{}{
+				"type":        "array",
+				"description": "步骤列表，每个步骤包含id、name、type、action、parameters、on_success、on_failure、timeout、retries、continue_on_error，循环和等待步骤的loop、wait，以及声明输出的outputs",
+				"items":       map[string]interface{}{"type": "object"},
+			},
+			"connections": map[string]interface{}{
+				"type":        "array",
+				"description": "步骤之间的连接，每个连接包含from、to和可选的condition",
+				"items":       map[string]interface{}{"type": "object"},
+			},
+			"start_step": stringSchema("起始步骤ID"),
+		}, "name", "type", "steps", "start_step"),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var req models.CreateWorkflowRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		if req.Name == "" || req.Type == "" || len(req.Steps) == 0 || req.StartStep == "" {
+			return nil, errors.New("name, type, steps and start_step are required")
+		}
+		return workflows.Create(ctx, &req)
+	})
+
+	s.AddTool(Tool{
+		Name:        "list_workflows",
+		Description: "列出工作流，按创建时间倒序，可按类型过滤。",
+		InputSchema: objectSchema(map[string]interface{}{
+			"type":  enumSchema("工作流类型", workflowTypes()),
+			"limit": integerSchema("数量，默认20，最多100"),
+		}),
+	}, func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		var filter struct {
+			Type  string `json:"type"`
+			Limit int    `json:"limit"`
+		}
+		if err := json.Unmarshal(args, &filter); err != nil {
+			return nil, err
+		}
+		return workflows.List(ctx, service.WorkflowFilter{Type: filter.Type, Limit: filter.Limit})
+	})
+}
+
+// parseID 解析ObjectID参数
+func parseID(field, value string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid %s: %q", field, value)
+	}
+	return id, nil
+}
+
+func taskTypes() []string {
+	return []string{
+		string(models.TaskTypeCodeReview),
+		string(models.TaskTypeAutoTest),
+		string(models.TaskTypeDeployment),
+		string(models.TaskTypeDataBackup),
+		string(models.TaskTypeCustom),
+		string(models.TaskTypeScript),
+		string(models.TaskTypeAPI),
+		string(models.TaskTypeWorkflow),
+		string(models.TaskTypeAgent),
+		string(models.TaskTypeMCPTool),
+	}
+}
+
+func workflowTypes() []string {
+	return []string{
+		string(models.WorkflowTypeSequential),
+		string(models.WorkflowTypeParallel),
+		string(models.WorkflowTypeConditional),
+	}
+}
+
+func taskStatuses() []string {
+	return []string{
+		string(models.TaskStatusActive),
+		string(models.TaskStatusInactive),
+		string(models.TaskStatusPaused),
+		string(models.TaskStatusCompleted),
+		string(models.TaskStatusFailed),
+	}
+}
+
+func cronConfigSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"expression": stringSchema("6段Cron表达式（秒 分 时 日 月 周），为空时只能手动执行"),
+		"timezone":   stringSchema("IANA时区，例如Asia/Shanghai"),
+	})
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringSchema(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func integerSchema(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description}
+}
+
+func enumSchema(description string, values []string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description, "enum": values}
+}
@@ -0,0 +1,35 @@
+/**
+ * 管理员识别中间件
+ * 请求携带管理员令牌时标记为管理员请求
+ */
+
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// adminContextKey 管理员标记在gin上下文中的键
+const adminContextKey = "is_admin"
+
+// AdminToken 识别管理员请求：X-Admin-Token 或 Authorization: Bearer 与配置的令牌一致
+// 未配置令牌时不会有请求被识别为管理员
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" {
+			provided := c.GetHeader("X-Admin-Token")
+			if provided == "" {
+				provided = bearerToken(c)
+			}
+			if tokenMatches(provided, token) {
+				c.Set(adminContextKey, true)
+			}
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin 判断请求是否来自管理员
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool(adminContextKey)
+}
@@ -0,0 +1,76 @@
+/**
+ * API认证中间件
+ * 配置了API令牌时，请求必须携带API令牌或管理员令牌；
+ * MCP端点和密钥接口在未配置API令牌时只接受管理员令牌
+ */
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenContextKey 携带有效API令牌的标记在gin上下文中的键
+const apiTokenContextKey = "api_token"
+
+// APIToken 校验X-API-Token或Authorization: Bearer中的令牌，未配置令牌时不校验
+// 需要放在AdminToken之后，已识别为管理员的请求直接放行
+func APIToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" || IsAdmin(c) {
+			c.Next()
+			return
+		}
+
+		provided := c.GetHeader("X-API-Token")
+		if provided == "" {
+			provided = bearerToken(c)
+		}
+		if !tokenMatches(provided, token) {
+			HandleUnauthorizedError(c)
+			return
+		}
+		c.Set(apiTokenContextKey, true)
+		c.Next()
+	}
+}
+
+// Identity 请求的认证身份：管理员令牌为admin，API令牌为api，未认证（未配置API令牌）为anonymous
+func Identity(c *gin.Context) string {
+	switch {
+	case IsAdmin(c):
+		return "admin"
+	case c.GetBool(apiTokenContextKey):
+		return "api"
+	}
+	return "anonymous"
+}
+
+// RequireToken 用于不能匿名访问的接口（MCP端点、密钥）：未配置API令牌时只放行管理员请求，
+// 配置了API令牌时由APIToken校验
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" && !IsAdmin(c) {
+			HandleUnauthorizedError(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken 取Authorization: Bearer <token>中的令牌，其他认证方式返回空
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// tokenMatches 以固定时间比较令牌，空令牌总是不匹配
+func tokenMatches(provided, token string) bool {
+	return provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
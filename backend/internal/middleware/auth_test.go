@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(adminToken, apiToken string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AdminToken(adminToken))
+	api := r.Group("/api", APIToken(apiToken))
+	api.GET("/tasks", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/mcp", RequireToken(apiToken), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestAPIToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		apiToken   string
+		path       string
+		headers    map[string]string
+		want       int
+	}{
+		{name: "open api without token", path: "/api/tasks", want: http.StatusOK},
+		{name: "mcp closed without token", path: "/api/mcp", want: http.StatusUnauthorized},
+		{name: "mcp closed with anything when no token configured", path: "/api/mcp",
+			headers: map[string]string{"Authorization": "Bearer "}, want: http.StatusUnauthorized},
+		{name: "mcp with admin token when no api token", adminToken: "adm", path: "/api/mcp",
+			headers: map[string]string{"X-Admin-Token": "adm"}, want: http.StatusOK},
+		{name: "api token header", apiToken: "tok", path: "/api/mcp",
+			headers: map[string]string{"X-API-Token": "tok"}, want: http.StatusOK},
+		{name: "bearer token", apiToken: "tok", path: "/api/mcp",
+			headers: map[string]string{"Authorization": "Bearer tok"}, want: http.StatusOK},
+		{name: "bearer scheme is case insensitive", apiToken: "tok", path: "/api/tasks",
+			headers: map[string]string{"Authorization": "bearer tok"}, want: http.StatusOK},
+		{name: "raw authorization token", apiToken: "tok", path: "/api/tasks",
+			headers: map[string]string{"Authorization": "tok"}, want: http.StatusUnauthorized},
+		{name: "other scheme", apiToken: "tok", path: "/api/tasks",
+			headers: map[string]string{"Authorization": "Basic tok"}, want: http.StatusUnauthorized},
+		{name: "wrong token", apiToken: "tok", path: "/api/tasks",
+			headers: map[string]string{"X-API-Token": "nope"}, want: http.StatusUnauthorized},
+		{name: "missing token", apiToken: "tok", path: "/api/tasks", want: http.StatusUnauthorized},
+		{name: "admin bearer token", adminToken: "adm", apiToken: "tok", path: "/api/mcp",
+			headers: map[string]string{"Authorization": "Bearer adm"}, want: http.StatusOK},
+		{name: "raw admin token", adminToken: "adm", apiToken: "tok", path: "/api/mcp",
+			headers: map[string]string{"Authorization": "adm"}, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(tt.adminToken, tt.apiToken)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiToken string
+		headers  map[string]string
+		want     string
+	}{
+		{name: "no token configured", want: "anonymous"},
+		{name: "api token", apiToken: "tok", headers: map[string]string{"Authorization": "Bearer tok"}, want: "api"},
+		{name: "admin token", apiToken: "tok", headers: map[string]string{"X-Admin-Token": "adm"}, want: "admin"},
+		{name: "admin without api token", headers: map[string]string{"Authorization": "Bearer adm"}, want: "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			var got string
+			r.Use(AdminToken("adm"), APIToken(tt.apiToken))
+			r.GET("/", func(c *gin.Context) { got = Identity(c) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/**
+ * 备份数据模型
+ * 定义数据备份任务生成的备份记录及恢复请求
+ */
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BackupSources 备份的数据来源
+type BackupSources struct {
+	Paths       []string `json:"paths,omitempty" bson:"paths,omitempty"`             // 本地目录（绝对路径）
+	Database    string   `json:"database,omitempty" bson:"database,omitempty"`       // MongoDB数据库
+	Collections []string `json:"collections,omitempty" bson:"collections,omitempty"` // MongoDB集合
+}
+
+// BackupRetention 备份保留策略，全部为0时保留所有备份
+type BackupRetention struct {
+	KeepLast int `json:"keep_last" bson:"keep_last"` // 保留最近的N个备份
+	Daily    int `json:"daily" bson:"daily"`         // 最近N天每天保留最新的一个
+	Weekly   int `json:"weekly" bson:"weekly"`       // 最近N周每周保留最新的一个
+}
+
+// BackupCollection 备份中的集合
+type BackupCollection struct {
+	Name      string `json:"name" bson:"name"`
+	Documents int64  `json:"documents" bson:"documents"`
+}
+
+// Backup 备份记录
+type Backup struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TaskID         primitive.ObjectID `json:"task_id" bson:"task_id"`
+	ExecutionLogID primitive.ObjectID `json:"execution_log_id" bson:"execution_log_id"`
+
+	// 归档
+	Name     string `json:"name" bson:"name"`         // 归档文件名
+	Checksum string `json:"checksum" bson:"checksum"` // 归档的SHA-256，也是产物存储地址
+	Size     int64  `json:"size" bson:"size"`         // 归档大小(字节)
+
+	// 内容
+	Sources     BackupSources      `json:"sources" bson:"sources"`
+	Files       int                `json:"files" bson:"files"` // 文件数
+	Collections []BackupCollection `json:"collections,omitempty" bson:"collections,omitempty"`
+
+	// 校验
+	Verified   bool       `json:"verified" bson:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// RestoreBackupRequest 恢复备份请求，target_dir和database至少指定一个
+type RestoreBackupRequest struct {
+	TargetDir string `json:"target_dir"` // 文件恢复到的目录，为空时不恢复文件
+	Database  string `json:"database"`   // 集合恢复到的数据库，为空时不恢复集合
+	Overwrite bool   `json:"overwrite"`  // 允许写入非空目录，并先删除已存在的集合
+}
+
+// RestoreBackupResult 恢复结果
+type RestoreBackupResult struct {
+	Files       int                `json:"files"`
+	Collections []BackupCollection `json:"collections"`
+}
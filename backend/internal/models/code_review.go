@@ -0,0 +1,38 @@
+/**
+ * 代码审查数据模型
+ * 定义代码审查任务的审查范围和模型给出的问题
+ */
+
+package models
+
+// ReviewSeverity 问题的严重程度
+type ReviewSeverity string
+
+const (
+	ReviewSeverityError   ReviewSeverity = "error"   // 错误
+	ReviewSeverityWarning ReviewSeverity = "warning" // 警告
+	ReviewSeverityInfo    ReviewSeverity = "info"    // 提示
+)
+
+// ReviewFinding 审查发现的问题
+type ReviewFinding struct {
+	File     string         `json:"file" bson:"file"`                     // 相对仓库根目录的路径
+	Line     int            `json:"line,omitempty" bson:"line,omitempty"` // 新版本中的行号，0表示整个文件
+	Severity ReviewSeverity `json:"severity" bson:"severity"`
+	Message  string         `json:"message" bson:"message"`
+	Rule     string         `json:"rule,omitempty" bson:"rule,omitempty"` // 问题类别，如security、bug
+}
+
+// CodeReview 一次执行的代码审查结果
+type CodeReview struct {
+	Base      string          `json:"base,omitempty" bson:"base,omitempty"` // 比较的基准提交，按修改时间收集文件时为空
+	Head      string          `json:"head,omitempty" bson:"head,omitempty"` // 审查的提交
+	Model     string          `json:"model" bson:"model"`
+	Files     []string        `json:"files" bson:"files"`                             // 审查的文件
+	Chunks    int             `json:"chunks" bson:"chunks"`                           // 发送给模型的分块数
+	Truncated bool            `json:"truncated,omitempty" bson:"truncated,omitempty"` // 变更过多，只审查了部分
+	Errors    int             `json:"errors" bson:"errors"`
+	Warnings  int             `json:"warnings" bson:"warnings"`
+	Infos     int             `json:"infos" bson:"infos"`
+	Findings  []ReviewFinding `json:"findings" bson:"findings"`
+}
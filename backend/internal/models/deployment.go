@@ -0,0 +1,33 @@
+/**
+ * 部署数据模型
+ * 定义部署任务记录的版本和各阶段结果
+ */
+
+package models
+
+// TriggerTypeRollback 回滚触发类型，触发数据中带有目标版本
+const TriggerTypeRollback = "rollback"
+
+// DeploymentPhase 部署阶段的结果
+type DeploymentPhase struct {
+	Name     string `json:"name" bson:"name"`                             // pre_check, deploy, health_check, rollback
+	Status   string `json:"status" bson:"status"`                         // completed, failed
+	Duration int64  `json:"duration" bson:"duration"`                     // 耗时(毫秒)
+	Attempts int    `json:"attempts,omitempty" bson:"attempts,omitempty"` // 健康检查的尝试次数
+	Error    string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// DeploymentInfo 一次部署的记录
+type DeploymentInfo struct {
+	Version         string            `json:"version" bson:"version"`                                       // 本次部署的版本
+	PreviousVersion string            `json:"previous_version,omitempty" bson:"previous_version,omitempty"` // 上一次成功部署的版本
+	RolledBack      bool              `json:"rolled_back" bson:"rolled_back"`                               // 健康检查失败后已执行回滚
+	RollbackOf      string            `json:"rollback_of,omitempty" bson:"rollback_of,omitempty"`           // 回滚触发时目标版本所在的执行日志ID
+	Phases          []DeploymentPhase `json:"phases" bson:"phases"`
+}
+
+// RollbackTaskRequest 回滚请求，未指定执行日志时回滚到上一个不同版本的成功部署
+type RollbackTaskRequest struct {
+	ExecutionLogID string `json:"execution_log_id"`
+	TriggeredBy    string `json:"triggered_by"`
+}
@@ -30,6 +30,7 @@ const (
 	ExecutionStatusFailed    ExecutionStatus = "failed"    // 失败
 	ExecutionStatusCancelled ExecutionStatus = "cancelled" // 取消
 	ExecutionStatusTimeout   ExecutionStatus = "timeout"   // 超时
+	ExecutionStatusWaiting   ExecutionStatus = "waiting"   // 工作流等待中，由调度器恢复
 )
 
 // LogEntry 日志条目
@@ -39,7 +40,12 @@ type LogEntry struct {
 	Message   string    `json:"message" bson:"message"`
 	Source    string    `json:"source" bson:"source"` // 日志来源（agent, system等）
 	StepID    string    `json:"step_id,omitempty" bson:"step_id,omitempty"`
+	Stream    string    `json:"stream,omitempty" bson:"stream,omitempty"` // 输出流（stdout, stderr）
 	Data      map[string]interface{} `json:"data,omitempty" bson:"data,omitempty"`
+
+	// 脱敏信息，命中的规则只对管理员返回
+	Redacted       bool     `json:"redacted,omitempty" bson:"redacted,omitempty"`
+	RedactionRules []string `json:"redaction_rules,omitempty" bson:"redaction_rules,omitempty"`
 }
 
 // PerformanceMetrics 性能指标
@@ -48,9 +54,32 @@ type PerformanceMetrics struct {
 	MemoryUsage float64 `json:"memory_usage" bson:"memory_usage"` // 内存使用率 (%)
 	DiskIO      float64 `json:"disk_io" bson:"disk_io"`           // 磁盘IO (MB/s)
 	NetworkIO   float64 `json:"network_io" bson:"network_io"`     // 网络IO (MB/s)
+	MemoryRSS   int64   `json:"memory_rss,omitempty" bson:"memory_rss,omitempty"` // 常驻内存 (字节)
 	Timestamp   time.Time `json:"timestamp" bson:"timestamp"`
 }
 
+// MetricsSummary 执行期间性能指标汇总
+type MetricsSummary struct {
+	Samples       int     `json:"samples" bson:"samples"`
+	PeakCPU       float64 `json:"peak_cpu" bson:"peak_cpu"`
+	AvgCPU        float64 `json:"avg_cpu" bson:"avg_cpu"`
+	PeakMemoryRSS int64   `json:"peak_memory_rss" bson:"peak_memory_rss"`
+	AvgMemoryRSS  int64   `json:"avg_memory_rss" bson:"avg_memory_rss"`
+	PeakDiskIO    float64 `json:"peak_disk_io" bson:"peak_disk_io"`
+	AvgDiskIO     float64 `json:"avg_disk_io" bson:"avg_disk_io"`
+	PeakNetworkIO float64 `json:"peak_network_io" bson:"peak_network_io"`
+	AvgNetworkIO  float64 `json:"avg_network_io" bson:"avg_network_io"`
+}
+
+// Artifact 执行产物
+type Artifact struct {
+	Name        string    `json:"name" bson:"name"`         // 产物名称（相对工作目录的路径）
+	Size        int64     `json:"size" bson:"size"`         // 大小(字节)
+	Checksum    string    `json:"checksum" bson:"checksum"` // SHA-256摘要，也是存储地址
+	ContentType string    `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
 // ExecutionResult 执行结果
 type ExecutionResult struct {
 	Success    bool                   `json:"success" bson:"success"`
@@ -58,7 +87,26 @@ type ExecutionResult struct {
 	Error      string                 `json:"error,omitempty" bson:"error,omitempty"`
 	ExitCode   int                    `json:"exit_code" bson:"exit_code"`
 	Data       map[string]interface{} `json:"data,omitempty" bson:"data,omitempty"`
-	Artifacts  []string               `json:"artifacts,omitempty" bson:"artifacts,omitempty"` // 生成的文件路径
+	Artifacts  []Artifact             `json:"artifacts,omitempty" bson:"artifacts,omitempty"` // 生成的产物
+	Metrics    *MetricsSummary        `json:"metrics,omitempty" bson:"metrics,omitempty"`     // 性能指标汇总
+	Tests      *TestReport            `json:"tests,omitempty" bson:"tests,omitempty"`         // 测试报告
+	Deployment *DeploymentInfo        `json:"deployment,omitempty" bson:"deployment,omitempty"` // 部署记录
+	Review     *CodeReview            `json:"review,omitempty" bson:"review,omitempty"`         // 代码审查结果
+
+	// 脱敏信息，命中的规则只对管理员返回
+	Redacted       bool     `json:"redacted,omitempty" bson:"redacted,omitempty"`
+	RedactionRules []string `json:"redaction_rules,omitempty" bson:"redaction_rules,omitempty"`
+}
+
+// TriggerInfo 执行的触发信息
+type TriggerInfo struct {
+	Type          string                 `json:"type" bson:"type"`                                         // manual, scheduled, webhook等
+	By            string                 `json:"by,omitempty" bson:"by,omitempty"`                         // 触发者
+	ScheduledTime *time.Time             `json:"scheduled_time,omitempty" bson:"scheduled_time,omitempty"` // 计划运行时间
+	Payload       map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`               // 触发时附带的数据
+
+	// 预先分配的执行日志ID，调用方需要在执行开始前得到ID时设置，为空时由执行器生成
+	LogID primitive.ObjectID `json:"-" bson:"-"`
 }
 
 // ExecutionLog 执行日志模型
@@ -84,6 +132,9 @@ type ExecutionLog struct {
 	WorkflowVersion  string              `json:"workflow_version,omitempty" bson:"workflow_version,omitempty"`
 	CurrentStepID    string              `json:"current_step_id,omitempty" bson:"current_step_id,omitempty"`
 	CompletedSteps   []string            `json:"completed_steps" bson:"completed_steps"`
+
+	// 工作区检出的提交
+	CommitSHA string `json:"commit_sha,omitempty" bson:"commit_sha,omitempty"`
 	
 	// 日志和结果
 	Logs        []LogEntry         `json:"logs" bson:"logs"`
@@ -91,8 +142,14 @@ type ExecutionLog struct {
 	Metrics     []PerformanceMetrics `json:"metrics" bson:"metrics"`
 	
 	// 触发信息
-	TriggerType string `json:"trigger_type" bson:"trigger_type"` // manual, scheduled, webhook等
-	TriggerBy   string `json:"trigger_by,omitempty" bson:"trigger_by,omitempty"`
+	TriggerType    string                 `json:"trigger_type" bson:"trigger_type"` // manual, scheduled, webhook等
+	TriggerBy      string                 `json:"trigger_by,omitempty" bson:"trigger_by,omitempty"`
+	ScheduledTime  *time.Time             `json:"scheduled_time,omitempty" bson:"scheduled_time,omitempty"`
+	TriggerPayload map[string]interface{} `json:"trigger_payload,omitempty" bson:"trigger_payload,omitempty"`
+	
+	// 取消信息
+	CancelledBy string `json:"cancelled_by,omitempty" bson:"cancelled_by,omitempty"` // 认证身份：admin、api或anonymous
+	CancelLabel string `json:"cancel_label,omitempty" bson:"cancel_label,omitempty"` // 调用方自报的说明（如操作人），只作记录
 	
 	// 重试信息
 	RetryCount    int    `json:"retry_count" bson:"retry_count"`
@@ -124,6 +181,13 @@ type UpdateExecutionLogRequest struct {
 	CompletedAt    *time.Time            `json:"completed_at,omitempty"`
 }
 
+// CancelExecutionRequest 取消执行请求
+// 取消者由请求的认证身份决定，label只作记录
+type CancelExecutionRequest struct {
+	Label  string `json:"label,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // AddLogEntryRequest 添加日志条目请求
 type AddLogEntryRequest struct {
 	Level   LogLevel               `json:"level" binding:"required"`
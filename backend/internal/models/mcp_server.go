@@ -0,0 +1,85 @@
+/**
+ * MCP服务端数据模型
+ * 登记外部MCP服务端的连接配置，以及定期发现的工具列表
+ */
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MCPServer 已登记的外部MCP服务端，mcp_tool任务和工作流步骤按名称引用
+type MCPServer struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	Enabled     bool               `json:"enabled" bson:"enabled"`
+
+	// 连接配置：stdio服务端使用command/args/env/dir，websocket和http服务端使用url/headers
+	Transport string            `json:"transport" bson:"transport"`
+	Command   string            `json:"command,omitempty" bson:"command,omitempty"`
+	Args      []string          `json:"args,omitempty" bson:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty" bson:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty" bson:"dir,omitempty"`
+	URL       string            `json:"url,omitempty" bson:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty" bson:"headers,omitempty"`
+	Auth      *MCPServerAuth    `json:"auth,omitempty" bson:"auth,omitempty"`
+
+	// 工具发现结果，发现失败时保留上一次的工具列表
+	Tools          []MCPToolInfo `json:"tools" bson:"tools"`
+	ToolsUpdatedAt *time.Time    `json:"tools_updated_at,omitempty" bson:"tools_updated_at,omitempty"`
+	LastCheckedAt  *time.Time    `json:"last_checked_at,omitempty" bson:"last_checked_at,omitempty"`
+	LastError      string        `json:"last_error,omitempty" bson:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// MCPServerAuth websocket和http服务端的认证，令牌和密码可以使用secret://引用
+type MCPServerAuth struct {
+	Type     string `json:"type" bson:"type"`                             // bearer, basic, header
+	Token    string `json:"token,omitempty" bson:"token,omitempty"`       // bearer和header认证的令牌
+	Header   string `json:"header,omitempty" bson:"header,omitempty"`     // header认证的请求头名称
+	Username string `json:"username,omitempty" bson:"username,omitempty"` // basic认证
+	Password string `json:"password,omitempty" bson:"password,omitempty"`
+}
+
+// MCPToolInfo 发现的工具，inputSchema以JSON原文保存（其中的$schema等键不能作为文档字段名）
+type MCPToolInfo struct {
+	Name        string          `json:"name" bson:"name"`
+	Description string          `json:"description,omitempty" bson:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty" bson:"input_schema,omitempty"`
+}
+
+// CreateMCPServerRequest 登记MCP服务端请求
+type CreateMCPServerRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Enabled     *bool             `json:"enabled"` // 默认启用
+	Transport   string            `json:"transport"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args"`
+	Env         map[string]string `json:"env"`
+	Dir         string            `json:"dir"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+	Auth        *MCPServerAuth    `json:"auth"`
+}
+
+// UpdateMCPServerRequest 更新MCP服务端请求
+type UpdateMCPServerRequest struct {
+	Description *string            `json:"description,omitempty"`
+	Enabled     *bool              `json:"enabled,omitempty"`
+	Transport   *string            `json:"transport,omitempty"`
+	Command     *string            `json:"command,omitempty"`
+	Args        *[]string          `json:"args,omitempty"`
+	Env         *map[string]string `json:"env,omitempty"`
+	Dir         *string            `json:"dir,omitempty"`
+	URL         *string            `json:"url,omitempty"`
+	Headers     *map[string]string `json:"headers,omitempty"`
+	Auth        *MCPServerAuth     `json:"auth,omitempty"`
+}
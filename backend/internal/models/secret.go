@@ -0,0 +1,41 @@
+/**
+ * 密钥数据模型
+ * 定义密钥相关的数据结构，密钥值只以密文形式保存且不会出现在API响应中
+ */
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Secret 密钥模型
+type Secret struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+
+	// 加密后的值（AES-256-GCM），不参与JSON序列化
+	Ciphertext []byte `json:"-" bson:"ciphertext"`
+	Nonce      []byte `json:"-" bson:"nonce"`
+
+	CreatedBy string    `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CreateSecretRequest 创建密钥请求
+type CreateSecretRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Value       string `json:"value" binding:"required"`
+	Description string `json:"description"`
+	CreatedBy   string `json:"created_by"`
+}
+
+// UpdateSecretRequest 更新密钥请求
+type UpdateSecretRequest struct {
+	Value       *string `json:"value,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
@@ -31,6 +31,11 @@ const (
 	TaskTypeDeployment   TaskType = "deployment"    // 部署
 	TaskTypeDataBackup   TaskType = "data_backup"   // 数据备份
 	TaskTypeCustom       TaskType = "custom"        // 自定义
+	TaskTypeScript       TaskType = "script"        // 脚本
+	TaskTypeAPI          TaskType = "api"           // API调用
+	TaskTypeWorkflow     TaskType = "workflow"      // 工作流
+	TaskTypeAgent        TaskType = "agent"         // Agent
+	TaskTypeMCPTool      TaskType = "mcp_tool"      // MCP工具调用
 )
 
 // CronConfig Cron配置
@@ -46,6 +51,9 @@ type AgentConfig struct {
 	Parameters map[string]interface{} `json:"parameters" bson:"parameters"`   // 执行参数
 	Timeout    int                    `json:"timeout" bson:"timeout"`         // 超时时间(秒)
 	Retries    int                    `json:"retries" bson:"retries"`         // 重试次数
+
+	// 严格模板模式：参数模板引用不存在的键时执行失败，否则渲染为空
+	StrictTemplates bool `json:"strict_templates" bson:"strict_templates"`
 }
 
 // ExecutionEnvironment 执行环境配置
@@ -53,6 +61,17 @@ type ExecutionEnvironment struct {
 	WorkingDirectory string            `json:"working_directory" bson:"working_directory"` // 工作目录
 	EnvironmentVars  map[string]string `json:"environment_vars" bson:"environment_vars"`   // 环境变量
 	ResourceLimits   ResourceLimits    `json:"resource_limits" bson:"resource_limits"`     // 资源限制
+
+	// Git工作区，配置后在执行前检出仓库，工作目录为相对检出目录的路径
+	Workspace *WorkspaceSpec `json:"workspace,omitempty" bson:"workspace,omitempty"`
+}
+
+// WorkspaceSpec Git工作区配置
+type WorkspaceSpec struct {
+	Repo    string   `json:"repo" bson:"repo"`                         // 仓库URL或本地路径（可以是裸仓库）
+	Ref     string   `json:"ref" bson:"ref"`                           // 分支、标签或提交，默认HEAD
+	Shallow bool     `json:"shallow" bson:"shallow"`                   // 只检出单个提交，不拉取历史
+	Sparse  []string `json:"sparse,omitempty" bson:"sparse,omitempty"` // 稀疏检出的目录
 }
 
 // ResourceLimits 资源限制
@@ -119,6 +138,28 @@ type UpdateTaskRequest struct {
 	WorkflowID  *primitive.ObjectID   `json:"workflow_id,omitempty"`
 }
 
+// ExecuteTaskRequest 立即执行任务请求
+type ExecuteTaskRequest struct {
+	TriggeredBy string                 `json:"triggered_by"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+}
+
+// SchedulePreviewRequest 调度时间预览请求
+type SchedulePreviewRequest struct {
+	CronConfig CronConfig `json:"cron_config" binding:"required"`
+	Count      int        `json:"count"` // 预览次数，默认5，最多100
+}
+
+// RenderPreviewRequest 参数模板预览请求
+type RenderPreviewRequest struct {
+	TaskID     string                            `json:"task_id,omitempty"`    // 使用已有任务的数据和参数
+	Template   string                            `json:"template,omitempty"`   // 单个模板字符串
+	Parameters map[string]interface{}            `json:"parameters,omitempty"` // 待渲染参数，为空时使用任务参数
+	Trigger    TriggerInfo                       `json:"trigger"`              // 模拟的触发信息
+	Steps      map[string]map[string]interface{} `json:"steps,omitempty"`      // 模拟的步骤输出，按步骤ID索引
+	Strict     *bool                             `json:"strict,omitempty"`     // 为空时使用任务的strict_templates
+}
+
 // TaskListResponse 任务列表响应
 type TaskListResponse struct {
 	Tasks      []Task     `json:"tasks"`
@@ -0,0 +1,47 @@
+/**
+ * 测试报告数据模型
+ * 定义自动测试任务解析出的测试结果和结果对比
+ */
+
+package models
+
+// TestStatus 单个测试的结果
+type TestStatus string
+
+const (
+	TestStatusPassed  TestStatus = "passed"  // 通过
+	TestStatusFailed  TestStatus = "failed"  // 失败
+	TestStatusSkipped TestStatus = "skipped" // 跳过
+)
+
+// TestResult 单个测试的结果
+type TestResult struct {
+	Suite    string     `json:"suite,omitempty" bson:"suite,omitempty"`     // 所属包、类或测试文件
+	Name     string     `json:"name" bson:"name"`                           // 测试名称
+	Status   TestStatus `json:"status" bson:"status"`                       // 结果
+	Duration int64      `json:"duration" bson:"duration"`                   // 耗时(毫秒)
+	Message  string     `json:"message,omitempty" bson:"message,omitempty"` // 失败或跳过的原因
+}
+
+// TestReport 一次执行的测试报告
+type TestReport struct {
+	Format    string       `json:"format" bson:"format"` // go_json, junit, tap
+	Total     int          `json:"total" bson:"total"`
+	Passed    int          `json:"passed" bson:"passed"`
+	Failed    int          `json:"failed" bson:"failed"`
+	Skipped   int          `json:"skipped" bson:"skipped"`
+	Duration  int64        `json:"duration" bson:"duration"`                       // 总耗时(毫秒)
+	Truncated bool         `json:"truncated,omitempty" bson:"truncated,omitempty"` // 结果过多时只保留部分（失败的优先保留）
+	Results   []TestResult `json:"results" bson:"results"`
+}
+
+// TestComparison 两次执行的测试结果对比
+type TestComparison struct {
+	BaseExecutionID string       `json:"base_execution_id"`
+	HeadExecutionID string       `json:"head_execution_id"`
+	NewFailures     []TestResult `json:"new_failures"`  // 本次失败、上次通过或不存在的测试
+	Fixed           []TestResult `json:"fixed"`         // 上次失败、本次通过的测试
+	StillFailing    []TestResult `json:"still_failing"` // 两次都失败的测试
+	Added           []TestResult `json:"added"`         // 本次新增的测试
+	Removed         []TestResult `json:"removed"`       // 本次不再出现的测试
+}
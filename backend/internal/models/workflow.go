@@ -50,6 +50,7 @@ const (
 	StepStatusCompleted StepStatus = "completed" // 已完成
 	StepStatusFailed    StepStatus = "failed"    // 失败
 	StepStatusSkipped   StepStatus = "skipped"   // 跳过
+	StepStatusWaiting   StepStatus = "waiting"   // 等待中（工作流挂起，由调度器恢复）
 )
 
 // WorkflowStep 工作流步骤
@@ -74,10 +75,51 @@ type WorkflowStep struct {
 	Retries     int  `json:"retries" bson:"retries"`
 	ContinueOnError bool `json:"continue_on_error" bson:"continue_on_error"`
 	
+	// 循环和等待配置
+	Loop *LoopConfig `json:"loop,omitempty" bson:"loop,omitempty"`
+	Wait *WaitConfig `json:"wait,omitempty" bson:"wait,omitempty"`
+	
+	// 声明的输出，步骤成功后提取，供后续步骤的参数和条件引用
+	Outputs []StepOutput `json:"outputs,omitempty" bson:"outputs,omitempty"`
+	
 	// 位置信息（用于可视化编辑器）
 	Position Position `json:"position" bson:"position"`
 }
 
+// OutputSource 步骤输出的来源
+type OutputSource string
+
+const (
+	OutputSourceStdout   OutputSource = "stdout"   // JSON格式的标准输出
+	OutputSourceFile     OutputSource = "file"     // 工作目录中的文件
+	OutputSourceResponse OutputSource = "response" // 模型回复（agent动作）
+)
+
+// StepOutput 步骤声明的输出
+type StepOutput struct {
+	Name string       `json:"name" bson:"name"`
+	From OutputSource `json:"from" bson:"from"`
+	File string       `json:"file,omitempty" bson:"file,omitempty"` // from为file时相对工作目录的路径
+	Path string       `json:"path,omitempty" bson:"path,omitempty"` // JSONPath，如$.version，为空时取整个值
+}
+
+// LoopConfig 循环步骤配置，items和while二选一，每次迭代执行步骤的动作
+type LoopConfig struct {
+	Items         string `json:"items,omitempty" bson:"items,omitempty"`                   // for-each：结果为列表的表达式
+	While         string `json:"while,omitempty" bson:"while,omitempty"`                   // while：每次迭代前计算的条件
+	Parallelism   int    `json:"parallelism,omitempty" bson:"parallelism,omitempty"`       // for-each同时执行的迭代数，默认1
+	MaxIterations int    `json:"max_iterations,omitempty" bson:"max_iterations,omitempty"` // 迭代次数上限，默认100
+}
+
+// WaitConfig 等待步骤配置，duration、until、signal和condition四选一
+type WaitConfig struct {
+	Duration     string `json:"duration,omitempty" bson:"duration,omitempty"`           // 固定时长，如30m
+	Until        string `json:"until,omitempty" bson:"until,omitempty"`                 // 等到指定时间(RFC3339)，支持参数模板
+	Signal       string `json:"signal,omitempty" bson:"signal,omitempty"`               // 等到收到指定名称的外部信号
+	Condition    string `json:"condition,omitempty" bson:"condition,omitempty"`         // 等到表达式成立
+	PollInterval int    `json:"poll_interval,omitempty" bson:"poll_interval,omitempty"` // condition的检查间隔(秒)，默认60
+}
+
 // Position 步骤位置
 type Position struct {
 	X int `json:"x" bson:"x"`
@@ -153,6 +195,7 @@ type WorkflowExecution struct {
 	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	WorkflowID primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
 	TaskID     primitive.ObjectID `json:"task_id" bson:"task_id"`
+	ExecutionLogID primitive.ObjectID `json:"execution_log_id" bson:"execution_log_id"` // 所属任务执行的执行日志
 	
 	// 执行状态
 	Status     StepStatus `json:"status" bson:"status"`
@@ -169,6 +212,49 @@ type WorkflowExecution struct {
 	
 	// 错误信息
 	Error string `json:"error,omitempty" bson:"error,omitempty"`
+	
+	// 挂起信息：等待步骤的记录、收到的信号和恢复所需的调度状态
+	Waits    map[string]WorkflowWait   `json:"waits,omitempty" bson:"waits,omitempty"`
+	Signals  map[string]WorkflowSignal `json:"signals,omitempty" bson:"signals,omitempty"`
+	ResumeAt *time.Time                `json:"resume_at,omitempty" bson:"resume_at,omitempty"` // 调度器下次检查的时间，只等待信号时为空
+	State    *WorkflowState            `json:"state,omitempty" bson:"state,omitempty"`
+
+	// 开始执行时的工作流定义，挂起后按此恢复，不受之后修改工作流的影响
+	Definition *WorkflowDefinition `json:"definition,omitempty" bson:"definition,omitempty"`
+}
+
+// WorkflowDefinition 工作流定义快照
+type WorkflowDefinition struct {
+	Name        string               `json:"name" bson:"name"`
+	Type        WorkflowType         `json:"type" bson:"type"`
+	Version     string               `json:"version" bson:"version"`
+	Steps       []WorkflowStep       `json:"steps" bson:"steps"`
+	Connections []WorkflowConnection `json:"connections" bson:"connections"`
+	StartStep   string               `json:"start_step" bson:"start_step"`
+}
+
+// WorkflowWait 等待中的步骤
+type WorkflowWait struct {
+	Kind      string     `json:"kind" bson:"kind"` // duration, until, signal, condition
+	Signal    string     `json:"signal,omitempty" bson:"signal,omitempty"`
+	Until     *time.Time `json:"until,omitempty" bson:"until,omitempty"`         // duration和until的目标时间
+	Deadline  *time.Time `json:"deadline,omitempty" bson:"deadline,omitempty"`   // 步骤超时的时间
+	ResumeAt  *time.Time `json:"resume_at,omitempty" bson:"resume_at,omitempty"` // 下次检查的时间
+	StartedAt time.Time  `json:"started_at" bson:"started_at"`
+}
+
+// WorkflowSignal 发送给工作流执行的外部信号
+type WorkflowSignal struct {
+	Payload    map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+	ReceivedAt time.Time              `json:"received_at" bson:"received_at"`
+}
+
+// WorkflowState 挂起时的调度状态，恢复后从这里继续
+type WorkflowState struct {
+	Pending   map[string]int  `json:"pending" bson:"pending"`
+	Activated map[string]bool `json:"activated" bson:"activated"`
+	Failure   string          `json:"failure,omitempty" bson:"failure,omitempty"`
+	Artifacts []Artifact      `json:"artifacts,omitempty" bson:"artifacts,omitempty"` // 挂起前步骤产生的产物
 }
 
 // WorkflowListResponse 工作流列表响应
@@ -0,0 +1,240 @@
+/**
+ * 日志脱敏
+ * 在日志条目和执行结果写入、推送之前，替换已知密钥值和匹配敏感信息规则的文本
+ */
+
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+	"aischedule/internal/secrets"
+)
+
+const (
+	// Mask 替换敏感信息的掩码
+	Mask = secrets.Mask
+	// RuleSecretValue 已知密钥值命中时记录的规则名
+	RuleSecretValue = "secret_value"
+	// secretGroup 规则中只替换该命名分组，未定义时替换整个匹配
+	secretGroup = "secret"
+)
+
+// Rule 脱敏规则
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ruleConfig 规则文件中的规则定义
+type ruleConfig struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultRules 内置规则
+func DefaultRules() []Rule {
+	return []Rule{
+		{"aws_access_key_id", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+		{"aws_secret_access_key", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`)},
+		{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+		{"bearer_token", regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{8,}=*)`)},
+		{"url_credentials", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`)},
+		{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	}
+}
+
+// LoadRules 从JSON文件加载规则，文件内容为 [{"name": "...", "pattern": "..."}]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []ruleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid redaction rules file: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("redaction rule name is required")
+		}
+		pattern, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for redaction rule %s: %w", cfg.Name, err)
+		}
+		rules = append(rules, Rule{Name: cfg.Name, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// Redactor 脱敏器
+type Redactor struct {
+	rules []Rule
+}
+
+// New 创建脱敏器
+func New(rules []Rule) *Redactor {
+	return &Redactor{rules: rules}
+}
+
+// String 对字符串脱敏，返回结果和命中的规则
+func (r *Redactor) String(s string, known *secrets.Masker) (string, []string) {
+	var fired []string
+
+	if masked := known.MaskString(s); masked != s {
+		s = masked
+		fired = append(fired, RuleSecretValue)
+	}
+
+	for _, rule := range r.rules {
+		replaced := replaceMatches(rule.Pattern, s)
+		if replaced != s {
+			s = replaced
+			fired = append(fired, rule.Name)
+		}
+	}
+	return s, fired
+}
+
+// Value 对任意值中的字符串递归脱敏，返回新的值和命中的规则
+func (r *Redactor) Value(value interface{}, known *secrets.Masker) (interface{}, []string) {
+	var fired []string
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			masked, rules := r.String(v, known)
+			fired = append(fired, rules...)
+			return masked
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(v))
+			for key, item := range v {
+				out[key] = walk(item)
+			}
+			return out
+		case map[string]string:
+			out := make(map[string]string, len(v))
+			for key, item := range v {
+				out[key] = walk(item).(string)
+			}
+			return out
+		case primitive.A:
+			return walk([]interface{}(v))
+		case []interface{}:
+			out := make([]interface{}, len(v))
+			for i, item := range v {
+				out[i] = walk(item)
+			}
+			return out
+		case []string:
+			out := make([]string, len(v))
+			for i, item := range v {
+				out[i] = walk(item).(string)
+			}
+			return out
+		default:
+			return v
+		}
+	}
+	return walk(value), fired
+}
+
+// Entry 对日志条目脱敏，命中时标记Redacted并记录规则
+func (r *Redactor) Entry(entry *models.LogEntry, known *secrets.Masker) {
+	message, fired := r.String(entry.Message, known)
+	entry.Message = message
+
+	if entry.Data != nil {
+		data, rules := r.Value(entry.Data, known)
+		entry.Data = data.(map[string]interface{})
+		fired = append(fired, rules...)
+	}
+
+	if len(fired) > 0 {
+		entry.Redacted = true
+		entry.RedactionRules = unique(entry.RedactionRules, fired)
+	}
+}
+
+// Result 对执行结果中的文本脱敏，返回命中的规则
+func (r *Redactor) Result(result *models.ExecutionResult, known *secrets.Masker) []string {
+	var fired, rules []string
+	result.Output, rules = r.String(result.Output, known)
+	fired = append(fired, rules...)
+	result.Error, rules = r.String(result.Error, known)
+	fired = append(fired, rules...)
+	if result.Data != nil {
+		var data interface{}
+		data, rules = r.Value(result.Data, known)
+		result.Data = data.(map[string]interface{})
+		fired = append(fired, rules...)
+	}
+	if result.Tests != nil {
+		for i := range result.Tests.Results {
+			result.Tests.Results[i].Message, rules = r.String(result.Tests.Results[i].Message, known)
+			fired = append(fired, rules...)
+		}
+	}
+	if result.Review != nil {
+		for i := range result.Review.Findings {
+			result.Review.Findings[i].Message, rules = r.String(result.Review.Findings[i].Message, known)
+			fired = append(fired, rules...)
+		}
+	}
+	return unique(nil, fired)
+}
+
+// replaceMatches 替换所有匹配，规则定义了secret分组时只替换该分组
+func replaceMatches(pattern *regexp.Regexp, s string) string {
+	matches := pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	group := pattern.SubexpIndex(secretGroup)
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if group > 0 && match[2*group] >= 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		out.WriteString(s[last:start])
+		out.WriteString(Mask)
+		last = end
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// unique 合并去重并排序
+func unique(existing, added []string) []string {
+	set := make(map[string]struct{}, len(existing)+len(added))
+	for _, name := range existing {
+		set[name] = struct{}{}
+	}
+	for _, name := range added {
+		set[name] = struct{}{}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
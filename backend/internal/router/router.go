@@ -6,22 +6,21 @@
 package router
 
 import (
+	"aischedule/internal/config"
 	"aischedule/internal/database"
+	"aischedule/internal/executor"
 	"aischedule/internal/handlers"
+	"aischedule/internal/mcp"
 	"aischedule/internal/middleware"
 	"aischedule/internal/scheduler"
 	"aischedule/internal/websocket"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Setup 设置路由
-func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *websocket.Manager) *gin.Engine {
-	// 创建MongoDB包装器
-	mongodb := &database.MongoDB{}
-	mongodb.SetDatabase(db)
+func Setup(cfg *config.Config, mongodb *database.MongoDB, taskScheduler *scheduler.Scheduler, taskExecutor *executor.DefaultTaskExecutor, wsManager *websocket.Manager) *gin.Engine {
 	r := gin.Default()
 
 	// CORS中间件
@@ -31,7 +30,8 @@ func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *we
 		"http://localhost:3000",
 	}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Token", "X-Admin-Token", mcp.SessionHeader}
+	config.ExposeHeaders = []string{mcp.SessionHeader}
 	config.AllowCredentials = true
 	r.Use(cors.New(config))
 
@@ -41,11 +41,18 @@ func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *we
 	// 错误处理中间件
 	r.Use(middleware.ErrorHandler())
 
+	// 管理员识别中间件
+	r.Use(middleware.AdminToken(cfg.AdminToken))
+
 	// 初始化处理器
-	taskHandler := handlers.NewTaskHandler(mongodb, taskScheduler)
-	workflowHandler := handlers.NewWorkflowHandler(mongodb)
-	executionLogHandler := handlers.NewExecutionLogHandler(mongodb)
-	systemHandler := handlers.NewSystemHandler(mongodb, taskScheduler, wsManager)
+	taskHandler := handlers.NewTaskHandler(mongodb, taskScheduler, taskExecutor.MCPServers())
+	workflowHandler := handlers.NewWorkflowHandler(mongodb, taskExecutor.MCPServers())
+	executionLogHandler := handlers.NewExecutionLogHandler(mongodb, taskExecutor, wsManager)
+	secretHandler := handlers.NewSecretHandler(taskExecutor.Secrets())
+	backupHandler := handlers.NewBackupHandler(taskExecutor.Backups())
+	mcpServerHandler := handlers.NewMCPServerHandler(taskExecutor.MCPServers())
+	systemHandler := handlers.NewSystemHandler(mongodb, taskScheduler, taskExecutor, wsManager)
+	mcpHandler := handlers.NewMCPHandler(mongodb, taskScheduler, taskExecutor.MCPServers(), wsManager, "/api/v1/mcp/messages", cfg.MCPSessionTimeout)
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -58,16 +65,30 @@ func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *we
 
 	// API路由组
 	api := r.Group("/api/v1")
+	api.Use(middleware.APIToken(cfg.APIToken))
 	{
+		// MCP路由：Streamable HTTP，以及旧版HTTP+SSE；未配置API令牌时只接受管理员令牌
+		mcp := api.Group("/mcp", middleware.RequireToken(cfg.APIToken))
+		{
+			mcp.POST("", mcpHandler.Streamable)
+			mcp.GET("", mcpHandler.Streamable)
+			mcp.DELETE("", mcpHandler.Streamable)
+			mcp.GET("/sse", mcpHandler.SSE)
+			mcp.POST("/messages", mcpHandler.Messages)
+		}
+
 		// 任务管理路由
 		tasks := api.Group("/tasks")
 		{
 			tasks.GET("", taskHandler.GetTasks)
 			tasks.POST("", taskHandler.CreateTask)
+			tasks.POST("/render-preview", taskHandler.RenderPreview)
+			tasks.POST("/schedule-preview", taskHandler.PreviewSchedule)
 			tasks.GET("/:id", taskHandler.GetTask)
 			tasks.PUT("/:id", taskHandler.UpdateTask)
 			tasks.DELETE("/:id", taskHandler.DeleteTask)
 			tasks.POST("/:id/execute", taskHandler.ExecuteTask)
+			tasks.POST("/:id/rollback", taskHandler.RollbackTask)
 			tasks.POST("/:id/start", taskHandler.StartTask)
 			tasks.POST("/:id/stop", taskHandler.StopTask)
 		}
@@ -80,6 +101,8 @@ func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *we
 			workflows.GET("/:id", workflowHandler.GetWorkflow)
 			workflows.PUT("/:id", workflowHandler.UpdateWorkflow)
 			workflows.DELETE("/:id", workflowHandler.DeleteWorkflow)
+			workflows.GET("/:id/executions", workflowHandler.GetWorkflowExecution)
+			workflows.POST("/:id/executions/:execution_id/signals/:name", workflowHandler.SignalWorkflowExecution)
 		}
 
 		// 执行日志路由
@@ -91,9 +114,46 @@ func Setup(db *mongo.Database, taskScheduler *scheduler.Scheduler, wsManager *we
 			logs.PUT("/:id", executionLogHandler.UpdateExecutionLog)
 			logs.POST("/:id/entries", executionLogHandler.AddLogEntry)
 			logs.POST("/:id/metrics", executionLogHandler.AddPerformanceMetric)
+			logs.POST("/:id/cancel", executionLogHandler.CancelExecution)
+			logs.GET("/:id/artifacts/*name", executionLogHandler.GetArtifact)
+			logs.GET("/:id/tests", executionLogHandler.GetTestResults)
+			logs.GET("/:id/tests/compare", executionLogHandler.CompareTestResults)
+			logs.GET("/:id/review", executionLogHandler.GetCodeReview)
+			logs.GET("/:id/review/sarif", executionLogHandler.ExportCodeReviewSARIF)
 			logs.DELETE("/:id", executionLogHandler.DeleteExecutionLog)
 		}
 
+		// 密钥管理路由（响应中不包含密钥的值）
+		secrets := api.Group("/secrets", middleware.RequireToken(cfg.APIToken))
+		{
+			secrets.GET("", secretHandler.GetSecrets)
+			secrets.POST("", secretHandler.CreateSecret)
+			secrets.GET("/:name", secretHandler.GetSecret)
+			secrets.PUT("/:name", secretHandler.UpdateSecret)
+			secrets.DELETE("/:name", secretHandler.DeleteSecret)
+		}
+
+		// 备份相关路由
+		backups := api.Group("/backups")
+		{
+			backups.GET("", backupHandler.GetBackups)
+			backups.GET("/:id", backupHandler.GetBackup)
+			backups.POST("/:id/verify", backupHandler.VerifyBackup)
+			backups.POST("/:id/restore", backupHandler.RestoreBackup)
+			backups.DELETE("/:id", backupHandler.DeleteBackup)
+		}
+
+		// MCP服务端登记路由（响应中的明文令牌和密码已遮盖）
+		mcpServers := api.Group("/mcp-servers")
+		{
+			mcpServers.GET("", mcpServerHandler.GetMCPServers)
+			mcpServers.POST("", mcpServerHandler.CreateMCPServer)
+			mcpServers.GET("/:name", mcpServerHandler.GetMCPServer)
+			mcpServers.PUT("/:name", mcpServerHandler.UpdateMCPServer)
+			mcpServers.DELETE("/:name", mcpServerHandler.DeleteMCPServer)
+			mcpServers.POST("/:name/refresh", mcpServerHandler.RefreshMCPServer)
+		}
+
 		// 系统管理路由
 		system := api.Group("/system")
 		{
@@ -0,0 +1,66 @@
+/**
+ * 调度表达式
+ * 解析带秒字段和时区的Cron表达式，并预览之后的运行时间
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"aischedule/internal/models"
+)
+
+// maxPreviewCount 预览的最多运行次数
+const maxPreviewCount = 100
+
+// scheduleParser 与调度器使用相同字段的解析器：秒 分 时 日 月 周，支持@daily等描述符
+var scheduleParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// scheduleSpec 调度器使用的表达式，配置了时区时加上CRON_TZ前缀
+func scheduleSpec(cfg models.CronConfig) string {
+	if cfg.Timezone == "" {
+		return cfg.Expression
+	}
+	return "CRON_TZ=" + cfg.Timezone + " " + cfg.Expression
+}
+
+// ParseSchedule 解析任务的调度配置
+func ParseSchedule(cfg models.CronConfig) (cron.Schedule, error) {
+	if cfg.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+	return scheduleParser.Parse(scheduleSpec(cfg))
+}
+
+// Preview 计算from之后的count次运行时间
+func Preview(cfg models.CronConfig, from time.Time, count int) ([]time.Time, error) {
+	schedule, err := ParseSchedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = 5
+	}
+	if count > maxPreviewCount {
+		count = maxPreviewCount
+	}
+
+	runs := make([]time.Time, 0, count)
+	next := from
+	for len(runs) < count {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
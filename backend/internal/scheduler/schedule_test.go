@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t.UTC()
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  models.CronConfig
+		from string
+		want []string
+	}{
+		{
+			name: "seconds field",
+			cfg:  models.CronConfig{Expression: "*/20 * * * * *"},
+			from: "2026-01-01T00:00:05Z",
+			want: []string{"2026-01-01T00:00:20Z", "2026-01-01T00:00:40Z", "2026-01-01T00:01:00Z", "2026-01-01T00:01:20Z"},
+		},
+		{
+			name: "fixed second within minute",
+			cfg:  models.CronConfig{Expression: "30 */10 * * * *"},
+			from: "2026-01-01T00:00:30Z",
+			want: []string{"2026-01-01T00:10:30Z", "2026-01-01T00:20:30Z", "2026-01-01T00:30:30Z", "2026-01-01T00:40:30Z"},
+		},
+		{
+			name: "weekdays in timezone",
+			cfg:  models.CronConfig{Expression: "0 30 9 * * MON-FRI", Timezone: "Asia/Shanghai"},
+			from: "2026-01-02T12:00:00Z", // 周五20:00
+			want: []string{"2026-01-05T01:30:00Z", "2026-01-06T01:30:00Z", "2026-01-07T01:30:00Z", "2026-01-08T01:30:00Z"},
+		},
+		{
+			name: "descriptor",
+			cfg:  models.CronConfig{Expression: "@daily", Timezone: "Europe/Berlin"},
+			from: "2026-01-01T12:00:00Z",
+			want: []string{"2026-01-01T23:00:00Z", "2026-01-02T23:00:00Z", "2026-01-03T23:00:00Z", "2026-01-04T23:00:00Z"},
+		},
+		{
+			name: "every",
+			cfg:  models.CronConfig{Expression: "@every 90s"},
+			from: "2026-01-01T00:00:00Z",
+			want: []string{"2026-01-01T00:01:30Z", "2026-01-01T00:03:00Z", "2026-01-01T00:04:30Z", "2026-01-01T00:06:00Z"},
+		},
+		{
+			// 2026-03-08 02:00 EST跳到03:00 EDT，当天不存在的02:30不运行
+			name: "dst spring forward skips missing time",
+			cfg:  models.CronConfig{Expression: "0 30 2 * * *", Timezone: "America/New_York"},
+			from: "2026-03-06T17:00:00Z",
+			want: []string{"2026-03-07T07:30:00Z", "2026-03-09T06:30:00Z", "2026-03-10T06:30:00Z", "2026-03-11T06:30:00Z"},
+		},
+		{
+			name: "dst spring forward hourly",
+			cfg:  models.CronConfig{Expression: "0 0 * * * *", Timezone: "America/New_York"},
+			from: "2026-03-08T05:30:00Z", // 00:30 EST
+			want: []string{"2026-03-08T06:00:00Z", "2026-03-08T07:00:00Z", "2026-03-08T08:00:00Z", "2026-03-08T09:00:00Z"},
+		},
+		{
+			// 2026-11-01 02:00 EDT回到01:00 EST，01:30出现两次，两次都运行
+			name: "dst fall back repeats time",
+			cfg:  models.CronConfig{Expression: "0 30 1 * * *", Timezone: "America/New_York"},
+			from: "2026-10-30T16:00:00Z",
+			want: []string{"2026-10-31T05:30:00Z", "2026-11-01T05:30:00Z", "2026-11-01T06:30:00Z", "2026-11-02T06:30:00Z"},
+		},
+		{
+			name: "never matches",
+			cfg:  models.CronConfig{Expression: "0 0 0 30 2 *"},
+			from: "2026-01-01T00:00:00Z",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := Preview(tt.cfg, utc(tt.from), 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(runs))
+			for i, run := range runs {
+				got[i] = run.UTC().Format(time.RFC3339)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewCount(t *testing.T) {
+	cfg := models.CronConfig{Expression: "* * * * * *"}
+	from := utc("2026-01-01T00:00:00Z")
+	tests := []struct {
+		count int
+		want  int
+	}{
+		{0, 5},
+		{-1, 5},
+		{3, 3},
+		{maxPreviewCount + 1, maxPreviewCount},
+	}
+	for _, tt := range tests {
+		runs, err := Preview(cfg, from, tt.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != tt.want {
+			t.Errorf("count %d: got %d runs, want %d", tt.count, len(runs), tt.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  models.CronConfig
+		want string
+	}{
+		{"five fields", models.CronConfig{Expression: "0 9 * * *"}, "expected exactly 6 fields, found 5"},
+		{"out of range second", models.CronConfig{Expression: "60 * * * * *"}, "end of range (60) above maximum (59)"},
+		{"bad field", models.CronConfig{Expression: "0 0 9 * * MON-XYZ"}, "failed to parse int from XYZ"},
+		{"invalid timezone", models.CronConfig{Expression: "0 0 9 * * *", Timezone: "Mars/Olympus"}, "invalid timezone \"Mars/Olympus\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchedule(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleSpec(t *testing.T) {
+	if got := scheduleSpec(models.CronConfig{Expression: "0 0 9 * * *"}); got != "0 0 9 * * *" {
+		t.Errorf("got %q", got)
+	}
+	if got := scheduleSpec(models.CronConfig{Expression: "0 0 9 * * *", Timezone: "UTC"}); got != "CRON_TZ=UTC 0 0 9 * * *" {
+		t.Errorf("got %q", got)
+	}
+}
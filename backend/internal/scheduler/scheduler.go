@@ -19,14 +19,23 @@ import (
 
 // TaskExecutor 任务执行器接口
 type TaskExecutor interface {
-	Execute(ctx context.Context, task *models.Task) error
+	Execute(ctx context.Context, task *models.Task, trigger models.TriggerInfo) error
 }
 
+// WorkflowResumer 恢复等待时间已到或收到信号的挂起工作流
+type WorkflowResumer interface {
+	ResumeWorkflows(ctx context.Context) error
+}
+
+// resumeCheckInterval 检查挂起工作流的间隔
+const resumeCheckInterval = 10 * time.Second
+
 // Scheduler 任务调度器
 type Scheduler struct {
 	cron     *cron.Cron
 	tasks    map[primitive.ObjectID]*ScheduledTask
 	executor TaskExecutor
+	resumer  WorkflowResumer
 	mutex    sync.RWMutex
 	running  bool
 }
@@ -52,6 +61,11 @@ func (s *Scheduler) SetExecutor(executor TaskExecutor) {
 	s.executor = executor
 }
 
+// SetResumer 设置挂起工作流的恢复器，需要在Start之前调用
+func (s *Scheduler) SetResumer(resumer WorkflowResumer) {
+	s.resumer = resumer
+}
+
 // Start 启动调度器
 func (s *Scheduler) Start() error {
 	s.mutex.Lock()
@@ -61,6 +75,12 @@ func (s *Scheduler) Start() error {
 		return nil
 	}
 
+	if s.resumer != nil {
+		_, err := s.cron.AddFunc("@every "+resumeCheckInterval.String(), s.resumeWorkflows)
+		if err != nil {
+			return err
+		}
+	}
 	s.cron.Start()
 	s.running = true
 	log.Println("Task scheduler started")
@@ -94,8 +114,14 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 	}
 
 	// 解析Cron表达式
-	entryID, err := s.cron.AddFunc(task.CronConfig.Expression, func() {
-		s.executeTask(task)
+	entryID, err := s.cron.AddFunc(scheduleSpec(task.CronConfig), func() {
+		// cron在整秒触发，截断到秒即为计划运行时间
+		scheduledTime := time.Now().Truncate(time.Second)
+		s.executeTask(task, models.TriggerInfo{
+			Type:          "scheduled",
+			By:            "scheduler",
+			ScheduledTime: &scheduledTime,
+		})
 	})
 	if err != nil {
 		return err
@@ -171,8 +197,8 @@ func (s *Scheduler) IsTaskScheduled(taskID primitive.ObjectID) bool {
 }
 
 // ExecuteTaskNow 立即执行任务
-func (s *Scheduler) ExecuteTaskNow(task *models.Task) {
-	go s.executeTask(task)
+func (s *Scheduler) ExecuteTaskNow(task *models.Task, trigger models.TriggerInfo) {
+	go s.executeTask(task, trigger)
 }
 
 // IsRunning 检查调度器是否正在运行
@@ -189,14 +215,14 @@ func (s *Scheduler) GetActiveJobCount() int {
 	return len(s.tasks)
 }
 
-// ValidateCronExpression 验证Cron表达式
+// ValidateCronExpression 验证Cron表达式，与调度时使用相同的格式（带秒字段）
 func (s *Scheduler) ValidateCronExpression(expression string) error {
-	_, err := cron.ParseStandard(expression)
+	_, err := scheduleParser.Parse(expression)
 	return err
 }
 
 // executeTask 执行任务的内部方法
-func (s *Scheduler) executeTask(task *models.Task) {
+func (s *Scheduler) executeTask(task *models.Task, trigger models.TriggerInfo) {
 	log.Printf("Executing task: %s", task.Name)
 
 	// 更新最后运行时间
@@ -205,10 +231,14 @@ func (s *Scheduler) executeTask(task *models.Task) {
 
 	// 如果设置了执行器，使用执行器执行任务
 	if s.executor != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(task.AgentConfig.Timeout)*time.Second)
-		defer cancel()
+		ctx := context.Background()
+		if task.AgentConfig.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(task.AgentConfig.Timeout)*time.Second)
+			defer cancel()
+		}
 
-		err := s.executor.Execute(ctx, task)
+		err := s.executor.Execute(ctx, task, trigger)
 		if err != nil {
 			log.Printf("Task execution failed: %s, error: %v", task.Name, err)
 			task.FailureCount++
@@ -232,6 +262,16 @@ func (s *Scheduler) executeTask(task *models.Task) {
 	s.mutex.Unlock()
 }
 
+// resumeWorkflows 领取到期的挂起工作流，恢复的执行在后台运行
+func (s *Scheduler) resumeWorkflows() {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeCheckInterval)
+	defer cancel()
+
+	if err := s.resumer.ResumeWorkflows(ctx); err != nil {
+		log.Printf("Failed to resume waiting workflows: %v", err)
+	}
+}
+
 // GetStats 获取调度器统计信息
 func (s *Scheduler) GetStats() map[string]interface{} {
 	s.mutex.RLock()
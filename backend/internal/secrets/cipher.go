@@ -0,0 +1,71 @@
+/**
+ * 密钥加解密
+ * 使用主密钥通过AES-256-GCM加密密钥值
+ */
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrNoMasterKey 未配置主密钥
+var ErrNoMasterKey = errors.New("secrets master key not configured")
+
+// Cipher 基于主密钥的加解密器
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher 由主密钥创建加解密器
+// 主密钥为base64编码的32字节时直接使用，否则取其SHA-256作为密钥
+func NewCipher(masterKey string) (*Cipher, error) {
+	if masterKey == "" {
+		return nil, ErrNoMasterKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil || len(key) != 32 {
+		log.Println("SECRETS_MASTER_KEY is not a base64 encoded 32-byte key, deriving key with SHA-256")
+		sum := sha256.Sum256([]byte(masterKey))
+		key = sum[:]
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt 加密，name作为附加数据绑定到密文，防止密文在记录间被挪用
+func (c *Cipher) Encrypt(name string, plaintext []byte) (ciphertext, nonce []byte, err error) {
+	nonce = make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return c.aead.Seal(nil, nonce, plaintext, []byte(name)), nonce, nil
+}
+
+// Decrypt 解密
+func (c *Cipher) Decrypt(name string, ciphertext, nonce []byte) ([]byte, error) {
+	if len(nonce) != c.aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce for secret %s", name)
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s: wrong master key or corrupted data", name)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,79 @@
+/**
+ * 密钥值脱敏
+ * 把日志和结果中出现的已解析密钥值替换为掩码
+ */
+
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Mask 替换密钥值的掩码
+	Mask = "******"
+	// minMaskLength 过短的值不做替换，避免把普通文本大面积打码
+	minMaskLength = 4
+)
+
+// Masker 密钥值脱敏器，可并发使用
+type Masker struct {
+	mutex    sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// NewMasker 创建脱敏器
+func NewMasker() *Masker {
+	return &Masker{values: make(map[string]struct{})}
+}
+
+// Add 添加需要脱敏的值
+func (m *Masker) Add(values ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changed := false
+	for _, value := range values {
+		if len(value) < minMaskLength {
+			continue
+		}
+		if _, exists := m.values[value]; !exists {
+			m.values[value] = struct{}{}
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// 较长的值优先替换，避免一个值是另一个值的子串时只替换了一部分
+	sorted := make([]string, 0, len(m.values))
+	for value := range m.values {
+		sorted = append(sorted, value)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	pairs := make([]string, 0, len(sorted)*2)
+	for _, value := range sorted {
+		pairs = append(pairs, value, Mask)
+	}
+	m.replacer = strings.NewReplacer(pairs...)
+}
+
+// MaskString 替换字符串中的密钥值
+func (m *Masker) MaskString(s string) string {
+	if m == nil {
+		return s
+	}
+	m.mutex.RLock()
+	replacer := m.replacer
+	m.mutex.RUnlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
@@ -0,0 +1,185 @@
+/**
+ * 密钥引用解析
+ * 参数和环境变量中的 secret://name 在运行时替换为密钥的值
+ */
+
+package secrets
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefPrefix 密钥引用前缀
+const RefPrefix = "secret://"
+
+// refPattern 匹配字符串中的密钥引用，允许出现在字符串中间（如 "Bearer secret://token"）
+var refPattern = regexp.MustCompile(`secret://([A-Za-z0-9_.-]+)`)
+
+// Refs 返回字符串中引用的所有密钥名称
+func Refs(s string) []string {
+	if !strings.Contains(s, RefPrefix) {
+		return nil
+	}
+	var names []string
+	for _, match := range refPattern.FindAllStringSubmatch(s, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// CollectRefs 递归收集值中引用的所有密钥名称
+func CollectRefs(value interface{}) []string {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = append(names, Refs(v)...)
+	case map[string]interface{}:
+		for _, item := range v {
+			names = append(names, CollectRefs(item)...)
+		}
+	case map[string]string:
+		for _, item := range v {
+			names = append(names, Refs(item)...)
+		}
+	case primitive.A:
+		return CollectRefs([]interface{}(v))
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, CollectRefs(item)...)
+		}
+	case []string:
+		for _, item := range v {
+			names = append(names, Refs(item)...)
+		}
+	}
+	return names
+}
+
+// Resolver 单次运行的密钥解析器，缓存已解析的值并记录下来用于日志脱敏
+type Resolver struct {
+	store   *Store
+	allowed map[string]bool
+	values  map[string]string
+}
+
+// NewResolver 创建解析器，只解析allowed中的密钥，其余引用原样保留
+// allowed应取自模板渲染前的配置，避免触发数据等外部输入通过模板注入密钥引用
+func (s *Store) NewResolver(allowed []string) *Resolver {
+	r := &Resolver{
+		store:   s,
+		allowed: make(map[string]bool, len(allowed)),
+		values:  make(map[string]string),
+	}
+	for _, name := range allowed {
+		r.allowed[name] = true
+	}
+	return r
+}
+
+// Values 返回本次运行解析出的所有密钥值
+func (r *Resolver) Values() []string {
+	values := make([]string, 0, len(r.values))
+	for _, value := range r.values {
+		values = append(values, value)
+	}
+	return values
+}
+
+// ResolveString 替换字符串中的密钥引用
+func (r *Resolver) ResolveString(ctx context.Context, s string) (string, error) {
+	names := Refs(s)
+	if len(names) == 0 {
+		return s, nil
+	}
+
+	for _, name := range names {
+		if _, ok := r.values[name]; ok || !r.allowed[name] {
+			continue
+		}
+		value, err := r.store.Reveal(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		r.values[name] = value
+	}
+
+	return refPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := r.values[strings.TrimPrefix(ref, RefPrefix)]; ok {
+			return value
+		}
+		return ref
+	}), nil
+}
+
+// ResolveParams 递归替换参数中的密钥引用，返回新的参数映射
+func (r *Resolver) ResolveParams(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
+	if params == nil {
+		return nil, nil
+	}
+	resolved, err := r.resolveValue(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// ResolveEnv 替换环境变量中的密钥引用，返回新的映射
+func (r *Resolver) ResolveEnv(ctx context.Context, env map[string]string) (map[string]string, error) {
+	if env == nil {
+		return nil, nil
+	}
+	resolved := make(map[string]string, len(env))
+	for key, value := range env {
+		v, err := r.ResolveString(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		resolved[key] = v
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) resolveValue(ctx context.Context, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.ResolveString(ctx, v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := r.resolveValue(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case primitive.A:
+		return r.resolveValue(ctx, []interface{}(v))
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := r.resolveValue(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			resolved, err := r.ResolveString(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
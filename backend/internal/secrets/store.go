@@ -0,0 +1,198 @@
+/**
+ * 密钥存储
+ * 密钥以密文形式保存在secrets集合中，只有执行器在运行时才会解密
+ */
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+)
+
+var (
+	// ErrNotFound 密钥不存在
+	ErrNotFound = errors.New("secret not found")
+	// ErrExists 同名密钥已存在
+	ErrExists = errors.New("secret already exists")
+	// ErrInvalidName 密钥名称格式错误
+	ErrInvalidName = errors.New("invalid secret name")
+)
+
+// namePattern 密钥名称格式
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// Store 密钥存储
+type Store struct {
+	db     *database.MongoDB
+	cipher *Cipher
+}
+
+// NewStore 创建密钥存储，未配置主密钥时密钥的读写都会返回ErrNoMasterKey
+func NewStore(db *database.MongoDB, masterKey string) *Store {
+	c, err := NewCipher(masterKey)
+	if err != nil {
+		log.Printf("Secrets store disabled: %v", err)
+	}
+	return &Store{db: db, cipher: c}
+}
+
+// Enabled 是否已配置主密钥
+func (s *Store) Enabled() bool {
+	return s.cipher != nil
+}
+
+// ValidateName 校验密钥名称
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w %q: only letters, digits, '_', '.' and '-' are allowed", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Create 创建密钥
+func (s *Store) Create(ctx context.Context, req *models.CreateSecretRequest) (*models.Secret, error) {
+	if s.cipher == nil {
+		return nil, ErrNoMasterKey
+	}
+	if err := ValidateName(req.Name); err != nil {
+		return nil, err
+	}
+
+	ciphertext, nonce, err := s.cipher.Encrypt(req.Name, []byte(req.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	secret := &models.Secret{
+		Name:        req.Name,
+		Description: req.Description,
+		Ciphertext:  ciphertext,
+		Nonce:       nonce,
+		CreatedBy:   req.CreatedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	result, err := s.collection().InsertOne(ctx, secret)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrExists
+		}
+		return nil, err
+	}
+	secret.ID = result.InsertedID.(primitive.ObjectID)
+	return secret, nil
+}
+
+// Update 更新密钥的值或描述
+func (s *Store) Update(ctx context.Context, name string, req *models.UpdateSecretRequest) (*models.Secret, error) {
+	if s.cipher == nil {
+		return nil, ErrNoMasterKey
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if req.Value != nil {
+		ciphertext, nonce, err := s.cipher.Encrypt(name, []byte(*req.Value))
+		if err != nil {
+			return nil, err
+		}
+		update["ciphertext"] = ciphertext
+		update["nonce"] = nonce
+	}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+
+	var secret models.Secret
+	err := s.collection().FindOneAndUpdate(ctx, bson.M{"name": name}, bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&secret)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// Delete 删除密钥
+func (s *Store) Delete(ctx context.Context, name string) error {
+	result, err := s.collection().DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get 获取密钥元数据（不含值）
+func (s *Store) Get(ctx context.Context, name string) (*models.Secret, error) {
+	var secret models.Secret
+	err := s.collection().FindOne(ctx, bson.M{"name": name},
+		options.FindOne().SetProjection(bson.M{"ciphertext": 0, "nonce": 0})).Decode(&secret)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// List 列出所有密钥的元数据（不含值）
+func (s *Store) List(ctx context.Context) ([]models.Secret, error) {
+	cursor, err := s.collection().Find(ctx, bson.M{}, options.Find().
+		SetProjection(bson.M{"ciphertext": 0, "nonce": 0}).
+		SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []models.Secret{}
+	if err := cursor.All(ctx, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Reveal 解密密钥的值，只供执行器在运行时使用
+func (s *Store) Reveal(ctx context.Context, name string) (string, error) {
+	if s.cipher == nil {
+		return "", ErrNoMasterKey
+	}
+
+	var secret models.Secret
+	err := s.collection().FindOne(ctx, bson.M{"name": name}).Decode(&secret)
+	if err == mongo.ErrNoDocuments {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := s.cipher.Decrypt(secret.Name, secret.Ciphertext, secret.Nonce)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (s *Store) collection() *mongo.Collection {
+	return s.db.GetCollection("secrets")
+}
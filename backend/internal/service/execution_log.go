@@ -0,0 +1,99 @@
+/**
+ * 执行日志服务
+ * 执行日志的查询，HTTP处理器和MCP工具共用这部分逻辑
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+)
+
+// ErrExecutionLogNotFound 执行日志不存在
+var ErrExecutionLogNotFound = errors.New("execution log not found")
+
+// ExecutionLogService 执行日志服务
+type ExecutionLogService struct {
+	db *database.MongoDB
+}
+
+// NewExecutionLogService 创建执行日志服务
+func NewExecutionLogService(db *database.MongoDB) *ExecutionLogService {
+	return &ExecutionLogService{db: db}
+}
+
+// Get 获取执行日志，非管理员看不到命中的脱敏规则
+func (s *ExecutionLogService) Get(ctx context.Context, id primitive.ObjectID, admin bool) (*models.ExecutionLog, error) {
+	var log models.ExecutionLog
+	err := s.db.GetCollection("execution_logs").FindOne(ctx, bson.M{"_id": id}).Decode(&log)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrExecutionLogNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		HideRedactionRules(&log)
+	}
+	return &log, nil
+}
+
+// ExecutionLogFilter 执行日志列表的查询条件
+type ExecutionLogFilter struct {
+	TaskID   *primitive.ObjectID
+	Statuses []models.ExecutionStatus // 为空时不按状态过滤
+	Since    *time.Time               // 只返回此时间之后开始的执行
+	Limit    int
+}
+
+// List 按开始时间倒序列出执行日志的摘要，不包含日志条目和性能指标
+func (s *ExecutionLogService) List(ctx context.Context, filter ExecutionLogFilter) ([]models.ExecutionLog, error) {
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	query := bson.M{}
+	if filter.TaskID != nil {
+		query["task_id"] = *filter.TaskID
+	}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if filter.Since != nil {
+		query["started_at"] = bson.M{"$gte": *filter.Since}
+	}
+
+	cursor, err := s.db.GetCollection("execution_logs").Find(ctx, query,
+		options.Find().
+			SetSort(bson.D{{Key: "started_at", Value: -1}}).
+			SetLimit(int64(filter.Limit)).
+			SetProjection(bson.M{"logs": 0, "metrics": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var logs []models.ExecutionLog
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// HideRedactionRules 去掉命中的脱敏规则，只保留是否脱敏的标记
+func HideRedactionRules(log *models.ExecutionLog) {
+	log.Result.RedactionRules = nil
+	for i := range log.Logs {
+		log.Logs[i].RedactionRules = nil
+	}
+}
@@ -0,0 +1,246 @@
+/**
+ * 任务服务
+ * 任务的创建、查询、调度控制和立即执行，HTTP处理器和MCP工具共用这部分逻辑
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+	"aischedule/internal/scheduler"
+	"aischedule/internal/workspace"
+)
+
+// ErrTaskNotFound 任务不存在
+var ErrTaskNotFound = errors.New("task not found")
+
+// ValidationError 请求参数错误
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// TaskFilter 任务列表的查询条件
+type TaskFilter struct {
+	Status string
+	Type   string
+	Page   int
+	Limit  int
+}
+
+// TaskService 任务服务
+type TaskService struct {
+	db        *database.MongoDB
+	scheduler *scheduler.Scheduler
+	validator TaskValidator
+}
+
+// NewTaskService 创建任务服务，validator为nil时不做附加校验
+func NewTaskService(db *database.MongoDB, scheduler *scheduler.Scheduler, validator TaskValidator) *TaskService {
+	return &TaskService{
+		db:        db,
+		scheduler: scheduler,
+		validator: validator,
+	}
+}
+
+// Create 校验并创建任务，新任务为非活跃状态
+func (s *TaskService) Create(ctx context.Context, req *models.CreateTaskRequest) (*models.Task, error) {
+	// 验证Cron表达式
+	if req.CronConfig.Expression != "" {
+		if _, err := scheduler.ParseSchedule(req.CronConfig); err != nil {
+			return nil, &ValidationError{Err: err}
+		}
+	}
+
+	// 验证工作区配置
+	if req.Environment.Workspace != nil {
+		if err := workspace.Validate(req.Environment.Workspace); err != nil {
+			return nil, &ValidationError{Err: err}
+		}
+	}
+
+	// 按任务类型校验参数
+	if err := s.ValidateParams(ctx, req.Type, req.AgentConfig.Parameters); err != nil {
+		return nil, err
+	}
+
+	task := &models.Task{
+		ID:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Status:      models.TaskStatusInactive,
+		CronConfig:  req.CronConfig,
+		AgentConfig: req.AgentConfig,
+		Environment: req.Environment,
+		WorkflowID:  req.WorkflowID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result, err := s.collection().InsertOne(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	task.ID = result.InsertedID.(primitive.ObjectID)
+	return task, nil
+}
+
+// ValidateParams 按任务类型校验运行参数
+func (s *TaskService) ValidateParams(ctx context.Context, taskType models.TaskType, params map[string]interface{}) error {
+	if s.validator == nil {
+		return nil
+	}
+	if err := s.validator.ValidateTask(ctx, taskType, params); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+// List 分页查询任务，按创建时间倒序
+func (s *TaskService) List(ctx context.Context, filter TaskFilter) (*models.TaskListResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 10
+	}
+
+	// 构建查询条件
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+
+	total, err := s.collection().CountDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := (filter.Page - 1) * filter.Limit
+	cursor, err := s.collection().Find(ctx, query,
+		options.Find().
+			SetSkip(int64(skip)).
+			SetLimit(int64(filter.Limit)).
+			SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+
+	return &models.TaskListResponse{
+		Tasks: tasks,
+		Pagination: models.Pagination{
+			Page:  filter.Page,
+			Limit: filter.Limit,
+			Total: int(total),
+		},
+	}, nil
+}
+
+// Get 获取任务
+func (s *TaskService) Get(ctx context.Context, id primitive.ObjectID) (*models.Task, error) {
+	var task models.Task
+	err := s.collection().FindOne(ctx, bson.M{"_id": id}).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Start 把任务加入调度器并标记为活跃
+func (s *TaskService) Start(ctx context.Context, id primitive.ObjectID) error {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.scheduler.AddTask(task); err != nil {
+		return err
+	}
+	return s.setStatus(ctx, id, models.TaskStatusActive)
+}
+
+// Stop 把任务移出调度器并标记为非活跃
+func (s *TaskService) Stop(ctx context.Context, id primitive.ObjectID) error {
+	s.scheduler.RemoveTask(id)
+	return s.setStatus(ctx, id, models.TaskStatusInactive)
+}
+
+// Pause 暂停任务的定时调度，任务仍可手动执行
+func (s *TaskService) Pause(ctx context.Context, id primitive.ObjectID) error {
+	s.scheduler.PauseTask(id)
+	return s.setStatus(ctx, id, models.TaskStatusPaused)
+}
+
+// Execute 立即执行任务，返回本次执行的执行日志ID
+func (s *TaskService) Execute(ctx context.Context, id primitive.ObjectID, trigger models.TriggerInfo) (primitive.ObjectID, error) {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if trigger.LogID.IsZero() {
+		trigger.LogID = primitive.NewObjectID()
+	}
+	s.scheduler.ExecuteTaskNow(task, trigger)
+	return trigger.LogID, nil
+}
+
+// PreviewSchedule 预览调度配置之后的运行时间
+func (s *TaskService) PreviewSchedule(cfg models.CronConfig, count int) ([]time.Time, error) {
+	runs, err := scheduler.Preview(cfg, time.Now(), count)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	return runs, nil
+}
+
+// setStatus 更新任务状态
+func (s *TaskService) setStatus(ctx context.Context, id primitive.ObjectID, status models.TaskStatus) error {
+	result, err := s.collection().UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"status":     status,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (s *TaskService) collection() *mongo.Collection {
+	return s.db.GetCollection("tasks")
+}
@@ -0,0 +1,34 @@
+/**
+ * 任务参数校验
+ * 保存任务和工作流前按任务类型做的附加校验，由执行器提供实现（例如检查引用的MCP服务端和工具）
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"aischedule/internal/models"
+)
+
+// TaskValidator 按任务类型校验运行参数，参数可能包含运行时才渲染的模板
+type TaskValidator interface {
+	ValidateTask(ctx context.Context, taskType models.TaskType, params map[string]interface{}) error
+}
+
+// ValidateWorkflowSteps 校验工作流中动作步骤的参数，步骤的action为执行的任务类型
+func ValidateWorkflowSteps(ctx context.Context, validator TaskValidator, steps []models.WorkflowStep) error {
+	if validator == nil {
+		return nil
+	}
+	for _, step := range steps {
+		if step.Type != "" && step.Type != models.StepTypeAction && step.Type != models.StepTypeLoop {
+			continue
+		}
+		if err := validator.ValidateTask(ctx, models.TaskType(step.Action), step.Parameters); err != nil {
+			return &ValidationError{Err: fmt.Errorf("step %s: %w", step.ID, err)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+/**
+ * 工作流服务
+ * 工作流定义的校验、创建和查询，HTTP处理器和MCP工具共用这部分逻辑
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"aischedule/internal/database"
+	"aischedule/internal/models"
+	"aischedule/internal/templating"
+)
+
+// ErrWorkflowNotFound 工作流不存在
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// ErrWorkflowExecutionNotFound 工作流执行不存在
+var ErrWorkflowExecutionNotFound = errors.New("workflow execution not found")
+
+// signalName 信号名称，用作workflow_executions.signals的键
+var signalName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// outputName 步骤输出名称，需要能在参数模板中写作.Steps.<id>.Outputs.<name>
+var outputName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// WorkflowFilter 工作流列表的查询条件
+type WorkflowFilter struct {
+	Type  string
+	Limit int
+}
+
+// WorkflowService 工作流服务
+type WorkflowService struct {
+	db        *database.MongoDB
+	validator TaskValidator
+}
+
+// NewWorkflowService 创建工作流服务，validator按步骤的动作类型校验参数
+func NewWorkflowService(db *database.MongoDB, validator TaskValidator) *WorkflowService {
+	return &WorkflowService{
+		db:        db,
+		validator: validator,
+	}
+}
+
+// Create 校验并创建工作流，新工作流为非活跃状态
+func (s *WorkflowService) Create(ctx context.Context, req *models.CreateWorkflowRequest) (*models.Workflow, error) {
+	if err := ValidateWorkflow(req.Steps, req.Connections, req.StartStep); err != nil {
+		return nil, err
+	}
+	if err := ValidateWorkflowSteps(ctx, s.validator, req.Steps); err != nil {
+		return nil, err
+	}
+
+	workflow := &models.Workflow{
+		ID:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Steps:       req.Steps,
+		Connections: req.Connections,
+		StartStep:   req.StartStep,
+		IsTemplate:  req.IsTemplate,
+		Category:    req.Category,
+		Tags:        req.Tags,
+		Status:      models.WorkflowStatusInactive,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result, err := s.collection().InsertOne(ctx, workflow)
+	if err != nil {
+		return nil, err
+	}
+	workflow.ID = result.InsertedID.(primitive.ObjectID)
+	return workflow, nil
+}
+
+// Get 获取工作流
+func (s *WorkflowService) Get(ctx context.Context, id primitive.ObjectID) (*models.Workflow, error) {
+	var workflow models.Workflow
+	err := s.collection().FindOne(ctx, bson.M{"_id": id}).Decode(&workflow)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// Update 更新工作流，步骤、连接和起始步骤按更新后的完整定义重新校验
+func (s *WorkflowService) Update(ctx context.Context, id primitive.ObjectID, req *models.UpdateWorkflowRequest) (*models.Workflow, error) {
+	workflow, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		update["name"] = *req.Name
+	}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+	if req.Type != nil {
+		update["type"] = *req.Type
+	}
+	if req.IsTemplate != nil {
+		update["is_template"] = *req.IsTemplate
+	}
+	if req.Category != nil {
+		update["category"] = *req.Category
+	}
+	if req.Tags != nil {
+		update["tags"] = *req.Tags
+	}
+	if req.Steps != nil || req.Connections != nil || req.StartStep != nil {
+		if req.Steps != nil {
+			workflow.Steps = *req.Steps
+			update["steps"] = workflow.Steps
+		}
+		if req.Connections != nil {
+			workflow.Connections = *req.Connections
+			update["connections"] = workflow.Connections
+		}
+		if req.StartStep != nil {
+			workflow.StartStep = *req.StartStep
+			update["start_step"] = workflow.StartStep
+		}
+		if err := ValidateWorkflow(workflow.Steps, workflow.Connections, workflow.StartStep); err != nil {
+			return nil, err
+		}
+		if req.Steps != nil {
+			if err := ValidateWorkflowSteps(ctx, s.validator, workflow.Steps); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result, err := s.collection().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrWorkflowNotFound
+	}
+	return s.Get(ctx, id)
+}
+
+// Signal 向工作流执行发送信号，等待该信号的步骤在调度器下次检查时继续；
+// 信号在等待步骤开始前收到同样有效
+func (s *WorkflowService) Signal(ctx context.Context, workflowID, executionID primitive.ObjectID, name string, payload map[string]interface{}) error {
+	if !signalName.MatchString(name) {
+		return &ValidationError{Err: fmt.Errorf("invalid signal name %q, use letters, digits, _ and -", name)}
+	}
+
+	executions := s.db.GetCollection("workflow_executions")
+	signal := models.WorkflowSignal{Payload: payload, ReceivedAt: time.Now()}
+	result, err := executions.UpdateOne(ctx,
+		bson.M{
+			"_id":         executionID,
+			"workflow_id": workflowID,
+			"status":      bson.M{"$in": []models.StepStatus{models.StepStatusRunning, models.StepStatusWaiting}},
+		},
+		bson.M{"$set": bson.M{"signals." + name: signal}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		count, err := executions.CountDocuments(ctx, bson.M{"_id": executionID, "workflow_id": workflowID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrWorkflowExecutionNotFound
+		}
+		return &ValidationError{Err: fmt.Errorf("workflow execution has already finished")}
+	}
+
+	// 已挂起的执行立即到期
+	_, err = executions.UpdateOne(ctx,
+		bson.M{"_id": executionID, "status": models.StepStatusWaiting},
+		bson.M{"$set": bson.M{"resume_at": signal.ReceivedAt}},
+	)
+	return err
+}
+
+// List 按创建时间倒序列出工作流
+func (s *WorkflowService) List(ctx context.Context, filter WorkflowFilter) ([]models.Workflow, error) {
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	query := bson.M{}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+
+	cursor, err := s.collection().Find(ctx, query,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, err
+	}
+	workflows := []models.Workflow{}
+	if err := cursor.All(ctx, &workflows); err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// Executions 按开始时间倒序列出工作流最近的执行记录
+func (s *WorkflowService) Executions(ctx context.Context, workflowID primitive.ObjectID, limit int) ([]models.WorkflowExecution, error) {
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	cursor, err := s.db.GetCollection("workflow_executions").Find(ctx, bson.M{"workflow_id": workflowID},
+		options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	executions := []models.WorkflowExecution{}
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+	for i := range executions {
+		hideStepRedactionRules(&executions[i])
+	}
+	return executions, nil
+}
+
+// hideStepRedactionRules 去掉早期版本保存在step_results中的脱敏规则，规则只通过执行日志返回给管理员
+func hideStepRedactionRules(execution *models.WorkflowExecution) {
+	for id, result := range execution.StepResults {
+		switch result := result.(type) {
+		case map[string]interface{}:
+			delete(result, "redaction_rules")
+		case primitive.M:
+			delete(result, "redaction_rules")
+		case primitive.D:
+			kept := make(primitive.D, 0, len(result))
+			for _, field := range result {
+				if field.Key != "redaction_rules" {
+					kept = append(kept, field)
+				}
+			}
+			execution.StepResults[id] = kept
+		}
+	}
+}
+
+// ValidateWorkflow 校验工作流结构：步骤ID唯一，起始步骤和连接、跳转引用的步骤都存在，
+// 步骤和连接的条件通过类型检查
+func ValidateWorkflow(steps []models.WorkflowStep, connections []models.WorkflowConnection, startStep string) error {
+	ids := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if err := ValidateStepID(step.ID); err != nil {
+			return &ValidationError{Err: err}
+		}
+		if ids[step.ID] {
+			return &ValidationError{Err: fmt.Errorf("duplicate step id %q", step.ID)}
+		}
+		ids[step.ID] = true
+	}
+
+	if startStep != "" && !ids[startStep] {
+		return &ValidationError{Err: fmt.Errorf("start step %q not found", startStep)}
+	}
+	for _, step := range steps {
+		for _, next := range append(append([]string{}, step.OnSuccess...), step.OnFailure...) {
+			if !ids[next] {
+				return &ValidationError{Err: fmt.Errorf("step %s: next step %q not found", step.ID, next)}
+			}
+		}
+	}
+	for _, connection := range connections {
+		if !ids[connection.From] || !ids[connection.To] {
+			return &ValidationError{Err: fmt.Errorf("connection %s -> %s references unknown step", connection.From, connection.To)}
+		}
+	}
+	for _, step := range steps {
+		if err := validateStepType(&step); err != nil {
+			return &ValidationError{Err: fmt.Errorf("step %s: %w", step.ID, err)}
+		}
+		if err := validateStepOutputs(&step); err != nil {
+			return &ValidationError{Err: fmt.Errorf("step %s: %w", step.ID, err)}
+		}
+	}
+	if _, err := templating.CompileWorkflow(steps, connections); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+// ValidateStepID 校验步骤ID。ID用作workflow_executions中step_statuses、step_results的键，
+// 含.或以$开头会被MongoDB当作路径或操作符，破坏更新文档
+func ValidateStepID(id string) error {
+	if id == "" {
+		return fmt.Errorf("step id is required")
+	}
+	if strings.Contains(id, ".") || strings.HasPrefix(id, "$") {
+		return fmt.Errorf("invalid step id %q: must not contain '.' or start with '$'", id)
+	}
+	return nil
+}
+
+// validateStepType 校验循环和等待步骤的配置
+func validateStepType(step *models.WorkflowStep) error {
+	switch step.Type {
+	case models.StepTypeLoop:
+		loop := step.Loop
+		if loop == nil {
+			return fmt.Errorf("loop step requires loop configuration")
+		}
+		if (loop.Items == "") == (loop.While == "") {
+			return fmt.Errorf("loop requires exactly one of items and while")
+		}
+		if loop.Parallelism < 0 || loop.MaxIterations < 0 {
+			return fmt.Errorf("loop parallelism and max_iterations must not be negative")
+		}
+		if loop.While != "" && loop.Parallelism > 1 {
+			return fmt.Errorf("while loops run sequentially, parallelism is not supported")
+		}
+		if step.Action == "" {
+			return fmt.Errorf("loop step requires an action")
+		}
+
+	case models.StepTypeWait:
+		wait := step.Wait
+		if wait == nil {
+			return fmt.Errorf("wait step requires wait configuration")
+		}
+		set := 0
+		for _, value := range []string{wait.Duration, wait.Until, wait.Signal, wait.Condition} {
+			if value != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("wait requires exactly one of duration, until, signal and condition")
+		}
+		// 含模板的时长和时间在运行时渲染后再检查
+		if wait.Duration != "" && !strings.Contains(wait.Duration, "{{") {
+			if d, err := time.ParseDuration(wait.Duration); err != nil || d < 0 {
+				return fmt.Errorf("invalid wait duration %q", wait.Duration)
+			}
+		}
+		if wait.Until != "" && !strings.Contains(wait.Until, "{{") {
+			if _, err := time.Parse(time.RFC3339, wait.Until); err != nil {
+				return fmt.Errorf("invalid wait until %q, expected RFC3339", wait.Until)
+			}
+		}
+		if wait.Signal != "" && !signalName.MatchString(wait.Signal) {
+			return fmt.Errorf("invalid signal name %q, use letters, digits, _ and -", wait.Signal)
+		}
+		if wait.PollInterval < 0 {
+			return fmt.Errorf("wait poll_interval must not be negative")
+		}
+	}
+	return nil
+}
+
+// validateStepOutputs 校验声明的输出：只有动作步骤可以声明，名称唯一，来源和路径有效
+func validateStepOutputs(step *models.WorkflowStep) error {
+	if len(step.Outputs) == 0 {
+		return nil
+	}
+	if step.Type != models.StepTypeAction && step.Type != "" {
+		return fmt.Errorf("outputs are only supported on action steps")
+	}
+	names := make(map[string]bool, len(step.Outputs))
+	for _, output := range step.Outputs {
+		if !outputName.MatchString(output.Name) {
+			return fmt.Errorf("invalid output name %q, use letters, digits and _", output.Name)
+		}
+		if names[output.Name] {
+			return fmt.Errorf("duplicate output %q", output.Name)
+		}
+		names[output.Name] = true
+
+		switch output.From {
+		case models.OutputSourceStdout:
+		case models.OutputSourceFile:
+			if output.File == "" {
+				return fmt.Errorf("output %s: file is required", output.Name)
+			}
+			if !filepath.IsLocal(filepath.FromSlash(output.File)) {
+				return fmt.Errorf("output %s: file %q must be a relative path inside the working directory", output.Name, output.File)
+			}
+		case models.OutputSourceResponse:
+			if step.Action != string(models.TaskTypeAgent) {
+				return fmt.Errorf("output %s: response is only available for agent steps", output.Name)
+			}
+		default:
+			return fmt.Errorf("output %s: from must be one of stdout, file and response", output.Name)
+		}
+		if output.File != "" && output.From != models.OutputSourceFile {
+			return fmt.Errorf("output %s: file is only used with from file", output.Name)
+		}
+		if output.Path != "" && !strings.HasPrefix(output.Path, "$") {
+			return fmt.Errorf("output %s: path must be a JSONPath starting with $", output.Name)
+		}
+	}
+	return nil
+}
+
+func (s *WorkflowService) collection() *mongo.Collection {
+	return s.db.GetCollection("workflows")
+}
@@ -0,0 +1,443 @@
+/**
+ * 条件表达式
+ * 工作流的条件、循环和等待使用CEL表达式（github.com/google/cel-go），环境中只有下列变量：
+ * steps 已结束步骤的状态、结果和声明的输出，vars 运行变量，trigger 触发数据，run 本次运行，task 所属任务，
+ * iteration 循环步骤的当前迭代（loop是CEL的保留字），now 当前时间。表达式的长度、嵌套深度和求值开销都有上限
+ */
+
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+
+	"aischedule/internal/models"
+)
+
+const (
+	// maxExpressionLength 表达式长度上限(字符)
+	maxExpressionLength = 4096
+	// maxExpressionDepth 表达式嵌套深度上限
+	maxExpressionDepth = 32
+	// maxEvaluationCost 单次求值的开销上限，列表宏和字符串函数按数据大小计入开销
+	maxEvaluationCost = 100000
+)
+
+// 变量中可以访问的字段，用于在保存时发现拼写错误
+var (
+	stepFields      = []string{"error", "output", "outputs", "result", "status"}
+	runFields       = []string{"attempt", "id", "scheduled_time", "started_at", "trigger_type", "triggered_by"}
+	taskFields      = []string{"id", "name"}
+	iterationFields = []string{"index", "item", "last"}
+)
+
+// conditionEnv 条件表达式的CEL环境，所有工作流共用
+var conditionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	object := cel.MapType(cel.StringType, cel.DynType)
+	return cel.NewEnv(
+		cel.Variable("steps", cel.MapType(cel.StringType, object)),
+		cel.Variable("vars", object),
+		cel.Variable("trigger", object),
+		cel.Variable("run", object),
+		cel.Variable("task", object),
+		cel.Variable("iteration", cel.DynType),
+		cel.Variable("now", cel.TimestampType),
+		ext.Strings(),
+		cel.OptionalTypes(),
+		cel.CrossTypeNumericComparisons(true),
+		cel.ParserExpressionSizeLimit(maxExpressionLength),
+		cel.ParserRecursionLimit(maxExpressionDepth),
+	)
+})
+
+// ExpressionError 表达式错误，Line和Column从1开始
+type ExpressionError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// errorAt 没有位置的错误（如超出长度）报在表达式开头
+func errorAt(location common.Location, format string, args ...interface{}) *ExpressionError {
+	e := &ExpressionError{Line: 1, Column: 1, Message: fmt.Sprintf(format, args...)}
+	if location.Line() > 0 {
+		e.Line, e.Column = location.Line(), location.Column()+1
+	}
+	return e
+}
+
+// Expressions 工作流中已编译的表达式，按表达式文本索引
+type Expressions struct {
+	conditions map[string]cel.Program
+	lists      map[string]cel.Program
+}
+
+// CompileWorkflow 编译工作流中的全部表达式并做类型检查：步骤和连接的条件、
+// 循环的items和while、等待的condition
+func CompileWorkflow(steps []models.WorkflowStep, connections []models.WorkflowConnection) (*Expressions, error) {
+	env, err := conditionEnv()
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{env: env, steps: make(map[string]*models.WorkflowStep, len(steps))}
+	for i := range steps {
+		c.steps[steps[i].ID] = &steps[i]
+	}
+
+	e := &Expressions{
+		conditions: make(map[string]cel.Program),
+		lists:      make(map[string]cel.Program),
+	}
+	compile := func(programs map[string]cel.Program, src string, kind types.Kind) error {
+		if strings.TrimSpace(src) == "" || programs[src] != nil {
+			return nil
+		}
+		program, err := c.compile(src, kind)
+		if err != nil {
+			return err
+		}
+		programs[src] = program
+		return nil
+	}
+	for _, step := range steps {
+		if err := compile(e.conditions, step.Condition, types.BoolKind); err != nil {
+			return nil, fmt.Errorf("step %s: condition: %w", step.ID, err)
+		}
+		if step.Loop != nil {
+			if err := compile(e.lists, step.Loop.Items, types.ListKind); err != nil {
+				return nil, fmt.Errorf("step %s: loop.items: %w", step.ID, err)
+			}
+			if err := compile(e.conditions, step.Loop.While, types.BoolKind); err != nil {
+				return nil, fmt.Errorf("step %s: loop.while: %w", step.ID, err)
+			}
+		}
+		if step.Wait != nil {
+			if err := compile(e.conditions, step.Wait.Condition, types.BoolKind); err != nil {
+				return nil, fmt.Errorf("step %s: wait.condition: %w", step.ID, err)
+			}
+		}
+	}
+	for _, connection := range connections {
+		if err := compile(e.conditions, connection.Condition, types.BoolKind); err != nil {
+			return nil, fmt.Errorf("connection %s -> %s: condition: %w", connection.From, connection.To, err)
+		}
+	}
+	return e, nil
+}
+
+// compiler 编译单个工作流的表达式
+type compiler struct {
+	env   *cel.Env
+	steps map[string]*models.WorkflowStep
+}
+
+// compile 解析、类型检查并生成求值程序，kind为要求的结果类型（dyn总是允许）
+func (c *compiler) compile(src string, kind types.Kind) (cel.Program, error) {
+	checked, issues := c.env.Compile(src)
+	if issues != nil && issues.Err() != nil {
+		return nil, issueError(issues)
+	}
+	ast := checked.NativeRep()
+	if err := c.checkReferences(ast); err != nil {
+		return nil, err
+	}
+	if result := checked.OutputType(); result.Kind() != kind && result.Kind() != types.DynKind {
+		return nil, errorAt(ast.SourceInfo().GetStartLocation(ast.Expr().ID()), "expression must be a %s, got %s",
+			kindName(kind), result)
+	}
+	// 优化时预编译常量正则表达式，无效的正则在保存时报错
+	program, err := c.env.Program(checked, cel.CostLimit(maxEvaluationCost), cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, errorAt(ast.SourceInfo().GetStartLocation(ast.Expr().ID()), "%v", err)
+	}
+	return program, nil
+}
+
+func kindName(kind types.Kind) string {
+	switch kind {
+	case types.BoolKind:
+		return "bool"
+	case types.ListKind:
+		return "list"
+	}
+	return "value"
+}
+
+// issueError 取第一个编译错误
+func issueError(issues *cel.Issues) error {
+	errs := issues.Errors()
+	if len(errs) == 0 {
+		return issues.Err()
+	}
+	message := errs[0].Message
+	// 未声明的变量附上可用的变量
+	if strings.HasPrefix(message, "undeclared reference to") {
+		message += " (available: iteration, now, run, steps, task, trigger, vars)"
+	}
+	return errorAt(errs[0].Location, "%s", message)
+}
+
+// checkReferences 检查对步骤、步骤字段、声明的输出以及run、task、loop字段的引用
+func (c *compiler) checkReferences(ast *celast.AST) error {
+	var err error
+	celast.PreOrderVisit(ast.Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		if err != nil {
+			return
+		}
+		operand, field, ok := fieldAccess(e)
+		if !ok {
+			return
+		}
+		location := ast.SourceInfo().GetStartLocation(e.ID())
+		if operand.Kind() == celast.IdentKind {
+			switch operand.AsIdent() {
+			case "steps":
+				if c.steps[field] == nil {
+					err = errorAt(location, "unknown step %q (available: %s)", field, strings.Join(c.stepIDs(), ", "))
+				}
+			case "run":
+				err = checkField(location, "run field", field, runFields)
+			case "task":
+				err = checkField(location, "task field", field, taskFields)
+			case "iteration":
+				err = checkField(location, "iteration field", field, iterationFields)
+			}
+			return
+		}
+		if _, ok := stepReference(operand); ok {
+			err = checkField(location, "step field", field, stepFields)
+			return
+		}
+		if inner, name, ok := fieldAccess(operand); ok && name == "outputs" {
+			if id, ok := stepReference(inner); ok {
+				err = checkField(location, "output", field, outputNames(c.steps[id]))
+			}
+		}
+	}))
+	return err
+}
+
+func (c *compiler) stepIDs() []string {
+	ids := make([]string, 0, len(c.steps))
+	for id := range c.steps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func outputNames(step *models.WorkflowStep) []string {
+	if step == nil {
+		return nil
+	}
+	names := make([]string, 0, len(step.Outputs))
+	for _, output := range step.Outputs {
+		names = append(names, output.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkField(location common.Location, kind, field string, fields []string) error {
+	for _, name := range fields {
+		if name == field {
+			return nil
+		}
+	}
+	available := strings.Join(fields, ", ")
+	if available == "" {
+		available = "(none)"
+	}
+	return errorAt(location, "unknown %s %q (available: %s)", kind, field, available)
+}
+
+// fieldAccess 识别x.f、x["f"]以及has(x.f)
+func fieldAccess(e celast.Expr) (celast.Expr, string, bool) {
+	switch e.Kind() {
+	case celast.SelectKind:
+		selection := e.AsSelect()
+		return selection.Operand(), selection.FieldName(), true
+	case celast.CallKind:
+		call := e.AsCall()
+		if call.FunctionName() != "_[_]" || len(call.Args()) != 2 || call.Args()[1].Kind() != celast.LiteralKind {
+			return nil, "", false
+		}
+		if key, ok := call.Args()[1].AsLiteral().Value().(string); ok {
+			return call.Args()[0], key, true
+		}
+	}
+	return nil, "", false
+}
+
+// stepReference 识别steps.<id>和steps["<id>"]
+func stepReference(e celast.Expr) (string, bool) {
+	operand, id, ok := fieldAccess(e)
+	if !ok || operand.Kind() != celast.IdentKind || operand.AsIdent() != "steps" {
+		return "", false
+	}
+	return id, true
+}
+
+// ConditionVars 表达式的变量值：只有已结束的步骤出现在steps中，循环之外iteration为null；
+// JSON中的整数为int，其他数字为double，时间为timestamp
+func (d *Data) ConditionVars() map[string]interface{} {
+	steps := make(map[string]interface{}, len(d.Steps))
+	for id, step := range d.Steps {
+		steps[id] = map[string]interface{}{
+			"status":  step.Status,
+			"error":   step.Error,
+			"output":  celValue(emptyIfNil(step.Output)),
+			"result":  celValue(emptyIfNil(step.Result)),
+			"outputs": celValue(emptyIfNil(step.Outputs)),
+		}
+	}
+
+	run := map[string]interface{}{
+		"id":             d.Run.ID,
+		"attempt":        int64(d.Run.Attempt),
+		"scheduled_time": d.Run.ScheduledTime,
+		"started_at":     d.Run.StartedAt,
+		"trigger_type":   d.Trigger.Type,
+		"triggered_by":   d.Trigger.By,
+	}
+	task := map[string]interface{}{"id": "", "name": ""}
+	if d.Task != nil {
+		task["id"] = d.Task.ID.Hex()
+		task["name"] = d.Task.Name
+	}
+
+	var iteration interface{}
+	if d.Loop != nil {
+		iteration = map[string]interface{}{
+			"index": int64(d.Loop.Index),
+			"item":  celValue(d.Loop.Item),
+			"last":  celValue(emptyIfNil(d.Loop.Last)),
+		}
+	}
+
+	now := d.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return map[string]interface{}{
+		"steps":     steps,
+		"vars":      celValue(emptyIfNil(d.Vars)),
+		"trigger":   celValue(emptyIfNil(d.Trigger.Payload)),
+		"run":       run,
+		"task":      task,
+		"iteration": iteration,
+		"now":       now,
+	}
+}
+
+// celValue 通过JSON往返把值统一为CEL可以处理的类型，整数转为int64
+func celValue(value interface{}) interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return value
+	}
+	return convertNumbers(decoded)
+}
+
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return value
+}
+
+func emptyIfNil(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+// Condition 计算条件，条件为空时成立，结果为null时不成立
+func (e *Expressions) Condition(src string, data *Data) (bool, error) {
+	if strings.TrimSpace(src) == "" {
+		return true, nil
+	}
+	program := e.conditions[src]
+	if program == nil {
+		return false, fmt.Errorf("condition %q was not compiled", src)
+	}
+	value, err := eval(program, data)
+	if err != nil {
+		return false, err
+	}
+	if value.Type() == types.NullType {
+		return false, nil
+	}
+	if v, ok := value.Value().(bool); ok {
+		return v, nil
+	}
+	return false, fmt.Errorf("condition must be a bool, got %s", value.Type().TypeName())
+}
+
+// List 计算结果为列表的表达式，结果为null时返回空列表
+func (e *Expressions) List(src string, data *Data) ([]interface{}, error) {
+	program := e.lists[src]
+	if program == nil {
+		return nil, fmt.Errorf("expression %q was not compiled", src)
+	}
+	value, err := eval(program, data)
+	if err != nil {
+		return nil, err
+	}
+	if value.Type() == types.NullType {
+		return []interface{}{}, nil
+	}
+	if value.Type() != types.ListType {
+		return nil, fmt.Errorf("expression must be a list, got %s", value.Type().TypeName())
+	}
+	items, err := value.ConvertToNative(reflect.TypeOf([]interface{}{}))
+	if err != nil {
+		return nil, err
+	}
+	return items.([]interface{}), nil
+}
+
+func eval(program cel.Program, data *Data) (ref.Val, error) {
+	value, _, err := program.Eval(data.ConditionVars())
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,323 @@
+package templating
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"aischedule/internal/models"
+)
+
+func testSteps() []models.WorkflowStep {
+	return []models.WorkflowStep{
+		{ID: "build", Type: models.StepTypeAction, Outputs: []models.StepOutput{
+			{Name: "version", From: models.OutputSourceStdout, Path: "$.version"},
+		}},
+		{ID: "unit-tests", Type: models.StepTypeAction},
+		{ID: "deploy", Type: models.StepTypeAction},
+	}
+}
+
+func TestCompileWorkflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		step        models.WorkflowStep
+		connections []models.WorkflowConnection
+		wantErr     string
+	}{
+		{
+			name: "declared output",
+			step: models.WorkflowStep{ID: "check", Condition: "steps.build.outputs.version != ''"},
+		},
+		{
+			name: "step id with dash",
+			step: models.WorkflowStep{ID: "check", Condition: "steps['unit-tests'].status == 'completed'"},
+		},
+		{
+			name: "all variables",
+			step: models.WorkflowStep{ID: "check", Condition: "vars.env == 'prod' && trigger.ref != '' && run.attempt > 1 && task.name != '' && iteration == null && now > run.started_at"},
+		},
+		{
+			name: "macros and optional fields",
+			step: models.WorkflowStep{ID: "check", Condition: "has(steps.deploy) && vars.?regions.orValue([]).all(r, r.startsWith('eu-'))"},
+		},
+		{
+			name:    "undeclared output",
+			step:    models.WorkflowStep{ID: "check", Condition: "steps.build.outputs.commit != ''"},
+			wantErr: "step check: condition: 1:20: unknown output \"commit\" (available: version)",
+		},
+		{
+			name:    "step without outputs",
+			step:    models.WorkflowStep{ID: "check", Condition: "steps.deploy.outputs.url != ''"},
+			wantErr: "step check: condition: 1:21: unknown output \"url\" (available: (none))",
+		},
+		{
+			name:    "unknown step",
+			step:    models.WorkflowStep{ID: "check", Condition: "steps.tests.status == 'completed'"},
+			wantErr: "step check: condition: 1:6: unknown step \"tests\" (available: build, check, deploy, unit-tests)",
+		},
+		{
+			name:    "unknown step field",
+			step:    models.WorkflowStep{ID: "check", Condition: "has(steps.build.state)"},
+			wantErr: "step check: condition: 1:4: unknown step field \"state\" (available: error, output, outputs, result, status)",
+		},
+		{
+			name:    "unknown run field",
+			step:    models.WorkflowStep{ID: "check", Condition: "run['attempts'] > 1"},
+			wantErr: "step check: condition: 1:4: unknown run field \"attempts\" (available: attempt, id, scheduled_time, started_at, trigger_type, triggered_by)",
+		},
+		{
+			name:    "undeclared variable",
+			step:    models.WorkflowStep{ID: "check", Condition: "env == 'prod'"},
+			wantErr: "step check: condition: 1:1: undeclared reference to 'env' (in container '') (available: iteration, now, run, steps, task, trigger, vars)",
+		},
+		{
+			name:    "condition type",
+			step:    models.WorkflowStep{ID: "check", Condition: "size(vars)"},
+			wantErr: "step check: condition: 1:5: expression must be a bool, got int",
+		},
+		{
+			name:    "loop items",
+			step:    models.WorkflowStep{ID: "check", Loop: &models.LoopConfig{Items: "'a,b'"}},
+			wantErr: "step check: loop.items: 1:1: expression must be a list, got string",
+		},
+		{
+			name:    "loop while",
+			step:    models.WorkflowStep{ID: "check", Loop: &models.LoopConfig{While: "iteration.index < 3 && size(now) > 0"}},
+			wantErr: "step check: loop.while: 1:28: found no matching overload for 'size' applied to '(timestamp)'",
+		},
+		{
+			name:    "invalid regex",
+			step:    models.WorkflowStep{ID: "check", Condition: "vars.branch.matches('release-(')"},
+			wantErr: "step check: condition: 1:20: error parsing regexp: missing closing ): `release-(`",
+		},
+		{
+			name:    "wait condition",
+			step:    models.WorkflowStep{ID: "check", Wait: &models.WaitConfig{Condition: "vars.ready ="}},
+			wantErr: "step check: wait.condition: 1:12: Syntax error: token recognition error at: '='",
+		},
+		{
+			name:        "connection condition",
+			step:        models.WorkflowStep{ID: "check"},
+			connections: []models.WorkflowConnection{{From: "build", To: "deploy", Condition: "steps.build.result.success &&"}},
+			wantErr:     "connection build -> deploy: condition: 1:30: Syntax error: mismatched input '<EOF>'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := append(testSteps(), tt.step)
+			_, err := CompileWorkflow(steps, tt.connections)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		wantErr   string
+	}{
+		{
+			name:      "nesting within limit",
+			condition: strings.Repeat("(", 20) + "true" + strings.Repeat(")", 20),
+		},
+		{
+			name:      "nesting too deep",
+			condition: strings.Repeat("(", maxExpressionDepth+1) + "true" + strings.Repeat(")", maxExpressionDepth+1),
+			wantErr:   "expression recursion limit exceeded",
+		},
+		{
+			name:      "nested lists too deep",
+			condition: "size(" + strings.Repeat("[", maxExpressionDepth+1) + strings.Repeat("]", maxExpressionDepth+1) + ") > 0",
+			wantErr:   "expression recursion limit exceeded",
+		},
+		{
+			name:      "too long",
+			condition: "vars.note == '" + strings.Repeat("x", maxExpressionLength) + "'",
+			wantErr:   "1:1: expression code point size exceeds limit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflow([]models.WorkflowStep{{ID: "check", Condition: tt.condition}}, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluationCostLimit(t *testing.T) {
+	condition := "vars.items.all(x, vars.items.all(y, vars.items.exists(z, x + y + z >= 0)))"
+	exprs, err := CompileWorkflow([]models.WorkflowStep{{ID: "check", Condition: condition}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := func(n int) []int {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = i
+		}
+		return values
+	}
+
+	// 小列表在开销上限内
+	ok, err := exprs.Condition(condition, &Data{Vars: map[string]interface{}{"items": items(10)}})
+	if err != nil || !ok {
+		t.Fatalf("small input: got %v, %v", ok, err)
+	}
+	start := time.Now()
+	_, err = exprs.Condition(condition, &Data{Vars: map[string]interface{}{"items": items(1000)}})
+	if err == nil || !strings.Contains(err.Error(), "cost limit exceeded") {
+		t.Errorf("large input: got %v, want cost limit error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("evaluation was not stopped early: %s", elapsed)
+	}
+}
+
+func TestExpressionsCondition(t *testing.T) {
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := &Data{
+		Run:     Run{ID: "run-1", ScheduledTime: started, StartedAt: started, Attempt: 2},
+		Vars:    map[string]interface{}{"env": "prod", "ratio": 0.5, "regions": []string{"eu-west", "eu-north"}},
+		Trigger: models.TriggerInfo{Type: "webhook", Payload: map[string]interface{}{"ref": "main"}},
+		Now:     started.Add(90 * time.Minute),
+		Steps: map[string]Step{
+			"build": {
+				Status:  "completed",
+				Result:  map[string]interface{}{"success": true, "exit_code": 0, "passed": 12},
+				Outputs: map[string]interface{}{"version": "1.4.0"},
+			},
+			"unit-tests": {Status: "failed", Error: "2 tests failed"},
+		},
+	}
+	tests := []struct {
+		condition string
+		want      bool
+		wantErr   string
+	}{
+		{condition: "", want: true},
+		{condition: "steps.build.outputs.version == '1.4.0'", want: true},
+		{condition: "steps.build.result.success && steps.build.result.exit_code == 0", want: true},
+		// JSON中的整数为int，可以和double比较
+		{condition: "steps.build.result.passed > 10 && vars.ratio < 1", want: true},
+		{condition: "steps['unit-tests'].status == 'failed' && steps['unit-tests'].error.contains('failed')", want: true},
+		// 未结束的步骤不在steps中
+		{condition: "has(steps.deploy)", want: false},
+		{condition: "'build' in steps && !('deploy' in steps)", want: true},
+		{condition: "steps.deploy.status == 'completed'", wantErr: "no such key: deploy"},
+		// 短路求值时不访问缺少的键
+		{condition: "has(steps.deploy) && steps.deploy.status == 'completed'", want: false},
+		// 没有输出的步骤outputs为空对象
+		{condition: "size(steps['unit-tests'].outputs) == 0", want: true},
+		{condition: "vars.env == 'prod' && run.attempt == 2", want: true},
+		{condition: "vars.regions.all(r, r.startsWith('eu-'))", want: true},
+		{condition: "run.started_at == timestamp('2026-03-01T12:00:00Z')", want: true},
+		{condition: "now - run.started_at > duration('1h')", want: true},
+		{condition: "trigger.ref == 'main' && run.trigger_type == 'webhook'", want: true},
+		{condition: "trigger.?tag.orValue('') == ''", want: true},
+		{condition: "trigger.tag == ''", wantErr: "no such key: tag"},
+		// 循环之外iteration为null，条件结果为null视为不成立
+		{condition: "iteration == null", want: true},
+		{condition: "vars.?missing.orValue(null)", want: false},
+		{condition: "vars.env", wantErr: "condition must be a bool, got string"},
+		{condition: "task.name == ''", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			exprs, err := CompileWorkflow(append(testSteps(), models.WorkflowStep{ID: "check", Condition: tt.condition}), nil)
+			if err != nil {
+				t.Fatalf("CompileWorkflow: %v", err)
+			}
+			got, err := exprs.Condition(tt.condition, data)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Condition: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	exprs, err := CompileWorkflow(testSteps(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exprs.Condition("vars.env == 'prod'", data); err == nil || !strings.Contains(err.Error(), "was not compiled") {
+		t.Errorf("uncompiled condition: got %v", err)
+	}
+}
+
+func TestExpressionsIteration(t *testing.T) {
+	condition := "iteration.index == 0 || iteration.last.status != 'done'"
+	exprs, err := CompileWorkflow([]models.WorkflowStep{{ID: "poll", Loop: &models.LoopConfig{While: condition}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		loop *Loop
+		want bool
+	}{
+		{&Loop{Index: 0}, true},
+		{&Loop{Index: 1, Last: map[string]interface{}{"status": "pending"}}, true},
+		{&Loop{Index: 2, Last: map[string]interface{}{"status": "done"}}, false},
+	}
+	for _, tt := range tests {
+		got, err := exprs.Condition(condition, &Data{Loop: tt.loop})
+		if err != nil || got != tt.want {
+			t.Errorf("index %d: got %v, %v, want %v", tt.loop.Index, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpressionsList(t *testing.T) {
+	steps := []models.WorkflowStep{
+		{ID: "fan-out", Loop: &models.LoopConfig{Items: "vars.regions"}},
+		{ID: "optional", Loop: &models.LoopConfig{Items: "vars.?regions.orValue(null)"}},
+	}
+	exprs, err := CompileWorkflow(steps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := exprs.List("vars.regions", &Data{Vars: map[string]interface{}{"regions": []string{"eu", "us"}}})
+	if err != nil || len(items) != 2 || items[0] != "eu" {
+		t.Errorf("got %#v, %v", items, err)
+	}
+	items, err = exprs.List("vars.?regions.orValue(null)", &Data{})
+	if err != nil || items == nil || len(items) != 0 {
+		t.Errorf("null list: got %#v, %v", items, err)
+	}
+	if _, err := exprs.List("vars.regions", &Data{}); err == nil || err.Error() != "no such key: regions" {
+		t.Errorf("missing list: got %v", err)
+	}
+	if _, err := exprs.List("vars.regions", &Data{Vars: map[string]interface{}{"regions": "eu"}}); err == nil ||
+		err.Error() != "expression must be a list, got string" {
+		t.Errorf("wrong kind: got %v", err)
+	}
+	if _, err := exprs.List("vars.hosts", &Data{}); err == nil || !strings.Contains(err.Error(), "was not compiled") {
+		t.Errorf("uncompiled list: got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+/**
+ * 参数模板
+ * 在运行前用Go模板渲染任务和步骤参数中的字符串
+ */
+
+package templating
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"aischedule/internal/models"
+)
+
+// noValue 非严格模式下缺失键的渲染结果，替换为空字符串
+const noValue = "<no value>"
+
+// Data 模板可用的数据
+type Data struct {
+	Task        *models.Task
+	Run         Run
+	Trigger     models.TriggerInfo
+	Steps       map[string]Step
+	Params      map[string]interface{} // 渲染前的原始参数
+	Env         map[string]string      // 任务配置的环境变量
+	Vars        map[string]interface{} // 工作流运行变量
+	Loop        *Loop                  // 循环步骤当前的迭代，其余情况为nil
+	ExecutionID string
+	Now         time.Time
+}
+
+// Run 本次运行的信息
+type Run struct {
+	ID            string
+	ScheduledTime time.Time // 计划运行时间，手动触发时为开始时间
+	StartedAt     time.Time
+	Attempt       int // 第几次尝试，从1开始
+}
+
+// Loop 循环步骤当前迭代的信息
+type Loop struct {
+	Index int                    // 从0开始
+	Item  interface{}            // for-each的当前元素
+	Last  map[string]interface{} // 上一次迭代结果的data
+}
+
+// Step 已完成步骤的信息
+type Step struct {
+	Status  string
+	Output  map[string]interface{}
+	Result  map[string]interface{} // 运行器数据加上success、exit_code和测试统计，供条件使用
+	Outputs map[string]interface{} // 步骤声明的输出
+	Error   string
+}
+
+// NewData 为任务的一次运行构建模板数据
+func NewData(task *models.Task, executionID string, startedAt time.Time, trigger models.TriggerInfo) *Data {
+	run := Run{
+		ID:            executionID,
+		ScheduledTime: startedAt,
+		StartedAt:     startedAt,
+		Attempt:       1,
+	}
+	if trigger.ScheduledTime != nil {
+		run.ScheduledTime = *trigger.ScheduledTime
+	}
+	if trigger.Payload == nil {
+		trigger.Payload = map[string]interface{}{}
+	}
+
+	data := &Data{
+		Task:        task,
+		Run:         run,
+		Trigger:     trigger,
+		Steps:       map[string]Step{},
+		ExecutionID: executionID,
+		Now:         startedAt,
+	}
+	if task != nil {
+		data.Params = task.AgentConfig.Parameters
+		data.Env = task.Environment.EnvironmentVars
+	}
+	return data
+}
+
+// Render 渲染单个模板字符串，不含模板语法的字符串原样返回
+// strict为true时引用不存在的键会返回错误，否则渲染为空
+func Render(name, text string, data *Data, strict bool) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	missingKey := "missingkey=default"
+	if strict {
+		missingKey = "missingkey=error"
+	}
+	tmpl, err := template.New(name).Option(missingKey).Funcs(funcMap(data, strict)).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	if strict {
+		return out.String(), nil
+	}
+	return strings.ReplaceAll(out.String(), noValue, ""), nil
+}
+
+// RenderParams 递归渲染参数中的所有字符串，返回新的参数映射
+func RenderParams(params map[string]interface{}, data *Data, strict bool) (map[string]interface{}, error) {
+	if params == nil {
+		return nil, nil
+	}
+	rendered, err := renderValue("parameters", params, data, strict)
+	if err != nil {
+		return nil, err
+	}
+	return rendered.(map[string]interface{}), nil
+}
+
+func renderValue(path string, value interface{}, data *Data, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return Render(path, v, data, strict)
+	case map[string]interface{}:
+		// 按键排序，保证错误信息稳定
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		out := make(map[string]interface{}, len(v))
+		for _, key := range keys {
+			rendered, err := renderValue(path+"."+key, v[key], data, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+	case primitive.A:
+		return renderValue(path, []interface{}(v), data, strict)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := renderValue(path+"["+strconv.Itoa(i)+"]", item, data, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			rendered, err := Render(path+"["+strconv.Itoa(i)+"]", item, data, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// processEnvAllowlist 模板可以读取的服务进程环境变量，其他变量（密钥、令牌、数据库地址等）不对模板开放
+var processEnvAllowlist = map[string]bool{
+	"HOME": true,
+	"PATH": true,
+	"LANG": true,
+	"TZ":   true,
+}
+
+// funcMap 模板函数
+func funcMap(data *Data, strict bool) template.FuncMap {
+	return template.FuncMap{
+		// env 读取任务配置的环境变量，其次只读取白名单中的服务进程环境变量；
+		// 其他变量渲染为空，严格模式下报错
+		"env": func(key string) (string, error) {
+			if value, ok := data.Env[key]; ok {
+				return value, nil
+			}
+			if processEnvAllowlist[key] {
+				return os.Getenv(key), nil
+			}
+			if strict {
+				return "", fmt.Errorf("env %q is not set in the task environment", key)
+			}
+			return "", nil
+		},
+		"date":    formatDate,
+		"default": defaultValue,
+		"json":    toJSON,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"now":     time.Now,
+	}
+}
+
+// formatDate 按Go时间格式格式化时间，支持time.Time、RFC3339字符串和Unix秒
+func formatDate(layout string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		return t.Format(layout), nil
+	case int:
+		return time.Unix(int64(v), 0).Format(layout), nil
+	case int64:
+		return time.Unix(v, 0).Format(layout), nil
+	case float64:
+		return time.Unix(int64(v), 0).Format(layout), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("date: unsupported value of type %T", value)
+	}
+}
+
+// defaultValue 值为空时使用默认值，用法 {{ .Trigger.Payload.ref | default "main" }}
+func defaultValue(def, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	}
+	return value
+}
+
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
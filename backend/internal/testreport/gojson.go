@@ -0,0 +1,112 @@
+/**
+ * go test -json 解析
+ */
+
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+// packageTestName 包级别失败（如编译失败）时记录的测试名称
+const packageTestName = "(package)"
+
+// goTestEvent go test -json 输出的事件
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// parseGoJSON 解析 go test -json 的输出，非JSON行（如混入的stderr）被忽略
+func parseGoJSON(data []byte) ([]models.TestResult, error) {
+	var results []models.TestResult
+	outputs := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool) // 包 -> 是否有测试失败
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var event goTestEvent
+		if err := json.Unmarshal(line, &event); err != nil || event.Action == "" {
+			continue
+		}
+
+		key := event.Package + "\x00" + event.Test
+		switch event.Action {
+		case "output":
+			if isGoTestMarker(event.Output) {
+				continue
+			}
+			output, ok := outputs[key]
+			if !ok {
+				output = &strings.Builder{}
+				outputs[key] = output
+			}
+			output.WriteString(event.Output)
+		case "pass", "fail", "skip":
+			status := goTestStatus(event.Action)
+			if event.Test == "" {
+				// 包级别的结果，只在包失败且没有具体测试失败时单独记录
+				if status != models.TestStatusFailed || failedTests[event.Package] {
+					continue
+				}
+			} else if status == models.TestStatusFailed {
+				failedTests[event.Package] = true
+			}
+
+			name := event.Test
+			if name == "" {
+				name = packageTestName
+			}
+			result := models.TestResult{
+				Suite:    event.Package,
+				Name:     name,
+				Status:   status,
+				Duration: int64(event.Elapsed * 1000),
+			}
+			if status != models.TestStatusPassed {
+				if output, ok := outputs[key]; ok {
+					result.Message = output.String()
+				}
+			}
+			results = append(results, result)
+			delete(outputs, key)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// goTestStatus 将事件动作转换为测试结果
+func goTestStatus(action string) models.TestStatus {
+	switch action {
+	case "pass":
+		return models.TestStatusPassed
+	case "skip":
+		return models.TestStatusSkipped
+	default:
+		return models.TestStatusFailed
+	}
+}
+
+// isGoTestMarker 判断输出是否为go test自身的进度行
+func isGoTestMarker(output string) bool {
+	trimmed := strings.TrimSpace(output)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS", "--- FAIL", "--- SKIP"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+/**
+ * JUnit XML 解析
+ * 根元素可以是 testsuites 或 testsuite，testsuite 可以嵌套
+ */
+
+package testreport
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+// junitSuite testsuite或testsuites元素
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+// junitCase testcase元素
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+// junitMessage failure、error或skipped元素
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit 解析JUnit XML报告
+func parseJUnit(data []byte) ([]models.TestResult, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var results []models.TestResult
+	var walk func(suite junitSuite)
+	walk = func(suite junitSuite) {
+		for _, testCase := range suite.Cases {
+			result := models.TestResult{
+				Suite:    testCase.Classname,
+				Name:     testCase.Name,
+				Status:   models.TestStatusPassed,
+				Duration: parseSeconds(testCase.Time),
+			}
+			if result.Suite == "" {
+				result.Suite = suite.Name
+			}
+
+			switch {
+			case testCase.Failure != nil:
+				result.Status = models.TestStatusFailed
+				result.Message = testCase.Failure.text()
+			case testCase.Error != nil:
+				result.Status = models.TestStatusFailed
+				result.Message = testCase.Error.text()
+			case testCase.Skipped != nil:
+				result.Status = models.TestStatusSkipped
+				result.Message = testCase.Skipped.text()
+			}
+			results = append(results, result)
+		}
+		for _, child := range suite.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+	return results, nil
+}
+
+// text 合并message属性和元素内容
+func (m *junitMessage) text() string {
+	text := strings.TrimSpace(m.Text)
+	switch {
+	case m.Message == "":
+		return text
+	case text == "" || strings.Contains(text, m.Message):
+		return m.Message
+	default:
+		return m.Message + "\n" + text
+	}
+}
+
+// parseSeconds 把秒数（可能带千位分隔符）转换为毫秒
+func parseSeconds(value string) int64 {
+	seconds, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return int64(seconds * 1000)
+}
@@ -0,0 +1,95 @@
+/**
+ * TAP 解析
+ * 只解析顶层测试行，缩进的子测试由其父测试的结果代表
+ */
+
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+var (
+	// tapLine 测试行：ok/not ok、可选编号、描述和指令
+	tapLine = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(.*))?$`)
+	// tapDuration YAML诊断块中的耗时
+	tapDuration = regexp.MustCompile(`^\s*duration_ms:\s*([\d.]+)`)
+)
+
+// parseTAP 解析TAP输出
+func parseTAP(data []byte) ([]models.TestResult, error) {
+	var results []models.TestResult
+	var diagnostic *strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// 上一个测试的YAML诊断块
+		if diagnostic != nil {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "..." {
+				last := &results[len(results)-1]
+				if last.Status != models.TestStatusPassed && last.Message == "" {
+					last.Message = diagnostic.String()
+				}
+				diagnostic = nil
+				continue
+			}
+			if match := tapDuration.FindStringSubmatch(line); match != nil {
+				if ms, err := strconv.ParseFloat(match[1], 64); err == nil {
+					results[len(results)-1].Duration = int64(ms)
+				}
+			}
+			diagnostic.WriteString(trimmed)
+			diagnostic.WriteByte('\n')
+			continue
+		}
+		if strings.TrimSpace(line) == "---" && len(results) > 0 {
+			diagnostic = &strings.Builder{}
+			continue
+		}
+
+		if strings.HasPrefix(line, "Bail out!") {
+			results = append(results, models.TestResult{
+				Name:    "Bail out!",
+				Status:  models.TestStatusFailed,
+				Message: strings.TrimSpace(strings.TrimPrefix(line, "Bail out!")),
+			})
+			continue
+		}
+
+		match := tapLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		result := models.TestResult{
+			Name:   strings.TrimSpace(match[3]),
+			Status: models.TestStatusPassed,
+		}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("test %s", match[2])
+		}
+		if match[1] != "" {
+			result.Status = models.TestStatusFailed
+		}
+
+		// SKIP的测试记为跳过，TODO的测试失败不算失败
+		directive := strings.TrimSpace(match[4])
+		upper := strings.ToUpper(directive)
+		if strings.HasPrefix(upper, "SKIP") || (strings.HasPrefix(upper, "TODO") && result.Status == models.TestStatusFailed) {
+			result.Status = models.TestStatusSkipped
+			result.Message = directive
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,198 @@
+/**
+ * 测试报告解析
+ * 支持 go test -json、JUnit XML 和 TAP 三种格式，并提供两次执行之间的结果对比
+ */
+
+package testreport
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aischedule/internal/models"
+)
+
+// 支持的报告格式
+const (
+	FormatAuto   = "auto"
+	FormatGoJSON = "go_json"
+	FormatJUnit  = "junit"
+	FormatTAP    = "tap"
+)
+
+const (
+	// MaxResults 报告中保留的测试结果数量上限
+	MaxResults = 5000
+	// maxMessageLength 单条失败信息的最大长度
+	maxMessageLength = 4096
+)
+
+// parsers 各格式的解析函数
+var parsers = map[string]func(data []byte) ([]models.TestResult, error){
+	FormatGoJSON: parseGoJSON,
+	FormatJUnit:  parseJUnit,
+	FormatTAP:    parseTAP,
+}
+
+// ValidateFormat 校验报告格式
+func ValidateFormat(format string) error {
+	if format == "" || format == FormatAuto {
+		return nil
+	}
+	if _, ok := parsers[format]; !ok {
+		return fmt.Errorf("unsupported test report format: %s", format)
+	}
+	return nil
+}
+
+// Detect 根据内容推断报告格式
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return FormatJUnit
+	case bytes.Contains(trimmed, []byte(`"Action"`)):
+		return FormatGoJSON
+	default:
+		return FormatTAP
+	}
+}
+
+// Parse 解析测试报告，format为空或auto时自动推断
+func Parse(format string, data []byte) (*models.TestReport, error) {
+	if format == "" || format == FormatAuto {
+		format = Detect(data)
+	}
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported test report format: %s", format)
+	}
+
+	results, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s test report: %w", format, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no test results found in %s report", format)
+	}
+	return Build(format, results), nil
+}
+
+// Build 汇总测试结果生成报告，结果超过上限时优先保留失败的测试
+func Build(format string, results []models.TestResult) *models.TestReport {
+	report := &models.TestReport{Format: format}
+	for i := range results {
+		results[i].Message = truncate(results[i].Message)
+		report.Total++
+		report.Duration += results[i].Duration
+		switch results[i].Status {
+		case models.TestStatusPassed:
+			report.Passed++
+		case models.TestStatusFailed:
+			report.Failed++
+		case models.TestStatusSkipped:
+			report.Skipped++
+		}
+	}
+
+	if len(results) > MaxResults {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Status == models.TestStatusFailed && results[j].Status != models.TestStatusFailed
+		})
+		results = results[:MaxResults]
+		report.Truncated = true
+	}
+	report.Results = results
+	return report
+}
+
+// Merge 合并多个报告（例如多个JUnit文件）
+func Merge(reports []*models.TestReport) *models.TestReport {
+	if len(reports) == 1 {
+		return reports[0]
+	}
+	var results []models.TestResult
+	for _, report := range reports {
+		results = append(results, report.Results...)
+	}
+	merged := Build(reports[0].Format, results)
+
+	// 单个报告可能已被截断，计数以各报告的汇总为准
+	merged.Total, merged.Passed, merged.Failed, merged.Skipped, merged.Duration = 0, 0, 0, 0, 0
+	for _, report := range reports {
+		merged.Total += report.Total
+		merged.Passed += report.Passed
+		merged.Failed += report.Failed
+		merged.Skipped += report.Skipped
+		merged.Duration += report.Duration
+		merged.Truncated = merged.Truncated || report.Truncated
+	}
+	return merged
+}
+
+// Key 测试的唯一标识，用于跨执行对比
+func Key(result models.TestResult) string {
+	if result.Suite == "" {
+		return result.Name
+	}
+	return result.Suite + "/" + result.Name
+}
+
+// Compare 对比两次执行的测试结果
+func Compare(base, head *models.TestReport) models.TestComparison {
+	comparison := models.TestComparison{
+		NewFailures:  []models.TestResult{},
+		Fixed:        []models.TestResult{},
+		StillFailing: []models.TestResult{},
+		Added:        []models.TestResult{},
+		Removed:      []models.TestResult{},
+	}
+
+	previous := make(map[string]models.TestResult)
+	if base != nil {
+		for _, result := range base.Results {
+			previous[Key(result)] = result
+		}
+	}
+
+	seen := make(map[string]bool)
+	if head != nil {
+		for _, result := range head.Results {
+			key := Key(result)
+			seen[key] = true
+			before, existed := previous[key]
+			if !existed {
+				comparison.Added = append(comparison.Added, result)
+			}
+
+			switch {
+			case result.Status == models.TestStatusFailed && existed && before.Status == models.TestStatusFailed:
+				comparison.StillFailing = append(comparison.StillFailing, result)
+			case result.Status == models.TestStatusFailed:
+				comparison.NewFailures = append(comparison.NewFailures, result)
+			case result.Status == models.TestStatusPassed && existed && before.Status == models.TestStatusFailed:
+				comparison.Fixed = append(comparison.Fixed, result)
+			}
+		}
+	}
+
+	if base != nil {
+		for _, result := range base.Results {
+			if !seen[Key(result)] {
+				comparison.Removed = append(comparison.Removed, result)
+			}
+		}
+	}
+	return comparison
+}
+
+// truncate 截断过长的失败信息
+func truncate(message string) string {
+	message = strings.TrimSpace(message)
+	if len(message) <= maxMessageLength {
+		return message
+	}
+	return strings.ToValidUTF8(message[:maxMessageLength], "") + "...(truncated)"
+}
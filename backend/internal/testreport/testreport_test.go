@@ -0,0 +1,224 @@
+package testreport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aischedule/internal/models"
+)
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file    string
+		format  string
+		want    models.TestReport
+		results []models.TestResult
+	}{
+		{
+			file:   "junit.xml",
+			format: FormatJUnit,
+			want:   models.TestReport{Format: FormatJUnit, Total: 5, Passed: 2, Failed: 2, Skipped: 1, Duration: 1203015},
+			results: []models.TestResult{
+				{Suite: "com.example.CalculatorTest", Name: "adds", Status: models.TestStatusPassed, Duration: 12},
+				{Suite: "com.example.CalculatorTest", Name: "divides", Status: models.TestStatusFailed, Duration: 3,
+					Message: "expected 2 but was 3"},
+				{Suite: "com.example.CalculatorTest", Name: "overflows", Status: models.TestStatusFailed, Duration: 1200500,
+					Message: "NullPointerException\nat com.example.Calculator.multiply(Calculator.java:40)"},
+				{Suite: "integration.db", Name: "migrates", Status: models.TestStatusPassed, Duration: 2500},
+				{Suite: "integration.db", Name: "seeds", Status: models.TestStatusSkipped, Message: "database not available"},
+			},
+		},
+		{
+			file:   "gotest.json",
+			format: FormatGoJSON,
+			want:   models.TestReport{Format: FormatGoJSON, Total: 5, Passed: 2, Failed: 2, Skipped: 1, Duration: 262},
+			results: []models.TestResult{
+				{Suite: "example.com/calc", Name: "TestAdd", Status: models.TestStatusPassed, Duration: 10},
+				{Suite: "example.com/calc", Name: "TestDivide", Status: models.TestStatusFailed, Duration: 250,
+					Message: "calc_test.go:21: expected 2, got 3"},
+				{Suite: "example.com/calc", Name: "TestSlow", Status: models.TestStatusSkipped,
+					Message: "calc_test.go:30: skipping in short mode"},
+				{Suite: "example.com/broken", Name: packageTestName, Status: models.TestStatusFailed,
+					Message: "# example.com/broken\nbroken.go:3:1: syntax error: unexpected }\nFAIL\texample.com/broken [build failed]"},
+				{Suite: "example.com/util", Name: "TestTrim", Status: models.TestStatusPassed, Duration: 2},
+			},
+		},
+		{
+			file:   "tap.txt",
+			format: FormatTAP,
+			want:   models.TestReport{Format: FormatTAP, Total: 7, Passed: 2, Failed: 3, Skipped: 2, Duration: 120},
+			results: []models.TestResult{
+				{Name: "parses config", Status: models.TestStatusPassed},
+				{Name: "connects to database", Status: models.TestStatusFailed, Duration: 120,
+					Message: "message: connection refused\nduration_ms: 120.5"},
+				{Name: "uploads artifacts", Status: models.TestStatusSkipped, Message: "SKIP no credentials"},
+				{Name: "retries on timeout", Status: models.TestStatusSkipped, Message: "TODO not implemented"},
+				{Name: "test 5", Status: models.TestStatusPassed},
+				{Name: "cleans up", Status: models.TestStatusFailed},
+				{Name: "Bail out!", Status: models.TestStatusFailed, Message: "disk full"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Detect(data); got != tt.format {
+				t.Errorf("Detect: got %s, want %s", got, tt.format)
+			}
+
+			for _, format := range []string{FormatAuto, tt.format} {
+				report, err := Parse(format, data)
+				if err != nil {
+					t.Fatalf("Parse(%s): %v", format, err)
+				}
+				results := report.Results
+				report.Results = nil
+				if !reflect.DeepEqual(*report, tt.want) {
+					t.Errorf("Parse(%s) summary: got %+v, want %+v", format, *report, tt.want)
+				}
+				if !reflect.DeepEqual(results, tt.results) {
+					t.Errorf("Parse(%s) results:\ngot  %+v\nwant %+v", format, results, tt.results)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+		want   string
+	}{
+		{"nunit", "<test-run/>", "unsupported test report format: nunit"},
+		{FormatJUnit, "<testsuite><testcase>", "failed to parse junit test report: XML syntax error"},
+		{FormatJUnit, "<testsuites></testsuites>", "no test results found in junit report"},
+		{FormatGoJSON, "not json\n{\"broken\"\n", "no test results found in go_json report"},
+		{FormatAuto, "1..0 # nothing to run\n", "no test results found in tap report"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.want, func(t *testing.T) {
+			_, err := Parse(tt.format, []byte(tt.data))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{"", FormatAuto, FormatGoJSON, FormatJUnit, FormatTAP} {
+		if err := ValidateFormat(format); err != nil {
+			t.Errorf("ValidateFormat(%q): %v", format, err)
+		}
+	}
+	if err := ValidateFormat("xunit"); err == nil {
+		t.Error("ValidateFormat(xunit): expected error")
+	}
+}
+
+func TestBuildTruncates(t *testing.T) {
+	results := make([]models.TestResult, MaxResults+10)
+	for i := range results {
+		results[i] = models.TestResult{Name: "passing", Status: models.TestStatusPassed, Duration: 1}
+	}
+	results[len(results)-1] = models.TestResult{
+		Name:    "failing",
+		Status:  models.TestStatusFailed,
+		Message: strings.Repeat("x", maxMessageLength+10),
+	}
+
+	report := Build(FormatGoJSON, results)
+	if !report.Truncated || len(report.Results) != MaxResults {
+		t.Fatalf("got truncated=%v with %d results", report.Truncated, len(report.Results))
+	}
+	if report.Total != MaxResults+10 || report.Passed != MaxResults+9 || report.Failed != 1 || report.Duration != MaxResults+9 {
+		t.Errorf("counts: %+v", *report)
+	}
+	first := report.Results[0]
+	if first.Name != "failing" {
+		t.Errorf("failed test should be kept first, got %s", first.Name)
+	}
+	if !strings.HasSuffix(first.Message, "...(truncated)") || len(first.Message) != maxMessageLength+len("...(truncated)") {
+		t.Errorf("message not truncated: %d bytes", len(first.Message))
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := Build(FormatJUnit, []models.TestResult{
+		{Suite: "a", Name: "one", Status: models.TestStatusPassed, Duration: 5},
+	})
+	b := Build(FormatJUnit, []models.TestResult{
+		{Suite: "b", Name: "two", Status: models.TestStatusFailed, Duration: 7},
+		{Suite: "b", Name: "three", Status: models.TestStatusSkipped},
+	})
+	b.Truncated = true
+
+	merged := Merge([]*models.TestReport{a, b})
+	if merged.Total != 3 || merged.Passed != 1 || merged.Failed != 1 || merged.Skipped != 1 || merged.Duration != 12 {
+		t.Errorf("counts: %+v", *merged)
+	}
+	if !merged.Truncated || len(merged.Results) != 3 {
+		t.Errorf("got truncated=%v with %d results", merged.Truncated, len(merged.Results))
+	}
+	if single := Merge([]*models.TestReport{a}); single != a {
+		t.Error("merging one report should return it unchanged")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	result := func(name string, status models.TestStatus) models.TestResult {
+		return models.TestResult{Suite: "pkg", Name: name, Status: status}
+	}
+	base := &models.TestReport{Results: []models.TestResult{
+		result("still", models.TestStatusFailed),
+		result("fixed", models.TestStatusFailed),
+		result("regressed", models.TestStatusPassed),
+		result("stable", models.TestStatusPassed),
+		result("removed", models.TestStatusPassed),
+	}}
+	head := &models.TestReport{Results: []models.TestResult{
+		result("still", models.TestStatusFailed),
+		result("fixed", models.TestStatusPassed),
+		result("regressed", models.TestStatusFailed),
+		result("stable", models.TestStatusPassed),
+		result("added", models.TestStatusFailed),
+	}}
+
+	names := func(results []models.TestResult) []string {
+		out := []string{}
+		for _, r := range results {
+			out = append(out, Key(r))
+		}
+		return out
+	}
+	comparison := Compare(base, head)
+	tests := []struct {
+		name string
+		got  []models.TestResult
+		want []string
+	}{
+		{"new failures", comparison.NewFailures, []string{"pkg/regressed", "pkg/added"}},
+		{"fixed", comparison.Fixed, []string{"pkg/fixed"}},
+		{"still failing", comparison.StillFailing, []string{"pkg/still"}},
+		{"added", comparison.Added, []string{"pkg/added"}},
+		{"removed", comparison.Removed, []string{"pkg/removed"}},
+	}
+	for _, tt := range tests {
+		if got := names(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 没有上次的报告时全部为新增
+	comparison = Compare(nil, head)
+	if len(comparison.Added) != len(head.Results) || len(comparison.NewFailures) != 3 || len(comparison.Removed) != 0 {
+		t.Errorf("without base: %+v", comparison)
+	}
+}
@@ -40,6 +40,10 @@ type Client struct {
 	Topics map[string]bool // 订阅的主题
 }
 
+// Listener 进程内的消息监听器，与WebSocket客户端收到相同的消息，广播消息的topic为空
+// 监听器在发送消息的协程中同步调用，不能阻塞
+type Listener func(topic string, message Message)
+
 // Manager WebSocket管理器
 type Manager struct {
 	clients    map[string]*Client
@@ -48,6 +52,10 @@ type Manager struct {
 	broadcast  chan Message
 	mutex      sync.RWMutex
 	upgrader   websocket.Upgrader
+
+	listeners      map[int]Listener
+	nextListenerID int
+	listenerMutex  sync.RWMutex
 }
 
 // NewManager 创建新的WebSocket管理器
@@ -57,6 +65,7 @@ func NewManager() *Manager {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan Message),
+		listeners:  make(map[int]Listener),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				// 在生产环境中应该检查Origin
@@ -202,6 +211,8 @@ func (m *Manager) BroadcastMessage(msgType MessageType, data interface{}) {
 		Data:      data,
 	}
 	
+	m.notifyListeners("", message)
+
 	select {
 	case m.broadcast <- message:
 	default:
@@ -240,6 +251,8 @@ func (m *Manager) SendToTopic(topic string, msgType MessageType, data interface{
 		Data:      data,
 	}
 
+	m.notifyListeners(topic, message)
+
 	m.mutex.RLock()
 	for _, client := range m.clients {
 		if client.Topics[topic] {
@@ -253,6 +266,30 @@ func (m *Manager) SendToTopic(topic string, msgType MessageType, data interface{
 	m.mutex.RUnlock()
 }
 
+// AddListener 添加消息监听器，返回移除监听器的函数
+func (m *Manager) AddListener(listener Listener) func() {
+	m.listenerMutex.Lock()
+	id := m.nextListenerID
+	m.nextListenerID++
+	m.listeners[id] = listener
+	m.listenerMutex.Unlock()
+
+	return func() {
+		m.listenerMutex.Lock()
+		delete(m.listeners, id)
+		m.listenerMutex.Unlock()
+	}
+}
+
+// notifyListeners 把消息交给所有监听器
+func (m *Manager) notifyListeners(topic string, message Message) {
+	m.listenerMutex.RLock()
+	defer m.listenerMutex.RUnlock()
+	for _, listener := range m.listeners {
+		listener(topic, message)
+	}
+}
+
 // GetConnectedClients 获取连接的客户端数量
 func (m *Manager) GetConnectedClients() int {
 	m.mutex.RLock()
@@ -0,0 +1,290 @@
+/**
+ * Git工作区
+ * 为每次执行准备独立的仓库检出目录，源仓库以镜像形式缓存，执行结束后清理检出目录
+ */
+
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"aischedule/internal/models"
+)
+
+// ErrInvalidSpec 工作区配置错误
+var ErrInvalidSpec = errors.New("invalid workspace spec")
+
+// shaPattern 完整的提交SHA
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Logger 准备过程的日志输出
+type Logger func(format string, args ...interface{})
+
+// Workspace 一次执行的检出目录
+type Workspace struct {
+	Dir    string
+	Commit string
+	Ref    string
+
+	manager *Manager
+	cache   string
+	shallow bool
+}
+
+// Release 删除检出目录
+func (w *Workspace) Release() error {
+	w.manager.mutex.Lock()
+	delete(w.manager.active, w.Dir)
+	w.manager.mutex.Unlock()
+	return os.RemoveAll(w.Dir)
+}
+
+// Fetch 把镜像中的ref（分支、标签或提交）连同历史拉取到检出目录，返回提交SHA；
+// 用于比较检出提交与其他版本，浅检出的目录会先补全历史
+func (w *Workspace) Fetch(ctx context.Context, ref string) (string, error) {
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("%w: ref must not start with '-'", ErrInvalidSpec)
+	}
+	commit, err := w.manager.resolve(ctx, w.cache, ref)
+	if err != nil {
+		return "", err
+	}
+
+	source, err := filepath.Abs(w.cache)
+	if err != nil {
+		return "", err
+	}
+	fetch := []string{"fetch", "--quiet", "--no-tags"}
+	if w.shallow {
+		fetch = append(fetch, "--unshallow")
+	}
+	if _, err := git(ctx, w.Dir, append(fetch, source, commit)...); err != nil {
+		return "", err
+	}
+	w.shallow = false
+	return commit, nil
+}
+
+// Manager 工作区管理器，目录结构为 <root>/cache/<仓库哈希>.git 和 <root>/runs/<执行ID>
+type Manager struct {
+	root   string
+	mutex  sync.Mutex
+	locks  map[string]*sync.Mutex // 镜像目录 -> 更新锁
+	active map[string]bool        // 使用中的检出目录
+}
+
+// NewManager 创建工作区管理器
+func NewManager(root string) *Manager {
+	return &Manager{
+		root:   root,
+		locks:  make(map[string]*sync.Mutex),
+		active: make(map[string]bool),
+	}
+}
+
+// Validate 校验工作区配置
+func Validate(spec *models.WorkspaceSpec) error {
+	if strings.TrimSpace(spec.Repo) == "" {
+		return fmt.Errorf("%w: repo is required", ErrInvalidSpec)
+	}
+	if strings.HasPrefix(spec.Ref, "-") || strings.HasPrefix(spec.Repo, "-") {
+		return fmt.Errorf("%w: repo and ref must not start with '-'", ErrInvalidSpec)
+	}
+	for _, path := range spec.Sparse {
+		if path == "" || strings.HasPrefix(path, "-") || filepath.IsAbs(path) ||
+			strings.Contains(filepath.ToSlash(path), "..") {
+			return fmt.Errorf("%w: invalid sparse path %q", ErrInvalidSpec, path)
+		}
+	}
+	return nil
+}
+
+// Prepare 为执行检出仓库：先更新缓存的镜像，解析出提交，再在执行目录中检出
+func (m *Manager) Prepare(ctx context.Context, executionID string, spec *models.WorkspaceSpec, logf Logger) (*Workspace, error) {
+	if err := Validate(spec); err != nil {
+		return nil, err
+	}
+	ref := spec.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	cache, err := m.updateCache(ctx, spec.Repo, ref, logf)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := m.resolve(ctx, cache, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(m.root, "runs", executionID)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	m.active[dir] = true
+	m.mutex.Unlock()
+
+	ws := &Workspace{Dir: dir, Commit: commit, Ref: ref, manager: m, cache: cache, shallow: spec.Shallow}
+	if err := checkout(ctx, cache, dir, commit, spec); err != nil {
+		ws.Release()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// Sweep 删除不再使用的检出目录（进程异常退出时遗留的目录）
+func (m *Manager) Sweep() error {
+	runs := filepath.Join(m.root, "runs")
+	entries, err := os.ReadDir(runs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, entry := range entries {
+		dir := filepath.Join(runs, entry.Name())
+		if m.active[dir] {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateCache 创建或更新仓库镜像。ref是缓存中已有的提交时不再拉取；
+// 拉取失败但缓存可用时继续使用缓存，以便离线运行
+func (m *Manager) updateCache(ctx context.Context, repo, ref string, logf Logger) (string, error) {
+	sum := sha256.Sum256([]byte(repo))
+	cache := filepath.Join(m.root, "cache", hex.EncodeToString(sum[:8])+".git")
+
+	lock := m.lock(cache)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(cache); os.IsNotExist(err) {
+		logf("克隆仓库 %s", repo)
+		if err := os.MkdirAll(filepath.Dir(cache), 0755); err != nil {
+			return "", err
+		}
+		tmp := cache + ".tmp"
+		os.RemoveAll(tmp)
+		if _, err := git(ctx, "", "clone", "--quiet", "--mirror", "--", repo, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+		// 允许按提交SHA浅拉取
+		if _, err := git(ctx, tmp, "config", "uploadpack.allowAnySHA1InWant", "true"); err != nil {
+			os.RemoveAll(tmp)
+			return "", err
+		}
+		if err := os.Rename(tmp, cache); err != nil {
+			return "", err
+		}
+		return cache, nil
+	}
+
+	if shaPattern.MatchString(ref) {
+		if _, err := git(ctx, cache, "cat-file", "-e", ref+"^{commit}"); err == nil {
+			return cache, nil
+		}
+	}
+
+	logf("更新仓库缓存 %s", repo)
+	if _, err := git(ctx, cache, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		logf("更新仓库缓存失败，使用已缓存的版本: %v", err)
+	}
+	return cache, nil
+}
+
+// resolve 在镜像中把ref解析为提交SHA
+func (m *Manager) resolve(ctx context.Context, cache, ref string) (string, error) {
+	out, err := git(ctx, cache, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("ref %q not found in repository", ref)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// lock 获取镜像目录的更新锁
+func (m *Manager) lock(cache string) *sync.Mutex {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	lock, ok := m.locks[cache]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[cache] = lock
+	}
+	return lock
+}
+
+// checkout 在执行目录中检出指定提交
+func checkout(ctx context.Context, cache, dir, commit string, spec *models.WorkspaceSpec) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if _, err := git(ctx, "", "init", "--quiet", dir); err != nil {
+		return err
+	}
+	if len(spec.Sparse) > 0 {
+		args := append([]string{"sparse-checkout", "set", "--"}, spec.Sparse...)
+		if _, err := git(ctx, dir, args...); err != nil {
+			return err
+		}
+	}
+
+	// 浅拉取需要file://协议，本地路径会忽略--depth
+	source, err := filepath.Abs(cache)
+	if err != nil {
+		return err
+	}
+	fetch := []string{"fetch", "--quiet", "--no-tags"}
+	if spec.Shallow {
+		fetch = append(fetch, "--depth", "1")
+		source = "file://" + filepath.ToSlash(source)
+	}
+	if _, err := git(ctx, dir, append(fetch, source, commit)...); err != nil {
+		return err
+	}
+	_, err = git(ctx, dir, "checkout", "--quiet", "--detach", commit)
+	return err
+}
+
+// git 执行git命令，失败时错误中包含stderr
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
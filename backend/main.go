@@ -19,15 +19,25 @@ import (
 
 	"aischedule/internal/config"
 	"aischedule/internal/database"
+	"aischedule/internal/executor"
 	"aischedule/internal/router"
 	"aischedule/internal/scheduler"
 	"aischedule/internal/websocket"
 )
 
 func main() {
+	// 以rlimit包装器身份启动时设置资源限制后替换为任务进程，不会返回
+	executor.RunLimitWrapper()
+
 	// 加载配置
 	cfg := config.Load()
 
+	// aischedule mcp 以stdio方式提供MCP服务，不启动HTTP服务器
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		runMCP(cfg)
+		return
+	}
+
 	// 设置Gin模式
 	gin.SetMode(cfg.GinMode)
 
@@ -38,17 +48,32 @@ func main() {
 	}
 	defer database.Disconnect()
 
+	// 初始化WebSocket管理器
+	wsManager := websocket.NewManager()
+	go wsManager.Start()
+
 	// 初始化定时任务调度器
+	mongodb := &database.MongoDB{}
+	mongodb.SetDatabase(db)
+	taskExecutor := executor.NewDefaultTaskExecutor(mongodb, wsManager, cfg)
 	taskScheduler := scheduler.New()
+	taskScheduler.SetExecutor(taskExecutor)
+	taskScheduler.SetResumer(taskExecutor)
 	taskScheduler.Start()
 	defer taskScheduler.Stop()
 
-	// 初始化WebSocket管理器
-	wsManager := websocket.NewManager()
-	go wsManager.Start()
+	// 定期清理过期的执行日志和产物
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go taskExecutor.RunRetention(retentionCtx, cfg.LogRetentionDays)
+
+	// 定期发现已登记MCP服务端的工具
+	discoveryCtx, stopDiscovery := context.WithCancel(context.Background())
+	defer stopDiscovery()
+	go taskExecutor.MCPServers().RunDiscovery(discoveryCtx, cfg.MCPDiscoveryInterval)
 
 	// 设置路由
-	r := router.Setup(db, taskScheduler, wsManager)
+	r := router.Setup(cfg, mongodb, taskScheduler, taskExecutor, wsManager)
 
 	// 创建HTTP服务器
 	srv := &http.Server{
@@ -0,0 +1,40 @@
+/**
+ * MCP子命令
+ * aischedule mcp 通过标准输入输出提供MCP服务，供本地的MCP客户端直接启动；
+ * 消息转发到正在运行的服务进程，任务由服务进程的调度器和执行器处理
+ */
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"aischedule/internal/config"
+	"aischedule/internal/mcp"
+)
+
+// runMCP 运行stdio MCP代理，标准输出只用于协议消息，日志写到标准错误
+func runMCP(cfg *config.Config) {
+	log.SetOutput(os.Stderr)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 优先使用管理员令牌，与直接访问服务时一样可以看到命中的脱敏规则
+	headers := map[string]string{}
+	if token := cfg.AdminToken; token != "" {
+		headers["Authorization"] = "Bearer " + token
+	} else if token := cfg.APIToken; token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	log.Printf("MCP stdio proxy forwarding to %s", cfg.MCPEndpoint)
+	err := mcp.ProxyStdio(ctx, mcp.ClientConfig{URL: cfg.MCPEndpoint, Headers: headers}, os.Stdin, os.Stdout)
+	if err != nil && err != context.Canceled {
+		log.Printf("MCP stdio proxy stopped: %v", err)
+	}
+}